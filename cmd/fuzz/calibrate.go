@@ -0,0 +1,188 @@
+package fuzz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/cli"
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/response"
+)
+
+// calibrationLengths are the lengths of the random values sent to find the
+// baseline. The lengths differ so that reflected values are detected.
+var calibrationLengths = []int{6, 12, 24, 36}
+
+// calibration sends requests with random values before the run and hides the
+// responses which are similar to those, e.g. the "not found" page of the
+// server. It is also a filter, which hides nothing until the baseline is
+// known.
+type calibration struct {
+	names []string
+	apply bool // use the filter, otherwise it is just suggested
+	term  cli.Terminal
+
+	done   chan struct{}
+	filter response.Filter
+}
+
+func newCalibration(names []string, apply bool, term cli.Terminal) *calibration {
+	return &calibration{
+		names: names,
+		apply: apply,
+		term:  term,
+		done:  make(chan struct{}),
+	}
+}
+
+// Reject decides if r is to be printed.
+func (c *calibration) Reject(r response.Response) bool {
+	if c.filter == nil {
+		return false
+	}
+	return c.filter.Reject(r)
+}
+
+// randomValue returns a random string of lower case letters and digits.
+func randomValue(rnd *rand.Rand, length int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	buf := make([]byte, length)
+	for i := range buf {
+		buf[i] = chars[rnd.Intn(len(chars))]
+	}
+	return string(buf)
+}
+
+// Items sends the items for the calibration, then waits until the baseline
+// is known before the items from in are forwarded.
+func (c *calibration) Items(ctx context.Context, in <-chan producer.Item) <-chan producer.Item {
+	out := make(chan producer.Item)
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	go func() {
+		defer close(out)
+
+		for _, length := range calibrationLengths {
+			item := producer.Item{Index: -1}
+			for range c.names {
+				item.Values = append(item.Values, randomValue(rnd, length))
+			}
+
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return
+		}
+
+		for item := range in {
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Responses removes the responses for the calibration from in and sets up
+// the filter.
+func (c *calibration) Responses(ctx context.Context, in <-chan response.Response) <-chan response.Response {
+	out := make(chan response.Response)
+
+	go func() {
+		defer close(out)
+
+		var responses []response.Response
+		for len(responses) < len(calibrationLengths) {
+			res, ok := <-in
+			if !ok {
+				break
+			}
+			responses = append(responses, res)
+		}
+
+		if ctx.Err() == nil {
+			c.setup(responses)
+		}
+		close(c.done)
+
+		for res := range in {
+			out <- res
+		}
+	}()
+
+	return out
+}
+
+// setup analyzes the responses and sets the filter.
+func (c *calibration) setup(responses []response.Response) {
+	baseline, err := response.NewBaseline(responses)
+	if err != nil {
+		c.term.Printf("calibration failed: %v\n", err)
+		return
+	}
+
+	flag, value, err := calibrationFilter(baseline)
+	if err != nil {
+		c.term.Printf("calibration failed: %v\n", err)
+		return
+	}
+
+	if !c.apply {
+		c.term.Printf("calibration: suggested filter %v %v\n", flag, value)
+		return
+	}
+
+	var filter response.Filter
+	switch flag {
+	case "--hide-status":
+		filter, err = response.NewFilterStatusCode([]string{value}, nil)
+	case "--hide-body-size":
+		filter, err = response.NewFilterSize(nil, []string{value})
+	case "--hide-words":
+		filter, err = response.NewFilterCount([]string{value}, nil)
+	case "--hide-lines":
+		filter, err = response.NewFilterCount(nil, []string{value})
+	}
+	if err != nil {
+		c.term.Printf("calibration failed: %v\n", err)
+		return
+	}
+
+	c.filter = filter
+	c.term.Printf("calibration: hiding responses with %v %v\n", flag, value)
+}
+
+// calibrationFilter returns the filter option which hides responses like the
+// baseline. Error status codes are hidden, for other status codes (e.g. a
+// "not found" page with the status 200) the body is compared.
+func calibrationFilter(b response.Baseline) (flag, value string, err error) {
+	switch {
+	case b.StatusCode < 0:
+		return "", "", errors.New("the status codes of the responses differ")
+	case b.StatusCode >= 400:
+		return "--hide-status", strconv.Itoa(b.StatusCode), nil
+	case b.Bytes >= 0:
+		return "--hide-body-size", strconv.Itoa(b.Bytes), nil
+	case b.Words >= 0:
+		return "--hide-words", strconv.Itoa(b.Words), nil
+	case b.Lines >= 0:
+		return "--hide-lines", strconv.Itoa(b.Lines), nil
+	}
+
+	return "", "", fmt.Errorf("the bodies of the responses (status %v) differ", b.StatusCode)
+}
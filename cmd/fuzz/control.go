@@ -0,0 +1,211 @@
+package fuzz
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/cli"
+	"github.com/RedTeamPentesting/monsoon/response"
+)
+
+// parseFilterSpec returns the filter for a spec consisting of the name of a
+// filter option and its value, e.g. "hide-status 404,500".
+func parseFilterSpec(spec string) (response.Filter, error) {
+	spec = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(spec), "--"))
+	name, value := spec, ""
+	if pos := strings.IndexAny(spec, " \t"); pos >= 0 {
+		name, value = spec[:pos], strings.TrimSpace(spec[pos+1:])
+	}
+
+	if value == "" {
+		return nil, fmt.Errorf("invalid filter %q, expected name and value", spec)
+	}
+
+	list := strings.Split(value, ",")
+
+	switch name {
+	case "hide-status", "show-status":
+		if name == "hide-status" {
+			return response.NewFilterStatusCode(list, nil)
+		}
+		return response.NewFilterStatusCode(nil, list)
+	case "hide-header-size":
+		return response.NewFilterSize(list, nil)
+	case "hide-body-size":
+		return response.NewFilterSize(nil, list)
+	case "hide-words":
+		return response.NewFilterCount(list, nil)
+	case "hide-lines":
+		return response.NewFilterCount(nil, list)
+	case "hide-time":
+		return response.NewFilterTime(list, nil)
+	case "show-time":
+		return response.NewFilterTime(nil, list)
+	case "hide-header":
+		return response.NewFilterHeader([]string{value}, nil)
+	case "show-header":
+		return response.NewFilterHeader(nil, []string{value})
+	case "hide-content-type":
+		return response.NewFilterContentType(list, nil)
+	case "show-content-type":
+		return response.NewFilterContentType(nil, list)
+	case "filter-expr":
+		return response.ParseFilterExpr(value)
+	case "hide-json", "show-json":
+		c, err := response.ParseJSONCondition(value)
+		if err != nil {
+			return nil, err
+		}
+		if name == "hide-json" {
+			return response.FilterJSON{Rejects: []*response.JSONCondition{c}}, nil
+		}
+		return response.FilterJSON{Accepts: []*response.JSONCondition{c}}, nil
+	case "hide-pattern", "show-pattern", "hide-body-pattern", "show-body-pattern", "hide-location", "show-location":
+		pattern, err := compileRegexps([]string{value})
+		if err != nil {
+			return nil, err
+		}
+
+		switch name {
+		case "hide-pattern":
+			return response.FilterRejectPattern{Pattern: pattern}, nil
+		case "show-pattern":
+			return response.FilterAcceptPattern{Pattern: pattern}, nil
+		case "hide-body-pattern":
+			return response.FilterRejectBodyPattern{Pattern: pattern}, nil
+		case "hide-location":
+			return response.FilterLocation{Rejects: pattern}, nil
+		case "show-location":
+			return response.FilterLocation{Accepts: pattern}, nil
+		default:
+			return response.FilterAcceptBodyPattern{Pattern: pattern}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown filter %q", name)
+}
+
+// controlHandler changes the filters in set: GET /filters lists them, POST
+// adds and DELETE removes the filters in the request body (one per line).
+func controlHandler(set *response.FilterSet, term cli.Terminal) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/filters", func(w http.ResponseWriter, req *http.Request) {
+		var change func(...string) error
+		var action string
+
+		switch req.Method {
+		case http.MethodGet:
+			for _, spec := range set.Specs() {
+				fmt.Fprintln(w, spec)
+			}
+			return
+		case http.MethodPost:
+			change, action = set.Add, "added"
+		case http.MethodDelete:
+			change, action = set.Remove, "removed"
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// parse all lines first so that either all filters are changed or
+		// none of them
+		var specs []string
+		sc := bufio.NewScanner(req.Body)
+		for sc.Scan() {
+			spec := strings.TrimSpace(sc.Text())
+			if spec == "" {
+				continue
+			}
+			specs = append(specs, spec)
+		}
+
+		if sc.Err() != nil {
+			http.Error(w, sc.Err().Error(), http.StatusBadRequest)
+			return
+		}
+
+		err := change(specs...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, spec := range specs {
+			term.Printf("filter %v: %v\n", action, spec)
+			fmt.Fprintf(w, "filter %v: %v\n", action, spec)
+		}
+	})
+
+	return mux
+}
+
+// serveControl listens on the Unix socket at path for requests which change
+// the filters in set until ctx is cancelled.
+func serveControl(ctx context.Context, path string, set *response.FilterSet, term cli.Terminal) error {
+	// remove the socket of a previous run
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+
+	l, err := listenPrivate(path)
+	if err != nil {
+		return fmt.Errorf("control socket: %v", err)
+	}
+
+	srv := &http.Server{Handler: controlHandler(set, term)}
+
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+		_ = os.Remove(path)
+	}()
+
+	go func() {
+		err := srv.Serve(l)
+		if err != nil && err != http.ErrServerClosed {
+			term.Printf("control socket: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// listenPrivate listens on a Unix socket at path which only the current user
+// can connect to. The socket is created with mode 0600 in a private directory
+// and then moved to path, so it is never accessible for other users.
+func listenPrivate(path string) (net.Listener, error) {
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".monsoon-control-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "socket")
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	// the socket is removed when the server is closed
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	err = os.Chmod(tmp, 0600)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
@@ -0,0 +1,65 @@
+package fuzz
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/request"
+	"github.com/RedTeamPentesting/monsoon/response"
+)
+
+// setupFeedback returns a stage which sends the values extracted from the
+// responses again.
+func setupFeedback(opts *Options, names []string) (*producer.Feedback, error) {
+	for i, name := range names {
+		if name == opts.Request.Replace {
+			return producer.NewFeedback(i), nil
+		}
+	}
+
+	return nil, fmt.Errorf("--feedback requires values for %v", opts.Request.Replace)
+}
+
+// feedbackTemplate returns the request template for the values extracted from
+// the responses, or nil if the template for the run should be used.
+func feedbackTemplate(opts *Options) *request.Request {
+	if opts.FeedbackURL == "" {
+		return nil
+	}
+
+	tmpl := *opts.Request
+	tmpl.URL = opts.FeedbackURL
+	return &tmpl
+}
+
+// feedback adds the data extracted from all shown responses to f, including
+// the values of the fields. Responses for values which have been fed back are
+// not used again.
+func feedback(in <-chan response.Response, f *producer.Feedback, fieldNames []string) <-chan response.Response {
+	out := make(chan response.Response)
+
+	go func() {
+		defer close(out)
+		for res := range in {
+			if !res.Hide && !res.Feedback {
+				values := append([]string{}, res.Extract...)
+				for _, name := range fieldNames {
+					values = append(values, res.Fields[name]...)
+				}
+
+				for _, v := range values {
+					v = strings.TrimSpace(v)
+					if v != "" {
+						f.Add(res.Values, v)
+					}
+				}
+			}
+			f.Done()
+
+			out <- res
+		}
+	}()
+
+	return out
+}
@@ -2,14 +2,18 @@ package fuzz
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +24,7 @@ import (
 	"github.com/RedTeamPentesting/monsoon/request"
 	"github.com/RedTeamPentesting/monsoon/response"
 	"github.com/RedTeamPentesting/monsoon/shell"
+	"github.com/RedTeamPentesting/monsoon/state"
 	"github.com/fd0/termstatus"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
@@ -29,34 +34,182 @@ import (
 type Options struct {
 	Range       []string
 	RangeFormat string
-	Filename    string
+	RangeBase   string
+	RangeWidth  int
+	Sitemap     bool
+	Wayback     bool
+	Conditional string
+	WaybackMax  int
+	Filenames   []string
+	Builtin     []string
+	FilePrio    []string
+	filePrio    map[string]int
+	Replace     []string
+	Methods     []string
+	ReplaceMode string
+	Command     string
+	Follow      string
+	Charset     string
+	MinLength   int
+	MaxLength   int
+	Mask        string
+	Random      string
+	DateRange   string
+	DateFormat  string
+	DateStep    string
+	IPRange     []string
+	CSV         string
+	Usernames   string
+	Subdomains  string
+	PermWords   string
+	UsernameFmt []string
+	JSONL       string
+	Split       string
+	SplitDelim  string
+	Case        []string
+	Unicode     []string
+	ValueMatch  []string
+	valueMatch  []*regexp.Regexp
+	ValueReject []string
+	valueReject []*regexp.Regexp
+	Mutate      []string
+	MutateYears []int
+	Prefix      []string
+	Suffix      []string
 	Logfile     string
 	Logdir      string
+	Resume      string
+	StateFile   string
+	Checkpoint  time.Duration
+	CheckpointN int
 	Threads     int
+	MaxPerHost  int
 
 	RequestsPerSecond float64
 
-	BufferSize int
-	Skip       int
-	Limit      int
+	Retry        int
+	RetryOn      []string
+	RetryBackoff string
 
-	Request        *request.Request // the template for the HTTP request
-	FollowRedirect int
+	Throttle    bool
+	ThrottleMax time.Duration
+
+	BufferSize  int
+	Skip        int
+	Limit       int
+	Shuffle     string
+	shuffleSeed int64
+	Shard       string
+	shard       *producer.FilterShard
+	Encode      []string
+	Transform   []string
+
+	RecursionDepth  int
+	RecursionStatus []string
+
+	Feedback    bool
+	FeedbackURL string
+
+	GraphQLBatch int
+
+	Request         *request.Request // the template for the HTTP request
+	FollowRedirects int
+	RedirectFilter  string
+
+	UserAgentFile   string
+	UserAgentRandom bool
+
+	CorrelationHeader string
+
+	ProxyFile   string
+	ProxyRandom bool
+
+	TargetFile string
+	targets    []string
+
+	CookieJar          bool
+	CookieJarPerThread bool
+
+	LoginRequest     string
+	LoginExtract     string
+	LoginJSON        string
+	LoginPlaceholder string
+	LoginExpired     []string
+
+	CSRFURL            string
+	CSRFExtract        string
+	CSRFSelector       string
+	CSRFPlaceholder    string
+	CSRFExpired        []string
+	CSRFExpiredPattern string
+
+	OAuth2TokenURL     string
+	OAuth2ClientID     string
+	OAuth2ClientSecret string
+	OAuth2Scope        string
 
 	HideStatusCodes []string
 	ShowStatusCodes []string
 	HideHeaderSize  []string
 	HideBodySize    []string
+	HideWords       []string
+	HideLines       []string
+	HideTime        []string
+	ShowTime        []string
 	HidePattern     []string
 	hidePattern     []*regexp.Regexp
 	ShowPattern     []string
 	showPattern     []*regexp.Regexp
 
+	HideHeader []string
+	ShowHeader []string
+
+	HideLocation []string
+	hideLocation []*regexp.Regexp
+	ShowLocation []string
+	showLocation []*regexp.Regexp
+
+	HideJSON   []string
+	ShowJSON   []string
+	FilterExpr []string
+
+	Calibrate        bool
+	CalibrateSuggest bool
+
+	HideSimilar     bool
+	HideSoft404     bool
+	SimilarDistance int
+
+	ControlSocket string
+
+	StopAfterMatches int
+	StopOnStatus     []string
+
+	HideContentType []string
+	ShowContentType []string
+
+	HideBodyPattern []string
+	hideBodyPattern []*regexp.Regexp
+	ShowBodyPattern []string
+	showBodyPattern []*regexp.Regexp
+
 	Extract        []string
 	extract        []*regexp.Regexp
 	ExtractPipe    []string
 	extractPipe    [][]string
+	ExtractJSON    []string
+	extractJSON    []*response.JSONPath
+	ExtractCSS     []string
+	ExtractXPath   []string
+	extractHTML    []*response.HTMLQuery
+	ExtractHeader  []string
+	extractHeader  []response.HeaderExtract
+	ExtractCookie  []string
 	BodyBufferSize int
+	SaveBodies     string
+
+	WebSocketWait     time.Duration
+	WebSocketMessages int
 }
 
 var opts Options
@@ -89,20 +242,332 @@ func splitShell(cmds []string) ([][]string, error) {
 	return data, nil
 }
 
+// sourceSpec describes a source for values.
+type sourceSpec struct {
+	typ, options string
+}
+
+func (s sourceSpec) String() string {
+	return s.typ
+}
+
+// defaultSources returns the sources specified for the default placeholder.
+func (opts *Options) defaultSources() (specs []sourceSpec) {
+	// paths discovered on the target are sent before all other values
+	if opts.Sitemap {
+		specs = append(specs, sourceSpec{"sitemap", ""})
+	}
+
+	if opts.Wayback {
+		specs = append(specs, sourceSpec{"wayback", strconv.Itoa(opts.WaybackMax)})
+	}
+
+	if opts.Conditional != "" {
+		specs = append(specs, sourceSpec{"conditional", opts.Conditional})
+	}
+
+	if len(opts.Range) > 0 {
+		specs = append(specs, sourceSpec{"range", strings.Join(opts.Range, ",")})
+	}
+
+	for _, filename := range opts.Filenames {
+		specs = append(specs, sourceSpec{"file", filename})
+	}
+
+	for _, name := range opts.Builtin {
+		specs = append(specs, sourceSpec{"builtin", name})
+	}
+
+	if opts.Command != "" {
+		specs = append(specs, sourceSpec{"cmd", opts.Command})
+	}
+
+	if opts.Follow != "" {
+		specs = append(specs, sourceSpec{"follow", opts.Follow})
+	}
+
+	if opts.Charset != "" {
+		specs = append(specs, sourceSpec{"charset", fmt.Sprintf("%d-%d:%s", opts.MinLength, opts.MaxLength, opts.Charset)})
+	}
+
+	if opts.Mask != "" {
+		specs = append(specs, sourceSpec{"mask", opts.Mask})
+	}
+
+	if opts.Random != "" {
+		specs = append(specs, sourceSpec{"random", opts.Random})
+	}
+
+	if opts.DateRange != "" {
+		specs = append(specs, sourceSpec{"dates", opts.DateRange})
+	}
+
+	if len(opts.IPRange) > 0 {
+		specs = append(specs, sourceSpec{"ips", strings.Join(opts.IPRange, ",")})
+	}
+
+	if opts.CSV != "" {
+		specs = append(specs, sourceSpec{"csv", opts.CSV})
+	}
+
+	if opts.Usernames != "" {
+		specs = append(specs, sourceSpec{"usernames", opts.Usernames})
+	}
+
+	if opts.Subdomains != "" {
+		specs = append(specs, sourceSpec{"subdomains", opts.Subdomains})
+	}
+
+	return specs
+}
+
+// unmergeableSources maps the types of sources which cannot be merged with
+// other sources to their options: the values of commands and random tokens
+// change when they are read again, charsets, masks and IP ranges may produce
+// too many values to deduplicate them. Following a file is checked
+// separately.
+var unmergeableSources = map[string]string{
+	"cmd":     "--values-from-cmd",
+	"random":  "--random",
+	"charset": "--charset",
+	"mask":    "--mask",
+	"ips":     "--ip-range",
+}
+
+// methodPlaceholder is replaced by the values passed to --methods.
+const methodPlaceholder = "FUZZ_METHOD"
+
 // valid validates the options and returns an error if something is invalid.
 func (opts *Options) valid() (err error) {
 	if opts.Threads <= 0 {
 		return errors.New("invalid number of threads")
 	}
 
-	if len(opts.Range) > 0 && opts.Filename != "" {
-		return errors.New("only one source allowed but both range and filename specified")
+	if opts.MaxPerHost < 0 {
+		return errors.New("invalid number of requests per host")
 	}
 
-	if len(opts.Range) == 0 && opts.Filename == "" {
+	if opts.Retry < 0 {
+		return errors.New("invalid number of retries")
+	}
+
+	if opts.FollowRedirects < 0 {
+		return errors.New("invalid number of redirects")
+	}
+
+	if opts.CheckpointN < 0 {
+		return errors.New("invalid number of values for --checkpoint-values")
+	}
+
+	if opts.RedirectFilter != "first" && opts.RedirectFilter != "final" {
+		return fmt.Errorf("invalid --redirect-filter %q, use first or final", opts.RedirectFilter)
+	}
+
+	if opts.ThrottleMax <= 0 {
+		return errors.New("invalid maximal pause for --throttle")
+	}
+
+	sources := opts.defaultSources()
+	if len(sources) > 1 {
+		// sources are merged and read twice, this only works for sources
+		// which produce the same values each time and do not keep all
+		// values of a huge space in memory
+		for _, spec := range sources {
+			if spec.typ == "file" && spec.options == "-" {
+				return errors.New("reading values from stdin cannot be combined with other sources")
+			}
+
+			if flag, ok := unmergeableSources[spec.typ]; ok {
+				return fmt.Errorf("%v cannot be combined with other sources", flag)
+			}
+		}
+	}
+
+	if opts.Follow != "" {
+		// the file is never read to the end
+		switch {
+		case len(sources) > 1:
+			return errors.New("--values-follow cannot be combined with other sources")
+		case opts.Shuffle != "":
+			return errors.New("--values-follow cannot be combined with --shuffle")
+		case opts.RecursionDepth > 0:
+			return errors.New("--values-follow cannot be combined with --recursion-depth")
+		}
+	}
+
+	if len(sources) == 0 && len(opts.Replace) == 0 && opts.JSONL == "" && opts.Split == "" {
 		return errors.New("neither file nor range specified, nothing to do")
 	}
 
+	if len(opts.Methods) > 0 {
+		if opts.Request.Method != "" {
+			return errors.New("--methods cannot be combined with --method")
+		}
+		opts.Request.Method = methodPlaceholder
+	}
+
+	if opts.RangeBase != "" || opts.RangeWidth != 0 {
+		if opts.RangeFormat != "%d" {
+			return errors.New("--range-format cannot be combined with --range-base or --range-width")
+		}
+
+		opts.RangeFormat, err = producer.RangeFormat(opts.RangeBase, opts.RangeWidth)
+		if err != nil {
+			return err
+		}
+	}
+
+	opts.filePrio, err = parseFilePriorities(opts.FilePrio, opts.Filenames)
+	if err != nil {
+		return err
+	}
+
+	switch opts.Shuffle {
+	case "":
+	case "random":
+		opts.shuffleSeed = time.Now().UnixNano()
+	default:
+		opts.shuffleSeed, err = strconv.ParseInt(opts.Shuffle, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid seed for --shuffle: %v", err)
+		}
+	}
+
+	if opts.Shard != "" {
+		opts.shard, err = producer.ParseShard(opts.Shard)
+		if err != nil {
+			return err
+		}
+
+		// all machines must use the same order of values
+		if opts.Shuffle == "random" {
+			return errors.New("--shard requires a seed for --shuffle")
+		}
+	}
+
+	if opts.RecursionDepth > 0 {
+		switch {
+		case opts.Shuffle != "":
+			return errors.New("--recursion-depth cannot be combined with --shuffle")
+		case opts.Resume != "":
+			return errors.New("recursive runs cannot be resumed")
+		}
+
+		for _, spec := range sources {
+			if spec.typ == "file" && spec.options == "-" {
+				return errors.New("--recursion-depth requires reading the values more than once, this does not work for stdin")
+			}
+		}
+	}
+
+	if opts.FeedbackURL != "" {
+		opts.Feedback = true
+	}
+
+	if opts.Feedback {
+		switch {
+		case len(opts.Extract) == 0 && len(opts.ExtractPipe) == 0 && len(opts.ExtractJSON) == 0 && len(opts.ExtractCSS) == 0 && len(opts.ExtractXPath) == 0 && len(opts.ExtractHeader) == 0 && len(opts.ExtractCookie) == 0:
+			return errors.New("--feedback requires --extract, --extract-json, --extract-selector, --extract-xpath, --extract-header, --extract-cookie or --extract-pipe")
+		case opts.RecursionDepth > 0:
+			return errors.New("--feedback cannot be combined with --recursion-depth")
+		case opts.Resume != "":
+			return errors.New("runs with --feedback cannot be resumed")
+		}
+	}
+
+	err = validSession(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.Request.NTLM != "" && opts.Request.Digest != "" {
+		return errors.New("--ntlm cannot be combined with --digest")
+	}
+
+	if opts.Request.AWSSign != "" && (opts.Request.NTLM != "" || opts.Request.Digest != "") {
+		return errors.New("--aws-sign cannot be combined with --ntlm or --digest")
+	}
+
+	if opts.Request.CustomWriter() && (opts.Request.NTLM != "" || opts.Request.ForceHTTP2) {
+		return errors.New("--header-order, --chunk-size, --chunk-extension, --http1.0 and --minimal cannot be combined with --ntlm or --http2")
+	}
+
+	if opts.TargetFile != "" {
+		switch {
+		case opts.RecursionDepth > 0:
+			return errors.New("--target-file cannot be combined with --recursion-depth")
+		case opts.Feedback:
+			return errors.New("--target-file cannot be combined with --feedback")
+		}
+	}
+
+	if opts.UserAgentRandom && opts.UserAgentFile == "" {
+		return errors.New("--user-agent-random requires --user-agent-file")
+	}
+
+	if strings.ContainsAny(opts.CorrelationHeader, ": \t\r\n") {
+		return fmt.Errorf("invalid header name %q for --correlation-header", opts.CorrelationHeader)
+	}
+
+	if opts.ProxyRandom && opts.ProxyFile == "" {
+		return errors.New("--proxy-random requires --proxy-file")
+	}
+
+	if opts.ProxyFile != "" {
+		switch {
+		case opts.Request.Proxy != "":
+			return errors.New("--proxy-file cannot be combined with --proxy")
+		case opts.Request.SOCKS5 != "" || opts.Request.ConnectTo != "" || opts.Request.UnixSocket != "":
+			return errors.New("--proxy-file cannot be combined with --socks5, --connect-to or --unix-socket")
+		}
+	}
+
+	if opts.Request.AWSCredentials != "" && opts.Request.AWSSign == "" {
+		return errors.New("--aws-credentials requires --aws-sign")
+	}
+
+	if opts.Calibrate || opts.CalibrateSuggest {
+		switch {
+		case opts.Calibrate && opts.CalibrateSuggest:
+			return errors.New("--calibrate cannot be combined with --calibrate-suggest")
+		case opts.TargetFile != "":
+			return errors.New("--calibrate cannot be combined with --target-file")
+		case opts.GraphQLBatch > 1:
+			return errors.New("--calibrate cannot be combined with --graphql-batch")
+		}
+	}
+
+	if opts.HideSoft404 && opts.TargetFile != "" {
+		return errors.New("--hide-soft404 cannot be combined with --target-file")
+	}
+
+	if opts.SimilarDistance < 0 || opts.SimilarDistance > 64 {
+		return errors.New("--similar-distance must be between 0 and 64")
+	}
+
+	if opts.GraphQLBatch > 1 {
+		switch {
+		case opts.Request.GraphQLQuery == "":
+			return errors.New("--graphql-batch requires --graphql-query")
+		case opts.RecursionDepth > 0:
+			return errors.New("--graphql-batch cannot be combined with --recursion-depth")
+		case opts.Feedback:
+			return errors.New("--graphql-batch cannot be combined with --feedback")
+		case opts.Resume != "":
+			return errors.New("runs with --graphql-batch cannot be resumed")
+		}
+	}
+
+	opts.valueMatch, err = compileRegexps(opts.ValueMatch)
+	if err != nil {
+		return err
+	}
+
+	opts.valueReject, err = compileRegexps(opts.ValueReject)
+	if err != nil {
+		return err
+	}
+
 	opts.extract, err = compileRegexps(opts.Extract)
 	if err != nil {
 		return err
@@ -113,6 +578,38 @@ func (opts *Options) valid() (err error) {
 		return err
 	}
 
+	for _, expr := range opts.ExtractJSON {
+		p, err := response.ParseJSONPath(expr)
+		if err != nil {
+			return err
+		}
+		opts.extractJSON = append(opts.extractJSON, p)
+	}
+
+	for _, sel := range opts.ExtractCSS {
+		q, err := response.ParseCSSSelector(sel)
+		if err != nil {
+			return err
+		}
+		opts.extractHTML = append(opts.extractHTML, q)
+	}
+
+	for _, expr := range opts.ExtractXPath {
+		q, err := response.ParseXPath(expr)
+		if err != nil {
+			return err
+		}
+		opts.extractHTML = append(opts.extractHTML, q)
+	}
+
+	for _, spec := range opts.ExtractHeader {
+		h, err := response.ParseHeaderExtract(spec)
+		if err != nil {
+			return err
+		}
+		opts.extractHeader = append(opts.extractHeader, h)
+	}
+
 	opts.hidePattern, err = compileRegexps(opts.HidePattern)
 	if err != nil {
 		return err
@@ -123,9 +620,66 @@ func (opts *Options) valid() (err error) {
 		return err
 	}
 
+	opts.hideLocation, err = compileRegexps(opts.HideLocation)
+	if err != nil {
+		return err
+	}
+
+	opts.showLocation, err = compileRegexps(opts.ShowLocation)
+	if err != nil {
+		return err
+	}
+
+	opts.hideBodyPattern, err = compileRegexps(opts.HideBodyPattern)
+	if err != nil {
+		return err
+	}
+
+	opts.showBodyPattern, err = compileRegexps(opts.ShowBodyPattern)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// parseFilePriorities parses the list of filename:priority pairs. All
+// filenames must also be passed via --file.
+func parseFilePriorities(list []string, filenames []string) (map[string]int, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	res := make(map[string]int, len(list))
+	for _, s := range list {
+		pos := strings.LastIndex(s, ":")
+		if pos < 0 {
+			return nil, fmt.Errorf("invalid file priority %q, expected filename:priority", s)
+		}
+
+		filename := s[:pos]
+		prio, err := strconv.Atoi(s[pos+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid file priority %q: %v", s, err)
+		}
+
+		found := false
+		for _, f := range filenames {
+			if f == filename {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("priority set for %q, but the file was not specified with --file", filename)
+		}
+
+		res[filename] = prio
+	}
+
+	return res, nil
+}
+
 var cmd = &cobra.Command{
 	Use:                   "fuzz [options] URL",
 	DisableFlagsInUseLine: true,
@@ -148,47 +702,158 @@ func AddCommand(c *cobra.Command) {
 	fs := cmd.Flags()
 	fs.SortFlags = false
 
-	fs.StringSliceVarP(&opts.Range, "range", "r", nil, "set range `from-to`")
+	fs.StringSliceVarP(&opts.Range, "range", "r", nil, "set range `from-to[/step]`")
 	fs.StringVar(&opts.RangeFormat, "range-format", "%d", "set `format` for range")
-
-	fs.StringVarP(&opts.Filename, "file", "f", "", "read values from `filename`")
+	fs.StringVar(&opts.RangeBase, "range-base", "", "format range values in `base` (dec, hex, oct, bin)")
+	fs.IntVar(&opts.RangeWidth, "range-width", 0, "pad range values with zeroes to `n` digits")
+
+	fs.BoolVar(&opts.Sitemap, "values-from-sitemap", false, "send the paths listed in robots.txt and sitemap.xml of the target first")
+	fs.StringVar(&opts.Conditional, "values-conditional", "", "use values for conditional headers derived from the `kind` of validator (etag, date) of the target URL")
+	fs.BoolVar(&opts.Wayback, "values-from-wayback", false, "send the paths of the target archived by the Wayback Machine first")
+	fs.IntVar(&opts.WaybackMax, "wayback-limit", 10000, "request at most `n` URLs from the Wayback Machine, 0 means no limit")
+	fs.StringArrayVarP(&opts.Filenames, "file", "f", nil, "read values from `filename` (can be specified multiple times, duplicate values are removed)")
+	fs.StringArrayVar(&opts.FilePrio, "file-priority", nil, "read values from the file passed to --file as `filename:n` before files with a lower priority (default 0, can be specified multiple times)")
+	fs.StringArrayVar(&opts.Builtin, "values-builtin", nil, "use the built-in wordlist `name` (common-paths, parameters, ranges, vhosts), can be specified multiple times")
+	fs.StringVar(&opts.Command, "values-from-cmd", "", "run `command` and use the lines it prints as values")
+	fs.StringVar(&opts.Follow, "values-follow", "", "read values from `filename` and wait for new lines to be appended (like tail -f) until interrupted")
+	fs.StringVar(&opts.Charset, "charset", "", "produce all strings consisting of characters from `charset` (e.g. a-z0-9)")
+	fs.IntVar(&opts.MinLength, "min-len", 1, "set minimal length `n` of strings produced for --charset")
+	fs.IntVar(&opts.MaxLength, "max-len", 4, "set maximal length `n` of strings produced for --charset")
+	fs.StringVar(&opts.Mask, "mask", "", "produce all strings matching `mask` (e.g. ?u?l?l?d?d)")
+	fs.StringVar(&opts.Random, "random", "", "produce random tokens, `num:kind[:length]` selects the number, the kind (uuid, hex, alnum) and the length (default 16), e.g. 100:uuid")
+	fs.StringVar(&opts.DateRange, "date-range", "", "produce dates between `first..last` (e.g. 2023-01-01..2023-12-31)")
+	fs.StringVar(&opts.DateFormat, "date-format", "%Y-%m-%d", "set strftime-like `format` for dates")
+	fs.StringVar(&opts.DateStep, "date-step", "1d", "set `interval` between dates (e.g. 1d, 12h)")
+	fs.StringSliceVar(&opts.IPRange, "ip-range", nil, "produce IP addresses in `range` (e.g. 10.0.0.0/24,10.0.1.5-10.0.1.20)")
+	fs.StringVar(&opts.Split, "values-split", "", "read lines from a file and split them into the values for several placeholders, `NAME,...:file` lists the placeholders (e.g. USER,PASS:creds.txt)")
+	fs.StringVar(&opts.SplitDelim, "split-delimiter", ":", "split the lines for --values-split at `string`")
+	fs.StringVar(&opts.JSONL, "values-from-jsonl", "", "read objects from a JSON lines file and bind each field to the placeholder {{.field}}, `file[:field,...]` selects the fields (default: fields of the first object)")
+	fs.StringVar(&opts.Usernames, "usernames", "", "generate user names from full names (e.g. \"John Smith\") read from `filename`")
+	fs.StringSliceVar(&opts.UsernameFmt, "username-formats", producer.DefaultUsernameFormats, "generate user names in `formats` using {first}, {last}, {f} and {l}")
+	fs.StringVar(&opts.Subdomains, "subdomains", "", "generate permutations of the subdomains read from `filename` with the words from --permutation-words")
+	fs.StringVar(&opts.PermWords, "permutation-words", "", "read words for permutations of subdomains from `filename`")
+	fs.StringVar(&opts.CSV, "values-from-csv", "", "read values from a column of a CSV file, `file:column` selects the column by number (starting at 1) or name")
+	fs.StringArrayVar(&opts.ValueMatch, "value-filter-match", nil, "only use values for FUZZ matching `regex` (can be specified multiple times)")
+	fs.StringArrayVar(&opts.ValueReject, "value-filter-reject", nil, "do not use values for FUZZ matching `regex` (can be specified multiple times)")
+	fs.StringSliceVar(&opts.Mutate, "mutate", nil, "derive password variants from each value for FUZZ by applying `rules` in order ("+strings.Join(producer.MutationRules, ",")+")")
+	year := time.Now().Year()
+	fs.IntSliceVar(&opts.MutateYears, "mutate-years", []int{year - 2, year - 1, year}, "append `years` for the mutation rule 'years'")
+	fs.StringSliceVar(&opts.Prefix, "prefix", nil, "also send each value for FUZZ with all `prefixes` (e.g. .,_)")
+	fs.StringSliceVar(&opts.Suffix, "suffix", nil, "also send each value for FUZZ with all `suffixes` (e.g. .php,.bak,.old)")
+	fs.StringSliceVar(&opts.Unicode, "unicode", nil, "send each value for FUZZ in all Unicode `variants` ("+strings.Join(producer.UnicodeVariantNames(), ",")+")")
+	fs.StringSliceVar(&opts.Case, "case", nil, "send each value for FUZZ in all `modes` (original,lower,upper,title,alternating)")
+	fs.StringSliceVar(&opts.Methods, "methods", nil, "send each request with all HTTP methods in `list` (e.g. GET,POST,PUT,FOO)")
+	fs.StringArrayVar(&opts.Replace, "replace", nil, "replace `NAME:type:options` with values from a source (file, range, cmd, charset, mask, dates, ips), can be specified multiple times")
+	fs.StringVar(&opts.ReplaceMode, "replace-mode", "clusterbomb", "combine values of several placeholders with `mode` (clusterbomb, pitchfork)")
 	fs.StringVar(&opts.Logfile, "logfile", "", "write copy of printed messages to `filename`.log")
 	fs.StringVar(&opts.Logdir, "logdir", os.Getenv("MONSOON_LOG_DIR"), "automatically log all output to files in `dir`")
+	fs.StringVar(&opts.Resume, "resume", "", "resume an interrupted run from the state saved in `filename`")
+	fs.DurationVar(&opts.Checkpoint, "checkpoint-interval", 30*time.Second, "save the state every `duration` while running, 0 disables (requires a state file)")
+	fs.IntVar(&opts.CheckpointN, "checkpoint-values", 0, "also save the state every `n` values processed while running, 0 disables (requires a state file)")
+	fs.StringVar(&opts.StateFile, "state-file", "", "save state to `filename` so the run can be resumed (default: with --logfile or --logdir, next to the log file)")
 
 	fs.IntVarP(&opts.Threads, "threads", "t", 5, "make as many as `n` parallel requests")
+	fs.IntVar(&opts.MaxPerHost, "max-per-host", 0, "make at most `n` parallel requests to each host (0 means no limit)")
 	fs.IntVar(&opts.BufferSize, "buffer-size", 100000, "set number of buffered items to `n`")
 	fs.IntVar(&opts.Skip, "skip", 0, "skip the first `n` requests")
 	fs.IntVar(&opts.Limit, "limit", 0, "only run `n` requests, then exit")
+	fs.IntVar(&opts.RecursionDepth, "recursion-depth", 0, "send the values again below discovered directories up to `n` levels deep")
+	fs.StringSliceVar(&opts.RecursionStatus, "recursion-status", nil, "also treat responses with this status `code,[code-code],[...]` as directories")
+	fs.BoolVar(&opts.Feedback, "feedback", false, "send the strings extracted from shown responses as new values")
+	fs.StringVar(&opts.FeedbackURL, "feedback-url", "", "use `url` instead of the target URL for the extracted values (implies --feedback)")
+	fs.IntVar(&opts.GraphQLBatch, "graphql-batch", 0, "send `n` values per request as aliases in the GraphQL query")
+	fs.StringVar(&opts.Shard, "shard", "", "only send every M-th value starting with the N-th, for distributing a run across machines (`N/M`)")
+	fs.StringVar(&opts.Shuffle, "shuffle", "", "randomize the order of the values, using `seed` if specified (all values are read into memory)")
+	fs.Lookup("shuffle").NoOptDefVal = "random"
+	fs.StringArrayVar(&opts.Transform, "transform", nil, "rewrite values with a Go `template`, optionally prefixed with the placeholder name and a colon (default: FUZZ, can be specified multiple times)")
+	fs.StringArrayVar(&opts.Encode, "encode", nil, "encode values with `[NAME:]encoder,...` before inserting them (can be specified multiple times)")
 	fs.Float64Var(&opts.RequestsPerSecond, "requests-per-second", 0, "do at most `n` requests per second (e.g. 0.5)")
+	fs.IntVar(&opts.Retry, "retry", 0, "send requests which failed with a transient error again up to `n` times")
+	fs.StringSliceVar(&opts.RetryOn, "retry-on", response.DefaultRetryOn, "retry requests with this status `code,[code-code],[timeout],[error],[...]`")
+	fs.StringVar(&opts.RetryBackoff, "retry-backoff", "1s..30s", "wait between retries for an exponential backoff in `min..max` (or a fixed duration)")
+	fs.BoolVar(&opts.Throttle, "throttle", false, "pause all requests when the target responds with status 429 or 503 and a Retry-After header")
+	fs.DurationVar(&opts.ThrottleMax, "throttle-max", response.DefaultThrottleMax, "pause for at most `duration` when throttled")
 
 	// add all options to define a request
 	opts.Request = request.New("")
 	request.AddFlags(opts.Request, fs)
 
-	fs.IntVar(&opts.FollowRedirect, "follow-redirect", 0, "follow `n` redirects")
+	fs.IntVar(&opts.FollowRedirects, "follow-redirect", 0, "follow `n` redirects")
+	_ = fs.MarkDeprecated("follow-redirect", "use --follow-redirects")
+	fs.IntVar(&opts.FollowRedirects, "follow-redirects", 0, "follow up to `n` redirects and record the chain")
+	fs.StringVar(&opts.RedirectFilter, "redirect-filter", "final", "apply the filters to the `first` or the `final` response of followed redirects")
+	fs.StringVar(&opts.TargetFile, "target-file", "", "send the requests to all targets (`file` with scheme://host[:port] per line), the URL may be just a path")
+	fs.StringVar(&opts.UserAgentFile, "user-agent-file", "", "send a User-Agent from `file` (one per line) with each request, in order")
+	fs.BoolVar(&opts.UserAgentRandom, "user-agent-random", false, "select the User-Agent from --user-agent-file at random")
+	fs.StringVar(&opts.CorrelationHeader, "correlation-header", "", "send a unique ID in the header field `name` with each request and record it")
+	fs.StringVar(&opts.ProxyFile, "proxy-file", "", "send the requests via the proxies from `file` (one URL per line), in order")
+	fs.BoolVar(&opts.ProxyRandom, "proxy-random", false, "select the proxy from --proxy-file at random")
+	fs.BoolVar(&opts.CookieJar, "cookie-jar", false, "store cookies set by the server and send them with subsequent requests")
+	fs.StringVar(&opts.LoginRequest, "login-request", "", "send the raw HTTP request from `file` to the target before the run to obtain a token")
+	fs.StringVar(&opts.LoginExtract, "login-extract", "", "extract the token from the login response with `regex` (first subexpression or whole match)")
+	fs.StringVar(&opts.LoginJSON, "login-json", "", "extract the token from the JSON body of the login response at JSONPath `expr` (e.g. $.data.token)")
+	fs.StringVar(&opts.LoginPlaceholder, "login-placeholder", "TOKEN", "replace `string` with the token in all requests")
+	fs.StringSliceVar(&opts.LoginExpired, "login-expired", nil, "send the login request again and retry when a response has this status `code,[code-code],[...]`")
+	fs.StringVar(&opts.CSRFURL, "csrf-url", "", "fetch a CSRF token from `url` (may be relative to the target) before the run")
+	fs.StringVar(&opts.CSRFExtract, "csrf-extract", "", "extract the CSRF token with `regex` (first subexpression or whole match)")
+	fs.StringVar(&opts.CSRFSelector, "csrf-selector", "", "extract the CSRF token from the HTML element matching `selector` (e.g. input[name=csrf])")
+	fs.StringVar(&opts.CSRFPlaceholder, "csrf-placeholder", "CSRF", "replace `string` with the CSRF token in all requests")
+	fs.StringSliceVar(&opts.CSRFExpired, "csrf-expired", nil, "fetch the CSRF token again and retry when a response has this status `code,[code-code],[...]`")
+	fs.StringVar(&opts.CSRFExpiredPattern, "csrf-expired-pattern", "", "fetch the CSRF token again and retry when a response matches `regex`")
+	fs.StringVar(&opts.OAuth2TokenURL, "oauth2-token-url", "", "fetch an OAuth2 access token from `url` (client credentials grant) and send it in the Authorization header")
+	fs.StringVar(&opts.OAuth2ClientID, "oauth2-client-id", "", "use `id` as the OAuth2 client ID")
+	fs.StringVar(&opts.OAuth2ClientSecret, "oauth2-client-secret", "", "use `secret` as the OAuth2 client secret")
+	fs.StringVar(&opts.OAuth2Scope, "oauth2-scope", "", "request the OAuth2 `scope` (space separated)")
+	fs.BoolVar(&opts.CookieJarPerThread, "cookie-jar-per-thread", false, "use a separate cookie jar for each thread (implies --cookie-jar)")
 
 	fs.StringSliceVar(&opts.HideStatusCodes, "hide-status", nil, "hide responses with this status `code,[code-code],[-code],[...]`")
 	fs.StringSliceVar(&opts.ShowStatusCodes, "show-status", nil, "show only responses with this status `code,[code-code],[code-],[...]`")
+	fs.StringSliceVar(&opts.HideTime, "hide-time", nil, "hide responses which took this `time,[time-time],[<time],[>time],[...]`, e.g. <100ms")
+	fs.StringSliceVar(&opts.ShowTime, "show-time", nil, "show only responses which took this `time,[time-time],[<time],[>time],[...]`, e.g. >2s")
 	fs.StringSliceVar(&opts.HideHeaderSize, "hide-header-size", nil, "hide responses with this header size (`size,from-to,from-,-to`)")
 	fs.StringSliceVar(&opts.HideBodySize, "hide-body-size", nil, "hide responses with this body size (`size,from-to,from-,-to`)")
+	fs.StringSliceVar(&opts.HideWords, "hide-words", nil, "hide responses with this number of words in the body (`n,from-to,from-,-to`)")
+	fs.StringSliceVar(&opts.HideLines, "hide-lines", nil, "hide responses with this number of lines in the body (`n,from-to,from-,-to`)")
 	fs.StringArrayVar(&opts.HidePattern, "hide-pattern", nil, "hide responses containing `regex` in response header or body (can be specified multiple times)")
 	fs.StringArrayVar(&opts.ShowPattern, "show-pattern", nil, "show only responses containing `regex` in response header or body (can be specified multiple times)")
-
-	fs.StringArrayVar(&opts.Extract, "extract", nil, "extract `regex` from response body (can be specified multiple times)")
+	fs.BoolVar(&opts.Calibrate, "calibrate", false, "send requests with random values before the run and hide similar responses")
+	fs.BoolVar(&opts.CalibrateSuggest, "calibrate-suggest", false, "send requests with random values before the run and suggest a filter")
+	fs.StringArrayVar(&opts.HideJSON, "hide-json", nil, "hide responses with a JSON body matching `condition` (e.g. '$.error == true', can be specified multiple times)")
+	fs.StringArrayVar(&opts.ShowJSON, "show-json", nil, "show only responses with a JSON body matching `condition` (e.g. '$.error == false', can be specified multiple times)")
+	fs.StringArrayVar(&opts.FilterExpr, "filter-expr", nil, "show only responses for which `expr` is true, e.g. 'status == 200 and (size > 1000 or body =~ \"(?i)error\")' (can be specified multiple times)")
+	fs.BoolVar(&opts.HideSimilar, "hide-similar", false, "hide responses with a body similar to the one of a response shown before")
+	fs.BoolVar(&opts.HideSoft404, "hide-soft404", false, "request paths which do not exist before the run and hide similar responses")
+	fs.IntVar(&opts.SimilarDistance, "similar-distance", response.DefaultSimilarDistance, "consider bodies (for --hide-similar and --hide-soft404) similar if their hashes differ in at most `n` bits")
+	fs.StringArrayVar(&opts.HideHeader, "hide-header", nil, "hide responses with a header field `name=regex` (can be specified multiple times)")
+	fs.StringArrayVar(&opts.ShowHeader, "show-header", nil, "show only responses with a header field `name=regex` (can be specified multiple times)")
+	fs.StringArrayVar(&opts.HideLocation, "hide-location", nil, "hide redirects to a Location matching `regex` (can be specified multiple times)")
+	fs.StringArrayVar(&opts.ShowLocation, "show-location", nil, "show only redirects to a Location matching `regex` (can be specified multiple times)")
+	fs.StringSliceVar(&opts.HideContentType, "hide-content-type", nil, "hide responses with a content type in `class,[...]` (html, json, xml, javascript, css, text, image, binary or a media type)")
+	fs.StringSliceVar(&opts.ShowContentType, "show-content-type", nil, "show only responses with a content type in `class,[...]` (html, json, xml, javascript, css, text, image, binary or a media type)")
+	fs.StringArrayVar(&opts.HideBodyPattern, "hide-body-pattern", nil, "hide responses containing `regex` in the response body (can be specified multiple times)")
+	fs.StringArrayVar(&opts.ShowBodyPattern, "show-body-pattern", nil, "show only responses containing `regex` in the response body (can be specified multiple times)")
+	fs.StringVar(&opts.ControlSocket, "control-socket", "", "listen on the Unix socket at `path` for requests which add or remove filters during the run")
+	fs.IntVar(&opts.StopAfterMatches, "stop-after-matches", 0, "stop the run after `n` responses have been shown")
+	fs.StringSliceVar(&opts.StopOnStatus, "stop-on-status", nil, "stop the run at the first shown response with this status `code,[code-code],[-code],[...]`")
+
+	fs.StringArrayVar(&opts.Extract, "extract", nil, "extract `regex` from response body, named groups like (?P<name>...) are shown in a column each (can be specified multiple times)")
+	fs.StringArrayVar(&opts.ExtractJSON, "extract-json", nil, "extract the values at JSONPath `expr` (e.g. '$.items[*].id') from the JSON response body (can be specified multiple times)")
+	fs.StringArrayVar(&opts.ExtractCSS, "extract-selector", nil, "extract the text of the HTML elements matching CSS `selector` (e.g. 'table td.name' or 'a@href' for an attribute, can be specified multiple times)")
+	fs.StringArrayVar(&opts.ExtractXPath, "extract-xpath", nil, "extract the text of the HTML elements matching XPath `expr` (e.g. '//tr[2]/td' or '//a/@href', can be specified multiple times)")
+	fs.StringArrayVar(&opts.ExtractHeader, "extract-header", nil, "extract the values of the response header field `name[=regex]`, or the matches of regex in them (can be specified multiple times)")
+	fs.StringArrayVar(&opts.ExtractCookie, "extract-cookie", nil, "extract the value of the cookie `name` set by the response (can be specified multiple times)")
 	fs.StringArrayVar(&opts.ExtractPipe, "extract-pipe", nil, "pipe response body to `cmd` to extract data (can be specified multiple times)")
 	fs.IntVar(&opts.BodyBufferSize, "body-buffer-size", 5, "use `n` MiB as the buffer size for extracting strings from a response body")
+	fs.StringVar(&opts.SaveBodies, "save-bodies", "", "save the body of each shown response to a file in `dir`, named after the value and the status code")
+	fs.DurationVar(&opts.WebSocketWait, "ws-wait", response.DefaultWebSocketWait, "wait at most `duration` for each message received via a WebSocket")
+	fs.IntVar(&opts.WebSocketMessages, "ws-messages", response.DefaultWebSocketMessages, "close the WebSocket after receiving `n` messages")
 }
 
 // logfilePath returns the prefix for the logfiles, if any.
 func logfilePath(opts *Options, inputURL string) (prefix string, err error) {
 	if opts.Logdir != "" && opts.Logfile == "" {
-		url, err := url.Parse(inputURL)
-		if err != nil {
-			return "", err
-		}
-
 		ts := time.Now().Format("20060102_150405")
-		fn := fmt.Sprintf("monsoon_%s_%s", url.Host, ts)
+		fn := fmt.Sprintf("monsoon_%s_%s", urlHost(inputURL), ts)
 		p := filepath.Join(opts.Logdir, fn)
 		return p, nil
 	}
@@ -196,69 +861,485 @@ func logfilePath(opts *Options, inputURL string) (prefix string, err error) {
 	return opts.Logfile, nil
 }
 
-func setupProducer(ctx context.Context, g *errgroup.Group, opts *Options, ch chan<- string, count chan<- int) error {
-	switch {
-	case len(opts.Range) > 0:
+// newSource returns the source described by typ and options.
+func newSource(typ, options string, opts *Options) (producer.Source, error) {
+	switch typ {
+	case "file":
+		return &producer.FileSource{Filename: options}, nil
+
+	case "range":
 		var ranges []producer.Range
-		for _, r := range opts.Range {
+		for _, r := range strings.Split(options, ",") {
 			rng, err := producer.ParseRange(r)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			ranges = append(ranges, rng)
 		}
 
-		g.Go(func() error {
-			return producer.Ranges(ctx, ranges, opts.RangeFormat, ch, count)
-		})
-		return nil
-
-	case opts.Filename == "-":
-		g.Go(func() error {
-			return producer.Reader(ctx, os.Stdin, ch, count)
-		})
-		return nil
+		return &producer.RangeSource{Ranges: ranges, Format: opts.RangeFormat}, nil
 
-	case opts.Filename != "":
-		file, err := os.Open(opts.Filename)
+	case "sitemap":
+		base, client, err := targetClient(opts)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		g.Go(func() error {
-			return producer.Reader(ctx, file, ch, count)
-		})
-		return nil
-
-	default:
-		return errors.New("neither file nor range specified, nothing to do")
-	}
-}
-
-func setupTerminal(ctx context.Context, g *errgroup.Group, logfilePrefix string) (term cli.Terminal, cleanup func(), err error) {
-	ctx, cancel := context.WithCancel(context.Background())
+		return &producer.SitemapSource{Base: base, Client: client}, nil
 
-	if logfilePrefix != "" {
-		fmt.Printf("logfile is %s.log\n", logfilePrefix)
+	case "conditional":
+		if strings.Contains(opts.Request.URL, opts.Request.Replace) {
+			return nil, fmt.Errorf("conditional values require a target URL without the placeholder, got %q", opts.Request.URL)
+		}
 
-		logfile, err := os.Create(logfilePrefix + ".log")
+		_, client, err := targetClient(opts)
 		if err != nil {
-			return nil, cancel, err
+			return nil, err
 		}
 
-		fmt.Fprintln(logfile, shell.Join(os.Args))
+		return producer.NewConditionalSource(opts.Request.URL, options, client)
 
-		// write copies of messages to logfile
-		term = &cli.LogTerminal{
-			Terminal: termstatus.New(os.Stdout, os.Stderr, false),
-			Writer:   logfile,
+	case "wayback":
+		limit, err := strconv.Atoi(options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit for wayback source %q: %v", options, err)
 		}
-	} else {
-		term = termstatus.New(os.Stdout, os.Stderr, false)
-	}
 
-	// make sure error messages logged via the log package are printed nicely
+		// the archive is queried directly, not via the target
+		client, err := externalClient(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		return &producer.WaybackSource{Host: targetHost(opts), Limit: limit, Client: client}, nil
+
+	case "random":
+		return producer.ParseRandom(options)
+
+	case "builtin":
+		return producer.NewBuiltinSource(options)
+
+	case "follow":
+		return &producer.FollowSource{Filename: options}, nil
+
+	case "cmd":
+		args, err := shell.Split(options)
+		if err != nil {
+			return nil, err
+		}
+
+		return &producer.CommandSource{Command: args}, nil
+
+	case "charset":
+		data := strings.SplitN(options, ":", 2)
+		if len(data) != 2 {
+			return nil, fmt.Errorf("invalid charset options %q, expected min-max:charset", options)
+		}
+
+		var src producer.CharsetSource
+		_, err := fmt.Sscanf(data[0], "%d-%d", &src.MinLength, &src.MaxLength)
+		if err != nil {
+			return nil, fmt.Errorf("invalid length for charset %q: %v", data[0], err)
+		}
+
+		src.Charset, err = producer.ParseCharset(data[1])
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = src.Count()
+		if err != nil {
+			return nil, err
+		}
+
+		return &src, nil
+
+	case "mask":
+		return producer.ParseMask(options)
+
+	case "csv":
+		return producer.ParseCSVSource(options)
+
+	case "subdomains":
+		if opts.PermWords == "" {
+			return nil, errors.New("permutations of subdomains require --permutation-words")
+		}
+
+		words, err := producer.ReadLines(opts.PermWords)
+		if err != nil {
+			return nil, err
+		}
+
+		return producer.NewPermutationSource(&producer.FileSource{Filename: options}, words), nil
+
+	case "usernames":
+		for _, format := range opts.UsernameFmt {
+			err := producer.ParseUsernameFormat(format)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return &producer.UsernameSource{Source: &producer.FileSource{Filename: options}, Formats: opts.UsernameFmt}, nil
+
+	case "dates":
+		first, last, err := producer.ParseDateRange(options)
+		if err != nil {
+			return nil, err
+		}
+
+		step, err := producer.ParseDateStep(opts.DateStep)
+		if err != nil {
+			return nil, err
+		}
+
+		return &producer.DateSource{First: first, Last: last, Step: step, Format: opts.DateFormat}, nil
+
+	case "ips":
+		var src producer.IPSource
+		for _, s := range strings.Split(options, ",") {
+			r, err := producer.ParseIPRange(s)
+			if err != nil {
+				return nil, err
+			}
+
+			src.Ranges = append(src.Ranges, r)
+		}
+
+		_, err := src.Count()
+		if err != nil {
+			return nil, err
+		}
+
+		return &src, nil
+
+	default:
+		return nil, fmt.Errorf("unknown source type %q", typ)
+	}
+}
+
+// targetClient returns the scheme and host of the target URL and an HTTP
+// client for sources which discover values before the run.
+func targetClient(opts *Options) (*url.URL, *http.Client, error) {
+	base, err := url.Parse(opts.Request.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if base.Host == "" || strings.Contains(base.Host, opts.Request.Replace) {
+		return nil, nil, fmt.Errorf("discovering values requires a fixed host in the target URL, got %q", opts.Request.URL)
+	}
+
+	cert, _, err := clientCertificate(opts, []string{opts.Request.Replace})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transport, err := response.NewTransport(opts.Request, cert)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   60 * time.Second,
+	}
+
+	return &url.URL{Scheme: base.Scheme, Host: base.Host}, client, nil
+}
+
+// externalClient returns an HTTP client for requests to third parties, e.g.
+// the Wayback Machine. Only the proxy settings are used, the client
+// certificate and the other connection settings are meant for the target.
+func externalClient(opts *Options) (*http.Client, error) {
+	tmpl := &request.Request{
+		Proxy:  opts.Request.Proxy,
+		SOCKS5: opts.Request.SOCKS5,
+	}
+
+	transport, err := response.NewTransport(tmpl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   60 * time.Second,
+	}
+
+	return client, nil
+}
+
+// clientCertificate loads the TLS client certificate. If the file names
+// contain one of the placeholders, no certificate is loaded and perValue is
+// true, the certificate is loaded for each request instead.
+func clientCertificate(opts *Options, names []string) (cert *tls.Certificate, perValue bool, err error) {
+	for _, name := range names {
+		if strings.Contains(opts.Request.TLSClientKeyCertFile, name) || strings.Contains(opts.Request.TLSClientKeyFile, name) {
+			return nil, true, nil
+		}
+	}
+
+	cert, err = response.LoadClientCertificate(opts.Request.TLSClientKeyCertFile, opts.Request.TLSClientKeyFile, opts.Request.TLSClientCertPassword)
+	return cert, false, err
+}
+
+// targetHost returns the host name of the target URL without the port.
+func targetHost(opts *Options) string {
+	u, err := url.Parse(opts.Request.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// parseReplace parses a replace specification in the form NAME:type:options.
+func parseReplace(spec string, opts *Options) (name string, src producer.Source, err error) {
+	data := strings.SplitN(spec, ":", 3)
+	if len(data) != 3 || data[0] == "" {
+		return "", nil, fmt.Errorf("invalid replace spec %q, expected NAME:type:options", spec)
+	}
+
+	if data[1] == "follow" {
+		// all values of additional placeholders are read into memory
+		return "", nil, errors.New("source type follow can only be used for the default placeholder via --values-follow")
+	}
+
+	src, err = newSource(data[1], data[2], opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return data[0], src, nil
+}
+
+// expandSource wraps src so that only matching values are used and variants
+// of each value are produced.
+func expandSource(opts *Options, src producer.Source) (producer.Source, error) {
+	if len(opts.valueMatch) > 0 || len(opts.valueReject) > 0 {
+		src = &producer.MatchSource{Source: src, Match: opts.valueMatch, Reject: opts.valueReject}
+	}
+
+	if len(opts.Mutate) > 0 {
+		var err error
+		src, err = producer.NewMutateSource(src, opts.Mutate, opts.MutateYears)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(opts.Prefix) > 0 || len(opts.Suffix) > 0 {
+		src = producer.NewAffixSource(src, opts.Prefix, opts.Suffix)
+	}
+
+	if len(opts.Case) > 0 {
+		var err error
+		src, err = producer.NewCaseSource(src, opts.Case)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(opts.Unicode) > 0 {
+		var err error
+		src, err = producer.NewUnicodeSource(src, opts.Unicode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return src, nil
+}
+
+// jsonlSource returns the source for a JSON lines file described by
+// "filename[:field,...]" and the placeholders it is bound to.
+func jsonlSource(s string) (names []string, src producer.Source, split producer.SplitFunc, err error) {
+	filename := s
+	var fields []string
+	if pos := strings.LastIndex(s, ":"); pos >= 0 {
+		filename = s[:pos]
+		fields = strings.Split(s[pos+1:], ",")
+	}
+
+	if len(fields) == 0 {
+		if filename == "-" {
+			return nil, nil, nil, errors.New("fields must be specified when reading JSON lines from stdin")
+		}
+
+		fields, err = producer.JSONLFields(filename)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	for _, field := range fields {
+		if field == "" {
+			return nil, nil, nil, fmt.Errorf("invalid JSON lines source %q: empty field name", s)
+		}
+		names = append(names, "{{."+field+"}}")
+	}
+
+	return names, &producer.JSONLSource{Filename: filename}, producer.JSONFields(fields), nil
+}
+
+// splitSource returns the source for a file described by "NAME,...:filename"
+// and the placeholders it is bound to.
+func splitSource(s, delim string) (names []string, src producer.Source, split producer.SplitFunc, err error) {
+	data := strings.SplitN(s, ":", 2)
+	if len(data) != 2 || data[0] == "" || data[1] == "" {
+		return nil, nil, nil, fmt.Errorf("invalid split source %q, expected NAME,...:filename", s)
+	}
+
+	if delim == "" {
+		return nil, nil, nil, errors.New("delimiter for --values-split is empty")
+	}
+
+	names = strings.Split(data[0], ",")
+	for _, name := range names {
+		if name == "" {
+			return nil, nil, nil, fmt.Errorf("invalid split source %q: empty placeholder name", s)
+		}
+	}
+
+	return names, &producer.FileSource{Filename: data[1]}, producer.DelimitedFields(delim, len(names)), nil
+}
+
+// newMultiplexer returns a multiplexer for all sources specified in opts.
+func newMultiplexer(opts *Options) (mux *producer.Multiplexer, err error) {
+	mux = &producer.Multiplexer{}
+
+	mux.Mode, err = producer.ParseMode(opts.ReplaceMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// merge all sources for the default placeholder
+	var merge producer.MergeSource
+	for _, spec := range opts.defaultSources() {
+		src, err := newSource(spec.typ, spec.options, opts)
+		if err != nil {
+			return nil, err
+		}
+		merge.Sources = append(merge.Sources, src)
+
+		prio := 0
+		if spec.typ == "file" {
+			prio = opts.filePrio[spec.options]
+		}
+		merge.Priorities = append(merge.Priorities, prio)
+	}
+
+	var src producer.Source
+	switch len(merge.Sources) {
+	case 0:
+	case 1:
+		src = merge.Sources[0]
+	default:
+		src = &merge
+	}
+
+	if src != nil {
+		src, err = expandSource(opts, src)
+		if err != nil {
+			return nil, err
+		}
+		mux.AddSource(opts.Request.Replace, src)
+	}
+
+	if opts.JSONL != "" {
+		names, src, split, err := jsonlSource(opts.JSONL)
+		if err != nil {
+			return nil, err
+		}
+		mux.AddSplitSource(names, src, split)
+	}
+
+	if opts.Split != "" {
+		names, src, split, err := splitSource(opts.Split, opts.SplitDelim)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			for _, n := range mux.Names {
+				if n == name {
+					return nil, fmt.Errorf("more than one source specified for %q", name)
+				}
+			}
+		}
+		mux.AddSplitSource(names, src, split)
+	}
+
+	for _, spec := range opts.Replace {
+		name, src, err := parseReplace(spec, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, n := range mux.Names {
+			if n == name {
+				return nil, fmt.Errorf("more than one source specified for %q", name)
+			}
+		}
+
+		mux.AddSource(name, src)
+	}
+
+	if len(opts.Methods) > 0 {
+		for _, n := range mux.Names {
+			if n == methodPlaceholder {
+				return nil, fmt.Errorf("more than one source specified for %q", n)
+			}
+		}
+
+		// send all methods for each value
+		mux.AddSource(methodPlaceholder, producer.ListSource(opts.Methods))
+	}
+
+	if len(mux.Sources) == 0 {
+		return nil, errors.New("neither file nor range specified, nothing to do")
+	}
+
+	return mux, nil
+}
+
+func setupProducer(ctx context.Context, g *errgroup.Group, opts *Options, ch chan<- producer.Item, count chan<- int) (names []string, err error) {
+	mux, err := newMultiplexer(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	g.Go(func() error {
+		return mux.Run(ctx, ch, count)
+	})
+
+	return mux.Names, nil
+}
+
+func setupTerminal(ctx context.Context, g *errgroup.Group, logfilePrefix string) (term cli.Terminal, cleanup func(), err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if logfilePrefix != "" {
+		fmt.Printf("logfile is %s.log\n", logfilePrefix)
+
+		logfile, err := os.Create(logfilePrefix + ".log")
+		if err != nil {
+			return nil, cancel, err
+		}
+
+		fmt.Fprintln(logfile, shell.Join(os.Args))
+
+		// write copies of messages to logfile
+		term = &cli.LogTerminal{
+			Terminal: termstatus.New(os.Stdout, os.Stderr, false),
+			Writer:   logfile,
+		}
+	} else {
+		term = termstatus.New(os.Stdout, os.Stderr, false)
+	}
+
+	// make sure error messages logged via the log package are printed nicely
 	w := cli.NewStdioWrapper(term)
 	log.SetOutput(w.Stderr())
 
@@ -278,28 +1359,110 @@ func setupResponseFilters(opts *Options) ([]response.Filter, error) {
 		return nil, err
 	}
 
-	filters = append(filters, filter)
+	filters = append(filters, filter)
+
+	if len(opts.HideHeaderSize) > 0 || len(opts.HideBodySize) > 0 {
+		f, err := response.NewFilterSize(opts.HideHeaderSize, opts.HideBodySize)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if len(opts.HideWords) > 0 || len(opts.HideLines) > 0 {
+		f, err := response.NewFilterCount(opts.HideWords, opts.HideLines)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if len(opts.HideTime) > 0 || len(opts.ShowTime) > 0 {
+		f, err := response.NewFilterTime(opts.HideTime, opts.ShowTime)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if len(opts.hidePattern) > 0 {
+		filters = append(filters, response.FilterRejectPattern{Pattern: opts.hidePattern})
+	}
+
+	if len(opts.showPattern) > 0 {
+		filters = append(filters, response.FilterAcceptPattern{Pattern: opts.showPattern})
+	}
+
+	if len(opts.HideHeader) > 0 || len(opts.ShowHeader) > 0 {
+		f, err := response.NewFilterHeader(opts.HideHeader, opts.ShowHeader)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if len(opts.hideLocation) > 0 || len(opts.showLocation) > 0 {
+		filters = append(filters, response.FilterLocation{Rejects: opts.hideLocation, Accepts: opts.showLocation})
+	}
+
+	if len(opts.HideContentType) > 0 || len(opts.ShowContentType) > 0 {
+		f, err := response.NewFilterContentType(opts.HideContentType, opts.ShowContentType)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if len(opts.HideJSON) > 0 || len(opts.ShowJSON) > 0 {
+		var f response.FilterJSON
+		for _, s := range opts.HideJSON {
+			c, err := response.ParseJSONCondition(s)
+			if err != nil {
+				return nil, err
+			}
+			f.Rejects = append(f.Rejects, c)
+		}
+		for _, s := range opts.ShowJSON {
+			c, err := response.ParseJSONCondition(s)
+			if err != nil {
+				return nil, err
+			}
+			f.Accepts = append(f.Accepts, c)
+		}
+		filters = append(filters, f)
+	}
+
+	if len(opts.hideBodyPattern) > 0 {
+		filters = append(filters, response.FilterRejectBodyPattern{Pattern: opts.hideBodyPattern})
+	}
+
+	if len(opts.showBodyPattern) > 0 {
+		filters = append(filters, response.FilterAcceptBodyPattern{Pattern: opts.showBodyPattern})
+	}
 
-	if len(opts.HideHeaderSize) > 0 || len(opts.HideBodySize) > 0 {
-		f, err := response.NewFilterSize(opts.HideHeaderSize, opts.HideBodySize)
+	for _, expr := range opts.FilterExpr {
+		f, err := response.ParseFilterExpr(expr)
 		if err != nil {
 			return nil, err
 		}
 		filters = append(filters, f)
 	}
 
-	if len(opts.hidePattern) > 0 {
-		filters = append(filters, response.FilterRejectPattern{Pattern: opts.hidePattern})
-	}
+	return filters, nil
+}
 
-	if len(opts.showPattern) > 0 {
-		filters = append(filters, response.FilterAcceptPattern{Pattern: opts.showPattern})
+func setupValueFilters(ctx context.Context, opts *Options, valueCh <-chan producer.Item, countCh <-chan int) (<-chan producer.Item, <-chan int) {
+	if opts.Shuffle != "" {
+		f := &producer.FilterShuffle{Seed: opts.shuffleSeed}
+		countCh = f.Count(ctx, countCh)
+		valueCh = f.Select(ctx, valueCh)
 	}
 
-	return filters, nil
-}
+	if opts.shard != nil {
+		countCh = opts.shard.Count(ctx, countCh)
+		valueCh = opts.shard.Select(ctx, valueCh)
+	}
 
-func setupValueFilters(ctx context.Context, opts *Options, valueCh <-chan string, countCh <-chan int) (<-chan string, <-chan int) {
 	if opts.Skip > 0 {
 		f := &producer.FilterSkip{Skip: opts.Skip}
 		countCh = f.Count(ctx, countCh)
@@ -315,26 +1478,258 @@ func setupValueFilters(ctx context.Context, opts *Options, valueCh <-chan string
 	return valueCh, countCh
 }
 
-func startRunners(ctx context.Context, opts *Options, in <-chan string) (<-chan response.Response, error) {
+// setupEncoders returns the list of encoders for each of the placeholders.
+func setupEncoders(opts *Options, names []string) (encoders [][]func(string) string, err error) {
+	if len(opts.Transform) == 0 && len(opts.Encode) == 0 {
+		return nil, nil
+	}
+
+	encoders = make([][]func(string) string, len(names))
+	add := func(name string, funcs ...func(string) string) bool {
+		found := false
+		for i, n := range names {
+			if n == name {
+				encoders[i] = append(encoders[i], funcs...)
+				found = true
+			}
+		}
+		return found
+	}
+
+	// transformations are applied before the encoders
+	for _, spec := range opts.Transform {
+		name, text := opts.Request.Replace, spec
+		for _, n := range names {
+			if strings.HasPrefix(spec, n+":") {
+				name, text = n, spec[len(n)+1:]
+				break
+			}
+		}
+
+		f, err := producer.ParseTransform(text)
+		if err != nil {
+			return nil, err
+		}
+
+		if !add(name, f) {
+			return nil, fmt.Errorf("transformation specified for unknown placeholder %q", name)
+		}
+	}
+
+	for _, spec := range opts.Encode {
+		name, list := opts.Request.Replace, spec
+		if data := strings.SplitN(spec, ":", 2); len(data) == 2 {
+			name, list = data[0], data[1]
+		}
+
+		funcs, err := producer.ParseEncoders(list)
+		if err != nil {
+			return nil, err
+		}
+
+		if !add(name, funcs...) {
+			return nil, fmt.Errorf("encoder specified for unknown placeholder %q", name)
+		}
+	}
+
+	return encoders, nil
+}
+
+func startRunners(ctx context.Context, opts *Options, names []string, in <-chan producer.Item) (<-chan response.Response, error) {
 	out := make(chan response.Response)
 
 	var wg sync.WaitGroup
-	transport, err := response.NewTransport(opts.Request.Insecure, opts.Request.TLSClientKeyCertFile, opts.Request.DisableHTTP2)
+	cert, perValue, err := clientCertificate(opts, names)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := response.NewTransport(opts.Request, cert)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep one idle connection per thread to each host, so connections are
+	// reused when requests are sent to many hosts
+	transport.MaxIdleConnsPerHost = opts.Threads
+
+	var proxyPool *response.ProxyPool
+	if opts.ProxyFile != "" {
+		list, err := producer.ReadLines(opts.ProxyFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("no proxies found in %v", opts.ProxyFile)
+		}
+
+		proxyPool, err = response.NewProxyPool(list, opts.ProxyRandom)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = proxyPool.Proxy
+	}
+
+	var retry *response.Retry
+	if opts.Retry > 0 {
+		retry, err = response.NewRetry(opts.Retry, opts.RetryOn, opts.RetryBackoff)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var throttle *response.Throttle
+	if opts.Throttle {
+		throttle = response.NewThrottle(opts.ThrottleMax)
+	}
+
+	var hostLimiter *response.HostLimiter
+	if opts.MaxPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxPerHost
+		transport.MaxConnsPerHost = opts.MaxPerHost
+		hostLimiter = response.NewHostLimiter(opts.MaxPerHost)
+	}
+
+	// the server name may contain placeholders, it is set for each request
+	var sniPerValue bool
+	for _, name := range names {
+		if strings.Contains(opts.Request.SNI, name) {
+			sniPerValue = true
+		}
+	}
+
+	// all threads share a cookie jar unless each one has its own session
+	var jar http.CookieJar
+	if opts.CookieJar && !opts.CookieJarPerThread {
+		jar, err = response.NewCookieJar()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ntlm *response.NTLMCredentials
+	if opts.Request.NTLM != "" {
+		cred, err := response.ParseNTLMCredentials(opts.Request.NTLM)
+		if err != nil {
+			return nil, err
+		}
+		ntlm = &cred
+	}
+
+	// the authentication transports are built for each runner by
+	// wrapTransport, the ones below only hold the parsed settings
+	var digest *response.DigestTransport
+	if opts.Request.Digest != "" {
+		digest, err = response.NewDigestTransport(transport, opts.Request.Digest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var aws *response.AWSTransport
+	if opts.Request.AWSSign != "" {
+		cred, err := response.ParseAWSCredentials(opts.Request.AWSCredentials)
+		if err != nil {
+			return nil, err
+		}
+
+		aws, err = response.NewAWSTransport(transport, cred, opts.Request.AWSSign)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var signCommand []string
+	if opts.Request.SignCommand != "" {
+		signCommand, err = shell.Split(opts.Request.SignCommand)
+		if err != nil {
+			return nil, err
+		}
+		if len(signCommand) == 0 {
+			return nil, fmt.Errorf("invalid command: %q", opts.Request.SignCommand)
+		}
+	}
+
+	// wrapTransport adds authentication and signing to tr, it is called for
+	// each runner and for the transports built per value (client certificates
+	// or server names with placeholders)
+	wrapTransport := func(tr *http.Transport) http.RoundTripper {
+		var rt http.RoundTripper = tr
+		if opts.Request.CustomWriter() {
+			rt = response.NewHeaderOrderTransport(tr, opts.Request)
+		}
+
+		switch {
+		case ntlm != nil:
+			// NTLM authenticates connections, so each runner needs its own
+			rt = response.NewNTLMTransport(tr, *ntlm)
+		case digest != nil:
+			rt = &response.DigestTransport{Transport: rt, User: digest.User, Password: digest.Password}
+		case aws != nil:
+			signer := *aws
+			signer.Transport = rt
+			rt = &signer
+		}
+
+		if signCommand != nil {
+			rt = &response.SignCommandTransport{
+				Transport: rt,
+				Command:   signCommand,
+			}
+		}
+
+		return rt
+	}
+
+	sessions, err := setupSessions(ctx, opts, transport, jar)
 	if err != nil {
 		return nil, err
 	}
 
+	var userAgents *response.UserAgents
+	if opts.UserAgentFile != "" {
+		list, err := producer.ReadLines(opts.UserAgentFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("no user agents found in %v", opts.UserAgentFile)
+		}
+		userAgents = response.NewUserAgents(list, opts.UserAgentRandom)
+	}
+
 	for i := 0; i < opts.Threads; i++ {
 		runner := response.NewRunner(transport, opts.Request, in, out)
+		runner.Client.Jar = jar
+		runner.Sessions = sessions
+		runner.ClientCertPerValue = perValue
+		runner.SNIPerValue = sniPerValue
+		runner.HostLimiter = hostLimiter
+		runner.Retry = retry
+		runner.Throttle = throttle
+		runner.UserAgents = userAgents
+		runner.CorrelationHeader = opts.CorrelationHeader
+		runner.ProxyPool = proxyPool
+		runner.Client.Transport = wrapTransport(transport)
+		runner.WrapTransport = wrapTransport
+		if opts.CookieJarPerThread {
+			runner.Client.Jar, err = response.NewCookieJar()
+			if err != nil {
+				return nil, err
+			}
+		}
+		runner.Names = names
+		if len(opts.Methods) > 0 {
+			// the method is shown in a separate column
+			runner.HideItemValues = []string{methodPlaceholder}
+		}
 		runner.BodyBufferSize = opts.BodyBufferSize * 1024 * 1024
+		runner.WebSocketWait = opts.WebSocketWait
+		runner.WebSocketMessages = opts.WebSocketMessages
 		runner.Extract = opts.extract
+		runner.FeedbackTemplate = feedbackTemplate(opts)
 
-		runner.Client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			if len(via) <= opts.FollowRedirect {
-				return nil
-			}
-			return http.ErrUseLastResponse
-		}
+		runner.FollowRedirects = opts.FollowRedirects
 		wg.Add(1)
 		go func() {
 			runner.Run(ctx)
@@ -353,22 +1748,42 @@ func startRunners(ctx context.Context, opts *Options, in <-chan string) (<-chan
 
 func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) error {
 	// make sure the options and arguments are valid
-	if len(args) == 0 {
-		return errors.New("last argument needs to be the URL")
+	inputURL, err := opts.Request.TargetURL(args)
+	if err != nil {
+		return err
 	}
 
-	if len(args) > 1 {
-		return errors.New("more than one target URL specified")
+	err = opts.valid()
+	if err != nil {
+		return err
 	}
 
-	err := opts.valid()
+	// the body is the same for all requests, so the file is only read once
+	err = opts.Request.LoadBodyFile()
 	if err != nil {
 		return err
 	}
 
-	inputURL := args[0]
 	opts.Request.URL = inputURL
 
+	// the producers are stopped when run returns, also when the run is
+	// stopped early by a condition
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the scheme and host are replaced with each target
+	if opts.TargetFile != "" {
+		opts.targets, err = readTargets(opts.TargetFile)
+		if err != nil {
+			return err
+		}
+
+		opts.Request.URL, err = targetURL(inputURL)
+		if err != nil {
+			return err
+		}
+	}
+
 	// setup logging and the terminal
 	logfilePrefix, err := logfilePath(opts, inputURL)
 	if err != nil {
@@ -387,38 +1802,189 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 		return err
 	}
 
+	// detect "not found" pages with other status codes (if requested)
+	if opts.HideSoft404 {
+		f, err := soft404Filter(ctx, opts, term)
+		if err != nil {
+			return err
+		}
+		responseFilters = append(responseFilters, f)
+	}
+
+	// describe the run so it can be resumed later
+	stateFile := stateFilePath(opts, logfilePrefix)
+	runState, err := newState(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.Resume != "" {
+		prev, err := loadResumeState(opts.Resume, runState, term)
+		if err != nil {
+			return err
+		}
+
+		runState.Position, runState.Done = prev.Position, prev.Done
+		term.Printf("resuming at position %d, %d requests already done\n", prev.Position, prev.Done)
+	}
+	tracker := state.NewTracker(runState.Position, runState.Done)
+
+	// save the state periodically, so a crash does not lose the progress
+	checkpointDone := make(chan struct{})
+	checkpointCtx, stopCheckpoint := context.WithCancel(ctx)
+	defer stopCheckpoint()
+	if stateFile != "" && (opts.Checkpoint > 0 || opts.CheckpointN > 0) {
+		go func() {
+			tracker.Checkpoint(checkpointCtx, runState, stateFile, opts.Checkpoint, opts.CheckpointN, func(err error) {
+				term.Printf("saving state failed: %v\n", err)
+			})
+			close(checkpointDone)
+		}()
+	} else {
+		close(checkpointDone)
+	}
+
 	// setup the pipeline for the values
-	vch := make(chan string, opts.BufferSize)
-	var valueCh <-chan string = vch
+	vch := make(chan producer.Item, opts.BufferSize)
+	var valueCh <-chan producer.Item = vch
 	cch := make(chan int, 1)
 	var countCh <-chan int = cch
 
 	// start a producer from the options
-	err = setupProducer(ctx, g, opts, vch, cch)
+	names, err := setupProducer(ctx, g, opts, vch, cch)
 	if err != nil {
 		return err
 	}
 
-	// filter values (skip, limit)
+	// filter values (shuffle, skip, limit)
+	if opts.Shuffle != "" {
+		term.Printf("shuffling values with seed %d\n", opts.shuffleSeed)
+	}
 	valueCh, countCh = setupValueFilters(ctx, opts, valueCh, countCh)
 
+	// send all values to each target (if requested)
+	if len(opts.targets) > 0 {
+		f := &producer.FilterTargets{Targets: opts.targets}
+		countCh = f.Count(ctx, countCh)
+		valueCh = f.Select(ctx, valueCh)
+		names = append(names, targetPlaceholder)
+	}
+
+	// drop values already processed in a previous run
+	if opts.Resume != "" {
+		f := &producer.FilterResume{Position: runState.Position, Done: runState.Done}
+		countCh = f.Count(ctx, countCh)
+		valueCh = f.Select(ctx, valueCh)
+	}
+
+	// transform and encode values (if requested)
+	encoders, err := setupEncoders(opts, names)
+	if err != nil {
+		return err
+	}
+	if encoders != nil {
+		valueCh = producer.Encode(ctx, encoders, valueCh)
+	}
+
+	// combine several values into one GraphQL request (if requested)
+	if opts.GraphQLBatch > 1 {
+		f := &producer.FilterBatch{Size: opts.GraphQLBatch}
+		countCh = f.Count(ctx, countCh)
+		valueCh = f.Select(ctx, valueCh)
+	}
+
+	// send the values again for discovered directories (if requested)
+	var recursion *producer.Recursion
+	if opts.RecursionDepth > 0 {
+		recursion, err = setupRecursion(opts, names, term)
+		if err != nil {
+			return err
+		}
+		valueCh, countCh = recursion.Run(ctx, valueCh, countCh)
+	}
+
+	// send the extracted values again (if requested)
+	var fb *producer.Feedback
+	if opts.Feedback {
+		fb, err = setupFeedback(opts, names)
+		if err != nil {
+			return err
+		}
+		valueCh, countCh = fb.Run(ctx, valueCh, countCh)
+	}
+
 	// limit the throughput (if requested)
 	if opts.RequestsPerSecond > 0 {
 		valueCh = producer.Limit(ctx, opts.RequestsPerSecond, valueCh)
 	}
 
+	// no more values are sent to the runners when the run is stopped, the
+	// responses for requests which have already been sent are still reported
+	dispatchCtx, stopDispatch := context.WithCancel(ctx)
+	defer stopDispatch()
+	runnerCh := tracker.Dispatch(dispatchCtx, valueCh)
+
+	// find the responses for random values first (if requested)
+	var calib *calibration
+	if opts.Calibrate || opts.CalibrateSuggest {
+		calib = newCalibration(names, opts.Calibrate, term)
+		runnerCh = calib.Items(ctx, runnerCh)
+		responseFilters = append(responseFilters, calib)
+	}
+
+	// add and remove filters during the run (if requested)
+	if opts.ControlSocket != "" {
+		set := &response.FilterSet{Parse: parseFilterSpec}
+		err = serveControl(ctx, opts.ControlSocket, set, term)
+		if err != nil {
+			return err
+		}
+		responseFilters = append(responseFilters, set)
+	}
+
+	// hide near-duplicates of shown responses (if requested), this must be
+	// the last filter
+	var similar *response.FilterSimilar
+	if opts.HideSimilar {
+		similar = &response.FilterSimilar{Distance: opts.SimilarDistance}
+		responseFilters = append(responseFilters, similar)
+	}
+
 	// start the runners
-	responseCh, err := startRunners(ctx, opts, valueCh)
+	responseCh, err := startRunners(ctx, opts, names, runnerCh)
 	if err != nil {
 		return err
 	}
+	if calib != nil {
+		responseCh = calib.Responses(ctx, responseCh)
+	}
+	responseCh = tracker.Complete(responseCh)
+
+	// filter the first response of redirect chains instead of the final one
+	// (if requested)
+	if opts.RedirectFilter == "first" {
+		for i, f := range responseFilters {
+			responseFilters[i] = response.FilterFirst{Filter: f}
+		}
+	}
 
 	// filter the responses
 	responseCh = response.Mark(responseCh, responseFilters)
 
+	if recursion != nil {
+		responseCh, err = recurse(responseCh, recursion, opts, term)
+		if err != nil {
+			return err
+		}
+	}
+
 	// extract data from all interesting (non-hidden) responses
 	extracter := &response.Extracter{
 		Pattern:  opts.extract,
+		JSONPath: opts.extractJSON,
+		HTML:     opts.extractHTML,
+		Headers:  opts.extractHeader,
+		Cookies:  opts.ExtractCookie,
 		Commands: opts.extractPipe,
 		Error: func(err error) {
 			term.Printf("%v", err)
@@ -426,6 +1992,26 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 	}
 	responseCh = extracter.Run(responseCh)
 
+	if fb != nil {
+		responseCh = feedback(responseCh, fb, extracter.FieldNames())
+	}
+
+	// save the bodies of all interesting (non-hidden) responses (if requested)
+	if opts.SaveBodies != "" {
+		err = os.MkdirAll(opts.SaveBodies, 0755)
+		if err != nil {
+			return err
+		}
+
+		saver := &response.BodySaver{
+			Dir: opts.SaveBodies,
+			Error: func(err error) {
+				term.Printf("%v", err)
+			},
+		}
+		responseCh = saver.Run(responseCh)
+	}
+
 	if logfilePrefix != "" {
 		rec, err := recorder.New(logfilePrefix+".json", opts.Request)
 		if err != nil {
@@ -433,11 +2019,25 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 		}
 
 		// fill in information for generating the request
-		rec.Data.InputFile = opts.Filename
+		rec.Data.InputFile = strings.Join(opts.Filenames, ", ")
 		rec.Data.Ranges = opts.Range
 		rec.Data.RangeFormat = opts.RangeFormat
+		for _, spec := range opts.defaultSources() {
+			// file and range sources are recorded separately
+			if spec.typ != "file" && spec.typ != "range" {
+				rec.Data.Source = spec.typ + ":" + spec.options
+			}
+		}
+		rec.Data.Replace = opts.Replace
+		if len(opts.Replace) > 0 || opts.JSONL != "" || opts.Split != "" {
+			rec.Data.ReplaceMode = opts.ReplaceMode
+		}
 		rec.Data.Extract = opts.Extract
 		rec.Data.ExtractPipe = opts.ExtractPipe
+		rec.Data.ExtractJSON = opts.ExtractJSON
+		rec.Data.ExtractHTML = append(append([]string{}, opts.ExtractCSS...), opts.ExtractXPath...)
+		rec.Data.ExtractHeader = opts.ExtractHeader
+		rec.Data.ExtractCookie = opts.ExtractCookie
 
 		out := make(chan response.Response)
 		in := responseCh
@@ -452,8 +2052,92 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 		})
 	}
 
+	// stop the run when enough matches have been found (if requested)
+	var stop *response.Stop
+	if opts.StopAfterMatches > 0 || len(opts.StopOnStatus) > 0 {
+		stop, err = response.NewStop(opts.StopAfterMatches, opts.StopOnStatus, stopDispatch)
+		if err != nil {
+			return err
+		}
+		responseCh = stop.Run(responseCh)
+	}
+
 	// run the reporter
 	term.Printf("input URL %v\n\n", inputURL)
 	reporter := reporter.New(term)
-	return reporter.Display(responseCh, countCh)
+	reporter.ShowMethod = len(opts.Methods) > 0 || strings.Contains(opts.Request.Method, opts.Request.Replace)
+	reporter.ShowHost = opts.Request.ConnectTo != "" || fuzzHost(opts.Request) || len(opts.targets) > 0
+	reporter.ShowID = opts.CorrelationHeader != ""
+	reporter.HostStats = len(opts.targets) > 0
+	reporter.Fields = extracter.FieldNames()
+	err = reporter.Display(responseCh, countCh)
+	if err != nil {
+		return err
+	}
+
+	if similar != nil {
+		term.Printf("%d similar responses hidden\n", similar.Hidden())
+	}
+
+	if stop != nil && stop.Reason() != "" {
+		term.Printf("%v\n", stop.Reason())
+	}
+
+	stopCheckpoint()
+	<-checkpointDone
+
+	if stateFile != "" {
+		runState.Position, runState.Done = tracker.Position()
+		err = runState.Save(stateFile)
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			term.Printf("run interrupted, resume with --resume %v\n", stateFile)
+		}
+	}
+
+	return nil
+}
+
+// urlHost returns the host and port of rawurl. The URL is not parsed, so it
+// may contain placeholders in the port.
+func urlHost(rawurl string) string {
+	i := strings.Index(rawurl, "://")
+	if i < 0 {
+		return ""
+	}
+
+	host := rawurl[i+3:]
+	if end := strings.IndexAny(host, "/?#"); end >= 0 {
+		host = host[:end]
+	}
+
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		host = host[at+1:]
+	}
+
+	return host
+}
+
+// fuzzHost returns true if values are inserted into the host or port of the
+// target URL or the Host header.
+func fuzzHost(r *request.Request) bool {
+	if strings.Contains(urlHost(r.URL), r.Replace) {
+		return true
+	}
+
+	for name, values := range r.Header.Header {
+		if textproto.CanonicalMIMEHeaderKey(name) != "Host" {
+			continue
+		}
+		for _, v := range values {
+			if strings.Contains(v, r.Replace) {
+				return true
+			}
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,116 @@
+package fuzz
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/RedTeamPentesting/monsoon/cli"
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/response"
+)
+
+// setupRecursion returns a stage which runs the producer again for each
+// directory discovered in the responses.
+func setupRecursion(opts *Options, names []string, term cli.Terminal) (*producer.Recursion, error) {
+	placeholder := -1
+	for i, name := range names {
+		if name == opts.Request.Replace {
+			placeholder = i
+		}
+	}
+
+	if placeholder < 0 {
+		return nil, fmt.Errorf("recursion requires values for %v", opts.Request.Replace)
+	}
+
+	mux, err := newMultiplexer(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	encoders, err := setupEncoders(opts, names)
+	if err != nil {
+		return nil, err
+	}
+
+	produce := func(ctx context.Context) (<-chan producer.Item, <-chan int) {
+		ch := make(chan producer.Item)
+		count := make(chan int, 1)
+
+		go func() {
+			err := mux.Run(ctx, ch, count)
+			if err != nil {
+				term.Printf("recursion: %v\n", err)
+			}
+		}()
+
+		var out <-chan producer.Item = ch
+		if encoders != nil {
+			out = producer.Encode(ctx, encoders, out)
+		}
+
+		return out, count
+	}
+
+	return producer.NewRecursion(placeholder, "/", produce), nil
+}
+
+// isDirectory returns true if res is a response for a directory: either the
+// status code is accepted by filter or the server redirects to the requested
+// path with a trailing slash.
+func isDirectory(res response.Response, filter response.Filter) bool {
+	if res.Error != nil || res.HTTPResponse == nil {
+		return false
+	}
+
+	if filter != nil && !filter.Reject(res) {
+		return true
+	}
+
+	switch res.HTTPResponse.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return false
+	}
+
+	loc, err := res.HTTPResponse.Location()
+	if err != nil {
+		return false
+	}
+
+	req := res.HTTPResponse.Request.URL
+	return loc.Host == req.Host && loc.Path == req.Path+"/"
+}
+
+// recurse enqueues the values of all shown responses for directories in r, up
+// to the maximum depth.
+func recurse(in <-chan response.Response, r *producer.Recursion, opts *Options, term cli.Terminal) (<-chan response.Response, error) {
+	var filter response.Filter
+	if len(opts.RecursionStatus) > 0 {
+		f, err := response.NewFilterStatusCode(nil, opts.RecursionStatus)
+		if err != nil {
+			return nil, err
+		}
+		filter = f
+	}
+
+	out := make(chan response.Response)
+
+	go func() {
+		defer close(out)
+		for res := range in {
+			if !res.Hide && res.Depth < opts.RecursionDepth && isDirectory(res, filter) {
+				prefix := res.Values[r.Placeholder]
+				term.Printf("found directory %v, queued for depth %d\n", prefix, res.Depth+1)
+				r.Enqueue(prefix, res.Depth+1)
+			}
+			r.Done()
+
+			out <- res
+		}
+	}()
+
+	return out, nil
+}
@@ -0,0 +1,251 @@
+package fuzz
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"github.com/RedTeamPentesting/monsoon/response"
+)
+
+// validSession checks the options for the login and CSRF token requests.
+func validSession(opts *Options) error {
+	if opts.LoginRequest == "" {
+		if opts.LoginExtract != "" || opts.LoginJSON != "" || len(opts.LoginExpired) > 0 {
+			return errors.New("--login-extract, --login-json and --login-expired require --login-request")
+		}
+	} else {
+		switch {
+		case opts.LoginExtract == "" && opts.LoginJSON == "":
+			return errors.New("--login-request requires --login-extract or --login-json")
+		case opts.LoginExtract != "" && opts.LoginJSON != "":
+			return errors.New("--login-extract and --login-json cannot be combined")
+		case opts.LoginPlaceholder == "":
+			return errors.New("--login-placeholder must not be empty")
+		}
+	}
+
+	if opts.CSRFURL == "" {
+		if opts.CSRFExtract != "" || opts.CSRFSelector != "" || len(opts.CSRFExpired) > 0 || opts.CSRFExpiredPattern != "" {
+			return errors.New("--csrf-extract, --csrf-selector, --csrf-expired and --csrf-expired-pattern require --csrf-url")
+		}
+	} else {
+		switch {
+		case opts.CSRFExtract == "" && opts.CSRFSelector == "":
+			return errors.New("--csrf-url requires --csrf-extract or --csrf-selector")
+		case opts.CSRFExtract != "" && opts.CSRFSelector != "":
+			return errors.New("--csrf-extract and --csrf-selector cannot be combined")
+		case opts.CSRFPlaceholder == "":
+			return errors.New("--csrf-placeholder must not be empty")
+		case opts.LoginRequest != "" && opts.CSRFPlaceholder == opts.LoginPlaceholder:
+			return errors.New("--csrf-placeholder and --login-placeholder must be different")
+		}
+	}
+
+	if opts.OAuth2TokenURL == "" {
+		if opts.OAuth2ClientID != "" || opts.OAuth2ClientSecret != "" || opts.OAuth2Scope != "" {
+			return errors.New("--oauth2-client-id, --oauth2-client-secret and --oauth2-scope require --oauth2-token-url")
+		}
+	} else if opts.OAuth2ClientID == "" {
+		return errors.New("--oauth2-token-url requires --oauth2-client-id")
+	}
+
+	return nil
+}
+
+// newSession returns a session for req, the token is not fetched yet.
+func newSession(req *http.Request, placeholder, pattern string, expired []string, client *http.Client) (*response.Session, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	session := &response.Session{
+		Placeholder: placeholder,
+		Request:     req,
+		Body:        body,
+		Client:      client,
+	}
+
+	if pattern != "" {
+		var err error
+		session.Pattern, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(expired) > 0 {
+		f, err := response.NewFilterStatusCode(expired, nil)
+		if err != nil {
+			return nil, err
+		}
+		session.Expired = append(session.Expired, f)
+	}
+
+	return session, nil
+}
+
+// setupSessions sends the login request and fetches the CSRF token, if
+// configured. The returned sessions provide the tokens for all requests. The
+// login happens first, so that the cookies set are used to fetch the CSRF
+// token when a cookie jar is used.
+func setupSessions(ctx context.Context, opts *Options, transport *http.Transport, jar http.CookieJar) ([]*response.Session, error) {
+	if opts.LoginRequest == "" && opts.CSRFURL == "" && opts.OAuth2TokenURL == "" {
+		return nil, nil
+	}
+
+	base, _, err := targetClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Jar:       jar,
+	}
+
+	var sessions []*response.Session
+
+	if opts.LoginRequest != "" {
+		tmpl := request.New("")
+		tmpl.URL = base.String()
+		tmpl.TemplateFile = opts.LoginRequest
+
+		req, err := tmpl.Apply(nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		session, err := newSession(req, opts.LoginPlaceholder, opts.LoginExtract, opts.LoginExpired, client)
+		if err != nil {
+			return nil, err
+		}
+		if opts.LoginJSON != "" {
+			session.JSONPath, err = response.ParseJSONPath(opts.LoginJSON)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if opts.CSRFURL != "" {
+		// the URL may be relative to the target
+		u, err := url.Parse(opts.CSRFURL)
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl := request.New("")
+		tmpl.URL = base.ResolveReference(u).String()
+
+		req, err := tmpl.Apply(nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		session, err := newSession(req, opts.CSRFPlaceholder, opts.CSRFExtract, opts.CSRFExpired, client)
+		if err != nil {
+			return nil, err
+		}
+		if opts.CSRFSelector != "" {
+			session.Selector, err = response.ParseCSSSelector(opts.CSRFSelector)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if opts.CSRFExpiredPattern != "" {
+			pattern, err := regexp.Compile(opts.CSRFExpiredPattern)
+			if err != nil {
+				return nil, err
+			}
+			session.Expired = append(session.Expired, response.FilterRejectPattern{Pattern: []*regexp.Regexp{pattern}})
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if opts.OAuth2TokenURL != "" {
+		session, err := newOAuth2Session(base, opts, client)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	for _, session := range sessions {
+		err = session.Login(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
+}
+
+// newOAuth2Session returns a session which fetches an access token with the
+// client credentials grant (RFC 6749, section 4.4). The token is sent in the
+// Authorization header, and renewed before it expires or when the target
+// responds with status 401.
+func newOAuth2Session(base *url.URL, opts *Options, client *http.Client) (*response.Session, error) {
+	// the URL may be relative to the target
+	u, err := url.Parse(opts.OAuth2TokenURL)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{"grant_type": []string{"client_credentials"}}
+	if opts.OAuth2Scope != "" {
+		form.Set("scope", opts.OAuth2Scope)
+	}
+
+	tokenURL := base.ResolveReference(u)
+	if tokenURL.Scheme != base.Scheme || tokenURL.Host != base.Host {
+		// the token is issued by an external identity provider, which must
+		// not receive the client certificate or the cookies for the target
+		client, err = externalClient(opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tokenURL.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(opts.OAuth2ClientID), url.QueryEscape(opts.OAuth2ClientSecret))
+
+	session, err := newSession(req, "", "", []string{"401"}, client)
+	if err != nil {
+		return nil, err
+	}
+
+	session.JSONPath, err = response.ParseJSONPath("$.access_token")
+	if err != nil {
+		return nil, err
+	}
+
+	session.LifetimeJSONPath, err = response.ParseJSONPath("$.expires_in")
+	if err != nil {
+		return nil, err
+	}
+	session.Header = "Authorization"
+	session.HeaderPrefix = "Bearer "
+
+	return session, nil
+}
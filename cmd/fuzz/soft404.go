@@ -0,0 +1,90 @@
+package fuzz
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/cli"
+	"github.com/RedTeamPentesting/monsoon/response"
+)
+
+// soft404Paths returns paths which do not exist on the server, in the
+// directory which contains the placeholder in the path of the target URL (or
+// the root directory).
+func soft404Paths(opts *Options, rnd *rand.Rand) []string {
+	dir := "/"
+	if i := strings.Index(opts.Request.URL, "://"); i >= 0 {
+		rest := opts.Request.URL[i+3:]
+		path := ""
+		if j := strings.Index(rest, "/"); j >= 0 {
+			path = rest[j:]
+		}
+		if j := strings.IndexAny(path, "?#"); j >= 0 {
+			path = path[:j]
+		}
+		if j := strings.Index(path, opts.Request.Replace); j >= 0 {
+			dir = path[:strings.LastIndex(path[:j], "/")+1]
+		}
+	}
+
+	name := randomValue(rnd, 12)
+	return []string{
+		dir + name,
+		dir + name + ".html",
+		dir + name + "/",
+	}
+}
+
+// soft404Filter requests paths which do not exist and returns a filter for
+// responses which are similar to the responses for these paths.
+func soft404Filter(ctx context.Context, opts *Options, term cli.Terminal) (response.Filter, error) {
+	base, client, err := targetClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// handle redirects as during the run
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) <= opts.FollowRedirects {
+			return nil
+		}
+		return http.ErrUseLastResponse
+	}
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	filter := response.FilterSoft404{Distance: opts.SimilarDistance}
+
+	for _, path := range soft404Paths(opts, rnd) {
+		req, err := http.NewRequest(http.MethodGet, base.String()+path, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		value := randomValue(rnd, 12)
+		opts.Request.Header.Apply(req.Header, func(s string) string {
+			return strings.Replace(s, opts.Request.Replace, value, -1)
+		})
+
+		res, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("soft 404 detection: %v", err)
+		}
+
+		body, err := ioutil.ReadAll(io.LimitReader(res.Body, int64(opts.BodyBufferSize)*1024*1024))
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("soft 404 detection: %v", err)
+		}
+
+		term.Printf("soft 404 detection: %v returned %v (%d bytes)\n", path, res.Status, len(body))
+		filter.Fingerprints = append(filter.Fingerprints, response.NewFingerprint(res, body))
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,174 @@
+package fuzz
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/cli"
+	"github.com/RedTeamPentesting/monsoon/recorder"
+	"github.com/RedTeamPentesting/monsoon/state"
+)
+
+// stateFilePath returns the name of the file the state is saved to, if any.
+func stateFilePath(opts *Options, logfilePrefix string) string {
+	switch {
+	case opts.RecursionDepth > 0, opts.Feedback, opts.GraphQLBatch > 1:
+		// the queue of discovered directories or extracted values is not
+		// saved, and batches do not map to positions in the values
+		return ""
+	case opts.StateFile != "":
+		return opts.StateFile
+	case opts.Resume != "":
+		return opts.Resume
+	case logfilePrefix != "":
+		return logfilePrefix + ".state"
+	default:
+		return ""
+	}
+}
+
+// newState describes the current run. The position is not filled in.
+func newState(opts *Options) (state.State, error) {
+	tmpl, err := recorder.NewTemplate(opts.Request)
+	if err != nil {
+		return state.State{}, err
+	}
+
+	buf, err := json.Marshal(tmpl)
+	if err != nil {
+		return state.State{}, err
+	}
+
+	hash := sha256.Sum256(buf)
+
+	s := state.State{
+		URL:          opts.Request.URL,
+		TemplateHash: hex.EncodeToString(hash[:]),
+		Skip:         opts.Skip,
+		Limit:        opts.Limit,
+	}
+
+	for _, spec := range opts.defaultSources() {
+		s.Sources = append(s.Sources, fmt.Sprintf("%s:%s:%s", opts.Request.Replace, spec.typ, spec.options))
+	}
+	if len(opts.FilePrio) > 0 {
+		s.Sources = append(s.Sources, "file-priorities:"+strings.Join(opts.FilePrio, ","))
+	}
+	if opts.Usernames != "" {
+		s.Sources = append(s.Sources, "username-formats:"+strings.Join(opts.UsernameFmt, ","))
+	}
+	if opts.PermWords != "" {
+		s.Sources = append(s.Sources, "permutation-words:"+opts.PermWords)
+	}
+	for _, pat := range opts.ValueMatch {
+		s.Sources = append(s.Sources, "match:"+pat)
+	}
+	for _, pat := range opts.ValueReject {
+		s.Sources = append(s.Sources, "reject:"+pat)
+	}
+	if len(opts.Mutate) > 0 {
+		s.Sources = append(s.Sources, fmt.Sprintf("mutate:%s:%v", strings.Join(opts.Mutate, ","), opts.MutateYears))
+	}
+	if len(opts.Prefix) > 0 || len(opts.Suffix) > 0 {
+		s.Sources = append(s.Sources, fmt.Sprintf("affix:%s:%s", strings.Join(opts.Prefix, ","), strings.Join(opts.Suffix, ",")))
+	}
+	if len(opts.Case) > 0 {
+		s.Sources = append(s.Sources, "case:"+strings.Join(opts.Case, ","))
+	}
+	if len(opts.Unicode) > 0 {
+		s.Sources = append(s.Sources, "unicode:"+strings.Join(opts.Unicode, ","))
+	}
+	if opts.JSONL != "" {
+		s.Sources = append(s.Sources, "jsonl:"+opts.JSONL)
+	}
+	if opts.Split != "" {
+		s.Sources = append(s.Sources, fmt.Sprintf("split:%s:%s", opts.SplitDelim, opts.Split))
+	}
+	s.Sources = append(s.Sources, opts.Replace...)
+	if len(s.Sources) > 1 {
+		s.Sources = append(s.Sources, "mode:"+opts.ReplaceMode)
+	}
+	if opts.Shuffle != "" {
+		s.Sources = append(s.Sources, fmt.Sprintf("shuffle:%d", opts.shuffleSeed))
+	}
+	if opts.Shard != "" {
+		s.Sources = append(s.Sources, "shard:"+opts.Shard)
+	}
+	if len(opts.Methods) > 0 {
+		s.Sources = append(s.Sources, "methods:"+strings.Join(opts.Methods, ","))
+	}
+	if len(opts.targets) > 0 {
+		// the list of targets may be long, so only a hash of it is saved
+		targetsHash := sha256.Sum256([]byte(strings.Join(opts.targets, "\n")))
+		s.Sources = append(s.Sources, "targets:"+hex.EncodeToString(targetsHash[:]))
+	}
+
+	add := func(name string, values []string) {
+		if len(values) > 0 {
+			s.Filters = append(s.Filters, fmt.Sprintf("%s %s", name, strings.Join(values, ",")))
+		}
+	}
+	if opts.Calibrate {
+		s.Filters = append(s.Filters, "calibrate")
+	}
+	if opts.HideSoft404 {
+		s.Filters = append(s.Filters, fmt.Sprintf("hide-soft404 %d", opts.SimilarDistance))
+	}
+	if opts.HideSimilar {
+		s.Filters = append(s.Filters, fmt.Sprintf("hide-similar %d", opts.SimilarDistance))
+	}
+	add("hide-status", opts.HideStatusCodes)
+	add("show-status", opts.ShowStatusCodes)
+	add("hide-header-size", opts.HideHeaderSize)
+	add("hide-body-size", opts.HideBodySize)
+	add("hide-words", opts.HideWords)
+	add("hide-lines", opts.HideLines)
+	add("hide-time", opts.HideTime)
+	add("show-time", opts.ShowTime)
+	add("hide-pattern", opts.HidePattern)
+	add("show-pattern", opts.ShowPattern)
+	add("hide-header", opts.HideHeader)
+	add("show-header", opts.ShowHeader)
+	add("hide-location", opts.HideLocation)
+	add("show-location", opts.ShowLocation)
+	add("hide-content-type", opts.HideContentType)
+	add("show-content-type", opts.ShowContentType)
+	add("hide-json", opts.HideJSON)
+	add("show-json", opts.ShowJSON)
+	add("hide-body-pattern", opts.HideBodyPattern)
+	add("show-body-pattern", opts.ShowBodyPattern)
+	for _, expr := range opts.FilterExpr {
+		s.Filters = append(s.Filters, "filter-expr "+expr)
+	}
+
+	return s, nil
+}
+
+// loadResumeState loads the state from filename and checks that it matches
+// the current run. Different filters are reported, but allowed.
+func loadResumeState(filename string, current state.State, term cli.Terminal) (state.State, error) {
+	s, err := state.Load(filename)
+	if err != nil {
+		return state.State{}, fmt.Errorf("unable to load state: %v", err)
+	}
+
+	switch {
+	case s.URL != current.URL:
+		return state.State{}, fmt.Errorf("state file %v was saved for a different URL (%v)", filename, s.URL)
+	case s.TemplateHash != current.TemplateHash:
+		return state.State{}, fmt.Errorf("state file %v was saved for a different request template", filename)
+	case strings.Join(s.Sources, "\n") != strings.Join(current.Sources, "\n"):
+		return state.State{}, fmt.Errorf("state file %v was saved for different sources (%v)", filename, strings.Join(s.Sources, ", "))
+	case s.Skip != current.Skip || s.Limit != current.Limit:
+		return state.State{}, fmt.Errorf("state file %v was saved for different --skip or --limit (%v, %v)", filename, s.Skip, s.Limit)
+	}
+
+	if strings.Join(s.Filters, "\n") != strings.Join(current.Filters, "\n") {
+		term.Printf("warning: filters differ from the ones used in the previous run: %v\n", strings.Join(s.Filters, ", "))
+	}
+
+	return s, nil
+}
@@ -0,0 +1,66 @@
+package fuzz
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+)
+
+// targetPlaceholder is replaced with the scheme and host of each target read
+// from the file passed to --target-file.
+const targetPlaceholder = "%TARGET%"
+
+// readTargets returns the targets (scheme, host and port) listed in filename.
+// The scheme defaults to https.
+func readTargets(filename string) ([]string, error) {
+	lines, err := producer.ReadLines(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no targets found in %v", filename)
+	}
+
+	var targets []string
+	for _, line := range lines {
+		if !strings.Contains(line, "://") {
+			line = "https://" + line
+		}
+
+		u, err := url.Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: %v", line, err)
+		}
+
+		if u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return nil, fmt.Errorf("invalid target %q, only scheme, host and port are allowed", line)
+		}
+
+		targets = append(targets, u.Scheme+"://"+u.Host)
+	}
+
+	return targets, nil
+}
+
+// targetURL returns the URL s with scheme and host replaced by the
+// placeholder for the target. s may also be just a path.
+func targetURL(s string) (string, error) {
+	if strings.HasPrefix(s, "/") {
+		return targetPlaceholder + s, nil
+	}
+
+	pos := strings.Index(s, "://")
+	if pos < 0 {
+		return "", fmt.Errorf("URL %q must be absolute or a path starting with a slash", s)
+	}
+
+	end := strings.IndexAny(s[pos+3:], "/?#")
+	if end < 0 {
+		return targetPlaceholder, nil
+	}
+
+	return targetPlaceholder + s[pos+3+end:], nil
+}
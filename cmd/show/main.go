@@ -2,12 +2,12 @@ package show
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"net/http/httputil"
 	"os"
 
 	"github.com/RedTeamPentesting/monsoon/request"
+	"github.com/RedTeamPentesting/monsoon/response"
 	"github.com/spf13/cobra"
 )
 
@@ -41,17 +41,21 @@ var cmd = &cobra.Command{
 	Example: helpExamples,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) == 0 {
-			return errors.New("last argument needs to be the URL")
+		targetURL, err := opts.Request.TargetURL(args)
+		if err != nil {
+			return err
 		}
+		opts.Request.URL = targetURL
 
-		if len(args) > 1 {
-			return errors.New("more than one target URL specified")
+		names, values, err := response.Variables(0)
+		if err != nil {
+			return err
 		}
 
-		opts.Request.URL = args[0]
+		names = append([]string{opts.Request.Replace}, names...)
+		values = append([]string{opts.Value}, values...)
 
-		req, err := opts.Request.Apply(opts.Value)
+		req, err := opts.Request.Apply(names, values)
 		if err != nil {
 			return err
 		}
@@ -65,7 +69,14 @@ var cmd = &cobra.Command{
 		fmt.Printf("remote %v, port %v\n\n", host, port)
 
 		// print request with body
-		buf, err := httputil.DumpRequestOut(req, true)
+		var buf []byte
+		if opts.Request.CustomWriter() {
+			var b bytes.Buffer
+			err = response.NewHeaderOrderTransport(nil, opts.Request).WriteRequest(&b, req)
+			buf = b.Bytes()
+		} else {
+			buf, err = httputil.DumpRequestOut(req, true)
+		}
 		if err != nil {
 			return err
 		}
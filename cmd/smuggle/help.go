@@ -0,0 +1,38 @@
+package smuggle
+
+import (
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+const helpShort = "Test for HTTP request smuggling via timing-based probes"
+
+var helpLong = strings.TrimSpace(`
+The 'smuggle' command sends probes with conflicting Content-Length and
+Transfer-Encoding headers to detect HTTP request smuggling (desynchronization
+between a front-end and a back-end server). Each probe has a body which is
+complete for one interpretation and incomplete for the other one, so the
+back-end waits for more data and the request times out if the servers
+disagree:
+
+    CL.TE  the front-end uses Content-Length, the back-end Transfer-Encoding
+    TE.CL  the front-end uses Transfer-Encoding, the back-end Content-Length
+
+Both are tested with a plain and several obfuscated Transfer-Encoding headers,
+which may be ignored by only one of the servers (TE.TE). A probe which times
+out is sent again to confirm the result, and TE.CL is not tested for a
+variant once CL.TE was detected, as the TE.CL probe may disrupt other users
+in this case. A baseline request is sent first, it must be answered within
+the timeout (--timeout).
+
+The probes are sent via HTTP/1.1, each on a new connection (tunnelled with
+CONNECT if a proxy is used). The request line and header fields are built from the options below, the
+method defaults to POST and the body is replaced by the one of the probe.
+` + request.LongHelp)
+
+const helpExamples = `
+Test the server at example.com with a timeout of ten seconds:
+
+    monsoon smuggle --timeout 10s https://www.example.com/
+`
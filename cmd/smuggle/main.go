@@ -0,0 +1,158 @@
+package smuggle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/cli"
+	"github.com/RedTeamPentesting/monsoon/request"
+	"github.com/RedTeamPentesting/monsoon/response"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// Options collect options for the command.
+type Options struct {
+	Request *request.Request // the template for the HTTP request
+	Timeout time.Duration
+}
+
+var opts Options
+
+// AddCommand adds the command to c.
+func AddCommand(c *cobra.Command) {
+	c.AddCommand(cmd)
+
+	fs := cmd.Flags()
+	fs.SortFlags = false
+
+	opts.Request = request.New("")
+	request.AddFlags(opts.Request, fs)
+
+	fs.DurationVar(&opts.Timeout, "timeout", 5*time.Second, "consider a probe to be answered late after `duration`")
+}
+
+var cmd = &cobra.Command{
+	Use:                   "smuggle [options] URL",
+	DisableFlagsInUseLine: true,
+
+	Short:   helpShort,
+	Long:    helpLong,
+	Example: helpExamples,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.WithContext(func(ctx context.Context, g *errgroup.Group) error {
+			return run(ctx, g, &opts, args)
+		})
+	},
+}
+
+// result returns a description of res.
+func result(res response.SmuggleResult) string {
+	switch {
+	case res.Error != nil:
+		return fmt.Sprintf("error: %v", res.Error)
+	case res.Timeout:
+		return fmt.Sprintf("timeout after %v", res.Duration.Round(time.Millisecond))
+	default:
+		return fmt.Sprintf("%v (%v)", res.Status, res.Duration.Round(time.Millisecond))
+	}
+}
+
+// probe sends the probe and sends it again if it times out. It returns true
+// if both requests timed out.
+func probe(ctx context.Context, send func(response.SmuggleProbe) response.SmuggleResult, p response.SmuggleProbe) bool {
+	res := send(p)
+	if res.Timeout && ctx.Err() == nil {
+		res = send(p)
+	}
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	detected := res.Timeout
+	msg := result(res)
+	if detected {
+		msg += ", possible " + p.Technique + " desync"
+	}
+
+	fmt.Printf("%-6s  %-20s  %s\n", p.Technique, p.Variant.Name, msg)
+	return detected
+}
+
+func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) error {
+	if opts.Timeout <= 0 {
+		return errors.New("--timeout must be positive")
+	}
+
+	targetURL, err := opts.Request.TargetURL(args)
+	if err != nil {
+		return err
+	}
+	opts.Request.URL = targetURL
+
+	if opts.Request.Method == "" {
+		opts.Request.Method = "POST"
+	}
+
+	req, err := opts.Request.Apply(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if response.IsWebSocket(req) {
+		return errors.New("WebSocket URLs are not supported")
+	}
+
+	cert, err := response.LoadClientCertificate(opts.Request.TLSClientKeyCertFile,
+		opts.Request.TLSClientKeyFile, opts.Request.TLSClientCertPassword)
+	if err != nil {
+		return err
+	}
+
+	tr, err := response.NewTransport(opts.Request, cert)
+	if err != nil {
+		return err
+	}
+
+	send := func(p response.SmuggleProbe) response.SmuggleResult {
+		return response.Smuggle(ctx, req, tr, p, opts.Timeout)
+	}
+
+	baseline := send(response.SmuggleProbe{})
+	if baseline.Error != nil {
+		return fmt.Errorf("baseline request failed: %v", baseline.Error)
+	}
+	if baseline.Timeout {
+		return fmt.Errorf("baseline request timed out after %v, increase --timeout", opts.Timeout)
+	}
+	fmt.Printf("baseline: %v\n\n", result(baseline))
+
+	var found int
+	for _, variant := range response.SmuggleVariants {
+		if probe(ctx, send, response.SmuggleProbe{Technique: response.SmuggleCLTE, Variant: variant}) {
+			found++
+			continue
+		}
+
+		if probe(ctx, send, response.SmuggleProbe{Technique: response.SmuggleTECL, Variant: variant}) {
+			found++
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+
+	fmt.Println()
+	if found == 0 {
+		fmt.Println("no desync detected")
+		return nil
+	}
+
+	fmt.Printf("%d probes timed out twice, the target may be vulnerable to request smuggling\n", found)
+	return nil
+}
@@ -3,15 +3,16 @@ package test
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"net/http/httputil"
 	"os"
 	"strings"
 
 	"github.com/RedTeamPentesting/monsoon/cli"
+	"github.com/RedTeamPentesting/monsoon/producer"
 	"github.com/RedTeamPentesting/monsoon/request"
 	"github.com/RedTeamPentesting/monsoon/response"
+	"github.com/RedTeamPentesting/monsoon/shell"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 )
@@ -67,17 +68,22 @@ var cmd = &cobra.Command{
 }
 
 func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) error {
-	if len(args) == 0 {
-		return errors.New("last argument needs to be the URL")
+	targetURL, err := opts.Request.TargetURL(args)
+	if err != nil {
+		return err
 	}
+	opts.Request.URL = targetURL
 
-	if len(args) > 1 {
-		return errors.New("more than one target URL specified")
+	// show the request with the built-in variables the runner inserts
+	names, values, err := response.Variables(0)
+	if err != nil {
+		return err
 	}
 
-	opts.Request.URL = args[0]
+	names = append([]string{opts.Request.Replace}, names...)
+	values = append([]string{opts.Value}, values...)
 
-	req, err := opts.Request.Apply(opts.Value)
+	req, err := opts.Request.Apply(names, values)
 	if err != nil {
 		return err
 	}
@@ -93,7 +99,14 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 	if opts.ShowRequest {
 		fmt.Println(header("request"))
 		// print request with body
-		buf, err := httputil.DumpRequestOut(req, true)
+		var buf []byte
+		if opts.Request.CustomWriter() {
+			var b bytes.Buffer
+			err = response.NewHeaderOrderTransport(nil, opts.Request).WriteRequest(&b, req)
+			buf = b.Bytes()
+		} else {
+			buf, err = httputil.DumpRequestOut(req, true)
+		}
 		if err != nil {
 			return err
 		}
@@ -109,18 +122,62 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 		}
 	}
 
-	input := make(chan string, 1)
-	input <- opts.Value
+	input := make(chan producer.Item, 1)
+	input <- producer.Item{Values: []string{opts.Value}}
 	close(input)
 
 	output := make(chan response.Response, 1)
 
-	tr, err := response.NewTransport(opts.Request.Insecure, opts.Request.TLSClientKeyCertFile, opts.Request.DisableHTTP2)
+	// the file names and the server name may contain the placeholder
+	names, values = []string{opts.Request.Replace}, []string{opts.Value}
+	opts.Request.SNI = request.Insert(opts.Request.SNI, names, values)
+	cert, err := response.LoadClientCertificate(
+		request.Insert(opts.Request.TLSClientKeyCertFile, names, values),
+		request.Insert(opts.Request.TLSClientKeyFile, names, values),
+		opts.Request.TLSClientCertPassword)
+	if err != nil {
+		return err
+	}
+
+	tr, err := response.NewTransport(opts.Request, cert)
 	if err != nil {
 		return err
 	}
 
 	runner := response.NewRunner(tr, opts.Request, input, output)
+	if opts.Request.NTLM != "" {
+		cred, err := response.ParseNTLMCredentials(opts.Request.NTLM)
+		if err != nil {
+			return err
+		}
+		runner.Client.Transport = response.NewNTLMTransport(tr, cred)
+	}
+	if opts.Request.Digest != "" {
+		runner.Client.Transport, err = response.NewDigestTransport(runner.Client.Transport, opts.Request.Digest)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.Request.AWSSign != "" {
+		cred, err := response.ParseAWSCredentials(opts.Request.AWSCredentials)
+		if err != nil {
+			return err
+		}
+		runner.Client.Transport, err = response.NewAWSTransport(runner.Client.Transport, cred, opts.Request.AWSSign)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.Request.SignCommand != "" {
+		args, err := shell.Split(opts.Request.SignCommand)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("invalid command: %q", opts.Request.SignCommand)
+		}
+		runner.Client.Transport = &response.SignCommandTransport{Transport: runner.Client.Transport, Command: args}
+	}
 	runner.Run(ctx)
 	close(output)
 
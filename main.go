@@ -7,6 +7,7 @@ import (
 	"github.com/RedTeamPentesting/monsoon/cmd/fuzz"
 	"github.com/RedTeamPentesting/monsoon/cmd/list"
 	"github.com/RedTeamPentesting/monsoon/cmd/show"
+	"github.com/RedTeamPentesting/monsoon/cmd/smuggle"
 	"github.com/RedTeamPentesting/monsoon/cmd/test"
 	"github.com/spf13/cobra"
 )
@@ -23,6 +24,7 @@ func init() {
 	fuzz.AddCommand(cmdRoot)
 	show.AddCommand(cmdRoot)
 	test.AddCommand(cmdRoot)
+	smuggle.AddCommand(cmdRoot)
 	list.AddCommand(cmdRoot)
 }
 
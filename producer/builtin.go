@@ -0,0 +1,290 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// builtinLists contains small wordlists compiled into the binary, so quick
+// scans work without any external files.
+var builtinLists = map[string]string{
+	"common-paths": `.env
+.git/HEAD
+.git/config
+.htaccess
+.htpasswd
+.svn/entries
+.well-known/security.txt
+.DS_Store
+admin
+administrator
+admin.php
+adminer.php
+api
+api/v1
+api/v2
+app
+assets
+backup
+backups
+bak
+beta
+bin
+cgi-bin
+config
+config.php
+config.json
+console
+cpanel
+dashboard
+data
+db
+debug
+default
+dev
+docs
+download
+downloads
+dump
+error
+files
+graphql
+health
+healthz
+home
+images
+img
+include
+includes
+index.html
+index.php
+info.php
+install
+js
+lib
+log
+login
+logout
+logs
+manage
+manager
+metrics
+old
+panel
+php.ini
+phpinfo.php
+phpmyadmin
+portal
+private
+public
+robots.txt
+server-status
+server-info
+setup
+sitemap.xml
+static
+status
+swagger
+swagger.json
+swagger-ui.html
+temp
+test
+tmp
+upload
+uploads
+user
+users
+v1
+v2
+vendor
+web.config
+webdav
+wp-admin
+wp-config.php
+wp-content
+wp-login.php`,
+	"parameters": `access_token
+action
+api_key
+callback
+cat
+category
+cmd
+code
+command
+debug
+dir
+doc
+document
+download
+email
+file
+filename
+filter
+format
+from
+host
+id
+include
+key
+lang
+language
+limit
+locale
+login
+mode
+name
+next
+offset
+order
+page
+password
+path
+q
+query
+redirect
+redirect_uri
+ref
+return
+returnUrl
+search
+sort
+source
+src
+start
+table
+template
+to
+token
+type
+uid
+url
+user
+username
+view`,
+	"ranges": `bytes=0-0
+bytes=0-1
+bytes=0-99
+bytes=1-1
+bytes=100-
+bytes=0-
+bytes=-1
+bytes=-100
+bytes=-0
+bytes=1-0
+bytes=0-18446744073709551615
+bytes=18446744073709551615-
+bytes=-18446744073709551616
+bytes=0-0,-1
+bytes=0-1,2-3
+bytes=0-1,1-2
+bytes=0-,0-,0-,0-,0-
+bytes=0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0
+bytes = 0-0
+bytes=0 - 0
+Bytes=0-0
+BYTES=0-0
+bytes=0-0;
+bytes=00-01
+bytes=+0-1
+bytes=0x0-0x1
+bytes=a-b
+bytes=
+bytes
+items=0-0
+none=0-0`,
+	"vhosts": `admin
+api
+app
+auth
+beta
+blog
+cdn
+ci
+cms
+dev
+docs
+files
+ftp
+git
+gitlab
+grafana
+internal
+intranet
+jenkins
+jira
+login
+m
+mail
+monitor
+mx
+new
+ns1
+old
+portal
+proxy
+qa
+remote
+shop
+sso
+stage
+staging
+static
+status
+support
+test
+uat
+vpn
+webmail
+wiki
+www`,
+}
+
+// BuiltinLists returns the names of the built-in wordlists.
+func BuiltinLists() []string {
+	names := make([]string, 0, len(builtinLists))
+	for name := range builtinLists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuiltinSource produces the values of a built-in wordlist.
+type BuiltinSource struct {
+	Name string
+}
+
+// NewBuiltinSource returns a source for the built-in wordlist name.
+func NewBuiltinSource(name string) (*BuiltinSource, error) {
+	if _, ok := builtinLists[name]; !ok {
+		return nil, fmt.Errorf("unknown built-in wordlist %q, valid names are: %s", name, strings.Join(BuiltinLists(), ", "))
+	}
+
+	return &BuiltinSource{Name: name}, nil
+}
+
+// Yield sends all values of the wordlist to ch.
+func (s *BuiltinSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	list, ok := builtinLists[s.Name]
+	if !ok {
+		return fmt.Errorf("unknown built-in wordlist %q", s.Name)
+	}
+
+	values := strings.Split(list, "\n")
+	count <- len(values)
+
+	for _, v := range values {
+		select {
+		case ch <- v:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package producer
+
+import (
+	"testing"
+)
+
+func TestBuiltinSource(t *testing.T) {
+	for _, name := range BuiltinLists() {
+		t.Run(name, func(t *testing.T) {
+			src, err := NewBuiltinSource(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, n := yieldAll(t, src)
+
+			seen := make(map[string]struct{})
+			for _, v := range values {
+				if v == "" {
+					t.Errorf("empty value found")
+				}
+
+				if _, ok := seen[v]; ok {
+					t.Errorf("duplicate value %q found", v)
+				}
+				seen[v] = struct{}{}
+			}
+
+			if n != len(seen) {
+				t.Errorf("wrong count, want %d, got %d", len(seen), n)
+			}
+		})
+	}
+}
+
+func TestBuiltinSourceUnknown(t *testing.T) {
+	_, err := NewBuiltinSource("foobar")
+	if err == nil {
+		t.Fatal("expected error for unknown wordlist not found")
+	}
+}
@@ -0,0 +1,159 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ParseCharset expands a character set specification like "a-z0-9_". A dash
+// at the beginning or the end of the specification is used literally.
+func ParseCharset(spec string) ([]rune, error) {
+	var charset []rune
+	seen := make(map[rune]struct{})
+	add := func(r rune) {
+		if _, ok := seen[r]; ok {
+			return
+		}
+		seen[r] = struct{}{}
+		charset = append(charset, r)
+	}
+
+	s := []rune(spec)
+	for i := 0; i < len(s); i++ {
+		if i+2 < len(s) && s[i+1] == '-' {
+			first, last := s[i], s[i+2]
+			if first > last {
+				return nil, fmt.Errorf("invalid range %q in charset %q", string(s[i:i+3]), spec)
+			}
+
+			for r := first; r <= last; r++ {
+				add(r)
+			}
+			i += 2
+			continue
+		}
+
+		add(s[i])
+	}
+
+	if len(charset) == 0 {
+		return nil, errors.New("charset is empty")
+	}
+
+	return charset, nil
+}
+
+// CharsetSource produces all strings consisting of characters from Charset
+// with a length between MinLength and MaxLength (inclusive), shorter strings
+// are sent first.
+type CharsetSource struct {
+	Charset              []rune
+	MinLength, MaxLength int
+}
+
+// Count returns the number of strings the source produces.
+func (s *CharsetSource) Count() (int, error) {
+	if s.MinLength < 0 || s.MaxLength < s.MinLength {
+		return 0, fmt.Errorf("invalid length %d-%d", s.MinLength, s.MaxLength)
+	}
+
+	total := 0
+	for l := s.MinLength; l <= s.MaxLength; l++ {
+		sets := make([][]rune, l)
+		for i := range sets {
+			sets[i] = s.Charset
+		}
+
+		n, err := countProduct(sets)
+		if err != nil {
+			return 0, err
+		}
+
+		if total > maxInt-n {
+			return 0, errors.New("too many values")
+		}
+		total += n
+	}
+
+	return total, nil
+}
+
+// Yield sends all strings to ch.
+func (s *CharsetSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	total, err := s.Count()
+	if err != nil {
+		return err
+	}
+
+	count <- total
+
+	for l := s.MinLength; l <= s.MaxLength; l++ {
+		sets := make([][]rune, l)
+		for i := range sets {
+			sets[i] = s.Charset
+		}
+
+		if !sendProduct(ctx, sets, ch) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// maxInt is the largest value of type int.
+const maxInt = int(^uint(0) >> 1)
+
+// countProduct returns the number of strings built by taking one character
+// from each of the sets.
+func countProduct(sets [][]rune) (int, error) {
+	n := 1
+	for _, set := range sets {
+		if len(set) > 0 && n > maxInt/len(set) {
+			return 0, errors.New("too many values")
+		}
+		n *= len(set)
+	}
+	return n, nil
+}
+
+// sendProduct sends all strings built by taking one character from each of
+// the sets to ch, the last position changes most frequently. It returns false
+// when the context has been cancelled.
+func sendProduct(ctx context.Context, sets [][]rune, ch chan<- string) bool {
+	for _, set := range sets {
+		if len(set) == 0 {
+			return true
+		}
+	}
+
+	pos := make([]int, len(sets))
+	buf := make([]rune, len(sets))
+	for {
+		for i, set := range sets {
+			buf[i] = set[pos[i]]
+		}
+
+		select {
+		case ch <- string(buf):
+		case <-ctx.Done():
+			return false
+		}
+
+		i := len(pos) - 1
+		for ; i >= 0; i-- {
+			pos[i]++
+			if pos[i] < len(sets[i]) {
+				break
+			}
+			pos[i] = 0
+		}
+
+		if i < 0 {
+			return true
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package producer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCharset(t *testing.T) {
+	var tests = []struct {
+		spec string
+		want string
+		err  bool
+	}{
+		{spec: "abc", want: "abc"},
+		{spec: "a-e", want: "abcde"},
+		{spec: "a-c0-2_", want: "abc012_"},
+		{spec: "-a-c", want: "-abc"},
+		{spec: "a-c-", want: "abc-"},
+		{spec: "aab-c", want: "abc"},
+		{spec: "z-a", err: true},
+		{spec: "", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			res, err := ParseCharset(test.spec)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error for %q not returned", test.spec)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(res) != test.want {
+				t.Fatalf("wrong charset for %q, want %q, got %q", test.spec, test.want, string(res))
+			}
+		})
+	}
+}
+
+func TestCharsetSource(t *testing.T) {
+	src := &CharsetSource{Charset: []rune("ab"), MinLength: 1, MaxLength: 2}
+
+	values, n := yieldAll(t, src)
+
+	want := []string{"a", "b", "aa", "ab", "ba", "bb"}
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if n != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), n)
+	}
+}
+
+func TestMaskSource(t *testing.T) {
+	var tests = []struct {
+		mask  string
+		count int
+		first []string
+		err   bool
+	}{
+		{mask: "x?d", count: 10, first: []string{"x0", "x1", "x2"}},
+		{mask: "?u?l", count: 26 * 26, first: []string{"Aa", "Ab"}},
+		{mask: "???h", count: 16, first: []string{"?0", "?1"}},
+		{mask: "?a", count: 95, first: []string{"a", "b"}},
+		{mask: "abc", count: 1, first: []string{"abc"}},
+		{mask: "?x", err: true},
+		{mask: "a?", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			src, err := ParseMask(test.mask)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error for %q not returned", test.mask)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, n := yieldAll(t, src)
+
+			if n != test.count || len(values) != test.count {
+				t.Errorf("wrong count, want %d, got %d (%d values)", test.count, n, len(values))
+			}
+
+			if !cmp.Equal(test.first, values[:len(test.first)]) {
+				t.Error(cmp.Diff(test.first, values[:len(test.first)]))
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// CommandSource produces the lines printed by an external command to stdout.
+// The command is blocked when it writes faster than values are consumed.
+type CommandSource struct {
+	Command []string
+}
+
+// Yield runs the command and sends each line it prints to ch.
+func (s *CommandSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	if len(s.Command) == 0 {
+		close(ch)
+		return fmt.Errorf("command is empty")
+	}
+
+	cmd := exec.CommandContext(ctx, s.Command[0], s.Command[1:]...)
+	cmd.Stderr = os.Stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		close(ch)
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		close(ch)
+		return fmt.Errorf("command %s failed: %v", s.Command, err)
+	}
+
+	// the number of lines is only known when the command has exited
+	count <- UnknownCount
+
+	err = Reader(ctx, stdout, ch, make(chan int, 1))
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		// the command has been killed because the context was cancelled
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("command %s failed: %v", s.Command, err)
+	}
+
+	return nil
+}
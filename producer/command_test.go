@@ -0,0 +1,108 @@
+package producer
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func runCommandSource(cmd ...string) ([]string, error) {
+	src := &CommandSource{Command: cmd}
+	ch := make(chan string)
+	count := make(chan int, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- src.Yield(context.Background(), ch, count)
+	}()
+
+	var values []string
+	for v := range ch {
+		values = append(values, v)
+	}
+
+	return values, <-errCh
+}
+
+func TestCommandSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test needs a POSIX shell")
+	}
+
+	values, err := runCommandSource("sh", "-c", "echo foo; echo; printf 'bar\\nbaz'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"foo", "", "bar", "baz"}
+	if !cmp.Equal(want, values) {
+		t.Fatal(cmp.Diff(want, values))
+	}
+}
+
+func TestCommandSourceFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test needs a POSIX shell")
+	}
+
+	values, err := runCommandSource("sh", "-c", "echo foo; exit 23")
+	if err == nil {
+		t.Fatal("expected error for non-zero exit status not found")
+	}
+
+	want := []string{"foo"}
+	if !cmp.Equal(want, values) {
+		t.Fatal(cmp.Diff(want, values))
+	}
+
+	_, err = runCommandSource()
+	if err == nil {
+		t.Fatal("expected error for empty command not found")
+	}
+}
+
+func TestCommandSourceCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test needs a POSIX shell")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := &CommandSource{Command: []string{"sh", "-c", "while true; do echo foo; done"}}
+	ch := make(chan string)
+	count := make(chan int, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- src.Yield(ctx, ch, count)
+	}()
+
+	for i := 0; i < 3; i++ {
+		if v := <-ch; v != "foo" {
+			t.Fatalf("wrong value, want %q, got %q", "foo", v)
+		}
+	}
+
+	if n := <-count; n != UnknownCount {
+		t.Fatalf("wrong count, want %v, got %v", UnknownCount, n)
+	}
+
+	cancel()
+
+	// drain the values which were sent before the command was killed
+	go func() {
+		for range ch {
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("command was not stopped")
+	}
+}
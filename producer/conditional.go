@@ -0,0 +1,146 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConditionalSource produces values for conditional request headers, derived
+// from the ETag (Kind "etag", for If-None-Match and If-Match) or the
+// Last-Modified header (Kind "date", for If-Modified-Since and
+// If-Unmodified-Since) of the resource at URL. The resource is only fetched
+// once, even if Yield is called again.
+type ConditionalSource struct {
+	URL    string
+	Kind   string
+	Client *http.Client
+
+	once   sync.Once
+	values []string
+	err    error
+}
+
+// NewConditionalSource returns a source for the kind of values.
+func NewConditionalSource(url, kind string, client *http.Client) (*ConditionalSource, error) {
+	if kind != "etag" && kind != "date" {
+		return nil, fmt.Errorf("unknown kind of conditional values %q, use etag or date", kind)
+	}
+
+	return &ConditionalSource{URL: url, Kind: kind, Client: client}, nil
+}
+
+// Yield fetches the resource and sends the values to ch.
+func (s *ConditionalSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	s.once.Do(func() {
+		s.values, s.err = s.discover(ctx)
+	})
+	if s.err != nil {
+		return s.err
+	}
+
+	count <- len(s.values)
+
+	for _, v := range s.values {
+		select {
+		case ch <- v:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// discover fetches the resource and returns the values for its header.
+func (s *ConditionalSource) discover(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("fetch %v: %v", s.URL, err)
+	}
+	_ = res.Body.Close()
+
+	if s.Kind == "etag" {
+		return etagValues(res.Header.Get("ETag")), nil
+	}
+
+	lastModified, err := http.ParseTime(res.Header.Get("Last-Modified"))
+	if err != nil {
+		// without a date from the server, test around the current time
+		lastModified = time.Now().UTC().Truncate(time.Second)
+	}
+
+	return dateValues(lastModified), nil
+}
+
+// etagValues returns variations of etag (which may be empty).
+func etagValues(etag string) []string {
+	values := []string{`*`, `""`, `"x"`, `W/"x"`}
+	if etag == "" {
+		return values
+	}
+
+	opaque := strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	candidates := []string{
+		etag,
+		`"` + opaque + `"`,
+		`W/"` + opaque + `"`,
+		opaque,
+		`"` + strings.ToUpper(opaque) + `"`,
+		`"` + opaque + `-gzip"`,
+		`"` + opaque + `x"`,
+		`"` + opaque,
+		`"x", "` + opaque + `"`,
+		`"` + opaque + `", "x"`,
+	}
+
+	// the ETag may already be weak or upper case
+	seen := make(map[string]struct{})
+	for _, v := range values {
+		seen[v] = struct{}{}
+	}
+	for _, v := range candidates {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		values = append(values, v)
+	}
+
+	return values
+}
+
+// dateValues returns dates around t in several formats.
+func dateValues(t time.Time) []string {
+	t = t.UTC()
+	return []string{
+		t.Format(http.TimeFormat),
+		t.Add(-time.Second).Format(http.TimeFormat),
+		t.Add(time.Second).Format(http.TimeFormat),
+		t.AddDate(-1, 0, 0).Format(http.TimeFormat),
+		t.AddDate(1, 0, 0).Format(http.TimeFormat),
+		time.Unix(0, 0).UTC().Format(http.TimeFormat),
+		t.Format(time.RFC850),
+		t.Format(time.ANSIC),
+		t.Format(time.RFC3339),
+		strings.ToLower(t.Format(http.TimeFormat)),
+		t.Format("Mon, 02 Jan 2006 15:04:05 -0700"),
+		fmt.Sprintf("%d", t.Unix()),
+		"invalid",
+	}
+}
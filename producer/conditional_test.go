@@ -0,0 +1,68 @@
+package producer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConditionalSource(t *testing.T) {
+	modified := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `W/"abc"`)
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	}))
+	defer srv.Close()
+
+	var tests = []struct {
+		kind string
+		want []string // values which must be produced
+	}{
+		{"etag", []string{`*`, `W/"abc"`, `"abc"`, `abc`, `"ABC"`, `"x", "abc"`}},
+		{"date", []string{
+			"Mon, 01 May 2023 12:00:00 GMT",
+			"Mon, 01 May 2023 11:59:59 GMT",
+			"Mon, 01 May 2023 12:00:01 GMT",
+			"Thu, 01 Jan 1970 00:00:00 GMT",
+			"Monday, 01-May-23 12:00:00 UTC",
+			"invalid",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.kind, func(t *testing.T) {
+			src, err := NewConditionalSource(srv.URL, test.kind, srv.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, n := yieldAll(t, src)
+
+			seen := make(map[string]struct{})
+			for _, v := range values {
+				if _, ok := seen[v]; ok {
+					t.Errorf("duplicate value %q found", v)
+				}
+				seen[v] = struct{}{}
+			}
+
+			if n != len(seen) {
+				t.Errorf("wrong count, want %d, got %d", len(seen), n)
+			}
+
+			for _, v := range test.want {
+				if _, ok := seen[v]; !ok {
+					t.Errorf("value %q not found in %v", v, seen)
+				}
+			}
+		})
+	}
+}
+
+func TestConditionalSourceUnknown(t *testing.T) {
+	_, err := NewConditionalSource("http://example.com", "foobar", nil)
+	if err == nil {
+		t.Fatal("expected error for unknown kind not found")
+	}
+}
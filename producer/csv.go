@@ -0,0 +1,105 @@
+package producer
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CSVSource produces the values of one column of a CSV file. Files with the
+// extension .tsv are read as tab separated values. Column selects the column
+// either by number (starting at 1) or by the name in the first row, which is
+// skipped in this case.
+type CSVSource struct {
+	Filename string
+	Column   string
+}
+
+// ParseCSVSource parses a CSVSource from a string "filename:column".
+func ParseCSVSource(s string) (*CSVSource, error) {
+	pos := strings.LastIndex(s, ":")
+	if pos <= 0 || pos == len(s)-1 {
+		return nil, fmt.Errorf("invalid CSV source %q, expected filename:column", s)
+	}
+
+	src := &CSVSource{Filename: s[:pos], Column: s[pos+1:]}
+	if n, err := strconv.Atoi(src.Column); err == nil && n < 1 {
+		return nil, fmt.Errorf("invalid CSV column %d, columns are numbered starting at 1", n)
+	}
+
+	return src, nil
+}
+
+// Yield sends the values of the column to ch.
+func (s *CSVSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	f, err := os.Open(s.Filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rd := csv.NewReader(f)
+	rd.FieldsPerRecord = -1
+	rd.LazyQuotes = true
+	rd.ReuseRecord = true
+	if strings.ToLower(filepath.Ext(s.Filename)) == ".tsv" {
+		rd.Comma = '\t'
+	}
+
+	col, err := strconv.Atoi(s.Column)
+	if err == nil {
+		col--
+	} else {
+		// find the column by name
+		header, err := rd.Read()
+		if err != nil {
+			return fmt.Errorf("read header of %v: %v", s.Filename, err)
+		}
+
+		col = -1
+		for i, name := range header {
+			if name == s.Column {
+				col = i
+				break
+			}
+		}
+
+		if col < 0 {
+			return fmt.Errorf("column %q not found in %v", s.Column, s.Filename)
+		}
+	}
+
+	num := 0
+	for {
+		record, err := rd.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read %v: %v", s.Filename, err)
+		}
+
+		// skip rows which do not have the column
+		if col >= len(record) {
+			continue
+		}
+
+		num++
+
+		select {
+		case ch <- record[col]:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	count <- num
+	return nil
+}
@@ -0,0 +1,77 @@
+package producer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCSVSource(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	var tests = []struct {
+		filename string
+		data     string
+		column   string
+		want     []string
+	}{
+		{
+			filename: "users.csv",
+			data:     "1,admin,x\n2,\"foo, bar\",y\n3,\"multi\nline\"\n4\n",
+			column:   "2",
+			want:     []string{"admin", "foo, bar", "multi\nline"},
+		},
+		{
+			filename: "users.csv",
+			data:     "id,name\n1,admin\n2,test\n",
+			column:   "name",
+			want:     []string{"admin", "test"},
+		},
+		{
+			filename: "users.tsv",
+			data:     "1\tadmin,root\n2\ttest\n",
+			column:   "2",
+			want:     []string{"admin,root", "test"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			filename := filepath.Join(tempdir, test.filename)
+			err := ioutil.WriteFile(filename, []byte(test.data), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			src, err := ParseCSVSource(filename + ":" + test.column)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, count := yieldAll(t, src)
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if count != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), count)
+			}
+		})
+	}
+}
+
+func TestParseCSVSourceInvalid(t *testing.T) {
+	for _, s := range []string{"users.csv", "users.csv:", ":2", "users.csv:0"} {
+		_, err := ParseCSVSource(s)
+		if err == nil {
+			t.Errorf("expected error for %q not found", s)
+		}
+	}
+}
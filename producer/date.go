@@ -0,0 +1,166 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the layouts accepted for the start and end of a date range.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q, expected e.g. 2006-01-02 or 2006-01-02T15:04:05", s)
+}
+
+// DateSource produces all dates between First and Last (inclusive) with the
+// interval Step, formatted with a strftime-like format.
+type DateSource struct {
+	First, Last time.Time
+	Step        time.Duration
+	Format      string
+}
+
+// ParseDateRange parses a date range in the form "first..last".
+func ParseDateRange(s string) (first, last time.Time, err error) {
+	data := strings.SplitN(s, "..", 2)
+	if len(data) != 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("wrong format for date range, expected: first..last, got: %q", s)
+	}
+
+	first, err = parseDate(data[0])
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	last, err = parseDate(data[1])
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if last.Before(first) {
+		return time.Time{}, time.Time{}, fmt.Errorf("last date is before first date for range %q", s)
+	}
+
+	return first, last, nil
+}
+
+// ParseDateStep parses the interval between two dates. In addition to the
+// units understood by time.ParseDuration, the suffix "d" for days is accepted.
+func ParseDateStep(s string) (time.Duration, error) {
+	var step time.Duration
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid step %q: %v", s, err)
+		}
+		step = time.Duration(days) * 24 * time.Hour
+	} else {
+		var err error
+		step, err = time.ParseDuration(s)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if step <= 0 {
+		return 0, errors.New("step must be positive")
+	}
+
+	return step, nil
+}
+
+// Count returns the number of dates the source produces.
+func (s *DateSource) Count() int {
+	return int(s.Last.Sub(s.First)/s.Step) + 1
+}
+
+// Yield sends all dates to ch.
+func (s *DateSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	if s.Step <= 0 {
+		return errors.New("step must be positive")
+	}
+
+	count <- s.Count()
+
+	for t := s.First; !t.After(s.Last); t = t.Add(s.Step) {
+		select {
+		case ch <- Strftime(t, s.Format):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Strftime formats t according to format. Supported are %Y (year), %y (two
+// digit year), %m (month), %d (day), %e (day without padding), %j (day of the
+// year), %H (hour), %M (minute), %S (second), %b and %B (abbreviated and full
+// month name), %a and %A (abbreviated and full weekday name), %s (unix
+// timestamp) and %% (literal percent sign). Other characters are copied
+// literally.
+func Strftime(t time.Time, format string) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			sb.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		switch format[i] {
+		case 'Y':
+			fmt.Fprintf(&sb, "%04d", t.Year())
+		case 'y':
+			fmt.Fprintf(&sb, "%02d", t.Year()%100)
+		case 'm':
+			fmt.Fprintf(&sb, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&sb, "%02d", t.Day())
+		case 'e':
+			fmt.Fprintf(&sb, "%d", t.Day())
+		case 'j':
+			fmt.Fprintf(&sb, "%03d", t.YearDay())
+		case 'H':
+			fmt.Fprintf(&sb, "%02d", t.Hour())
+		case 'M':
+			fmt.Fprintf(&sb, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&sb, "%02d", t.Second())
+		case 'b':
+			sb.WriteString(t.Format("Jan"))
+		case 'B':
+			sb.WriteString(t.Format("January"))
+		case 'a':
+			sb.WriteString(t.Format("Mon"))
+		case 'A':
+			sb.WriteString(t.Format("Monday"))
+		case 's':
+			fmt.Fprintf(&sb, "%d", t.Unix())
+		case '%':
+			sb.WriteByte('%')
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(format[i])
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,58 @@
+package producer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStrftime(t *testing.T) {
+	ts := time.Date(2023, 2, 5, 7, 8, 9, 0, time.UTC)
+
+	var tests = []struct {
+		format string
+		want   string
+	}{
+		{"%Y-%m-%d", "2023-02-05"},
+		{"backup-%y%m%d.tar.gz", "backup-230205.tar.gz"},
+		{"%e %b %Y %H:%M:%S", "5 Feb 2023 07:08:09"},
+		{"%A, %B %j", "Sunday, February 036"},
+		{"%s", "1675580889"},
+		{"100%% %x %", "100% %x %"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			res := Strftime(ts, test.format)
+			if res != test.want {
+				t.Errorf("wrong result for %q, want %q, got %q", test.format, test.want, res)
+			}
+		})
+	}
+}
+
+func TestDateSource(t *testing.T) {
+	first, last, err := ParseDateRange("2023-12-30..2024-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, err := ParseDateStep("1d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := &DateSource{First: first, Last: last, Step: step, Format: "%Y%m%d"}
+
+	values, n := yieldAll(t, src)
+
+	want := []string{"20231230", "20231231", "20240101", "20240102"}
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if n != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), n)
+	}
+}
@@ -0,0 +1,82 @@
+package producer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Encoders contains all functions which can be used to encode values.
+var Encoders = map[string]func(string) string{
+	"url":        url.QueryEscape,
+	"double-url": func(s string) string { return url.QueryEscape(url.QueryEscape(s)) },
+	"path":       url.PathEscape,
+	"base64":     func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"base64url":  func(s string) string { return base64.URLEncoding.EncodeToString([]byte(s)) },
+	"hex":        func(s string) string { return hex.EncodeToString([]byte(s)) },
+	"html":       html.EscapeString,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+}
+
+// EncoderNames returns a sorted list of the names of all encoders.
+func EncoderNames() (names []string) {
+	for name := range Encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseEncoders returns the encoders for a comma-separated list of names.
+// The encoders are to be applied in order.
+func ParseEncoders(spec string) ([]func(string) string, error) {
+	var funcs []func(string) string
+	for _, name := range strings.Split(spec, ",") {
+		f, ok := Encoders[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown encoder %q, valid encoders: %v", name, strings.Join(EncoderNames(), ", "))
+		}
+		funcs = append(funcs, f)
+	}
+	return funcs, nil
+}
+
+// Encode applies the encoders to the values of each item, encoders[i] is
+// applied to the ith value in order. A new goroutine is started, which
+// terminates when in is closed or the context is cancelled.
+func Encode(ctx context.Context, encoders [][]func(string) string, in <-chan Item) <-chan Item {
+	out := make(chan Item)
+
+	go func() {
+		defer close(out)
+		for item := range in {
+			values := make([]string, len(item.Values))
+			copy(values, item.Values)
+
+			for i := range values {
+				if i >= len(encoders) {
+					break
+				}
+
+				for _, f := range encoders[i] {
+					values[i] = f(values[i])
+				}
+			}
+			item.Values = values
+
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,44 @@
+package producer
+
+import "testing"
+
+func TestEncoders(t *testing.T) {
+	var tests = []struct {
+		spec  string
+		input string
+		want  string
+	}{
+		{"url", "a b&c", "a+b%26c"},
+		{"double-url", "a&b", "a%2526b"},
+		{"path", "a b/c", "a%20b%2Fc"},
+		{"base64", "foo:bar", "Zm9vOmJhcg=="},
+		{"hex", "AB", "4142"},
+		{"html", `<a href="x">`, "&lt;a href=&#34;x&#34;&gt;"},
+		{"upper", "Foo", "FOO"},
+		{"lower,base64", "FOO", "Zm9v"},
+		{"base64,url", "ab?", "YWI%2F"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			funcs, err := ParseEncoders(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := test.input
+			for _, f := range funcs {
+				res = f(res)
+			}
+
+			if res != test.want {
+				t.Errorf("wrong result for %q with %q: want %q, got %q", test.input, test.spec, test.want, res)
+			}
+		})
+	}
+
+	_, err := ParseEncoders("url,foo")
+	if err == nil {
+		t.Errorf("expected error for unknown encoder not returned")
+	}
+}
@@ -0,0 +1,60 @@
+package producer
+
+import "context"
+
+// ExpandSource sends Factor variants for each value of Source. Expand must
+// return exactly Factor values, so the number of values can be computed.
+type ExpandSource struct {
+	Source
+	Expand func(string) []string
+	Factor int
+}
+
+// Yield sends the variants of all values to ch.
+func (s *ExpandSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	in := make(chan string)
+	inCount := make(chan int, 1)
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- s.Source.Yield(ctx, in, inCount)
+	}()
+
+	for {
+		select {
+		case n := <-inCount:
+			// disable receiving the count again
+			inCount = nil
+			count <- scaleCount(n, s.Factor)
+
+		case v, ok := <-in:
+			if !ok {
+				// forward the count if it has been sent before in was closed
+				if inCount != nil {
+					select {
+					case n := <-inCount:
+						count <- scaleCount(n, s.Factor)
+					default:
+					}
+				}
+				return <-errCh
+			}
+
+			for _, variant := range s.Expand(v) {
+				select {
+				case ch <- variant:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
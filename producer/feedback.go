@@ -0,0 +1,191 @@
+package producer
+
+import (
+	"context"
+	"sync"
+)
+
+// Feedback forwards items and additionally sends an item for each value added
+// with Add while the items are processed, e.g. strings extracted from the
+// responses. The added value replaces the value with the index Placeholder,
+// each value is only sent once. Items for added values have Feedback set.
+//
+// Done must be called once for each item after it has been processed. The
+// output channel is closed when all items have been processed and no value
+// is queued.
+type Feedback struct {
+	Placeholder int
+
+	m       sync.Mutex
+	queue   []Item
+	added   int
+	seen    map[string]struct{}
+	pending int
+	wakeup  chan struct{}
+}
+
+// NewFeedback returns a new Feedback.
+func NewFeedback(placeholder int) *Feedback {
+	return &Feedback{
+		Placeholder: placeholder,
+		seen:        make(map[string]struct{}),
+		wakeup:      make(chan struct{}, 1),
+	}
+}
+
+func (f *Feedback) signal() {
+	select {
+	case f.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Add queues an item with value for the placeholder, the values for the other
+// placeholders are taken from values. Values which have been added before
+// are ignored.
+func (f *Feedback) Add(values []string, value string) {
+	f.m.Lock()
+	if _, ok := f.seen[value]; ok {
+		f.m.Unlock()
+		return
+	}
+	f.seen[value] = struct{}{}
+
+	item := Item{Values: make([]string, len(values)), Feedback: true}
+	copy(item.Values, values)
+	item.Values[f.Placeholder] = value
+
+	f.queue = append(f.queue, item)
+	f.added++
+	f.m.Unlock()
+	f.signal()
+}
+
+// Done records that an item has been processed.
+func (f *Feedback) Done() {
+	f.m.Lock()
+	f.pending--
+	f.m.Unlock()
+	f.signal()
+}
+
+// Run forwards the items from in, interleaved with the items for the added
+// values. The total number of items is sent to the returned count channel
+// each time it changes. A new goroutine is started, which terminates when all
+// items have been processed or the context is cancelled.
+func (f *Feedback) Run(ctx context.Context, in <-chan Item, inCount <-chan int) (<-chan Item, <-chan int) {
+	out := make(chan Item)
+	outCount := make(chan int, 1)
+
+	go func() {
+		defer close(out)
+
+		index, total := 0, 0
+		unknown := false
+		addCount := func(n int) {
+			if n == UnknownCount {
+				unknown = true
+			} else {
+				total += n
+			}
+
+			// only the latest total is interesting, replace the previous
+			// value if it has not been received yet
+			select {
+			case <-outCount:
+			default:
+			}
+
+			if unknown {
+				outCount <- UnknownCount
+				return
+			}
+			outCount <- total
+		}
+
+		send := func(item Item) bool {
+			if item.Index < index {
+				item.Index = index
+			}
+			index = item.Index + 1
+
+			f.m.Lock()
+			f.pending++
+			f.m.Unlock()
+
+			select {
+			case out <- item:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			f.m.Lock()
+			var item Item
+			queued := len(f.queue) > 0
+			if queued {
+				item = f.queue[0]
+				f.queue = f.queue[1:]
+			}
+			added := f.added
+			f.added = 0
+			done := !queued && in == nil && f.pending == 0
+			f.m.Unlock()
+
+			if added > 0 {
+				addCount(added)
+			}
+
+			if done {
+				return
+			}
+
+			if queued {
+				if !send(item) {
+					return
+				}
+				continue
+			}
+
+			select {
+			case n, ok := <-inCount:
+				inCount = nil
+				if ok {
+					addCount(n)
+				}
+
+			case item, ok := <-in:
+				if !ok {
+					in = nil
+
+					// wait for the count if it has not been received yet
+					if inCount != nil {
+						select {
+						case n, ok := <-inCount:
+							if ok {
+								addCount(n)
+							}
+						case <-ctx.Done():
+							return
+						}
+						inCount = nil
+					}
+					continue
+				}
+
+				if !send(item) {
+					return
+				}
+
+			case <-f.wakeup:
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, outCount
+}
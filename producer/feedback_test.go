@@ -0,0 +1,43 @@
+package producer
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFeedback(t *testing.T) {
+	ch := make(chan Item)
+	count := make(chan int, 1)
+	go func() {
+		mux := &Multiplexer{Names: []string{"FUZZ", "USER"}, Sources: []Source{staticSource{"1", "2", "3"}, staticSource{"admin"}}}
+		_ = mux.Run(context.Background(), ch, count)
+	}()
+
+	f := NewFeedback(0)
+	out, outCount := f.Run(context.Background(), ch, count)
+
+	var values []string
+	for item := range out {
+		values = append(values, item.Values[0]+","+item.Values[1])
+
+		if !item.Feedback {
+			// the same value is extracted from two responses
+			f.Add(item.Values, "id"+item.Values[0])
+			f.Add(item.Values, "common")
+		}
+		f.Done()
+	}
+
+	sort.Strings(values)
+	want := []string{"1,admin", "2,admin", "3,admin", "common,admin", "id1,admin", "id2,admin", "id3,admin"}
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if n := <-outCount; n != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), n)
+	}
+}
@@ -1,6 +1,11 @@
 package producer
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+)
 
 // Filter selects/rejects items received from a producer.
 type Filter interface {
@@ -8,7 +13,7 @@ type Filter interface {
 	Count(ctx context.Context, in <-chan int) <-chan int
 
 	// Select filters the items
-	Select(ctx context.Context, in <-chan string) <-chan string
+	Select(ctx context.Context, in <-chan Item) <-chan Item
 }
 
 // FilterSkip skips the first n values sent over the channel.
@@ -29,9 +34,11 @@ func (f *FilterSkip) Count(ctx context.Context, in <-chan int) <-chan int {
 		}
 
 		// calculate the correct total count
-		if total < f.Skip {
+		switch {
+		case total == UnknownCount:
+		case total < f.Skip:
 			total = 0
-		} else {
+		default:
 			total -= f.Skip
 		}
 
@@ -45,14 +52,14 @@ func (f *FilterSkip) Count(ctx context.Context, in <-chan int) <-chan int {
 }
 
 // Select filters values sent over ch.
-func (f *FilterSkip) Select(ctx context.Context, in <-chan string) <-chan string {
-	out := make(chan string)
+func (f *FilterSkip) Select(ctx context.Context, in <-chan Item) <-chan Item {
+	out := make(chan Item)
 
 	go func() {
 		defer close(out)
 		var cur int
 		for {
-			var v string
+			var v Item
 			var ok bool
 			select {
 			case <-ctx.Done():
@@ -99,7 +106,7 @@ func (f *FilterLimit) Count(ctx context.Context, in <-chan int) <-chan int {
 		}
 
 		// calculate the correct total count
-		if total > f.Max {
+		if total != UnknownCount && total > f.Max {
 			total = f.Max
 		}
 
@@ -113,14 +120,14 @@ func (f *FilterLimit) Count(ctx context.Context, in <-chan int) <-chan int {
 }
 
 // Select filters values sent over ch.
-func (f *FilterLimit) Select(ctx context.Context, in <-chan string) <-chan string {
-	out := make(chan string)
+func (f *FilterLimit) Select(ctx context.Context, in <-chan Item) <-chan Item {
+	out := make(chan Item)
 
 	go func() {
 		defer close(out)
 		var cur int
 		for {
-			var v string
+			var v Item
 			var ok bool
 			select {
 			case <-ctx.Done():
@@ -148,3 +155,390 @@ func (f *FilterLimit) Select(ctx context.Context, in <-chan string) <-chan strin
 
 	return out
 }
+
+// FilterShard passes through every Shards-th value, starting with the value
+// at position Shard (starting at 1), so that the values can be distributed
+// across several machines.
+type FilterShard struct {
+	Shard  int
+	Shards int
+}
+
+// ParseShard parses a shard from a string "N/M".
+func ParseShard(s string) (*FilterShard, error) {
+	var f FilterShard
+	_, err := fmt.Sscanf(s, "%d/%d", &f.Shard, &f.Shards)
+	if err != nil || fmt.Sprintf("%d/%d", f.Shard, f.Shards) != s {
+		return nil, fmt.Errorf("invalid shard %q, expected N/M", s)
+	}
+
+	if f.Shards < 1 || f.Shard < 1 || f.Shard > f.Shards {
+		return nil, fmt.Errorf("invalid shard %q, N must be between 1 and M", s)
+	}
+
+	return &f, nil
+}
+
+// Count filters the number of values.
+func (f *FilterShard) Count(ctx context.Context, in <-chan int) <-chan int {
+	out := make(chan int, 1)
+
+	go func() {
+		defer close(out)
+		var total int
+		select {
+		case total = <-in:
+		case <-ctx.Done():
+		}
+
+		// calculate the correct total count
+		n := total / f.Shards
+		switch {
+		case total == UnknownCount:
+			n = UnknownCount
+		case total%f.Shards >= f.Shard:
+			n++
+		}
+
+		select {
+		case out <- n:
+		case <-ctx.Done():
+		}
+	}()
+
+	return out
+}
+
+// Select filters values sent over ch.
+func (f *FilterShard) Select(ctx context.Context, in <-chan Item) <-chan Item {
+	out := make(chan Item)
+
+	go func() {
+		defer close(out)
+		var cur int
+		for {
+			var v Item
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+				// when the input channel is closed we're done
+				if !ok {
+					return
+				}
+			}
+
+			cur++
+			if (cur-f.Shard)%f.Shards != 0 {
+				// drop value, receive next
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+		}
+	}()
+
+	return out
+}
+
+// FilterResume drops all items with an index smaller than Position, so that an
+// interrupted run can be continued. Done is the number of items which have
+// already been processed before Position.
+type FilterResume struct {
+	Position int
+	Done     int
+}
+
+// Count filters the number of values.
+func (f *FilterResume) Count(ctx context.Context, in <-chan int) <-chan int {
+	out := make(chan int, 1)
+
+	go func() {
+		defer close(out)
+		var total int
+		select {
+		case total = <-in:
+		case <-ctx.Done():
+		}
+
+		// calculate the correct total count
+		switch {
+		case total == UnknownCount:
+		case total < f.Done:
+			total = 0
+		default:
+			total -= f.Done
+		}
+
+		select {
+		case out <- total:
+		case <-ctx.Done():
+		}
+	}()
+
+	return out
+}
+
+// Select filters values sent over ch.
+func (f *FilterResume) Select(ctx context.Context, in <-chan Item) <-chan Item {
+	out := make(chan Item)
+
+	go func() {
+		defer close(out)
+		for {
+			var v Item
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+				// when the input channel is closed we're done
+				if !ok {
+					return
+				}
+			}
+
+			if v.Index < f.Position {
+				// drop value, receive next
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+		}
+	}()
+
+	return out
+}
+
+// FilterShuffle randomizes the order of the values. All values are read into
+// memory before the first one is passed on. The items are renumbered, so the
+// index reflects the position in the shuffled sequence.
+type FilterShuffle struct {
+	Seed int64
+}
+
+// Count filters the number of values.
+func (f *FilterShuffle) Count(ctx context.Context, in <-chan int) <-chan int {
+	return in
+}
+
+// Select filters values sent over ch.
+func (f *FilterShuffle) Select(ctx context.Context, in <-chan Item) <-chan Item {
+	out := make(chan Item)
+
+	go func() {
+		defer close(out)
+
+		var items []Item
+		for {
+			var v Item
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+			}
+
+			if !ok {
+				break
+			}
+
+			items = append(items, v)
+		}
+
+		rnd := rand.New(rand.NewSource(f.Seed))
+		rnd.Shuffle(len(items), func(i, j int) {
+			items[i], items[j] = items[j], items[i]
+		})
+
+		for i, item := range items {
+			item.Index = i
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- item:
+			}
+		}
+	}()
+
+	return out
+}
+
+// FilterBatch combines Size consecutive items into one, so several values can
+// be sent in a single request. The values of the combined items are kept in
+// Batch, Values contains them joined by " | " for display. The items are
+// renumbered.
+type FilterBatch struct {
+	Size int
+}
+
+// Count filters the number of values.
+func (f *FilterBatch) Count(ctx context.Context, in <-chan int) <-chan int {
+	out := make(chan int, 1)
+
+	go func() {
+		defer close(out)
+		var total int
+		select {
+		case total = <-in:
+		case <-ctx.Done():
+		}
+
+		if total != UnknownCount {
+			total = (total + f.Size - 1) / f.Size
+		}
+
+		select {
+		case out <- total:
+		case <-ctx.Done():
+		}
+	}()
+
+	return out
+}
+
+// Select filters values sent over ch.
+func (f *FilterBatch) Select(ctx context.Context, in <-chan Item) <-chan Item {
+	out := make(chan Item)
+
+	go func() {
+		defer close(out)
+
+		var index int
+		var batch [][]string
+
+		send := func() bool {
+			item := Item{Index: index, Batch: batch}
+			for i := range batch[0] {
+				var values []string
+				for _, v := range batch {
+					values = append(values, v[i])
+				}
+				item.Values = append(item.Values, strings.Join(values, " | "))
+			}
+
+			select {
+			case <-ctx.Done():
+				return false
+			case out <- item:
+			}
+
+			index++
+			batch = nil
+			return true
+		}
+
+		for {
+			var v Item
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+			}
+
+			if !ok {
+				break
+			}
+
+			batch = append(batch, v.Values)
+			if len(batch) == f.Size && !send() {
+				return
+			}
+		}
+
+		if len(batch) > 0 {
+			send()
+		}
+	}()
+
+	return out
+}
+
+// FilterTargets sends each item once for each of the targets, with the target
+// appended to the values (and to the values of a batch). The items for all
+// targets are sent directly one after the other (interleaved), so the
+// requests are spread across the targets. The items are renumbered.
+type FilterTargets struct {
+	Targets []string
+}
+
+// Count filters the number of values.
+func (f *FilterTargets) Count(ctx context.Context, in <-chan int) <-chan int {
+	out := make(chan int, 1)
+
+	go func() {
+		defer close(out)
+		var total int
+		select {
+		case total = <-in:
+		case <-ctx.Done():
+		}
+
+		if total != UnknownCount {
+			total *= len(f.Targets)
+		}
+
+		select {
+		case out <- total:
+		case <-ctx.Done():
+		}
+	}()
+
+	return out
+}
+
+// Select filters values sent over ch.
+func (f *FilterTargets) Select(ctx context.Context, in <-chan Item) <-chan Item {
+	out := make(chan Item)
+
+	go func() {
+		defer close(out)
+
+		var index int
+		for {
+			var v Item
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+			}
+
+			if !ok {
+				return
+			}
+
+			for _, target := range f.Targets {
+				item := v
+				item.Index = index
+				item.Values = append(append([]string{}, v.Values...), target)
+
+				item.Batch = nil
+				for _, values := range v.Batch {
+					item.Batch = append(item.Batch, append(append([]string{}, values...), target))
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case out <- item:
+				}
+
+				index++
+			}
+		}
+	}()
+
+	return out
+}
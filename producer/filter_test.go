@@ -0,0 +1,208 @@
+package producer
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// runFilter sends count and items through f and returns the results.
+func runFilter(f Filter, items []Item) ([]Item, int) {
+	ctx := context.Background()
+
+	in := make(chan Item)
+	inCount := make(chan int, 1)
+	inCount <- len(items)
+
+	go func() {
+		for _, item := range items {
+			in <- item
+		}
+		close(in)
+	}()
+
+	countCh := f.Count(ctx, inCount)
+	var res []Item
+	for item := range f.Select(ctx, in) {
+		res = append(res, item)
+	}
+
+	return res, <-countCh
+}
+
+func items(values ...string) (res []Item) {
+	for i, v := range values {
+		res = append(res, Item{Index: i, Values: []string{v}})
+	}
+	return res
+}
+
+func TestFilterShuffle(t *testing.T) {
+	input := items("a", "b", "c", "d", "e", "f", "g", "h")
+
+	first, count := runFilter(&FilterShuffle{Seed: 23}, input)
+	if count != len(input) {
+		t.Errorf("wrong count, want %d, got %d", len(input), count)
+	}
+
+	second, _ := runFilter(&FilterShuffle{Seed: 23}, input)
+	if !cmp.Equal(first, second) {
+		t.Errorf("same seed produced different order: %v", cmp.Diff(first, second))
+	}
+
+	var values []string
+	for i, item := range first {
+		if item.Index != i {
+			t.Errorf("item %d has wrong index %d", i, item.Index)
+		}
+		values = append(values, item.Values[0])
+	}
+
+	sort.Strings(values)
+	want := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+}
+
+func TestFilterShard(t *testing.T) {
+	input := items("a", "b", "c", "d", "e", "f", "g", "h", "i", "j")
+
+	var tests = []struct {
+		shard string
+		want  []string
+	}{
+		{"1/1", []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}},
+		{"1/4", []string{"a", "e", "i"}},
+		{"2/4", []string{"b", "f", "j"}},
+		{"3/4", []string{"c", "g"}},
+		{"4/4", []string{"d", "h"}},
+		{"3/20", []string{"c"}},
+		{"15/20", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.shard, func(t *testing.T) {
+			f, err := ParseShard(test.shard)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, count := runFilter(f, input)
+
+			var values []string
+			for _, item := range res {
+				values = append(values, item.Values[0])
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if count != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), count)
+			}
+		})
+	}
+}
+
+func TestParseShardInvalid(t *testing.T) {
+	for _, s := range []string{"", "1", "0/4", "5/4", "1/0", "-1/4", "1/4x", "a/b"} {
+		_, err := ParseShard(s)
+		if err == nil {
+			t.Errorf("expected error for %q not found", s)
+		}
+	}
+}
+
+func TestFilterSkipLimit(t *testing.T) {
+	input := items("a", "b", "c", "d", "e")
+
+	var tests = []struct {
+		filter Filter
+		want   []string
+	}{
+		{&FilterSkip{Skip: 2}, []string{"c", "d", "e"}},
+		{&FilterSkip{Skip: 5}, nil},
+		{&FilterSkip{Skip: 10}, nil},
+		{&FilterLimit{Max: 2}, []string{"a", "b"}},
+		{&FilterLimit{Max: 10}, []string{"a", "b", "c", "d", "e"}},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			res, count := runFilter(test.filter, input)
+
+			var values []string
+			for _, item := range res {
+				values = append(values, item.Values[0])
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if count != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), count)
+			}
+		})
+	}
+}
+
+func TestFilterUnknownCount(t *testing.T) {
+	var tests = []Filter{
+		&FilterSkip{Skip: 2},
+		&FilterLimit{Max: 2},
+		&FilterShard{Shard: 1, Shards: 3},
+		&FilterResume{Position: 5, Done: 3},
+		&FilterShuffle{Seed: 23},
+		&FilterBatch{Size: 3},
+	}
+
+	for _, f := range tests {
+		t.Run("", func(t *testing.T) {
+			inCount := make(chan int, 1)
+			inCount <- UnknownCount
+
+			n := <-f.Count(context.Background(), inCount)
+			if n != UnknownCount {
+				t.Errorf("wrong count, want %d, got %d", UnknownCount, n)
+			}
+		})
+	}
+}
+
+func TestFilterBatch(t *testing.T) {
+	res, count := runFilter(&FilterBatch{Size: 2}, items("a", "b", "c", "d", "e"))
+	if count != 3 {
+		t.Errorf("wrong count, want 3, got %d", count)
+	}
+
+	want := []Item{
+		{Index: 0, Values: []string{"a | b"}, Batch: [][]string{{"a"}, {"b"}}},
+		{Index: 1, Values: []string{"c | d"}, Batch: [][]string{{"c"}, {"d"}}},
+		{Index: 2, Values: []string{"e"}, Batch: [][]string{{"e"}}},
+	}
+	if !cmp.Equal(want, res) {
+		t.Error(cmp.Diff(want, res))
+	}
+}
+
+func TestFilterTargets(t *testing.T) {
+	res, count := runFilter(&FilterTargets{Targets: []string{"https://a", "http://b"}}, items("x", "y"))
+	if count != 4 {
+		t.Errorf("wrong count, want 4, got %d", count)
+	}
+
+	want := []Item{
+		{Index: 0, Values: []string{"x", "https://a"}},
+		{Index: 1, Values: []string{"x", "http://b"}},
+		{Index: 2, Values: []string{"y", "https://a"}},
+		{Index: 3, Values: []string{"y", "http://b"}},
+	}
+	if !cmp.Equal(want, res) {
+		t.Error(cmp.Diff(want, res))
+	}
+}
@@ -0,0 +1,94 @@
+package producer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// FollowSource produces the lines of a file and then waits for new lines to be
+// appended, like `tail -f`. It only stops when the context is cancelled, so
+// the number of values is UnknownCount. When the file is truncated, it is
+// read again from the start.
+type FollowSource struct {
+	Filename string
+	Interval time.Duration // how often the file is checked for new data, default 500ms
+}
+
+// Yield sends all lines of the file to ch, including lines appended later.
+func (s *FollowSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	file, err := os.Open(s.Filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// ignore error
+		_ = file.Close()
+	}()
+
+	count <- UnknownCount
+
+	interval := s.Interval
+	if interval == 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	rd := bufio.NewReader(file)
+	var offset int64
+	var partial string
+
+	for {
+		line, err := rd.ReadString('\n')
+		offset += int64(len(line))
+
+		if err == nil {
+			v := strings.TrimRight(partial+line, "\r\n")
+			partial = ""
+
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return nil
+			}
+			continue
+		}
+
+		if err != io.EOF {
+			return err
+		}
+
+		// incomplete line, wait for the rest
+		partial += line
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+
+		fi, err := file.Stat()
+		if err != nil {
+			return err
+		}
+
+		if fi.Size() < offset {
+			// file has been truncated, start again
+			_, err = file.Seek(0, io.SeekStart)
+			if err != nil {
+				return err
+			}
+
+			offset = 0
+			partial = ""
+			rd.Reset(file)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package producer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFollowSource(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-follow-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	filename := filepath.Join(tempdir, "values.txt")
+	err = ioutil.WriteFile(filename, []byte("foo\nbar\nba"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := &FollowSource{Filename: filename, Interval: 10 * time.Millisecond}
+	ch := make(chan string)
+	count := make(chan int, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- src.Yield(ctx, ch, count)
+	}()
+
+	var values []string
+	values = append(values, <-ch, <-ch)
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.WriteString("z\nnew\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values = append(values, <-ch, <-ch)
+	cancel()
+
+	for v := range ch {
+		values = append(values, v)
+	}
+
+	err = <-errCh
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"foo", "bar", "baz", "new"}
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if n := <-count; n != UnknownCount {
+		t.Errorf("wrong count, want %d, got %d", UnknownCount, n)
+	}
+}
@@ -0,0 +1,147 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"strings"
+)
+
+// IPRange is a range of IP addresses.
+type IPRange struct {
+	First, Last net.IP
+}
+
+// ParseIPRange parses an IP range. Valid formats are a single address, CIDR
+// notation (10.0.0.0/24) and ranges (10.0.0.1-10.0.0.20).
+func ParseIPRange(s string) (IPRange, error) {
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return IPRange{}, err
+		}
+
+		first := normalizeIP(network.IP)
+		last := make(net.IP, len(first))
+		for i := range first {
+			last[i] = first[i] | ^network.Mask[i]
+		}
+
+		return IPRange{First: first, Last: last}, nil
+	}
+
+	data := strings.SplitN(s, "-", 2)
+	first := normalizeIP(net.ParseIP(data[0]))
+	if first == nil {
+		return IPRange{}, fmt.Errorf("invalid IP address %q", data[0])
+	}
+
+	if len(data) == 1 {
+		return IPRange{First: first, Last: first}, nil
+	}
+
+	last := normalizeIP(net.ParseIP(data[1]))
+	if last == nil {
+		return IPRange{}, fmt.Errorf("invalid IP address %q", data[1])
+	}
+
+	if len(first) != len(last) {
+		return IPRange{}, fmt.Errorf("range %q mixes IPv4 and IPv6 addresses", s)
+	}
+
+	if bytes.Compare(first, last) > 0 {
+		return IPRange{}, fmt.Errorf("last address is smaller than first address for range %q", s)
+	}
+
+	return IPRange{First: first, Last: last}, nil
+}
+
+// normalizeIP returns the four byte representation for IPv4 addresses.
+func normalizeIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// Count returns the number of addresses in the range.
+func (r IPRange) Count() (int, error) {
+	n := new(big.Int).Sub(new(big.Int).SetBytes(r.Last), new(big.Int).SetBytes(r.First))
+	n.Add(n, big.NewInt(1))
+
+	if !n.IsInt64() || n.Int64() > math.MaxInt32 {
+		return 0, errors.New("too many addresses in range")
+	}
+
+	return int(n.Int64()), nil
+}
+
+// nextIP increments ip in place.
+func nextIP(ip net.IP) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] != 0 {
+			return
+		}
+	}
+}
+
+// IPSource produces all addresses within the ranges.
+type IPSource struct {
+	Ranges []IPRange
+}
+
+// Count returns the number of addresses the source produces.
+func (s *IPSource) Count() (int, error) {
+	total := 0
+	for _, r := range s.Ranges {
+		n, err := r.Count()
+		if err != nil {
+			return 0, err
+		}
+
+		// compare before adding so that total cannot overflow
+		if n > math.MaxInt32-total {
+			return 0, errors.New("too many addresses")
+		}
+		total += n
+	}
+
+	return total, nil
+}
+
+// Yield sends all addresses to ch.
+func (s *IPSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	total, err := s.Count()
+	if err != nil {
+		return err
+	}
+
+	count <- total
+
+	for _, r := range s.Ranges {
+		ip := make(net.IP, len(r.First))
+		copy(ip, r.First)
+
+		for {
+			select {
+			case ch <- ip.String():
+			case <-ctx.Done():
+				return nil
+			}
+
+			if ip.Equal(r.Last) {
+				break
+			}
+			nextIP(ip)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package producer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIPSource(t *testing.T) {
+	var tests = []struct {
+		ranges []string
+		want   []string
+	}{
+		{
+			ranges: []string{"192.168.0.1"},
+			want:   []string{"192.168.0.1"},
+		},
+		{
+			ranges: []string{"10.0.0.0/30"},
+			want:   []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3"},
+		},
+		{
+			ranges: []string{"10.0.0.254-10.0.1.1", "10.0.2.5/31"},
+			want:   []string{"10.0.0.254", "10.0.0.255", "10.0.1.0", "10.0.1.1", "10.0.2.4", "10.0.2.5"},
+		},
+		{
+			ranges: []string{"fe80::ffff-fe80::1:1"},
+			want:   []string{"fe80::ffff", "fe80::1:0", "fe80::1:1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var src IPSource
+			for _, s := range test.ranges {
+				r, err := ParseIPRange(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				src.Ranges = append(src.Ranges, r)
+			}
+
+			values, n := yieldAll(t, &src)
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if n != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), n)
+			}
+		})
+	}
+}
+
+func TestParseIPRangeInvalid(t *testing.T) {
+	for _, s := range []string{"foo", "10.0.0.5-10.0.0.1", "10.0.0.1-::1", "10.0.0.0/33", "::/64"} {
+		t.Run("", func(t *testing.T) {
+			r, err := ParseIPRange(s)
+			if err == nil {
+				_, err = r.Count()
+			}
+
+			if err == nil {
+				t.Errorf("expected error for %q not returned", s)
+			}
+		})
+	}
+}
+
+func TestIPSourceCountTooMany(t *testing.T) {
+	var src IPSource
+	for _, s := range []string{"0.0.0.0-127.255.255.254", "128.0.0.0-255.255.255.254"} {
+		r, err := ParseIPRange(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src.Ranges = append(src.Ranges, r)
+	}
+
+	_, err := src.Count()
+	if err == nil {
+		t.Fatal("expected error not returned")
+	}
+}
@@ -0,0 +1,128 @@
+package producer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// JSONLSource produces the lines of a file containing one JSON object per
+// line (JSON lines). Empty lines are skipped. The values are meant to be split
+// into fields with JSONFields.
+type JSONLSource struct {
+	Filename string
+}
+
+// Yield sends all non-empty lines of the file to ch.
+func (s *JSONLSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	lines := make(chan string)
+	inCount := make(chan int, 1)
+	errCh := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		errCh <- (&FileSource{Filename: s.Filename}).Yield(ctx, lines, inCount)
+	}()
+
+	defer close(ch)
+
+	num := 0
+	for line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		num++
+
+		select {
+		case ch <- line:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	err := <-errCh
+	if err != nil {
+		return err
+	}
+
+	count <- num
+	return nil
+}
+
+// JSONFields returns a SplitFunc which parses a value as a JSON object and
+// returns the values of the fields. Strings are returned as they are, other
+// values are JSON encoded. Missing fields and null yield an empty string.
+func JSONFields(fields []string) SplitFunc {
+	return func(s string) ([]string, error) {
+		var obj map[string]json.RawMessage
+		err := json.Unmarshal([]byte(s), &obj)
+		if err != nil {
+			return nil, fmt.Errorf("parse JSON object %q: %v", s, err)
+		}
+
+		values := make([]string, 0, len(fields))
+		for _, field := range fields {
+			raw, ok := obj[field]
+			if !ok || bytes.Equal(raw, []byte("null")) {
+				values = append(values, "")
+				continue
+			}
+
+			var str string
+			if json.Unmarshal(raw, &str) == nil {
+				values = append(values, str)
+				continue
+			}
+
+			values = append(values, string(raw))
+		}
+
+		return values, nil
+	}
+}
+
+// JSONLFields returns the sorted names of the fields of the first object in
+// the JSON lines file.
+func JSONLFields(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1024*1024)
+	for sc.Scan() {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+
+		var obj map[string]json.RawMessage
+		err := json.Unmarshal(sc.Bytes(), &obj)
+		if err != nil {
+			return nil, fmt.Errorf("parse first object in %v: %v", filename, err)
+		}
+
+		var fields []string
+		for field := range obj {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		return fields, nil
+	}
+
+	if sc.Err() != nil {
+		return nil, sc.Err()
+	}
+
+	return nil, fmt.Errorf("no object found in %v", filename)
+}
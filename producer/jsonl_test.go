@@ -0,0 +1,37 @@
+package producer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSONFields(t *testing.T) {
+	var tests = []struct {
+		value string
+		want  []string
+	}{
+		{`{"user": "admin", "id": 23}`, []string{"admin", "23"}},
+		{`{"id": "5", "extra": true}`, []string{"", "5"}},
+		{`{"user": null, "id": [1, 2]}`, []string{"", "[1, 2]"}},
+	}
+
+	split := JSONFields([]string{"user", "id"})
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			values, err := split(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+		})
+	}
+
+	_, err := split("foo")
+	if err == nil {
+		t.Fatal("expected error for invalid JSON not found")
+	}
+}
@@ -10,11 +10,11 @@ import (
 // Limit limits the number of values per second to the value perSecond. A new
 // goroutine is started, which terminates when in is closed or the context is
 // cancelled.
-func Limit(ctx context.Context, perSecond float64, in <-chan string) <-chan string {
+func Limit(ctx context.Context, perSecond float64, in <-chan Item) <-chan Item {
 	fillInterval := time.Duration(float64(time.Second) / float64(perSecond))
 	bucket := ratelimit.NewBucket(fillInterval, 1)
 
-	out := make(chan string)
+	out := make(chan Item)
 
 	go func() {
 		defer close(out)
@@ -0,0 +1,82 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+)
+
+// maskCharsets are the built-in charsets for masks, compatible with hashcat.
+var maskCharsets = map[rune]string{
+	'l': "abcdefghijklmnopqrstuvwxyz",
+	'u': "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	'd': "0123456789",
+	'h': "0123456789abcdef",
+	'H': "0123456789ABCDEF",
+	's': " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+	'a': "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+}
+
+// MaskSource produces all strings matching a mask like "?u?l?l?d?d".
+type MaskSource struct {
+	sets [][]rune
+}
+
+// ParseMask parses a mask. Supported placeholders are ?l (lower case
+// letters), ?u (upper case letters), ?d (digits), ?h and ?H (lower and upper
+// case hex digits), ?s (special characters) and ?a (all of them). All other
+// characters are used literally, "??" produces a literal question mark.
+func ParseMask(mask string) (*MaskSource, error) {
+	var sets [][]rune
+
+	s := []rune(mask)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '?' {
+			sets = append(sets, []rune{s[i]})
+			continue
+		}
+
+		if i+1 >= len(s) {
+			return nil, fmt.Errorf("mask %q ends with incomplete placeholder", mask)
+		}
+		i++
+
+		if s[i] == '?' {
+			sets = append(sets, []rune{'?'})
+			continue
+		}
+
+		charset, ok := maskCharsets[s[i]]
+		if !ok {
+			return nil, fmt.Errorf("unknown placeholder ?%c in mask %q", s[i], mask)
+		}
+		sets = append(sets, []rune(charset))
+	}
+
+	src := &MaskSource{sets: sets}
+	_, err := src.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	return src, nil
+}
+
+// Count returns the number of strings the source produces.
+func (s *MaskSource) Count() (int, error) {
+	return countProduct(s.sets)
+}
+
+// Yield sends all strings matching the mask to ch.
+func (s *MaskSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	total, err := s.Count()
+	if err != nil {
+		return err
+	}
+
+	count <- total
+
+	sendProduct(ctx, s.sets, ch)
+	return nil
+}
@@ -0,0 +1,74 @@
+package producer
+
+import (
+	"context"
+	"regexp"
+)
+
+// MatchSource only passes on values of Source which match at least one of the
+// patterns in Match (if any) and none of the patterns in Reject. The number
+// of values is sent after all values have been read from Source.
+type MatchSource struct {
+	Source
+	Match  []*regexp.Regexp
+	Reject []*regexp.Regexp
+}
+
+func (s *MatchSource) accept(v string) bool {
+	for _, pat := range s.Reject {
+		if pat.MatchString(v) {
+			return false
+		}
+	}
+
+	if len(s.Match) == 0 {
+		return true
+	}
+
+	for _, pat := range s.Match {
+		if pat.MatchString(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Yield sends all accepted values to ch.
+func (s *MatchSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	in := make(chan string)
+	errCh := make(chan error, 1)
+
+	go func() {
+		// the count of the source is not needed
+		errCh <- s.Source.Yield(ctx, in, make(chan int, 1))
+	}()
+
+	num := 0
+	for v := range in {
+		if !s.accept(v) {
+			continue
+		}
+
+		num++
+
+		select {
+		case ch <- v:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	err := <-errCh
+	if err != nil {
+		return err
+	}
+
+	count <- num
+	return nil
+}
@@ -0,0 +1,56 @@
+package producer
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatchSource(t *testing.T) {
+	input := staticSource{"admin", "admin.php", "login", "very-long-value-from-a-broken-wordlist", "index.php"}
+
+	var tests = []struct {
+		match, reject []string
+		want          []string
+	}{
+		{
+			match: []string{`\.php$`},
+			want:  []string{"admin.php", "index.php"},
+		},
+		{
+			match: []string{`^a`, `^l`},
+			want:  []string{"admin", "admin.php", "login"},
+		},
+		{
+			reject: []string{`^.{20,}$`},
+			want:   []string{"admin", "admin.php", "login", "index.php"},
+		},
+		{
+			match:  []string{`\.php$`},
+			reject: []string{`^admin`},
+			want:   []string{"index.php"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			src := &MatchSource{Source: input}
+			for _, pat := range test.match {
+				src.Match = append(src.Match, regexp.MustCompile(pat))
+			}
+			for _, pat := range test.reject {
+				src.Reject = append(src.Reject, regexp.MustCompile(pat))
+			}
+
+			values, count := yieldAll(t, src)
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if count != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), count)
+			}
+		})
+	}
+}
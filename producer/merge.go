@@ -0,0 +1,113 @@
+package producer
+
+import (
+	"context"
+	"sort"
+)
+
+// MergeSource produces the values of several sources, duplicate values are
+// only sent once. The sources are read twice: once to compute the number of
+// unique values, and again to send them. Only the set of unique values is kept
+// in memory. The sources must produce the same values each time they are
+// read.
+type MergeSource struct {
+	Sources []Source
+
+	// Priorities optionally assigns a priority to each source. Sources with a
+	// higher priority are read first, sources with the same priority are read
+	// in the order they are listed. A value contained in several sources is
+	// sent with the source with the highest priority.
+	Priorities []int
+}
+
+// order returns the sources sorted by priority.
+func (s *MergeSource) order() []Source {
+	if len(s.Priorities) == 0 {
+		return s.Sources
+	}
+
+	idx := make([]int, len(s.Sources))
+	for i := range idx {
+		idx[i] = i
+	}
+
+	prio := func(i int) int {
+		if i < len(s.Priorities) {
+			return s.Priorities[i]
+		}
+		return 0
+	}
+
+	sort.SliceStable(idx, func(a, b int) bool {
+		return prio(idx[a]) > prio(idx[b])
+	})
+
+	sources := make([]Source, 0, len(s.Sources))
+	for _, i := range idx {
+		sources = append(sources, s.Sources[i])
+	}
+	return sources
+}
+
+// each calls fn for all values of all sources.
+func (s *MergeSource) each(ctx context.Context, fn func(string) bool) error {
+	for _, src := range s.order() {
+		ch := make(chan string)
+		count := make(chan int, 1)
+		errCh := make(chan error, 1)
+
+		ctx, cancel := context.WithCancel(ctx)
+		go func() {
+			errCh <- src.Yield(ctx, ch, count)
+		}()
+
+		for v := range ch {
+			if !fn(v) {
+				cancel()
+			}
+		}
+		cancel()
+
+		err := <-errCh
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Yield sends the unique values of all sources to ch.
+func (s *MergeSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	unique := make(map[string]struct{})
+	err := s.each(ctx, func(v string) bool {
+		unique[v] = struct{}{}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	count <- len(unique)
+
+	return s.each(ctx, func(v string) bool {
+		if _, ok := unique[v]; !ok {
+			// duplicate, value has already been sent
+			return true
+		}
+		delete(unique, v)
+
+		select {
+		case ch <- v:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
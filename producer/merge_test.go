@@ -0,0 +1,64 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type staticSource []string
+
+func (s staticSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+	count <- len(s)
+	for _, v := range s {
+		select {
+		case ch <- v:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+func TestMergeSource(t *testing.T) {
+	src := &MergeSource{Sources: []Source{
+		staticSource{"admin", "login", "admin", "backup"},
+		staticSource{"login", "test", "admin"},
+		staticSource{"backup", "index.php"},
+	}}
+
+	values, n := yieldAll(t, src)
+
+	want := []string{"admin", "login", "backup", "test", "index.php"}
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if n != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), n)
+	}
+}
+
+func TestMergeSourcePriorities(t *testing.T) {
+	src := &MergeSource{
+		Sources: []Source{
+			staticSource{"foo", "bar", "admin"},
+			staticSource{"admin", "login"},
+			staticSource{"baz", "bar"},
+		},
+		Priorities: []int{0, 10, 5},
+	}
+
+	values, n := yieldAll(t, src)
+
+	want := []string{"admin", "login", "baz", "bar", "foo"}
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if n != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), n)
+	}
+}
@@ -0,0 +1,311 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Mode selects how the Multiplexer combines values.
+type Mode int
+
+// Multiplexer modes.
+const (
+	// ModeClusterbomb produces all combinations of values (cross product).
+	ModeClusterbomb Mode = iota
+	// ModePitchfork produces the nth value of all sources together and stops
+	// when one of the sources is exhausted.
+	ModePitchfork
+)
+
+// ParseMode returns the Mode for s.
+func ParseMode(s string) (Mode, error) {
+	switch s {
+	case "clusterbomb", "":
+		return ModeClusterbomb, nil
+	case "pitchfork":
+		return ModePitchfork, nil
+	default:
+		return 0, fmt.Errorf("unknown mode %q", s)
+	}
+}
+
+// Item is a set of values (one for each placeholder) produced by a
+// Multiplexer.
+type Item struct {
+	Index  int // position in the sequence of items
+	Values []string
+	Depth  int // recursion depth, see Recursion
+
+	Feedback bool // values have been extracted from a response, see Feedback
+
+	Batch [][]string // values of several items sent in one request, see FilterBatch
+}
+
+// SplitFunc splits a value into the values for several placeholders.
+type SplitFunc func(string) ([]string, error)
+
+// Multiplexer combines the values of several sources. Each source is bound
+// to the name of a placeholder. By default, all combinations of values are
+// produced (cross product), the first source is iterated in the outermost
+// loop.
+type Multiplexer struct {
+	Names   []string
+	Sources []Source
+	Mode    Mode
+
+	// Splits contains a SplitFunc for each source which is bound to several
+	// placeholders, and nil for all other sources.
+	Splits []SplitFunc
+}
+
+// AddSource binds src to the placeholder name.
+func (m *Multiplexer) AddSource(name string, src Source) {
+	m.Names = append(m.Names, name)
+	m.Sources = append(m.Sources, src)
+	m.Splits = append(m.Splits, nil)
+}
+
+// AddSplitSource binds src to several placeholders. Each value of src is
+// passed to split, which must return one value for each of the names.
+func (m *Multiplexer) AddSplitSource(names []string, src Source, split SplitFunc) {
+	m.Names = append(m.Names, names...)
+	m.Sources = append(m.Sources, src)
+	m.Splits = append(m.Splits, split)
+}
+
+// split returns the values for the placeholders the source with index i is
+// bound to.
+func (m *Multiplexer) split(i int, v string) ([]string, error) {
+	if i >= len(m.Splits) || m.Splits[i] == nil {
+		return []string{v}, nil
+	}
+
+	return m.Splits[i](v)
+}
+
+// load reads all values of the source with index i into memory.
+func (m *Multiplexer) load(ctx context.Context, i int) ([][]string, error) {
+	ch := make(chan string)
+	count := make(chan int, 1)
+	errCh := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		errCh <- m.Sources[i].Yield(ctx, ch, count)
+	}()
+
+	var values [][]string
+	for v := range ch {
+		tuple, err := m.split(i, v)
+		if err != nil {
+			cancel()
+			// drain the channel so the source can terminate
+			for range ch {
+			}
+			return nil, err
+		}
+		values = append(values, tuple)
+	}
+
+	err := <-errCh
+	if err != nil {
+		return nil, err
+	}
+
+	return values, ctx.Err()
+}
+
+// Run sends all combinations of values to the channel ch, and the number of
+// combinations to the channel count. All sources except for the first one are
+// read into memory before the first value is sent. Sending stops and ch is
+// closed when an error occurs or the context is cancelled.
+func (m *Multiplexer) Run(ctx context.Context, ch chan<- Item, count chan<- int) error {
+	defer close(ch)
+
+	if len(m.Sources) == 0 {
+		return errors.New("no sources specified")
+	}
+
+	if m.Mode == ModePitchfork {
+		return m.pitchfork(ctx, ch, count)
+	}
+
+	// load all sources but the first one
+	var lists [][][]string
+	combinations := 1
+	for i := 1; i < len(m.Sources); i++ {
+		values, err := m.load(ctx, i)
+		if err != nil {
+			return err
+		}
+
+		lists = append(lists, values)
+		combinations *= len(values)
+	}
+
+	// stream the values from the first source
+	in := make(chan string)
+	inCount := make(chan int, 1)
+	errCh := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		errCh <- m.Sources[0].Yield(ctx, in, inCount)
+	}()
+
+	index := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case n := <-inCount:
+			// disable receiving the count again
+			inCount = nil
+
+			select {
+			case count <- scaleCount(n, combinations):
+			case <-ctx.Done():
+				return nil
+			}
+
+		case v, ok := <-in:
+			if !ok {
+				// forward the count if it has been sent before ch was closed
+				if inCount != nil {
+					select {
+					case n := <-inCount:
+						count <- scaleCount(n, combinations)
+					default:
+					}
+				}
+				return <-errCh
+			}
+
+			first, err := m.split(0, v)
+			if err != nil {
+				return err
+			}
+
+			if !combine(ctx, &index, first, lists, ch) {
+				return nil
+			}
+		}
+	}
+}
+
+// combine sends first together with all combinations of values in lists to
+// ch, index is incremented for each item. It returns false when the context
+// has been cancelled.
+func combine(ctx context.Context, index *int, first []string, lists [][][]string, ch chan<- Item) bool {
+	for _, list := range lists {
+		if len(list) == 0 {
+			return true
+		}
+	}
+
+	pos := make([]int, len(lists))
+	for {
+		values := make([]string, 0, len(lists)+len(first))
+		values = append(values, first...)
+		for i, list := range lists {
+			values = append(values, list[pos[i]]...)
+		}
+
+		select {
+		case ch <- Item{Index: *index, Values: values}:
+			*index++
+		case <-ctx.Done():
+			return false
+		}
+
+		// advance the positions, the last list changes most frequently
+		i := len(pos) - 1
+		for ; i >= 0; i-- {
+			pos[i]++
+			if pos[i] < len(lists[i]) {
+				break
+			}
+			pos[i] = 0
+		}
+
+		if i < 0 {
+			return true
+		}
+	}
+}
+
+// pitchfork sends the values of all sources in lockstep to ch. The count is
+// the smallest number of values of all sources which know their count.
+func (m *Multiplexer) pitchfork(ctx context.Context, ch chan<- Item, count chan<- int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	inputs := make([]chan string, len(m.Sources))
+	counts := make([]chan int, len(m.Sources))
+	errCh := make(chan error, len(m.Sources))
+
+	for i, src := range m.Sources {
+		inputs[i] = make(chan string)
+		counts[i] = make(chan int, 1)
+
+		go func(src Source, in chan string, count chan int) {
+			errCh <- src.Yield(ctx, in, count)
+		}(src, inputs[i], counts[i])
+	}
+
+	// collect the counts of all sources in the background
+	go func() {
+		min := UnknownCount
+		for _, c := range counts {
+			select {
+			case n := <-c:
+				if n == UnknownCount {
+					continue
+				}
+				if min == UnknownCount || n < min {
+					min = n
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case count <- min:
+		case <-ctx.Done():
+		}
+	}()
+
+	for index := 0; ; index++ {
+		values := make([]string, 0, len(m.Names))
+		for i, in := range inputs {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					// the source is exhausted, return its error (if any)
+					return <-errCh
+				}
+
+				tuple, err := m.split(i, v)
+				if err != nil {
+					return err
+				}
+				values = append(values, tuple...)
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		select {
+		case ch <- Item{Index: index, Values: values}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// collect runs m and returns all values and the count.
+func collect(t testing.TB, m *Multiplexer) ([][]string, int) {
+	ch := make(chan Item)
+	count := make(chan int, 1)
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- m.Run(context.Background(), ch, count)
+	}()
+
+	var values [][]string
+	for item := range ch {
+		if item.Index != len(values) {
+			t.Errorf("wrong index for item %v, want %d, got %d", item.Values, len(values), item.Index)
+		}
+		values = append(values, item.Values)
+	}
+
+	err := <-errCh
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return values, <-count
+}
+
+func TestMultiplexer(t *testing.T) {
+	var tests = []struct {
+		sources []Source
+		mode    Mode
+		want    [][]string
+	}{
+		{
+			sources: []Source{
+				&RangeSource{Ranges: []Range{{First: 1, Last: 3}}},
+			},
+			want: [][]string{{"1"}, {"2"}, {"3"}},
+		},
+		{
+			sources: []Source{
+				&RangeSource{Ranges: []Range{{First: 1, Last: 2}}},
+				&RangeSource{Ranges: []Range{{First: 5, Last: 7}}},
+			},
+			want: [][]string{
+				{"1", "5"}, {"1", "6"}, {"1", "7"},
+				{"2", "5"}, {"2", "6"}, {"2", "7"},
+			},
+		},
+		{
+			sources: []Source{
+				&RangeSource{Ranges: []Range{{First: 1, Last: 2}}},
+				&RangeSource{Ranges: []Range{{First: 3, Last: 3}}},
+				&RangeSource{Ranges: []Range{{First: 5, Last: 6}}},
+			},
+			want: [][]string{
+				{"1", "3", "5"}, {"1", "3", "6"},
+				{"2", "3", "5"}, {"2", "3", "6"},
+			},
+		},
+		{
+			sources: []Source{
+				&RangeSource{Ranges: []Range{{First: 1, Last: 3}}},
+				&RangeSource{Ranges: []Range{{First: 5, Last: 7}}},
+			},
+			mode: ModePitchfork,
+			want: [][]string{{"1", "5"}, {"2", "6"}, {"3", "7"}},
+		},
+		{
+			sources: []Source{
+				&RangeSource{Ranges: []Range{{First: 1, Last: 3}}},
+				&RangeSource{Ranges: []Range{{First: 5, Last: 6}}},
+			},
+			mode: ModePitchfork,
+			want: [][]string{{"1", "5"}, {"2", "6"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			m := &Multiplexer{Mode: test.mode}
+			for _, src := range test.sources {
+				m.AddSource("FUZZ", src)
+			}
+
+			values, count := collect(t, m)
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if count != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), count)
+			}
+		})
+	}
+}
+
+func TestMultiplexerSplit(t *testing.T) {
+	split := JSONFields([]string{"user", "id"})
+
+	for _, mode := range []Mode{ModeClusterbomb, ModePitchfork} {
+		var m Multiplexer
+		m.Mode = mode
+		m.AddSplitSource([]string{"USER", "ID"}, staticSource{`{"user": "admin", "id": 1}`, `{"user": "test"}`}, split)
+		m.AddSource("FUZZ", staticSource{"a", "b"})
+
+		want := [][]string{{"admin", "1", "a"}, {"admin", "1", "b"}, {"test", "", "a"}, {"test", "", "b"}}
+		if mode == ModePitchfork {
+			want = [][]string{{"admin", "1", "a"}, {"test", "", "b"}}
+		}
+
+		values, count := collect(t, &m)
+		if !cmp.Equal(want, values) {
+			t.Error(cmp.Diff(want, values))
+		}
+
+		if count != len(want) {
+			t.Errorf("wrong count, want %d, got %d", len(want), count)
+		}
+	}
+}
+
+func TestMultiplexerMethods(t *testing.T) {
+	// --methods adds a list source, all methods are sent for each value
+	m := &Multiplexer{}
+	m.AddSource("FUZZ", &RangeSource{Ranges: []Range{{First: 1, Last: 2}}})
+	m.AddSource("FUZZ_METHOD", ListSource{"GET", "POST", "FOO"})
+
+	values, count := collect(t, m)
+
+	want := [][]string{
+		{"1", "GET"}, {"1", "POST"}, {"1", "FOO"},
+		{"2", "GET"}, {"2", "POST"}, {"2", "FOO"},
+	}
+	if !cmp.Equal(want, values) {
+		t.Fatal(cmp.Diff(want, values))
+	}
+
+	if count != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), count)
+	}
+}
@@ -0,0 +1,159 @@
+package producer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// caseMutations contains functions to change the case of a value.
+var caseMutations = map[string]func(string) string{
+	"original": func(s string) string { return s },
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"title": func(s string) string {
+		r := []rune(strings.ToLower(s))
+		if len(r) > 0 {
+			r[0] = unicode.ToUpper(r[0])
+		}
+		return string(r)
+	},
+	"alternating": func(s string) string {
+		r := []rune(s)
+		for i := range r {
+			if i%2 == 0 {
+				r[i] = unicode.ToLower(r[i])
+			} else {
+				r[i] = unicode.ToUpper(r[i])
+			}
+		}
+		return string(r)
+	},
+}
+
+// NewCaseSource returns a source which sends case variants of each value of
+// src. Modes is a list of original, lower, upper, title and alternating. Each
+// value is sent once for each mode, even if some of the variants are equal.
+func NewCaseSource(src Source, modes []string) (Source, error) {
+	var funcs []func(string) string
+	for _, mode := range modes {
+		f, ok := caseMutations[mode]
+		if !ok {
+			return nil, fmt.Errorf("unknown case mode %q", mode)
+		}
+		funcs = append(funcs, f)
+	}
+
+	return &ExpandSource{
+		Source: src,
+		Factor: len(funcs),
+		Expand: func(s string) []string {
+			res := make([]string, 0, len(funcs))
+			for _, f := range funcs {
+				res = append(res, f(s))
+			}
+			return res
+		},
+	}, nil
+}
+
+// NewAffixSource returns a source which sends each value of src with all
+// combinations of prefixes and suffixes. The value is also sent without
+// prefix and suffix.
+func NewAffixSource(src Source, prefixes, suffixes []string) Source {
+	prefixes = append([]string{""}, prefixes...)
+	suffixes = append([]string{""}, suffixes...)
+
+	return &ExpandSource{
+		Source: src,
+		Factor: len(prefixes) * len(suffixes),
+		Expand: func(s string) []string {
+			res := make([]string, 0, len(prefixes)*len(suffixes))
+			for _, prefix := range prefixes {
+				for _, suffix := range suffixes {
+					res = append(res, prefix+s+suffix)
+				}
+			}
+			return res
+		},
+	}
+}
+
+// leetReplacer substitutes common letters with similar looking digits.
+var leetReplacer = strings.NewReplacer(
+	"a", "4", "A", "4",
+	"e", "3", "E", "3",
+	"i", "1", "I", "1",
+	"o", "0", "O", "0",
+	"s", "5", "S", "5",
+	"t", "7", "T", "7",
+)
+
+// MutationRules contains the names of the rules supported by NewMutateSource.
+var MutationRules = []string{"capitalize", "leet", "years", "digits", "symbols"}
+
+// mutationRule returns the variants a rule produces for a value. The value
+// itself is always the first variant.
+func mutationRule(rule string, years []int) (func(string) []string, error) {
+	switch rule {
+	case "capitalize":
+		return func(s string) []string {
+			return []string{s, caseMutations["title"](s)}
+		}, nil
+	case "leet":
+		return func(s string) []string {
+			return []string{s, leetReplacer.Replace(s)}
+		}, nil
+	case "years":
+		return func(s string) []string {
+			res := []string{s}
+			for _, year := range years {
+				res = append(res, fmt.Sprintf("%s%d", s, year))
+			}
+			return res
+		}, nil
+	case "digits":
+		return func(s string) []string {
+			return []string{s, s + "1", s + "12", s + "123"}
+		}, nil
+	case "symbols":
+		return func(s string) []string {
+			return []string{s, s + "!", s + "?", s + "#"}
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown mutation rule %q, valid rules: %s", rule, strings.Join(MutationRules, ", "))
+	}
+}
+
+// NewMutateSource returns a source which derives password variants from each
+// value of src. The rules are applied in order, each rule is applied to all
+// variants produced by the previous rules, so all combinations are sent. The
+// rule years appends each of years to the value.
+func NewMutateSource(src Source, rules []string, years []int) (Source, error) {
+	var funcs []func(string) []string
+	factor := 1
+	for _, rule := range rules {
+		f, err := mutationRule(rule, years)
+		if err != nil {
+			return nil, err
+		}
+		funcs = append(funcs, f)
+		factor *= len(f(""))
+	}
+
+	return &ExpandSource{
+		Source: src,
+		Factor: factor,
+		Expand: func(s string) []string {
+			res := []string{s}
+			for _, f := range funcs {
+				var next []string
+				for _, v := range res {
+					next = append(next, f(v)...)
+				}
+				res = next
+			}
+			return res
+		},
+	}, nil
+}
@@ -0,0 +1,130 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// yieldAll runs src and returns all values and the count.
+func yieldAll(t testing.TB, src Source) ([]string, int) {
+	ch := make(chan string)
+	count := make(chan int, 1)
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- src.Yield(context.Background(), ch, count)
+	}()
+
+	var values []string
+	for v := range ch {
+		values = append(values, v)
+	}
+
+	err := <-errCh
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return values, <-count
+}
+
+func TestCaseSource(t *testing.T) {
+	src, err := NewCaseSource(staticSource{"admin", "Login"}, []string{"lower", "upper", "title", "alternating"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, count := yieldAll(t, src)
+	want := []string{
+		"admin", "ADMIN", "Admin", "aDmIn",
+		"login", "LOGIN", "Login", "lOgIn",
+	}
+
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if count != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), count)
+	}
+
+	_, err = NewCaseSource(staticSource{}, []string{"foo"})
+	if err == nil {
+		t.Errorf("expected error for unknown mode not returned")
+	}
+}
+
+func TestAffixSource(t *testing.T) {
+	src := NewAffixSource(staticSource{"admin", "index"}, []string{"."}, []string{".php", ".bak"})
+
+	values, count := yieldAll(t, src)
+	want := []string{
+		"admin", "admin.php", "admin.bak", ".admin", ".admin.php", ".admin.bak",
+		"index", "index.php", "index.bak", ".index", ".index.php", ".index.bak",
+	}
+
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if count != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), count)
+	}
+}
+
+func TestMutateSource(t *testing.T) {
+	var tests = []struct {
+		rules []string
+		years []int
+		want  []string
+	}{
+		{
+			rules: []string{"leet"},
+			want:  []string{"summer", "5umm3r"},
+		},
+		{
+			rules: []string{"capitalize", "years"},
+			years: []int{2019, 2020},
+			want: []string{
+				"summer", "summer2019", "summer2020",
+				"Summer", "Summer2019", "Summer2020",
+			},
+		},
+		{
+			rules: []string{"digits", "symbols"},
+			want: []string{
+				"summer", "summer!", "summer?", "summer#",
+				"summer1", "summer1!", "summer1?", "summer1#",
+				"summer12", "summer12!", "summer12?", "summer12#",
+				"summer123", "summer123!", "summer123?", "summer123#",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			src, err := NewMutateSource(staticSource{"summer"}, test.rules, test.years)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, count := yieldAll(t, src)
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if count != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), count)
+			}
+		})
+	}
+}
+
+func TestMutateSourceInvalidRule(t *testing.T) {
+	_, err := NewMutateSource(staticSource{"summer"}, []string{"foo"}, nil)
+	if err == nil {
+		t.Fatal("expected error not found")
+	}
+}
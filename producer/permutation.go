@@ -0,0 +1,63 @@
+package producer
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// permutationFormats describe how a word is combined with the first label of
+// a subdomain.
+var permutationFormats = []func(word, label string) string{
+	func(w, l string) string { return w + "-" + l },
+	func(w, l string) string { return l + "-" + w },
+	func(w, l string) string { return w + l },
+	func(w, l string) string { return l + w },
+	func(w, l string) string { return w + "." + l },
+}
+
+// NewPermutationSource returns a source which combines each subdomain
+// produced by seeds with all words (similar to altdns). The words are
+// inserted into the first label of the subdomain with and without a dash,
+// and prepended as a new label, e.g. for the subdomain "api.example.com" and
+// the word "dev", the values dev-api.example.com, api-dev.example.com,
+// devapi.example.com, apidev.example.com and dev.api.example.com are sent.
+func NewPermutationSource(seeds Source, words []string) Source {
+	return &ExpandSource{
+		Source: seeds,
+		Factor: len(words) * len(permutationFormats),
+		Expand: func(s string) []string {
+			label, rest := s, ""
+			if pos := strings.Index(s, "."); pos >= 0 {
+				label, rest = s[:pos], s[pos:]
+			}
+
+			res := make([]string, 0, len(words)*len(permutationFormats))
+			for _, word := range words {
+				for _, f := range permutationFormats {
+					res = append(res, f(word, label)+rest)
+				}
+			}
+			return res
+		},
+	}
+}
+
+// ReadLines returns all non-empty lines of a file.
+func ReadLines(filename string) (lines []string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, sc.Err()
+}
@@ -0,0 +1,27 @@
+package producer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPermutationSource(t *testing.T) {
+	src := NewPermutationSource(staticSource{"api.example.com", "mail"}, []string{"dev", "01"})
+
+	values, count := yieldAll(t, src)
+	want := []string{
+		"dev-api.example.com", "api-dev.example.com", "devapi.example.com", "apidev.example.com", "dev.api.example.com",
+		"01-api.example.com", "api-01.example.com", "01api.example.com", "api01.example.com", "01.api.example.com",
+		"dev-mail", "mail-dev", "devmail", "maildev", "dev.mail",
+		"01-mail", "mail-01", "01mail", "mail01", "01.mail",
+	}
+
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if count != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), count)
+	}
+}
@@ -0,0 +1,111 @@
+package producer
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// randomCharsets are the characters used for random tokens.
+var randomCharsets = map[string]string{
+	"hex":   "0123456789abcdef",
+	"alnum": "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+}
+
+// RandomSource produces Num random tokens: UUIDs (version 4), hex strings or
+// alphanumeric strings of length Length. The tokens are derived from Seed, so
+// reading the source again yields the same tokens.
+type RandomSource struct {
+	Kind   string // uuid, hex or alnum
+	Length int
+	Num    int
+	Seed   int64
+}
+
+// ParseRandom parses a description of random tokens in the form
+// "num:kind[:length]", e.g. "100:uuid" or "50:hex:32". The default length is
+// 16. The seed is chosen randomly.
+func ParseRandom(s string) (*RandomSource, error) {
+	data := strings.Split(s, ":")
+	if len(data) < 2 || len(data) > 3 {
+		return nil, fmt.Errorf("invalid random tokens %q, expected num:kind[:length]", s)
+	}
+
+	src := &RandomSource{Kind: data[1], Length: 16}
+
+	var err error
+	src.Num, err = strconv.Atoi(data[0])
+	if err != nil || src.Num < 0 {
+		return nil, fmt.Errorf("invalid number of random tokens %q", data[0])
+	}
+
+	if len(data) == 3 {
+		src.Length, err = strconv.Atoi(data[2])
+		if err != nil || src.Length <= 0 {
+			return nil, fmt.Errorf("invalid length of random tokens %q", data[2])
+		}
+	}
+
+	switch src.Kind {
+	case "uuid":
+		if len(data) == 3 {
+			return nil, fmt.Errorf("length cannot be specified for UUIDs")
+		}
+	case "hex", "alnum":
+	default:
+		return nil, fmt.Errorf("unknown kind of random token %q, valid kinds are: uuid, hex, alnum", src.Kind)
+	}
+
+	var buf [8]byte
+	_, err = crand.Read(buf[:])
+	if err != nil {
+		return nil, err
+	}
+	src.Seed = int64(binary.LittleEndian.Uint64(buf[:]))
+
+	return src, nil
+}
+
+// token returns a new random token.
+func (s *RandomSource) token(rnd *rand.Rand) string {
+	if s.Kind == "uuid" {
+		var buf [16]byte
+		rnd.Read(buf[:])
+		buf[6] = (buf[6] & 0x0f) | 0x40 // version 4
+		buf[8] = (buf[8] & 0x3f) | 0x80 // variant RFC 4122
+		return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:16])
+	}
+
+	charset := randomCharsets[s.Kind]
+	buf := make([]byte, s.Length)
+	for i := range buf {
+		buf[i] = charset[rnd.Intn(len(charset))]
+	}
+	return string(buf)
+}
+
+// Yield sends all tokens to ch.
+func (s *RandomSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	if s.Kind != "uuid" && randomCharsets[s.Kind] == "" {
+		return fmt.Errorf("unknown kind of random token %q", s.Kind)
+	}
+
+	count <- s.Num
+
+	rnd := rand.New(rand.NewSource(s.Seed))
+	for i := 0; i < s.Num; i++ {
+		select {
+		case ch <- s.token(rnd):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package producer
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRandomSource(t *testing.T) {
+	var tests = []struct {
+		spec    string
+		pattern string
+	}{
+		{"5:uuid", `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"3:hex", `^[0-9a-f]{16}$`},
+		{"4:hex:32", `^[0-9a-f]{32}$`},
+		{"2:alnum:8", `^[a-zA-Z0-9]{8}$`},
+		{"0:alnum", ``},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			src, err := ParseRandom(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, n := yieldAll(t, src)
+			if n != src.Num || len(values) != src.Num {
+				t.Fatalf("wrong number of values, want %d, got %d (count %d)", src.Num, len(values), n)
+			}
+
+			pattern := regexp.MustCompile(test.pattern)
+			for _, v := range values {
+				if !pattern.MatchString(v) {
+					t.Errorf("value %q does not match %v", v, pattern)
+				}
+			}
+
+			// reading the source again must yield the same values
+			again, _ := yieldAll(t, src)
+			if !cmp.Equal(values, again) {
+				t.Error(cmp.Diff(values, again))
+			}
+		})
+	}
+}
+
+func TestParseRandomInvalid(t *testing.T) {
+	for _, s := range []string{"", "uuid", "x:uuid", "-1:hex", "5:uuid:10", "5:hex:0", "5:foo", "5:hex:8:9"} {
+		t.Run("", func(t *testing.T) {
+			_, err := ParseRandom(s)
+			if err == nil {
+				t.Fatalf("expected error for %q not found", s)
+			}
+		})
+	}
+}
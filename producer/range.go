@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // Range defines a range of values which should be tested.
 type Range struct {
 	First, Last int
+	Step        int // distance between two values, 0 means 1
 }
 
-// ParseRange parses a range from the string s. Valid formats are `n` and `n-m`.
+// ParseRange parses a range from the string s. Valid formats are `n`, `n-m`
+// and `n-m/step`.
 func ParseRange(s string) (r Range, err error) {
 	// test if it's a number only
 	n, err := strconv.Atoi(s)
@@ -19,10 +22,18 @@ func ParseRange(s string) (r Range, err error) {
 		return Range{First: n, Last: n}, nil
 	}
 
+	if pos := strings.LastIndex(s, "/"); pos >= 0 {
+		r.Step, err = strconv.Atoi(s[pos+1:])
+		if err != nil || r.Step <= 0 {
+			return Range{}, fmt.Errorf("invalid step for range %q, expected a positive number", s)
+		}
+		s = s[:pos]
+	}
+
 	// otherwise assume it's a range
 	_, err = fmt.Sscanf(s, "%d-%d", &r.First, &r.Last)
 	if err != nil {
-		return Range{}, fmt.Errorf("wrong format for range, expected: first-last, got: %q", s)
+		return Range{}, fmt.Errorf("wrong format for range, expected: first-last[/step], got: %q", s)
 	}
 
 	if r.First > r.Last {
@@ -32,9 +43,44 @@ func ParseRange(s string) (r Range, err error) {
 	return r, nil
 }
 
+func (r Range) step() int {
+	if r.Step <= 0 {
+		return 1
+	}
+	return r.Step
+}
+
 // Count returns the number of items in the range.
 func (r Range) Count() int {
-	return r.Last - r.First + 1
+	return (r.Last-r.First)/r.step() + 1
+}
+
+// RangeFormat returns the format string for range values in base (one of
+// dec, hex, oct, bin), padded with zeroes to width digits.
+func RangeFormat(base string, width int) (string, error) {
+	var verb string
+	switch base {
+	case "", "dec":
+		verb = "d"
+	case "hex":
+		verb = "x"
+	case "oct":
+		verb = "o"
+	case "bin":
+		verb = "b"
+	default:
+		return "", fmt.Errorf("unknown base %q, valid bases are: dec, hex, oct, bin", base)
+	}
+
+	if width < 0 {
+		return "", fmt.Errorf("invalid width %d", width)
+	}
+
+	if width == 0 {
+		return "%" + verb, nil
+	}
+
+	return fmt.Sprintf("%%0%d%s", width, verb), nil
 }
 
 // Ranges sends all range values to the channel ch, and the number of items to
@@ -56,7 +102,7 @@ func Ranges(ctx context.Context, ranges []Range, format string, ch chan<- string
 	defer close(ch)
 
 	for _, r := range ranges {
-		for i := r.First; i <= r.Last; i++ {
+		for i := r.First; i <= r.Last; i += r.step() {
 			v := fmt.Sprintf(format, i)
 			select {
 			case ch <- v:
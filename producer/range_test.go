@@ -0,0 +1,87 @@
+package producer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRangeSource(t *testing.T) {
+	var tests = []struct {
+		ranges []string
+		base   string
+		width  int
+		want   []string
+	}{
+		{
+			ranges: []string{"1-3", "7"},
+			want:   []string{"1", "2", "3", "7"},
+		},
+		{
+			ranges: []string{"0-10/5", "20-29/4"},
+			want:   []string{"0", "5", "10", "20", "24", "28"},
+		},
+		{
+			ranges: []string{"98-100"},
+			width:  4,
+			want:   []string{"0098", "0099", "0100"},
+		},
+		{
+			ranges: []string{"254-257"},
+			base:   "hex",
+			width:  3,
+			want:   []string{"0fe", "0ff", "100", "101"},
+		},
+		{
+			ranges: []string{"7-9"},
+			base:   "oct",
+			want:   []string{"7", "10", "11"},
+		},
+		{
+			ranges: []string{"1-8/3"},
+			base:   "bin",
+			width:  4,
+			want:   []string{"0001", "0100", "0111"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var src RangeSource
+			for _, s := range test.ranges {
+				r, err := ParseRange(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				src.Ranges = append(src.Ranges, r)
+			}
+
+			format, err := RangeFormat(test.base, test.width)
+			if err != nil {
+				t.Fatal(err)
+			}
+			src.Format = format
+
+			values, n := yieldAll(t, &src)
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if n != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), n)
+			}
+		})
+	}
+}
+
+func TestParseRangeInvalid(t *testing.T) {
+	for _, s := range []string{"", "foo", "5-1", "1-10/0", "1-10/-2", "1-10/x"} {
+		t.Run("", func(t *testing.T) {
+			_, err := ParseRange(s)
+			if err == nil {
+				t.Fatalf("expected error for %q not found", s)
+			}
+		})
+	}
+}
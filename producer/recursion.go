@@ -0,0 +1,216 @@
+package producer
+
+import (
+	"context"
+	"sync"
+)
+
+// Recursion forwards items and afterwards runs Produce again for each prefix
+// added with Enqueue while the items are processed, e.g. for directories
+// discovered in the responses. For the items produced for a prefix, the
+// prefix and Separator are prepended to the value with the index
+// Placeholder.
+//
+// Done must be called once for each item after it has been processed. The
+// output channel is closed when all items have been processed and no prefix
+// is queued.
+type Recursion struct {
+	Placeholder int
+	Separator   string
+	Produce     func(context.Context) (<-chan Item, <-chan int)
+
+	m       sync.Mutex
+	queue   []recursionJob
+	pending int
+	wakeup  chan struct{}
+}
+
+type recursionJob struct {
+	prefix string
+	depth  int
+}
+
+// NewRecursion returns a new Recursion.
+func NewRecursion(placeholder int, separator string, produce func(context.Context) (<-chan Item, <-chan int)) *Recursion {
+	return &Recursion{
+		Placeholder: placeholder,
+		Separator:   separator,
+		Produce:     produce,
+		wakeup:      make(chan struct{}, 1),
+	}
+}
+
+func (r *Recursion) signal() {
+	select {
+	case r.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue adds a prefix for which the items are produced again with depth.
+func (r *Recursion) Enqueue(prefix string, depth int) {
+	r.m.Lock()
+	r.queue = append(r.queue, recursionJob{prefix: prefix, depth: depth})
+	r.m.Unlock()
+	r.signal()
+}
+
+// Done records that an item has been processed.
+func (r *Recursion) Done() {
+	r.m.Lock()
+	r.pending--
+	r.m.Unlock()
+	r.signal()
+}
+
+// next returns the next queued job. It blocks until a job has been queued and
+// returns false when all items have been processed or the context has been
+// cancelled.
+func (r *Recursion) next(ctx context.Context) (recursionJob, bool) {
+	for {
+		r.m.Lock()
+		if len(r.queue) > 0 {
+			job := r.queue[0]
+			r.queue = r.queue[1:]
+			r.m.Unlock()
+			return job, true
+		}
+
+		done := r.pending == 0
+		r.m.Unlock()
+
+		if done {
+			return recursionJob{}, false
+		}
+
+		select {
+		case <-r.wakeup:
+		case <-ctx.Done():
+			return recursionJob{}, false
+		}
+	}
+}
+
+// Run forwards the items from in, and the items produced for all queued
+// prefixes afterwards. The total number of items is sent to the returned
+// count channel each time it changes. A new goroutine is started, which
+// terminates when all items have been processed or the context is cancelled.
+func (r *Recursion) Run(ctx context.Context, in <-chan Item, inCount <-chan int) (<-chan Item, <-chan int) {
+	out := make(chan Item)
+	outCount := make(chan int, 1)
+
+	go func() {
+		defer close(out)
+
+		index, total := 0, 0
+		unknown := false
+		addCount := func(n int) {
+			if n == UnknownCount {
+				unknown = true
+			} else {
+				total += n
+			}
+
+			// only the latest total is interesting, replace the previous
+			// value if it has not been received yet
+			select {
+			case <-outCount:
+			default:
+			}
+
+			if unknown {
+				outCount <- UnknownCount
+				return
+			}
+			outCount <- total
+		}
+
+		// forward sends all items from in to out. When wait is set and in is
+		// closed before the count has been received, forward waits for it.
+		forward := func(in <-chan Item, inCount <-chan int, job recursionJob, wait bool) bool {
+			for {
+				select {
+				case n, ok := <-inCount:
+					inCount = nil
+					if ok {
+						addCount(n)
+					}
+
+				case item, ok := <-in:
+					if !ok {
+						if inCount == nil {
+							return true
+						}
+
+						if wait {
+							select {
+							case n, ok := <-inCount:
+								if ok {
+									addCount(n)
+								}
+							case <-ctx.Done():
+							}
+							return true
+						}
+
+						select {
+						case n, ok := <-inCount:
+							if ok {
+								addCount(n)
+							}
+						default:
+						}
+						return true
+					}
+
+					if job.prefix != "" {
+						values := make([]string, len(item.Values))
+						copy(values, item.Values)
+						values[r.Placeholder] = job.prefix + r.Separator + values[r.Placeholder]
+						item.Values = values
+					}
+					item.Depth = job.depth
+					if item.Index < index {
+						item.Index = index
+					}
+					index = item.Index + 1
+
+					r.m.Lock()
+					r.pending++
+					r.m.Unlock()
+
+					select {
+					case out <- item:
+					case <-ctx.Done():
+						return false
+					}
+
+				case <-ctx.Done():
+					return false
+				}
+			}
+		}
+
+		if !forward(in, inCount, recursionJob{}, true) {
+			return
+		}
+
+		for {
+			job, ok := r.next(ctx)
+			if !ok {
+				return
+			}
+
+			ctx, cancel := context.WithCancel(ctx)
+			items, count := r.Produce(ctx)
+			ok = forward(items, count, job, false)
+			cancel()
+
+			if !ok {
+				return
+			}
+		}
+	}()
+
+	return out, outCount
+}
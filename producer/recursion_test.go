@@ -0,0 +1,52 @@
+package producer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecursion(t *testing.T) {
+	produce := func(ctx context.Context) (<-chan Item, <-chan int) {
+		ch := make(chan Item)
+		count := make(chan int, 1)
+		go func() {
+			_ = (&Multiplexer{Names: []string{"FUZZ"}, Sources: []Source{staticSource{"a", "b"}}}).Run(ctx, ch, count)
+		}()
+		return ch, count
+	}
+
+	r := NewRecursion(0, "/", produce)
+	in, inCount := produce(context.Background())
+	out, count := r.Run(context.Background(), in, inCount)
+
+	var values []string
+	var depths []int
+	for item := range out {
+		v := item.Values[0]
+		values = append(values, v)
+		depths = append(depths, item.Depth)
+
+		// recurse into all values ending in "a" up to depth 2
+		if strings.HasSuffix(v, "a") && item.Depth < 2 {
+			r.Enqueue(v, item.Depth+1)
+		}
+		r.Done()
+	}
+
+	want := []string{"a", "b", "a/a", "a/b", "a/a/a", "a/a/b"}
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	wantDepths := []int{0, 0, 1, 1, 2, 2}
+	if !cmp.Equal(wantDepths, depths) {
+		t.Error(cmp.Diff(wantDepths, depths))
+	}
+
+	if n := <-count; n != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), n)
+	}
+}
@@ -0,0 +1,230 @@
+package producer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// SitemapSource produces the paths listed in robots.txt and sitemap.xml of a
+// web site, without the leading slash. Sitemaps referenced in robots.txt and
+// in a sitemap index are fetched as well, as long as they are on the same
+// host. The files are only fetched once, even if Yield is called again.
+type SitemapSource struct {
+	Base   *url.URL // scheme and host of the site
+	Client *http.Client
+
+	once   sync.Once
+	values []string
+	err    error
+}
+
+// maxSitemaps is the maximum number of sitemaps fetched for a site.
+const maxSitemaps = 100
+
+// Yield fetches robots.txt and the sitemaps and sends the paths to ch.
+func (s *SitemapSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	s.once.Do(func() {
+		s.values, s.err = s.discover(ctx)
+	})
+	if s.err != nil {
+		return s.err
+	}
+
+	count <- len(s.values)
+
+	for _, v := range s.values {
+		select {
+		case ch <- v:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// fetch returns the body of the file at path. Files which do not exist yield
+// an empty body.
+func (s *SitemapSource) fetch(ctx context.Context, path string) ([]byte, error) {
+	u := *s.Base
+	u.Path = path
+	u.RawQuery = ""
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("fetch %v: %v", u.String(), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		return nil, nil
+	}
+
+	return ioutil.ReadAll(io.LimitReader(res.Body, 10*1024*1024))
+}
+
+// discover returns the unique paths found in robots.txt and the sitemaps.
+func (s *SitemapSource) discover(ctx context.Context) ([]string, error) {
+	var values []string
+	seen := make(map[string]struct{})
+	add := func(path string) {
+		path = strings.TrimPrefix(path, "/")
+		if path == "" {
+			return
+		}
+		if _, ok := seen[path]; ok {
+			return
+		}
+		seen[path] = struct{}{}
+		values = append(values, path)
+	}
+
+	buf, err := s.fetch(ctx, "/robots.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	paths, sitemaps := ParseRobots(buf)
+	for _, path := range paths {
+		add(path)
+	}
+
+	queue := []string{"/sitemap.xml"}
+	for _, sitemap := range sitemaps {
+		if path, ok := s.sameHost(sitemap); ok {
+			queue = append(queue, path)
+		}
+	}
+
+	fetched := make(map[string]struct{})
+	for len(queue) > 0 && len(fetched) < maxSitemaps {
+		path := queue[0]
+		queue = queue[1:]
+
+		if _, ok := fetched[path]; ok {
+			continue
+		}
+		fetched[path] = struct{}{}
+
+		buf, err := s.fetch(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+
+		locs, index := ParseSitemap(buf)
+		for _, loc := range locs {
+			if path, ok := s.sameHost(loc); ok {
+				if index {
+					queue = append(queue, path)
+				} else {
+					add(path)
+				}
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// sameHost returns the path of the URL u if it is on the same host as Base.
+func (s *SitemapSource) sameHost(u string) (string, bool) {
+	target, err := s.Base.Parse(u)
+	if err != nil || target.Host != s.Base.Host {
+		return "", false
+	}
+
+	path := target.EscapedPath()
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+	return path, true
+}
+
+// ParseRobots returns the paths of all Allow and Disallow rules and the URLs
+// of all sitemaps listed in robots.txt. Rules are cut at the first wildcard.
+func ParseRobots(buf []byte) (paths, sitemaps []string) {
+	sc := bufio.NewScanner(bytes.NewReader(buf))
+	for sc.Scan() {
+		line := sc.Text()
+		if pos := strings.Index(line, "#"); pos >= 0 {
+			line = line[:pos]
+		}
+
+		data := strings.SplitN(line, ":", 2)
+		if len(data) != 2 {
+			continue
+		}
+
+		key, value := strings.ToLower(strings.TrimSpace(data[0])), strings.TrimSpace(data[1])
+		switch key {
+		case "allow", "disallow":
+			if pos := strings.IndexAny(value, "*$"); pos >= 0 {
+				value = value[:pos]
+			}
+			if value != "" && value != "/" {
+				paths = append(paths, value)
+			}
+		case "sitemap":
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		}
+	}
+
+	return paths, sitemaps
+}
+
+// ParseSitemap returns the locations listed in a sitemap. If the sitemap is
+// a sitemap index, the locations are sitemaps and index is true.
+func ParseSitemap(buf []byte) (locs []string, index bool) {
+	dec := xml.NewDecoder(bytes.NewReader(buf))
+	inLoc := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return locs, index
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sitemapindex":
+				index = true
+			case "loc":
+				inLoc = true
+			}
+		case xml.EndElement:
+			if t.Name.Local == "loc" {
+				inLoc = false
+			}
+		case xml.CharData:
+			if inLoc {
+				if loc := strings.TrimSpace(string(t)); loc != "" {
+					locs = append(locs, loc)
+				}
+			}
+		}
+	}
+}
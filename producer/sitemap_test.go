@@ -0,0 +1,59 @@
+package producer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSitemapSource(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\n# comment\nDisallow: /admin/\nDisallow: /search?q=*\nAllow: /\nSitemap: %s/sitemap-index.xml\nSitemap: https://other.example.com/sitemap.xml\n", srv.URL)
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>%[1]s/</loc></url>
+  <url><loc>%[1]s/about</loc></url>
+  <url><loc>https://other.example.com/foo</loc></url>
+</urlset>`, srv.URL)
+		case "/sitemap-index.xml":
+			fmt.Fprintf(w, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/sitemap-blog.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/sitemap.xml</loc></sitemap>
+</sitemapindex>`, srv.URL)
+		case "/sitemap-blog.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%[1]s/blog/post-1</loc></url><url><loc>%[1]s/about</loc></url></urlset>`, srv.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	base, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := &SitemapSource{Base: base}
+
+	// the source is read twice when merged with other sources
+	for i := 0; i < 2; i++ {
+		values, n := yieldAll(t, src)
+
+		want := []string{"admin/", "search?q=", "about", "blog/post-1"}
+		if !cmp.Equal(want, values) {
+			t.Error(cmp.Diff(want, values))
+		}
+
+		if n != len(want) {
+			t.Errorf("wrong count, want %d, got %d", len(want), n)
+		}
+	}
+}
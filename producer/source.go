@@ -0,0 +1,82 @@
+package producer
+
+import (
+	"context"
+	"os"
+)
+
+// Source produces values.
+type Source interface {
+	// Yield sends all values to the channel ch, and the number of items to
+	// the channel count. Sending stops and ch is closed when an error occurs
+	// or the context is cancelled. Sources which cannot know the number of
+	// items send UnknownCount.
+	Yield(ctx context.Context, ch chan<- string, count chan<- int) error
+}
+
+// UnknownCount is sent as the number of items when it cannot be known in
+// advance, e.g. for values read from stdin. It is passed on by all stages
+// which compute the number of items from the count of a source.
+const UnknownCount = -1
+
+// scaleCount returns n multiplied by factor, UnknownCount is passed on.
+func scaleCount(n, factor int) int {
+	if n == UnknownCount {
+		return UnknownCount
+	}
+	return n * factor
+}
+
+// FileSource produces the lines of a file. If Filename is "-", values are read
+// from stdin.
+type FileSource struct {
+	Filename string
+}
+
+// Yield sends all lines of the file to ch.
+func (s *FileSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	if s.Filename == "-" {
+		// the number of lines is only known at the end
+		count <- UnknownCount
+		return Reader(ctx, os.Stdin, ch, make(chan int, 1))
+	}
+
+	file, err := os.Open(s.Filename)
+	if err != nil {
+		close(ch)
+		return err
+	}
+
+	return Reader(ctx, file, ch, count)
+}
+
+// ListSource produces the values in the list.
+type ListSource []string
+
+// Yield sends all values to ch.
+func (s ListSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	count <- len(s)
+
+	for _, v := range s {
+		select {
+		case ch <- v:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// RangeSource produces the values of several ranges formatted with Format.
+type RangeSource struct {
+	Ranges []Range
+	Format string
+}
+
+// Yield sends all values within the ranges to ch.
+func (s *RangeSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	return Ranges(ctx, s.Ranges, s.Format, ch, count)
+}
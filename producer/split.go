@@ -0,0 +1,17 @@
+package producer
+
+import "strings"
+
+// DelimitedFields returns a SplitFunc which splits a value at sep into n
+// fields, e.g. "admin:P@ss1" into a user name and a password. The last field
+// contains the remainder of the value including further separators, missing
+// fields yield an empty string.
+func DelimitedFields(sep string, n int) SplitFunc {
+	return func(s string) ([]string, error) {
+		values := strings.SplitN(s, sep, n)
+		for len(values) < n {
+			values = append(values, "")
+		}
+		return values, nil
+	}
+}
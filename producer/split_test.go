@@ -0,0 +1,36 @@
+package producer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDelimitedFields(t *testing.T) {
+	var tests = []struct {
+		sep   string
+		n     int
+		value string
+		want  []string
+	}{
+		{":", 2, "admin:P@ss1", []string{"admin", "P@ss1"}},
+		{":", 2, "admin:pass:with:colons", []string{"admin", "pass:with:colons"}},
+		{":", 2, "admin", []string{"admin", ""}},
+		{":", 3, "a:b", []string{"a", "b", ""}},
+		{"\t", 2, "user\tsecret", []string{"user", "secret"}},
+		{"::", 2, "a::b:c", []string{"a", "b:c"}},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			values, err := DelimitedFields(test.sep, test.n)(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+		})
+	}
+}
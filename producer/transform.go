@@ -0,0 +1,70 @@
+package producer
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// transformFuncs contains the functions available in templates for
+// ParseTransform, in addition to all encoders with a valid identifier as
+// name.
+var transformFuncs = template.FuncMap{
+	"json": func(s string) string {
+		buf, _ := json.Marshal(s)
+		return string(buf)
+	},
+	"md5": func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+	"sha1": func(s string) string {
+		sum := sha1.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+	"sha256": func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+	"trim": strings.TrimSpace,
+}
+
+func init() {
+	for name, f := range Encoders {
+		if !strings.Contains(name, "-") {
+			transformFuncs[name] = f
+		}
+	}
+}
+
+// ParseTransform returns a function which rewrites a value with the Go
+// template text, the value is available as "." in the template. The template
+// is executed once with a test value, so errors are returned here. Should the
+// template fail for another value nonetheless, the value is returned
+// unchanged.
+func ParseTransform(text string) (func(string) string, error) {
+	tmpl, err := template.New("transform").Funcs(transformFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse template %q: %v", text, err)
+	}
+
+	var sb strings.Builder
+	err = tmpl.Execute(&sb, "test")
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %v", text, err)
+	}
+
+	return func(s string) string {
+		var sb strings.Builder
+		err := tmpl.Execute(&sb, s)
+		if err != nil {
+			return s
+		}
+		return sb.String()
+	}, nil
+}
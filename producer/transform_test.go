@@ -0,0 +1,40 @@
+package producer
+
+import "testing"
+
+func TestParseTransform(t *testing.T) {
+	var tests = []struct {
+		template string
+		value    string
+		want     string
+	}{
+		{`{{.}}`, "admin", "admin"},
+		{`{"user": {{json .}}}`, `a"b`, `{"user": "a\"b"}`},
+		{`{{printf "%05s" .}}`, "23", "00023"},
+		{`{{.}}-{{md5 . | printf "%.4s"}}`, "admin", "admin-2123"},
+		{`{{upper .}}{{base64 .}}`, "ab", "ABYWI="},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			f, err := ParseTransform(test.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := f(test.value)
+			if res != test.want {
+				t.Errorf("wrong result, want %q, got %q", test.want, res)
+			}
+		})
+	}
+}
+
+func TestParseTransformInvalid(t *testing.T) {
+	for _, tmpl := range []string{`{{.}`, `{{.Foo}}`, `{{unknown .}}`} {
+		_, err := ParseTransform(tmpl)
+		if err == nil {
+			t.Errorf("expected error for %q not found", tmpl)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package producer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// homoglyphs maps ASCII characters to similar looking characters from other
+// scripts (mostly Cyrillic and Greek).
+var homoglyphs = map[rune]rune{
+	'A': 'А', 'B': 'В', 'C': 'С', 'E': 'Е', 'H': 'Н', 'I': 'І', 'J': 'Ј',
+	'K': 'К', 'M': 'М', 'N': 'Ν', 'O': 'О', 'P': 'Р', 'S': 'Ѕ', 'T': 'Т',
+	'X': 'Х', 'Y': 'Υ', 'Z': 'Ζ',
+	'a': 'а', 'c': 'с', 'd': 'ԁ', 'e': 'е', 'h': 'һ', 'i': 'і', 'j': 'ј',
+	'o': 'о', 'p': 'р', 's': 'ѕ', 'x': 'х', 'y': 'у',
+	'/': '⁄', '.': '․', '-': '‐',
+}
+
+// fullwidth returns the fullwidth form of all printable ASCII characters.
+func fullwidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > ' ' && r <= '~' {
+			return r - '!' + '！'
+		}
+		return r
+	}, s)
+}
+
+// unicodeVariants contains functions to derive Unicode variants of a value.
+var unicodeVariants = map[string]func(string) string{
+	"original": func(s string) string { return s },
+	"homoglyph": func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if h, ok := homoglyphs[r]; ok {
+				return h
+			}
+			return r
+		}, s)
+	},
+	"fullwidth": fullwidth,
+	"nfc":       norm.NFC.String,
+	"nfd":       norm.NFD.String,
+	"nfkc":      norm.NFKC.String,
+	"nfkd":      norm.NFKD.String,
+}
+
+// UnicodeVariantNames returns a sorted list of the names of all Unicode
+// variants.
+func UnicodeVariantNames() (names []string) {
+	for name := range unicodeVariants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewUnicodeSource returns a source which sends Unicode variants of each value
+// of src, e.g. with characters replaced by similar looking ones (homoglyph)
+// or normalized (nfc, nfd, nfkc, nfkd). Each value is sent once for each
+// variant, even if some of the variants are equal.
+func NewUnicodeSource(src Source, variants []string) (Source, error) {
+	var funcs []func(string) string
+	for _, name := range variants {
+		f, ok := unicodeVariants[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown Unicode variant %q, valid variants: %v", name, strings.Join(UnicodeVariantNames(), ", "))
+		}
+		funcs = append(funcs, f)
+	}
+
+	return &ExpandSource{
+		Source: src,
+		Factor: len(funcs),
+		Expand: func(s string) []string {
+			res := make([]string, 0, len(funcs))
+			for _, f := range funcs {
+				res = append(res, f(s))
+			}
+			return res
+		},
+	}, nil
+}
@@ -0,0 +1,33 @@
+package producer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnicodeSource(t *testing.T) {
+	src, err := NewUnicodeSource(staticSource{"admin", "café"}, []string{"original", "homoglyph", "fullwidth", "nfd", "nfkc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, count := yieldAll(t, src)
+	want := []string{
+		"admin", "аԁmіn", "ａｄｍｉｎ", "admin", "admin",
+		"café", "саfé", "ｃａｆé", "cafe\u0301", "café",
+	}
+
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if count != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), count)
+	}
+
+	_, err = NewUnicodeSource(staticSource{"admin"}, []string{"foo"})
+	if err == nil {
+		t.Fatal("expected error for unknown variant not found")
+	}
+}
@@ -0,0 +1,102 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DefaultUsernameFormats are the formats used by UsernameSource if none are
+// specified.
+var DefaultUsernameFormats = []string{
+	"{first}.{last}", "{f}{last}", "{f}.{last}", "{first}{l}", "{first}.{l}",
+	"{last}{f}", "{last}.{first}", "{first}", "{last}",
+}
+
+// ParseUsernameFormat checks that format only contains the placeholders
+// {first}, {last}, {f} and {l}.
+func ParseUsernameFormat(format string) error {
+	s := usernameReplacer("", "").Replace(format)
+	if strings.ContainsAny(s, "{}") {
+		return fmt.Errorf("invalid username format %q, valid placeholders are {first}, {last}, {f} and {l}", format)
+	}
+
+	return nil
+}
+
+func usernameReplacer(first, last string) *strings.Replacer {
+	initial := func(s string) string {
+		for _, r := range s {
+			return string(r)
+		}
+		return ""
+	}
+
+	return strings.NewReplacer(
+		"{first}", first,
+		"{last}", last,
+		"{f}", initial(first),
+		"{l}", initial(last),
+	)
+}
+
+// UsernameSource generates user names from the values of Source, which are
+// full names such as "John Smith". The first word is used as the first name,
+// the last word as the last name, values with less than two words are
+// skipped. For each name, all Formats are sent in lower case. The number of
+// values is sent after all values have been read from Source.
+type UsernameSource struct {
+	Source
+	Formats []string
+}
+
+// Yield sends the user names to ch.
+func (s *UsernameSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	in := make(chan string)
+	errCh := make(chan error, 1)
+
+	go func() {
+		// the count of the source is not needed
+		errCh <- s.Source.Yield(ctx, in, make(chan int, 1))
+	}()
+
+	formats := s.Formats
+	if len(formats) == 0 {
+		formats = DefaultUsernameFormats
+	}
+
+	num := 0
+	for v := range in {
+		words := strings.FieldsFunc(strings.ToLower(v), func(r rune) bool {
+			return unicode.IsSpace(r) || r == ','
+		})
+		if len(words) < 2 {
+			continue
+		}
+
+		replacer := usernameReplacer(words[0], words[len(words)-1])
+		for _, format := range formats {
+			num++
+
+			select {
+			case ch <- replacer.Replace(format):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+
+	err := <-errCh
+	if err != nil {
+		return err
+	}
+
+	count <- num
+	return nil
+}
@@ -0,0 +1,44 @@
+package producer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUsernameSource(t *testing.T) {
+	src := &UsernameSource{
+		Source:  staticSource{"John Smith", "Prince", "Anna Maria  Müller", ""},
+		Formats: []string{"{f}{last}", "{first}.{last}", "{last}{f}", "{first}.{l}"},
+	}
+
+	values, count := yieldAll(t, src)
+	want := []string{
+		"jsmith", "john.smith", "smithj", "john.s",
+		"amüller", "anna.müller", "müllera", "anna.m",
+	}
+
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if count != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), count)
+	}
+}
+
+func TestParseUsernameFormat(t *testing.T) {
+	for _, format := range DefaultUsernameFormats {
+		err := ParseUsernameFormat(format)
+		if err != nil {
+			t.Errorf("format %q: unexpected error %v", format, err)
+		}
+	}
+
+	for _, format := range []string{"{first", "{middle}.{last}", "{F}{last}"} {
+		err := ParseUsernameFormat(format)
+		if err == nil {
+			t.Errorf("expected error for format %q not found", format)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package producer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultWaybackEndpoint is the CDX API of the Wayback Machine.
+const DefaultWaybackEndpoint = "https://web.archive.org/cdx/search/cdx"
+
+// WaybackSource produces the paths of all URLs for a host archived by the
+// Wayback Machine, without the leading slash. The API is only queried once,
+// even if Yield is called again.
+type WaybackSource struct {
+	Host     string
+	Limit    int    // maximum number of URLs requested from the API, 0 means no limit
+	Endpoint string // the CDX API, DefaultWaybackEndpoint is used if empty
+	Client   *http.Client
+
+	once   sync.Once
+	values []string
+	err    error
+}
+
+// Yield queries the API and sends the paths to ch.
+func (s *WaybackSource) Yield(ctx context.Context, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	s.once.Do(func() {
+		s.values, s.err = s.query(ctx)
+	})
+	if s.err != nil {
+		return s.err
+	}
+
+	count <- len(s.values)
+
+	for _, v := range s.values {
+		select {
+		case ch <- v:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// query returns the unique paths of the archived URLs.
+func (s *WaybackSource) query(ctx context.Context) ([]string, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultWaybackEndpoint
+	}
+
+	params := url.Values{}
+	params.Set("url", s.Host+"/*")
+	params.Set("output", "txt")
+	params.Set("fl", "original")
+	params.Set("collapse", "urlkey")
+	if s.Limit > 0 {
+		params.Set("limit", strconv.Itoa(s.Limit))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("query Wayback Machine: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("query Wayback Machine: unexpected status %v", res.Status)
+	}
+
+	var values []string
+	seen := make(map[string]struct{})
+
+	sc := bufio.NewScanner(res.Body)
+	for sc.Scan() {
+		u, err := url.Parse(strings.TrimSpace(sc.Text()))
+		if err != nil || !strings.EqualFold(u.Hostname(), s.Host) {
+			continue
+		}
+
+		path := strings.TrimPrefix(u.EscapedPath(), "/")
+		if path == "" {
+			continue
+		}
+
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		values = append(values, path)
+	}
+
+	if sc.Err() != nil {
+		return nil, fmt.Errorf("query Wayback Machine: %v", sc.Err())
+	}
+
+	return values, nil
+}
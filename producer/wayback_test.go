@@ -0,0 +1,38 @@
+package producer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWaybackSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("url") != "example.com/*" || r.URL.Query().Get("limit") != "100" {
+			t.Errorf("unexpected query %v", r.URL.RawQuery)
+		}
+
+		fmt.Fprint(w, "http://example.com/\n"+
+			"http://example.com/old/login.php?next=/\n"+
+			"https://example.com:443/old/login.php\n"+
+			"http://www.example.com/other\n"+
+			"http://example.com/robots.txt\n")
+	}))
+	defer srv.Close()
+
+	src := &WaybackSource{Host: "example.com", Limit: 100, Endpoint: srv.URL}
+
+	values, n := yieldAll(t, src)
+
+	want := []string{"old/login.php", "robots.txt"}
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	if n != len(want) {
+		t.Errorf("wrong count, want %d, got %d", len(want), n)
+	}
+}
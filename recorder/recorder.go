@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"time"
 
+	"github.com/RedTeamPentesting/monsoon/producer"
 	"github.com/RedTeamPentesting/monsoon/request"
 	"github.com/RedTeamPentesting/monsoon/response"
 )
@@ -31,22 +32,47 @@ type Data struct {
 	InputFile   string     `json:"input_file,omitempty"`
 	Ranges      []string   `json:"ranges,omitempty"`
 	RangeFormat string     `json:"range_format,omitempty"`
+	Replace     []string   `json:"replace,omitempty"`
+	ReplaceMode string     `json:"replace_mode,omitempty"`
+	Source      string     `json:"source,omitempty"`
 	Responses   []Response `json:"responses"`
 	Extract     []string   `json:"extract,omitempty"`
 	ExtractPipe []string   `json:"extract_pipe,omitempty"`
+	ExtractJSON []string   `json:"extract_json,omitempty"`
+	ExtractHTML []string   `json:"extract_html,omitempty"`
+
+	ExtractHeader []string `json:"extract_header,omitempty"`
+	ExtractCookie []string `json:"extract_cookie,omitempty"`
 }
 
 // Response is the result of a request sent to the target.
 type Response struct {
-	Item     string  `json:"item"`
-	Error    string  `json:"error,omitempty"`
-	Duration float64 `json:"duration"`
+	Item     string   `json:"item"`
+	Values   []string `json:"values,omitempty"`
+	ID       string   `json:"id,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Duration float64  `json:"duration"`
+	Retries  int      `json:"retries,omitempty"`
 
 	StatusCode    int                `json:"status_code"`
 	StatusText    string             `json:"status_text"`
 	Header        response.TextStats `json:"header"`
 	Body          response.TextStats `json:"body"`
 	ExtractedData []string           `json:"extracted_data,omitempty"`
+
+	ExtractedFields map[string][]string `json:"extracted_fields,omitempty"`
+
+	Redirects []Redirect `json:"redirects,omitempty"`
+	FinalURL  string     `json:"final_url,omitempty"`
+
+	BodyFile string `json:"body_file,omitempty"`
+}
+
+// Redirect is a response with a redirect which has been followed.
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location,omitempty"`
 }
 
 // New creates a new  recorder.
@@ -87,6 +113,7 @@ func (r *Recorder) Run(ctx context.Context, in <-chan response.Response, out cha
 	lastStatus := time.Now()
 
 	var countCh chan<- int // countCh is nil initially to disable sending
+	var count int          // the last total received from inCount
 
 loop:
 	for {
@@ -104,15 +131,21 @@ loop:
 				break loop
 			}
 
-		case total := <-inCount:
-			data.TotalRequests = total
-			// disable receiving on the in count channel
-			inCount = nil
+		case total, ok := <-inCount:
+			if !ok {
+				// disable receiving on the closed in count channel
+				inCount = nil
+				continue loop
+			}
+			count = total
+			if total != producer.UnknownCount {
+				data.TotalRequests = total
+			}
 			// enable sending by setting countCh to outCount (which is not nil)
 			countCh = outCount
 			continue loop
 
-		case countCh <- data.TotalRequests:
+		case countCh <- count:
 			// disable sending again by setting countCh to nil
 			countCh = nil
 			continue loop
@@ -144,6 +177,11 @@ loop:
 		}
 	}
 
+	// all requests have been sent when the run finished
+	if count == producer.UnknownCount && !data.Cancelled {
+		data.TotalRequests = data.SentRequests
+	}
+
 	data.End = time.Now()
 	return r.dump(data)
 }
@@ -162,9 +200,14 @@ func (r *Recorder) dump(data Data) error {
 // NewResponse builds a Response struct for serialization with JSON.
 func NewResponse(r response.Response) (res Response) {
 	res.Item = r.Item
+	if len(r.Values) > 1 {
+		res.Values = r.Values
+	}
+	res.ID = r.ID
 	if r.Duration != 0 {
 		res.Duration = float64(r.Duration) / float64(time.Second)
 	}
+	res.Retries = r.Retries
 	if r.Error != nil {
 		res.Error = r.Error.Error()
 	}
@@ -176,6 +219,19 @@ func NewResponse(r response.Response) (res Response) {
 	res.Header = r.Header
 	res.Body = r.Body
 	res.ExtractedData = r.Extract
+	res.ExtractedFields = r.Fields
+	res.BodyFile = r.BodyFile
+
+	for _, redirect := range r.Redirects {
+		res.Redirects = append(res.Redirects, Redirect{
+			URL:        redirect.URL,
+			StatusCode: redirect.HTTPResponse.StatusCode,
+			Location:   redirect.HTTPResponse.Header.Get("Location"),
+		})
+	}
+	if len(r.Redirects) > 0 {
+		res.FinalURL = r.FinalURL()
+	}
 
 	return res
 }
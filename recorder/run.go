@@ -73,13 +73,13 @@ func LoadRuns(dir string) (runs []Run, err error) {
 			continue
 		}
 
-		run.URL, err = url.Parse(run.Data.Template.URL)
+		var host, port string
+		run.URL, host, port, err = parseURL(run.Data.Template.URL)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "unable to parse template URL %v, skipping: %v\n", run.Data.Template.URL, err)
 			continue
 		}
 
-		port := run.URL.Port()
 		if port == "" {
 			switch run.URL.Scheme {
 			case "http":
@@ -89,9 +89,9 @@ func LoadRuns(dir string) (runs []Run, err error) {
 			}
 		}
 
-		run.Host = run.URL.Hostname()
+		run.Host = host
 		run.Port = port
-		run.Hostport = run.URL.Hostname() + ":" + port
+		run.Hostport = host + ":" + port
 		run.PathQuery = run.URL.Path
 		if run.URL.RawQuery != "" {
 			run.PathQuery += "?" + run.URL.RawQuery
@@ -103,6 +103,29 @@ func LoadRuns(dir string) (runs []Run, err error) {
 	return runs, nil
 }
 
+// parseURL parses the URL of a template and returns the host name and port,
+// the port may contain a placeholder.
+func parseURL(rawurl string) (u *url.URL, host, port string, err error) {
+	u, err = url.Parse(rawurl)
+	if err == nil {
+		return u, u.Hostname(), u.Port(), nil
+	}
+
+	before, port, after := splitPort(rawurl)
+	if port == "" {
+		return nil, "", "", err
+	}
+
+	u, err = url.Parse(before + portMarker + after)
+	if err != nil {
+		return nil, "", "", err
+	}
+	host = u.Hostname()
+	u.Host = strings.Replace(u.Host, portMarker, port, 1)
+
+	return u, host, port, nil
+}
+
 // SortRuns sorts the list first by URL then start timestamp.
 func SortRuns(list []Run) {
 	sort.SliceStable(list, func(i, j int) bool {
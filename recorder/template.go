@@ -1,8 +1,11 @@
 package recorder
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/RedTeamPentesting/monsoon/request"
 )
@@ -15,22 +18,101 @@ type Template struct {
 	Header http.Header `json:"header"`
 }
 
-// NewTemplate builds a template to write to the JSON data file.
-func NewTemplate(request *request.Request) (t Template, err error) {
-	req, err := request.Apply(request.Replace)
+// variablePattern matches variables such as %RANDOM% in the URL, which are no
+// valid percent-encodings.
+var variablePattern = regexp.MustCompile(`%[A-Z]+%`)
+
+// Markers for a placeholder in the scheme or the port of the URL, which must
+// be replaced by a valid scheme or port for parsing the URL.
+const (
+	schemeMarker = "monsoon-scheme"
+	portMarker   = "4294967296"
+)
+
+// NewTemplate builds a template to write to the JSON data file. Variables in
+// the URL (e.g. %RANDOM%) and placeholders in the scheme or port are kept as
+// they are.
+func NewTemplate(tmpl *request.Request) (t Template, err error) {
+	names := []string{tmpl.Replace}
+	values := []string{tmpl.Replace}
+
+	// insert markers which can be parsed as part of a URL (or as a URL at
+	// the start) for the variables and restore them afterwards
+	var restore []string
+
+	switch {
+	case strings.HasPrefix(tmpl.URL, tmpl.Replace+"://"):
+		values[0] = schemeMarker
+		restore = append(restore, schemeMarker, tmpl.Replace)
+	case inPort(tmpl.URL, tmpl.Replace):
+		values[0] = portMarker
+		restore = append(restore, portMarker, tmpl.Replace)
+	}
+
+	for i, name := range variablePattern.FindAllString(tmpl.URL, -1) {
+		marker := fmt.Sprintf("monsoon-variable-%d", i)
+		if strings.HasPrefix(tmpl.URL, name) {
+			marker = "http://" + marker
+		}
+		names = append(names, name)
+		values = append(values, marker)
+		restore = append(restore, marker, name)
+	}
+	restorer := strings.NewReplacer(restore...)
+
+	req, err := tmpl.Apply(names, values)
 	if err != nil {
 		return Template{}, err
 	}
 
-	t.URL = req.URL.String()
-	t.Method = req.Method
-	t.Header = req.Header
+	t.URL = restorer.Replace(request.URLString(req))
+	t.Method = restorer.Replace(req.Method)
+	t.Header = make(http.Header)
+	for name, vs := range req.Header {
+		for _, v := range vs {
+			t.Header[restorer.Replace(name)] = append(t.Header[restorer.Replace(name)], restorer.Replace(v))
+		}
+	}
 
 	buf, err := ioutil.ReadAll(req.Body)
 	if err != nil {
 		return Template{}, err
 	}
-	t.Body = string(buf)
+	t.Body = restorer.Replace(string(buf))
 
 	return t, nil
 }
+
+// splitPort splits rawurl at the port, which may contain placeholders. The
+// port is empty if rawurl does not contain a port.
+func splitPort(rawurl string) (before, port, after string) {
+	i := strings.Index(rawurl, "://")
+	if i < 0 {
+		return rawurl, "", ""
+	}
+
+	start := i + 3
+	end := len(rawurl)
+	if j := strings.IndexAny(rawurl[start:], "/?#"); j >= 0 {
+		end = start + j
+	}
+
+	// skip IPv6 addresses
+	host := start
+	if j := strings.LastIndex(rawurl[start:end], "]"); j >= 0 {
+		host = start + j + 1
+	}
+
+	j := strings.LastIndex(rawurl[host:end], ":")
+	if j < 0 {
+		return rawurl, "", ""
+	}
+
+	return rawurl[:host+j+1], rawurl[host+j+1 : end], rawurl[end:]
+}
+
+// inPort returns true if the placeholder is part of the port in rawurl.
+func inPort(rawurl, placeholder string) bool {
+	_, port, _ := splitPort(rawurl)
+	return strings.Contains(port, placeholder)
+}
@@ -94,6 +94,65 @@ foobar`)
 				},
 			},
 		},
+		{
+			request: func() *request.Request {
+				req := request.New("")
+				req.URL = "%TARGET%/FUZZ?cb=%RANDOM%&n=%N%"
+				_ = req.Header.Set("x-date: %DATE%")
+				return req
+			},
+			want: Template{
+				URL:    "%TARGET%/FUZZ?cb=%RANDOM%&n=%N%",
+				Method: "GET",
+				Header: http.Header{
+					"User-Agent": []string{"monsoon"},
+					"Accept":     []string{"*/*"},
+					"X-Date":     []string{"%DATE%"},
+				},
+			},
+		},
+		{
+			request: func() *request.Request {
+				req := request.New("")
+				req.TemplateFile = filepath.Join(tempdir, "req-from-file")
+				req.URL = "%TARGET%"
+				return req
+			},
+			want: Template{
+				URL:    "%TARGET%/?x=y",
+				Method: "GET",
+				Body:   "foobar",
+				Header: http.Header{
+					"User-Agent": []string{"Firefox"},
+					"Accept":     []string{"application/json", "image/jpeg"},
+					"X-Foo":      []string{"bar"},
+				},
+			},
+		},
+		{
+			request: func() *request.Request {
+				req := request.New("")
+				req.URL = "http://[::1]:80FUZZ/FUZZ"
+				return req
+			},
+			want: Template{
+				URL:    "http://[::1]:80FUZZ/FUZZ",
+				Method: "GET",
+				Header: request.DefaultHeader,
+			},
+		},
+		{
+			request: func() *request.Request {
+				req := request.New("")
+				req.URL = "FUZZ://example.com/"
+				return req
+			},
+			want: Template{
+				URL:    "FUZZ://example.com/",
+				Method: "GET",
+				Header: request.DefaultHeader,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -109,3 +168,32 @@ foobar`)
 		})
 	}
 }
+
+func TestParseURL(t *testing.T) {
+	var tests = []struct {
+		url        string
+		host, port string
+	}{
+		{"http://example.com/", "example.com", ""},
+		{"http://example.com:FUZZ/x?y", "example.com", "FUZZ"},
+		{"https://user@[::1]:FUZZ#x", "::1", "FUZZ"},
+		{"http://example.com:8080/a:b", "example.com", "8080"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			u, host, port, err := parseURL(test.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if host != test.host || port != test.port {
+				t.Errorf("wrong host or port, want %q %q, got %q %q", test.host, test.port, host, port)
+			}
+
+			if u.String() != test.url {
+				t.Errorf("wrong URL, want %q, got %q", test.url, u)
+			}
+		})
+	}
+}
@@ -3,15 +3,26 @@ package reporter
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/RedTeamPentesting/monsoon/cli"
+	"github.com/RedTeamPentesting/monsoon/producer"
 	"github.com/RedTeamPentesting/monsoon/response"
 )
 
 // Reporter prints the Responses to a terminal.
 type Reporter struct {
 	term cli.Terminal
+
+	ShowMethod bool // add a column with the HTTP method of the request
+	ShowHost   bool // add a column with the Host header of the request
+	ShowID     bool // add a column with the correlation ID of the request
+	HostStats  bool // report the statistics for each host at the end
+
+	// Fields are the names of values extracted with named subexpressions,
+	// each one is shown in an additional column.
+	Fields []string
 }
 
 // New returns a new reporter.
@@ -23,10 +34,14 @@ func New(term cli.Terminal) *Reporter {
 type HTTPStats struct {
 	Start          time.Time
 	StatusCodes    map[int]int
+	Depths         map[int]int // number of responses per recursion depth
 	Errors         int
+	Retries        int // requests sent again, not counted in Responses
+	Throttled      int // number of pauses requested by the target
+	ThrottledFor   time.Duration
 	Responses      int
 	ShownResponses int
-	Count          int
+	Count          int // total number of requests or producer.UnknownCount
 
 	lastRPS time.Time
 	rps     float64
@@ -61,8 +76,17 @@ func (h *HTTPStats) Report(current string) (res []string) {
 		status += fmt.Sprintf(", %.0f req/s", h.rps)
 	}
 
+	if h.Retries > 0 {
+		status += fmt.Sprintf(", %d retries", h.Retries)
+	}
+
+	if h.Throttled > 0 {
+		status += fmt.Sprintf(", throttled: %d (%s)", h.Throttled, formatSeconds(h.ThrottledFor.Seconds()))
+	}
+
+	// no estimate is possible when the number of requests is unknown
 	todo := h.Count - h.Responses
-	if todo > 0 {
+	if h.Count != producer.UnknownCount && todo > 0 {
 		status += fmt.Sprintf(", %d todo", todo)
 
 		if h.rps > 0 {
@@ -81,28 +105,131 @@ func (h *HTTPStats) Report(current string) (res []string) {
 		res = append(res, fmt.Sprintf("%v: %v", code, count))
 	}
 
+	// only show the depths when directories have been discovered
+	if len(h.Depths) > 1 {
+		for depth, count := range h.Depths {
+			res = append(res, fmt.Sprintf("depth %v: %v requests", depth, count))
+		}
+	}
+
 	sort.Strings(res[2:])
 
 	return res
 }
 
+// hostStats collects the status codes and errors for each host.
+type hostStats map[string]map[string]int
+
+// add records the response.
+func (h hostStats) add(res response.Response) {
+	codes, ok := h[res.Host]
+	if !ok {
+		codes = make(map[string]int)
+		h[res.Host] = codes
+	}
+
+	if res.Error != nil {
+		codes["errors"]++
+		return
+	}
+	codes[fmt.Sprintf("%d", res.HTTPResponse.StatusCode)]++
+}
+
+// Report returns one line for each host.
+func (h hostStats) Report() (res []string) {
+	var hosts []string
+	for host := range h {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		var keys []string
+		var total int
+		for key, count := range h[host] {
+			keys = append(keys, key)
+			total += count
+		}
+		sort.Strings(keys)
+
+		line := fmt.Sprintf("%v: %d requests", host, total)
+		for _, key := range keys {
+			line += fmt.Sprintf(", %v: %d", key, h[host][key])
+		}
+		res = append(res, line)
+	}
+
+	return res
+}
+
+// hostColumnWidth is the minimal width of the column with the Host header.
+const hostColumnWidth = 24
+
+// prefix returns the optional columns for the method, host and ID.
+func (r *Reporter) prefix(method, host, id string) string {
+	var s string
+	if r.ShowMethod {
+		s += fmt.Sprintf("%-7s ", method)
+	}
+	if r.ShowHost {
+		s += fmt.Sprintf("%-*s ", hostColumnWidth, host)
+	}
+	if r.ShowID {
+		s += fmt.Sprintf("%-16s ", id)
+	}
+	return s
+}
+
+// fieldColumnWidth is the minimal width of the columns with extracted values.
+const fieldColumnWidth = 16
+
+// fieldColumns returns the columns with the values for the Fields.
+func (r *Reporter) fieldColumns(value func(name string) string) string {
+	var s string
+	for _, name := range r.Fields {
+		width := fieldColumnWidth
+		if len(name) > width {
+			width = len(name)
+		}
+		s += fmt.Sprintf("%-*s ", width, value(name))
+	}
+	return s
+}
+
 // Display shows incoming Responses.
 func (r *Reporter) Display(ch <-chan response.Response, countChannel <-chan int) error {
-	r.term.Printf("%7s %8s %8s   %-8s %s\n", "status", "header", "body", "value", "extract")
+	header := r.prefix("method", "host", "id") + r.fieldColumns(func(name string) string { return name })
+	r.term.Printf("%s%7s %8s %8s   %-8s %s\n", header, "status", "header", "body", "value", "extract")
 
 	stats := &HTTPStats{
 		Start:       time.Now(),
 		StatusCodes: make(map[int]int),
+		Depths:      make(map[int]int),
 	}
+	hosts := make(hostStats)
 
 	for response := range ch {
 		select {
-		case c := <-countChannel:
-			stats.Count = c
+		case c, ok := <-countChannel:
+			if ok {
+				stats.Count = c
+			} else {
+				// disable receiving on the closed channel
+				countChannel = nil
+			}
 		default:
 		}
 
 		stats.Responses++
+		stats.Retries += response.Retries
+		if response.Throttled > 0 {
+			stats.Throttled++
+			stats.ThrottledFor += response.Throttled
+		}
+		stats.Depths[response.Depth]++
+		if r.HostStats {
+			hosts.add(response)
+		}
 
 		if response.Error != nil {
 			stats.Errors++
@@ -111,7 +238,10 @@ func (r *Reporter) Display(ch <-chan response.Response, countChannel <-chan int)
 		}
 
 		if !response.Hide {
-			r.term.Printf("%v\n", response)
+			fields := r.fieldColumns(func(name string) string {
+				return strings.Join(response.Fields[name], ", ")
+			})
+			r.term.Printf("%s%s%v\n", r.prefix(response.Method, response.Host, response.ID), fields, response)
 			stats.ShownResponses++
 		}
 
@@ -125,5 +255,12 @@ func (r *Reporter) Display(ch <-chan response.Response, countChannel <-chan int)
 		r.term.Print(line)
 	}
 
+	if r.HostStats {
+		r.term.Print("\nper host:")
+		for _, line := range hosts.Report() {
+			r.term.Print("  " + line)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,187 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/response"
+)
+
+// testTerminal records the lines printed.
+type testTerminal struct {
+	lines []string
+}
+
+func (t *testTerminal) Printf(msg string, data ...interface{}) {
+	t.Print(fmt.Sprintf(msg, data...))
+}
+
+func (t *testTerminal) Print(msg string) {
+	t.lines = append(t.lines, strings.TrimRight(msg, "\n"))
+}
+
+func (t *testTerminal) SetStatus([]string) {}
+
+func (t *testTerminal) Run(context.Context) {}
+
+func TestDisplayMethodColumn(t *testing.T) {
+	term := &testTerminal{}
+	r := New(term)
+	r.ShowMethod = true
+
+	ch := make(chan response.Response, 2)
+	for _, method := range []string{"GET", "PROPFIND"} {
+		ch <- response.Response{
+			Item:         "foo",
+			Values:       []string{"foo", method},
+			Method:       method,
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK, Header: http.Header{}},
+		}
+	}
+	close(ch)
+
+	err := r.Display(ch, make(chan int))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(term.lines[0], "method   status") {
+		t.Errorf("method column missing in header %q", term.lines[0])
+	}
+
+	for i, method := range []string{"GET", "PROPFIND"} {
+		line := term.lines[i+1]
+		if !strings.HasPrefix(line, fmt.Sprintf("%-7s ", method)) {
+			t.Errorf("line %q does not start with method %v", line, method)
+		}
+
+		// the method is only shown in its column
+		if strings.Count(line, method) != 1 {
+			t.Errorf("method %v shown more than once in line %q", method, line)
+		}
+	}
+}
+
+func TestDisplayWithoutMethodColumn(t *testing.T) {
+	term := &testTerminal{}
+	r := New(term)
+
+	ch := make(chan response.Response, 1)
+	ch <- response.Response{
+		Item:         "foo",
+		Method:       "GET",
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK, Header: http.Header{}},
+	}
+	close(ch)
+
+	err := r.Display(ch, make(chan int))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(term.lines[0], "method") || strings.Contains(term.lines[1], "GET") {
+		t.Errorf("unexpected method column in %q", term.lines[:2])
+	}
+}
+
+func TestDisplayRetries(t *testing.T) {
+	term := &testTerminal{}
+	r := New(term)
+
+	ch := make(chan response.Response, 2)
+	for _, retries := range []int{0, 3} {
+		ch <- response.Response{
+			Item:         "foo",
+			Retries:      retries,
+			Throttled:    time.Duration(retries) * 10 * time.Second,
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK, Header: http.Header{}},
+		}
+	}
+	close(ch)
+
+	err := r.Display(ch, make(chan int))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for _, line := range term.lines {
+		if strings.HasPrefix(line, "2 of 2 requests shown") && strings.Contains(line, ", 3 retries, throttled: 1 (0m30s)") {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("retries not reported in %q", term.lines)
+	}
+}
+
+func TestDisplayFieldColumns(t *testing.T) {
+	term := &testTerminal{}
+	r := New(term)
+	r.Fields = []string{"user", "a_very_long_field_name"}
+
+	ch := make(chan response.Response, 1)
+	ch <- response.Response{
+		Item:         "foo",
+		Fields:       map[string][]string{"user": {"alice", "bob"}},
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK, Header: http.Header{}},
+	}
+	close(ch)
+
+	err := r.Display(ch, make(chan int))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantHeader := fmt.Sprintf("%-16s %-22s %7s", "user", "a_very_long_field_name", "status")
+	if !strings.HasPrefix(term.lines[0], wantHeader) {
+		t.Errorf("wrong header, want prefix %q, got %q", wantHeader, term.lines[0])
+	}
+
+	wantLine := fmt.Sprintf("%-16s %-22s %7d", "alice, bob", "", 200)
+	if !strings.HasPrefix(term.lines[1], wantLine) {
+		t.Errorf("wrong line, want prefix %q, got %q", wantLine, term.lines[1])
+	}
+}
+
+func TestDisplayHeaderFieldColumns(t *testing.T) {
+	h, err := response.ParseHeaderExtract(`X-Session=id=(?P<session>\w+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &response.Extracter{Headers: []response.HeaderExtract{h}}
+
+	term := &testTerminal{}
+	r := New(term)
+	r.Fields = e.FieldNames()
+
+	in := make(chan response.Response, 1)
+	in <- response.Response{
+		Item: "foo",
+		HTTPResponse: &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Session": []string{"id=abc123"}},
+		},
+	}
+	close(in)
+
+	err = r.Display(e.Run(in), make(chan int))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantHeader := fmt.Sprintf("%-16s %7s", "session", "status")
+	if !strings.HasPrefix(term.lines[0], wantHeader) {
+		t.Errorf("wrong header, want prefix %q, got %q", wantHeader, term.lines[0])
+	}
+
+	wantLine := fmt.Sprintf("%-16s %7d", "abc123", 200)
+	if !strings.HasPrefix(term.lines[1], wantLine) {
+		t.Errorf("wrong line, want prefix %q, got %q", wantLine, term.lines[1])
+	}
+}
@@ -0,0 +1,88 @@
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// zstdMaxBlockSize is the maximum size of a zstd block.
+const zstdMaxBlockSize = 128 * 1024
+
+// zstdFrame returns data as a zstd frame (RFC 8878) with raw (uncompressed)
+// blocks. This is a valid zstd stream which every decoder accepts, but it is
+// not smaller than data.
+func zstdFrame(data []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0x28, 0xb5, 0x2f, 0xfd}) // magic number
+
+	// single segment, so no window descriptor is needed, the frame content
+	// size field is chosen so it can hold the size
+	size := len(data)
+	switch {
+	case size < 256:
+		buf.Write([]byte{0x20, byte(size)})
+	case size < 65536+256:
+		buf.WriteByte(0x60)
+		_ = binary.Write(&buf, binary.LittleEndian, uint16(size-256))
+	case uint64(size) < 1<<32:
+		buf.WriteByte(0xa0)
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(size))
+	default:
+		buf.WriteByte(0xe0)
+		_ = binary.Write(&buf, binary.LittleEndian, uint64(size))
+	}
+
+	for {
+		n := len(data)
+		if n > zstdMaxBlockSize {
+			n = zstdMaxBlockSize
+		}
+
+		// block header: last block flag, block type raw (0), block size
+		header := uint32(n) << 3
+		if n == len(data) {
+			header |= 1
+		}
+		buf.Write([]byte{byte(header), byte(header >> 8), byte(header >> 16)})
+		buf.Write(data[:n])
+
+		if header&1 == 1 {
+			return buf.Bytes()
+		}
+		data = data[n:]
+	}
+}
+
+// compressBody compresses data for the content encoding, deflate is the zlib
+// format as required for HTTP.
+func compressBody(data []byte, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+	var wr io.WriteCloser
+
+	switch encoding {
+	case "gzip":
+		wr = gzip.NewWriter(&buf)
+	case "deflate":
+		wr = zlib.NewWriter(&buf)
+	case "zstd":
+		return zstdFrame(data), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q, use gzip, deflate or zstd", encoding)
+	}
+
+	_, err := wr.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = wr.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
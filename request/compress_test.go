@@ -0,0 +1,118 @@
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCompressBody(t *testing.T) {
+	var tests = []struct {
+		encoding   string
+		newReader  func(io.Reader) (io.Reader, error)
+		compressed []byte
+	}{
+		{
+			encoding: "gzip",
+			newReader: func(rd io.Reader) (io.Reader, error) {
+				return gzip.NewReader(rd)
+			},
+		},
+		{
+			encoding: "deflate",
+			newReader: func(rd io.Reader) (io.Reader, error) {
+				return zlib.NewReader(rd)
+			},
+		},
+		{
+			encoding:   "zstd",
+			compressed: []byte("\x28\xb5\x2f\xfd\x20\x0d\x69\x00\x00{\"id\":\"test\"}"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.encoding, func(t *testing.T) {
+			r := New("")
+			r.URL = "https://www.example.com"
+			r.Method = "POST"
+			r.Body = `{"id":"FUZZ"}`
+			r.Compress = test.encoding
+
+			req, err := r.Apply([]string{"FUZZ"}, []string{"test"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Header.Get("Content-Encoding") != test.encoding {
+				t.Errorf("wrong Content-Encoding %q", req.Header.Get("Content-Encoding"))
+			}
+
+			buf, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.ContentLength != int64(len(buf)) {
+				t.Errorf("wrong content length %v for %v bytes", req.ContentLength, len(buf))
+			}
+
+			if test.compressed != nil {
+				if !bytes.Equal(buf, test.compressed) {
+					t.Errorf("wrong body, want %q, got %q", test.compressed, buf)
+				}
+				return
+			}
+
+			rd, err := test.newReader(bytes.NewReader(buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body, err := ioutil.ReadAll(rd)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != `{"id":"test"}` {
+				t.Errorf("wrong body %q", body)
+			}
+		})
+	}
+
+	r := New("")
+	r.URL = "https://www.example.com"
+	r.Compress = "br"
+	_, err := r.Apply(nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("expected error not found, got %v", err)
+	}
+}
+
+func TestZstdFrame(t *testing.T) {
+	var tests = []struct {
+		size   int
+		header []byte
+		blocks int
+	}{
+		{0, []byte{0x28, 0xb5, 0x2f, 0xfd, 0x20, 0x00, 0x01, 0x00, 0x00}, 1},
+		{300, []byte{0x28, 0xb5, 0x2f, 0xfd, 0x60, 0x2c, 0x00, 0x61, 0x09, 0x00}, 1},
+		{zstdMaxBlockSize, []byte{0x28, 0xb5, 0x2f, 0xfd, 0xa0, 0x00, 0x00, 0x02, 0x00, 0x01, 0x00, 0x10}, 1},
+		{zstdMaxBlockSize + 1, []byte{0x28, 0xb5, 0x2f, 0xfd, 0xa0, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x10}, 2},
+	}
+
+	for _, test := range tests {
+		frame := zstdFrame(bytes.Repeat([]byte("a"), test.size))
+		if !bytes.HasPrefix(frame, test.header) {
+			t.Errorf("wrong header for size %v: want % x, got % x", test.size, test.header, frame[:len(test.header)])
+		}
+
+		want := len(test.header) - 3 + test.blocks*3 + test.size
+		if len(frame) != want {
+			t.Errorf("wrong frame size for %v bytes, want %v, got %v", test.size, want, len(frame))
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// graphQLRequest is the body of a GraphQL request sent via POST.
+type graphQLRequest struct {
+	Query     string          `json:"query"`
+	Variables json.RawMessage `json:"variables,omitempty"`
+}
+
+// loadGraphQLQuery returns the query, it is read from a file if it starts
+// with @.
+func loadGraphQLQuery(query string) (string, error) {
+	if !strings.HasPrefix(query, "@") {
+		return query, nil
+	}
+
+	buf, err := ioutil.ReadFile(query[1:])
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
+
+// marshalGraphQL returns the JSON encoded request body.
+func marshalGraphQL(query string, variables json.RawMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	err := enc.Encode(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// graphQLBody builds the body for a GraphQL request. The placeholders are
+// replaced as they are in the query, and with JSON escaping in the strings of
+// the variables.
+func graphQLBody(query, variables string, insertValue func(string) string) ([]byte, error) {
+	var vars json.RawMessage
+	if variables != "" {
+		s, err := insertJSON(variables, insertValue)
+		if err != nil {
+			return nil, fmt.Errorf("GraphQL variables: %v", err)
+		}
+		vars = json.RawMessage(s)
+	}
+
+	return marshalGraphQL(insertValue(query), vars)
+}
+
+var graphQLVariable = regexp.MustCompile(`\$([_A-Za-z][_0-9A-Za-z]*)`)
+
+// matchingBrace returns the position of the bracket closing the one at
+// s[start], strings are skipped. If there is none, -1 is returned.
+func matchingBrace(s string, start int) int {
+	open, closing := s[start], byte('}')
+	if open == '(' {
+		closing = ')'
+	}
+
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			// skip the string
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case open:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// graphQLBatchBody builds the body for a GraphQL request which contains the
+// selection of the query once for each function in insert, with the aliases
+// b0, b1, and so on. The variables are renamed for each alias by appending
+// "_0", "_1" etc. The query must consist of a single operation which selects
+// a single field.
+func graphQLBatchBody(query, variables string, insert []func(string) string) ([]byte, error) {
+	start := strings.IndexByte(query, '{')
+	if start < 0 {
+		return nil, errors.New("GraphQL query has no selection")
+	}
+
+	end := matchingBrace(query, start)
+	if end < 0 {
+		return nil, errors.New("GraphQL query has unbalanced braces")
+	}
+
+	if strings.TrimSpace(query[end+1:]) != "" {
+		return nil, errors.New("batching requires a GraphQL query with a single operation")
+	}
+
+	header := query[:start]
+	selection := strings.TrimSpace(query[start+1 : end])
+
+	// split the variable definitions from the header, e.g. query Q($id: ID!)
+	var prefix, defs, suffix = header, "", ""
+	if pos := strings.IndexByte(header, '('); pos >= 0 {
+		defsEnd := matchingBrace(header, pos)
+		if defsEnd < 0 {
+			return nil, errors.New("GraphQL query has unbalanced parentheses")
+		}
+		prefix, defs, suffix = header[:pos], header[pos+1:defsEnd], header[defsEnd+1:]
+	}
+
+	var allDefs, selections []string
+	allVars := make(map[string]json.RawMessage)
+
+	for i, insertValue := range insert {
+		rename := fmt.Sprintf("$$${1}_%d", i)
+
+		if defs != "" {
+			allDefs = append(allDefs, graphQLVariable.ReplaceAllString(strings.TrimSpace(defs), rename))
+		}
+
+		sel := graphQLVariable.ReplaceAllString(insertValue(selection), rename)
+		selections = append(selections, fmt.Sprintf("b%d: %s", i, sel))
+
+		if variables == "" {
+			continue
+		}
+
+		s, err := insertJSON(variables, insertValue)
+		if err != nil {
+			return nil, fmt.Errorf("GraphQL variables: %v", err)
+		}
+
+		var vars map[string]json.RawMessage
+		err = json.Unmarshal([]byte(s), &vars)
+		if err != nil {
+			return nil, fmt.Errorf("GraphQL variables must be a JSON object: %v", err)
+		}
+
+		for name, value := range vars {
+			allVars[fmt.Sprintf("%s_%d", name, i)] = value
+		}
+	}
+
+	q := insert[0](prefix)
+	if len(allDefs) > 0 {
+		q += "(" + strings.Join(allDefs, ", ") + ")"
+	}
+	q += insert[0](suffix) + "{ " + strings.Join(selections, " ") + " }"
+
+	var vars json.RawMessage
+	if len(allVars) > 0 {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+
+		err := enc.Encode(allVars)
+		if err != nil {
+			return nil, err
+		}
+		vars = bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	}
+
+	return marshalGraphQL(q, vars)
+}
@@ -0,0 +1,114 @@
+package request
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGraphQL(t *testing.T) {
+	req := New("")
+	req.URL = "https://example.com/graphql"
+	req.GraphQLQuery = `query($u: String!) { user(name: $u) { id } }`
+	req.GraphQLVariables = `{"u": "FUZZ"}`
+
+	httpReq, err := req.Apply([]string{"FUZZ"}, []string{`a"b`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if httpReq.Method != "POST" {
+		t.Errorf("wrong method %v", httpReq.Method)
+	}
+
+	if ct := httpReq.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("wrong content type %q", ct)
+	}
+
+	buf, err := ioutil.ReadAll(httpReq.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"query":"query($u: String!) { user(name: $u) { id } }","variables":{"u":"a\"b"}}`
+	if string(buf) != want {
+		t.Errorf("wrong body, want:\n  %s\ngot:\n  %s", want, buf)
+	}
+}
+
+func TestGraphQLBatch(t *testing.T) {
+	var tests = []struct {
+		query     string
+		variables string
+		want      string
+		wantVars  map[string]string
+	}{
+		{
+			query:     `query Users($u: String!, $n: Int) { user(name: $u, limit: $n) { id } }`,
+			variables: `{"u": "FUZZ", "n": 1}`,
+			want:      `query Users($u_0: String!, $n_0: Int, $u_1: String!, $n_1: Int) { b0: user(name: $u_0, limit: $n_0) { id } b1: user(name: $u_1, limit: $n_1) { id } }`,
+			wantVars:  map[string]string{"u_0": `"foo"`, "u_1": `"bar"`, "n_0": "1", "n_1": "1"},
+		},
+		{
+			query: `{ user(name: "FUZZ") { id } }`,
+			want:  `{ b0: user(name: "foo") { id } b1: user(name: "bar") { id } }`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			req := New("")
+			req.URL = "https://example.com/graphql"
+			req.GraphQLQuery = test.query
+			req.GraphQLVariables = test.variables
+
+			httpReq, err := req.ApplyBatch([]string{"FUZZ"}, [][]string{{"foo"}, {"bar"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := ioutil.ReadAll(httpReq.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var body struct {
+				Query     string
+				Variables map[string]json.RawMessage
+			}
+			err = json.Unmarshal(buf, &body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if body.Query != test.want {
+				t.Errorf("wrong query, want:\n  %s\ngot:\n  %s", test.want, body.Query)
+			}
+
+			if len(body.Variables) != len(test.wantVars) {
+				t.Errorf("wrong variables, want %v, got %s", test.wantVars, buf)
+			}
+
+			for name, want := range test.wantVars {
+				if string(body.Variables[name]) != want {
+					t.Errorf("wrong value for variable %v, want %s, got %s", name, want, body.Variables[name])
+				}
+			}
+		})
+	}
+}
+
+func TestGraphQLBatchInvalid(t *testing.T) {
+	for _, query := range []string{`query Q`, `{ user { id }`, `query A { a } query B { b }`} {
+		t.Run("", func(t *testing.T) {
+			req := New("")
+			req.URL = "https://example.com/graphql"
+			req.GraphQLQuery = query
+
+			_, err := req.ApplyBatch([]string{"FUZZ"}, [][]string{{"foo"}})
+			if err == nil {
+				t.Fatalf("expected error for query %q not found", query)
+			}
+		})
+	}
+}
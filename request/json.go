@@ -0,0 +1,75 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// insertJSON replaces the placeholders in all strings (keys and values) of the
+// JSON document doc by calling insertValue, the results are escaped as JSON
+// strings. Everything outside of the strings is kept as it is.
+func insertJSON(doc string, insertValue func(string) string) (string, error) {
+	if !json.Valid([]byte(doc)) {
+		return "", errors.New("body is not valid JSON, placeholders must be used within strings")
+	}
+
+	var res strings.Builder
+	for {
+		start := strings.IndexByte(doc, '"')
+		if start < 0 {
+			res.WriteString(doc)
+			return res.String(), nil
+		}
+
+		// find the end of the string, skipping escaped characters
+		end := start + 1
+		for end < len(doc) && doc[end] != '"' {
+			if doc[end] == '\\' {
+				end++
+			}
+			end++
+		}
+
+		if end >= len(doc) {
+			return "", errors.New("unterminated string in JSON body")
+		}
+
+		var s string
+		err := json.Unmarshal([]byte(doc[start:end+1]), &s)
+		if err != nil {
+			return "", fmt.Errorf("invalid string in JSON body: %v", err)
+		}
+
+		res.WriteString(doc[:start])
+
+		if v := insertValue(s); v != s {
+			buf, err := marshalJSONString(v)
+			if err != nil {
+				return "", err
+			}
+			res.Write(buf)
+		} else {
+			res.WriteString(doc[start : end+1])
+		}
+
+		doc = doc[end+1:]
+	}
+}
+
+// marshalJSONString returns s encoded as a JSON string. In contrast to
+// json.Marshal, the characters <, > and & are not escaped.
+func marshalJSONString(s string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	err := enc.Encode(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
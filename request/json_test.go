@@ -0,0 +1,68 @@
+package request
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+func TestJSONBody(t *testing.T) {
+	var tests = []struct {
+		body  string
+		value string
+		want  string
+	}{
+		{`{"user": "FUZZ"}`, `admin`, `{"user": "admin"}`},
+		{`{"user": "FUZZ"}`, `a"b`, `{"user": "a\"b"}`},
+		{`{"user": "x-FUZZ-y"}`, "line1\nline2\\", `{"user": "x-line1\nline2\\-y"}`},
+		{`{"FUZZ": [1, "FUZZ", {"a": "\"FUZZ\""}], "b": "ä"}`, `<k>`, `{"<k>": [1, "<k>", {"a": "\"<k>\""}], "b": "ä"}`},
+		{`  ["x", "y"]  `, `z`, `  ["x", "y"]  `},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			req := New("")
+			req.URL = "https://example.com"
+			req.Body = test.body
+			req.JSONBody = true
+
+			httpReq, err := req.Apply([]string{"FUZZ"}, []string{test.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := ioutil.ReadAll(httpReq.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf) != test.want {
+				t.Errorf("wrong body, want:\n  %s\ngot:\n  %s", test.want, buf)
+			}
+
+			if !json.Valid(buf) {
+				t.Errorf("body is not valid JSON: %s", buf)
+			}
+
+			if ct := httpReq.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("wrong content type %q", ct)
+			}
+		})
+	}
+}
+
+func TestJSONBodyInvalid(t *testing.T) {
+	for _, body := range []string{``, `{"id": FUZZ}`, `{"a": "b"`} {
+		t.Run("", func(t *testing.T) {
+			req := New("")
+			req.URL = "https://example.com"
+			req.Body = body
+			req.JSONBody = true
+
+			_, err := req.Apply([]string{"FUZZ"}, []string{"foo"})
+			if err == nil {
+				t.Fatalf("expected error for %q not found", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+)
+
+// formPart is a part of a multipart/form-data body.
+type formPart struct {
+	Name, Value string
+
+	// for file uploads
+	File        string
+	Filename    string
+	ContentType string
+}
+
+// parseFormPart parses a form field in the form "name=value" or a file in
+// the form "name=@path[;filename=name][;type=content-type]".
+func parseFormPart(s string) (formPart, error) {
+	data := strings.SplitN(s, "=", 2)
+	if len(data) != 2 || data[0] == "" {
+		return formPart{}, fmt.Errorf("invalid form field %q, expected name=value or name=@file", s)
+	}
+
+	part := formPart{Name: data[0], Value: data[1]}
+	if !strings.HasPrefix(part.Value, "@") {
+		return part, nil
+	}
+
+	opts := strings.Split(part.Value[1:], ";")
+	part.Value = ""
+	part.File = opts[0]
+	part.Filename = filepath.Base(part.File)
+	part.ContentType = "application/octet-stream"
+
+	if part.File == "" {
+		return formPart{}, fmt.Errorf("invalid form field %q: filename is empty", s)
+	}
+
+	for _, opt := range opts[1:] {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return formPart{}, fmt.Errorf("invalid option %q for form field %q", opt, s)
+		}
+
+		switch kv[0] {
+		case "filename":
+			part.Filename = kv[1]
+		case "type":
+			part.ContentType = kv[1]
+		default:
+			return formPart{}, fmt.Errorf("unknown option %q for form field %q", kv[0], s)
+		}
+	}
+
+	return part, nil
+}
+
+// quoteEscaper escapes the characters which are not allowed in quoted
+// strings of the Content-Disposition header.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// buildMultipart returns a multipart/form-data body with the fields in form
+// and the content type including the boundary. The placeholders are replaced
+// with insertValue before the fields are parsed, so they can be used in the
+// names, values, file names and content types.
+func buildMultipart(form []string, insertValue func(string) string) (body []byte, contentType string, err error) {
+	buf := bytes.NewBuffer(nil)
+	wr := multipart.NewWriter(buf)
+
+	for _, s := range form {
+		part, err := parseFormPart(insertValue(s))
+		if err != nil {
+			return nil, "", err
+		}
+
+		hdr := make(textproto.MIMEHeader)
+		disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(part.Name))
+
+		data := []byte(part.Value)
+		if part.File != "" {
+			data, err = ioutil.ReadFile(part.File)
+			if err != nil {
+				return nil, "", err
+			}
+
+			disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(part.Filename))
+			hdr.Set("Content-Type", part.ContentType)
+		}
+		hdr.Set("Content-Disposition", disposition)
+
+		w, err := wr.CreatePart(hdr)
+		if err != nil {
+			return nil, "", err
+		}
+
+		_, err = w.Write(data)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	err = wr.Close()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), wr.FormDataContentType(), nil
+}
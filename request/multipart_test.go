@@ -0,0 +1,93 @@
+package request
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMultipartForm(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-request-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	filename := filepath.Join(tempdir, "shell.php")
+	err = ioutil.WriteFile(filename, []byte("<?php echo 1; ?>"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := New("")
+	req.URL = "https://example.com/upload"
+	req.Form = []string{
+		"user=FUZZ",
+		"upload=@" + filename + ";filename=test.FUZZ;type=image/FUZZ",
+		"other=@" + filename,
+	}
+
+	httpReq, err := req.Apply([]string{"FUZZ"}, []string{"png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if httpReq.Method != "POST" {
+		t.Errorf("wrong method, want POST, got %v", httpReq.Method)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(httpReq.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mediaType != "multipart/form-data" {
+		t.Fatalf("wrong content type %v", mediaType)
+	}
+
+	type part struct {
+		Name, Filename, ContentType, Data string
+	}
+
+	var parts []part
+	rd := multipart.NewReader(httpReq.Body, params["boundary"])
+	for {
+		p, err := rd.NextPart()
+		if err != nil {
+			break
+		}
+
+		buf, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parts = append(parts, part{p.FormName(), p.FileName(), p.Header.Get("Content-Type"), string(buf)})
+	}
+
+	want := []part{
+		{"user", "", "", "png"},
+		{"upload", "test.png", "image/png", "<?php echo 1; ?>"},
+		{"other", "shell.php", "application/octet-stream", "<?php echo 1; ?>"},
+	}
+
+	if !cmp.Equal(want, parts) {
+		t.Error(cmp.Diff(want, parts))
+	}
+}
+
+func TestMultipartFormInvalid(t *testing.T) {
+	for _, form := range []string{"foo", "=bar", "file=@", "file=@x;foo=bar", "file=@x;filename"} {
+		t.Run("", func(t *testing.T) {
+			_, err := parseFormPart(form)
+			if err == nil {
+				t.Fatalf("expected error for %q not found", form)
+			}
+		})
+	}
+}
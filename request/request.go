@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Header is an HTTP header that implements the pflag.Value interface.
@@ -84,7 +85,9 @@ func NewHeader(defaults http.Header) *Header {
 }
 
 // Apply applies the values in h to the target http.Header. The function
-// insertValue is called for all names and values before adding them.
+// insertValue is called for all names and values before adding them. Names
+// into which a value was inserted are not canonicalized, so they are sent as
+// they are (e.g. for header discovery).
 func (h Header) Apply(hdr http.Header, insertValue func(string) string) {
 	for k, vs := range h.Header {
 		// don't set the header if it is already set in the request and the
@@ -97,7 +100,13 @@ func (h Header) Apply(hdr http.Header, insertValue func(string) string) {
 		hdr.Del(k)
 
 		// add values
-		k = insertValue(k)
+		name := insertValue(k)
+		if name != k {
+			for _, v := range vs {
+				hdr[name] = append(hdr[name], insertValue(v))
+			}
+			continue
+		}
 
 		for _, v := range vs {
 			hdr.Add(k, insertValue(v))
@@ -109,6 +118,24 @@ func (h Header) Apply(hdr http.Header, insertValue func(string) string) {
 	}
 }
 
+// withoutDefaults returns a copy of h without the header fields which are
+// still set to the default value.
+func (h Header) withoutDefaults() *Header {
+	res := &Header{
+		Header: make(http.Header),
+		Remove: h.Remove,
+	}
+
+	for k, vs := range h.Header {
+		if headerDefaultValue(h, k) {
+			continue
+		}
+		res.Header[k] = vs
+	}
+
+	return res
+}
+
 func headerDefaultValue(h Header, name string) bool {
 	key := textproto.CanonicalMIMEHeaderKey(name)
 
@@ -159,18 +186,84 @@ type Request struct {
 	Header *Header
 	Body   string
 
+	BodyFile string   // the body is read from this file, values are inserted into the raw bytes
+	Form     []string // fields for a multipart/form-data body, see buildMultipart
+
+	bodyFileData []byte // contents of BodyFile, see LoadBodyFile
+
+	JSONBody bool // Body is a JSON document, values are inserted with JSON escaping
+
+	XMLBody    bool   // Body is an XML document, values are inserted with XML escaping
+	XMLRaw     bool   // insert values into the XML body without escaping
+	SOAPAction string // action of a SOAP request, requires XMLBody
+
+	GraphQLQuery     string // GraphQL query sent as JSON via POST, read from a file if it starts with @
+	GraphQLVariables string // JSON object with the variables for GraphQLQuery
+
 	UserPass string // user:password for HTTP basic auth
+	NTLM     string // user:password[:domain] for NTLM authentication
+	Digest   string // user:password for HTTP digest authentication
+
+	AWSSign        string // region/service for signing requests with AWS SigV4
+	AWSCredentials string // key:secret[:session-token], taken from the environment if empty
+
+	SignCommand string // command run for each request, prints header fields to add
 
 	TemplateFile string // used to read the request from a file
 
 	Replace string // this string is being replaced by a value in a specific http request
 
-	Insecure             bool
-	TLSClientKeyCertFile string
-	DisableHTTP2         bool
-	ForceChunkedEncoding bool
+	Insecure              bool
+	TLSClientKeyCertFile  string   // PEM or PKCS#12 file, may contain placeholders
+	TLSClientKeyFile      string   // PEM encoded key, if not contained in TLSClientKeyCertFile
+	TLSClientCertPassword string   // password for a PKCS#12 file
+	TLSMinVersion         string   // minimum TLS version (1.0 to 1.3)
+	TLSMaxVersion         string   // maximum TLS version (1.0 to 1.3)
+	TLSCiphers            []string // names of the cipher suites offered for TLS 1.0 to 1.2
+	CACert                string   // PEM file with the CA certificates used to verify servers
+	PinSHA256             []string // SHA-256 hashes of the accepted server public keys or certificates
+	DisableHTTP2          bool
+	ForceHTTP2            bool
+	ForceChunkedEncoding  bool
+	RawPath               bool // send the path and query string exactly as in the URL
+
+	HeaderOrder []string // header names sent first, in this order and spelling
+	HTTP10      bool     // send requests via HTTP/1.0
+	Minimal     bool     // do not send the default header fields
+
+	Compress string // content encoding (gzip, deflate or zstd) used to compress the body
+
+	ChunkSize      int    // size of the chunks of the body, implies chunked encoding
+	ChunkExtension string // appended to the size of each chunk, implies chunked encoding
+
+	ConnectTo  string   // host[:port] to connect to instead of the host in the URL
+	Resolve    []string // host:port:address entries which pin host names to addresses
+	DNSServer  string   // host[:port] of the DNS server used to resolve host names
+	UnixSocket string   // path of a Unix domain socket used for all connections
+	SNI        string   // server name sent in the TLS handshake instead of the host in the URL
+	SOCKS5     string   // [user:password@]host:port of a SOCKS5 proxy used for all connections
+	Proxy      string   // URL of the proxy (http, https or socks5), overrides the environment
+	SourceIP   string   // local address connections are made from
+	Interface  string   // name of the network interface connections are made from
+	IPv4Only   bool     // connect only via IPv4
+	IPv6Only   bool     // connect only via IPv6
+	PreferIPv4 bool     // try IPv4 addresses first, then IPv6
+	PreferIPv6 bool     // try IPv6 addresses first, then IPv4
+
+	// timeouts, the defaults are used when zero
+	ConnectTimeout time.Duration // for establishing the TCP connection
+	TLSTimeout     time.Duration // for the TLS handshake
+	HeaderTimeout  time.Duration // for receiving the response header after sending the request
+	Timeout        time.Duration // for the whole request including the response body, 0 means no limit
 }
 
+// Default timeouts for requests.
+const (
+	DefaultConnectTimeout = 30 * time.Second
+	DefaultTLSTimeout     = 10 * time.Second
+	DefaultHeaderTimeout  = 10 * time.Second
+)
+
 // New returns a new request. If replace is the empty string, "FUZZ" is used.
 func New(replace string) *Request {
 	if replace == "" {
@@ -182,29 +275,91 @@ func New(replace string) *Request {
 	}
 }
 
-func replaceTemplate(s, template, value string) string {
-	if !strings.Contains(s, template) {
-		return s
+// newReplacer returns a strings.Replacer which replaces all names with the
+// corresponding values. Longer names are replaced first, so that e.g. FUZZ1 is
+// not clobbered by a replacement for FUZZ.
+func newReplacer(names, values []string) *strings.Replacer {
+	idx := make([]int, 0, len(names))
+	for i := range names {
+		idx = append(idx, i)
+	}
+
+	sort.SliceStable(idx, func(i, j int) bool {
+		return len(names[idx[i]]) > len(names[idx[j]])
+	})
+
+	var pairs []string
+	for _, i := range idx {
+		pairs = append(pairs, names[i], values[i])
 	}
 
-	return strings.Replace(s, template, value, -1)
+	return strings.NewReplacer(pairs...)
 }
 
-func readRequestFromFile(filename string, target *url.URL, replace func([]byte) []byte) (*http.Request, error) {
+// Insert replaces each of the names with the corresponding value in s.
+func Insert(s string, names, values []string) string {
+	return newReplacer(names, values).Replace(s)
+}
+
+// insertedHeaderNames returns the header names in the raw request buf which
+// contain a placeholder, with the values inserted by replace.
+func insertedHeaderNames(buf []byte, replace func([]byte) []byte) (names []string) {
+	lines := strings.Split(string(buf), "\n")
+	for _, line := range lines[1:] {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			break
+		}
+
+		pos := strings.IndexByte(line, ':')
+		if pos <= 0 {
+			continue
+		}
+
+		name := line[:pos]
+		if inserted := string(replace([]byte(name))); inserted != name {
+			names = append(names, inserted)
+		}
+	}
+
+	return names
+}
+
+func readRequestFromFile(filename string, target *url.URL, rawPath bool, replace func([]byte) []byte) (*http.Request, error) {
 	buf, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
+	rawNames := insertedHeaderNames(buf, replace)
+
 	// replace the placeholder in the file we just read
 	buf = replace(buf)
 
+	// the request target is not parsed, so it may be anything
+	var rawTarget string
+	if rawPath {
+		buf, rawTarget, err = cutRequestTarget(buf)
+		if err != nil {
+			return nil, fmt.Errorf("error reading HTTP request from %v: %v", filename, err)
+		}
+	}
+
 	rd := bufio.NewReader(bytes.NewReader(buf))
 	req, err := http.ReadRequest(rd)
 	if err != nil {
 		return nil, fmt.Errorf("error reading HTTP request from %v: %v", filename, err)
 	}
 
+	// restore the spelling of header names into which values were inserted
+	for _, name := range rawNames {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		if vs, ok := req.Header[key]; ok && key != name {
+			delete(req.Header, key)
+			req.Header[name] = vs
+		}
+	}
+
 	// append the rest of the file to the body
 	rest, err := ioutil.ReadAll(rd)
 	if err == io.EOF {
@@ -250,18 +405,278 @@ func readRequestFromFile(filename string, target *url.URL, replace func([]byte)
 		req.URL.User = target.User
 	}
 
+	if rawPath {
+		setRawTarget(req, rawTarget)
+	}
+
 	return req, nil
 }
 
-// Apply replaces the template with value in all fields of the request and
-// returns a new http.Request.
-func (r *Request) Apply(value string) (*http.Request, error) {
+// splitRawURL splits the absolute URL s into scheme and host, and the request
+// target (path and query string), without parsing or normalizing the target.
+func splitRawURL(s string) (base, target string, err error) {
+	pos := strings.Index(s, "://")
+	if pos < 0 {
+		return "", "", fmt.Errorf("URL %q is not absolute", s)
+	}
+
+	end := strings.IndexAny(s[pos+3:], "/?#")
+	if end < 0 {
+		return s, "/", nil
+	}
+	end += pos + 3
+
+	target = s[end:]
+	if target[0] != '/' {
+		target = "/" + target
+	}
+
+	return s[:end], target, nil
+}
+
+// cutRequestTarget replaces the request target in the request line of the raw
+// request buf with "/" and returns it.
+func cutRequestTarget(buf []byte) ([]byte, string, error) {
+	end := bytes.IndexByte(buf, '\n')
+	if end < 0 {
+		end = len(buf)
+	}
+	line := string(buf[:end])
+
+	// the target may contain spaces
+	first := strings.IndexByte(line, ' ')
+	last := strings.LastIndexByte(line, ' ')
+	if first < 0 || first == last {
+		return nil, "", fmt.Errorf("malformed request line %q", line)
+	}
+
+	res := append([]byte(line[:first]+" /"+line[last:]), buf[end:]...)
+	return res, line[first+1 : last], nil
+}
+
+// setRawTarget configures req to send target as the request target, exactly
+// as it is. Targets starting with "//" are sent in absolute form
+// (scheme://host//path), as net/http would treat them as a host otherwise.
+func setRawTarget(req *http.Request, target string) {
+	req.URL.Path = ""
+	req.URL.RawPath = ""
+	req.URL.RawQuery = ""
+	req.URL.Fragment = ""
+
+	if strings.HasPrefix(target, "//") {
+		target = "//" + req.URL.Host + target
+	}
+	req.URL.Opaque = target
+}
+
+// URLString returns the URL of req, including a raw request target set with
+// RawPath.
+func URLString(req *http.Request) string {
+	if req.URL.Opaque == "" || strings.HasPrefix(req.URL.Opaque, "//") {
+		return req.URL.String()
+	}
+
+	return req.URL.Scheme + "://" + req.URL.Host + req.URL.Opaque
+}
+
+// CustomWriter returns true if the request is to be written by monsoon instead
+// of net/http, which is required for a fixed header order, chunk sizes and
+// extensions, HTTP/1.0 and minimal requests.
+func (r *Request) CustomWriter() bool {
+	return len(r.HeaderOrder) > 0 || r.ChunkSize > 0 || r.ChunkExtension != "" || r.HTTP10 || r.Minimal
+}
+
+// Apply replaces each of the names with the corresponding value in all fields
+// of the request and returns a new http.Request.
+func (r *Request) Apply(names, values []string) (*http.Request, error) {
+	return r.apply(names, values, nil)
+}
+
+// ApplyBatch returns a new http.Request which contains the GraphQL query once
+// for each set of values in batch, see graphQLBatchBody. All other fields of
+// the request are filled with the first set of values.
+func (r *Request) ApplyBatch(names []string, batch [][]string) (*http.Request, error) {
+	if r.GraphQLQuery == "" {
+		return nil, errors.New("sending several values in one request requires a GraphQL query")
+	}
+
+	if len(batch) == 0 {
+		return nil, errors.New("batch is empty")
+	}
+
+	query, err := loadGraphQLQuery(r.GraphQLQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	var insert []func(string) string
+	for _, values := range batch {
+		if len(names) != len(values) {
+			return nil, fmt.Errorf("got %d values for %d placeholders", len(values), len(names))
+		}
+
+		insert = append(insert, newReplacer(names, values).Replace)
+	}
+
+	body, err := graphQLBatchBody(query, r.GraphQLVariables, insert)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.apply(names, batch[0], body)
+}
+
+// LoadBodyFile reads the contents of BodyFile, which are then used for all
+// requests instead of reading the file again for each request.
+func (r *Request) LoadBodyFile() error {
+	if r.BodyFile == "" {
+		return nil
+	}
+
+	buf, err := ioutil.ReadFile(r.BodyFile)
+	if err != nil {
+		return err
+	}
+
+	r.bodyFileData = buf
+	return nil
+}
+
+// apply builds the request, graphQL is used as the body for a GraphQL query
+// if it is not nil.
+func (r *Request) apply(names, values []string, graphQL []byte) (*http.Request, error) {
+	if len(names) != len(values) {
+		return nil, fmt.Errorf("got %d values for %d placeholders", len(values), len(names))
+	}
+
+	replacer := newReplacer(names, values)
 	insertValue := func(s string) string {
-		return replaceTemplate(s, r.Replace, value)
+		return replacer.Replace(s)
 	}
 
 	targetURL := insertValue(r.URL)
 	body := []byte(insertValue(r.Body))
+	method := insertValue(r.Method)
+
+	if r.BodyFile != "" {
+		if r.Body != "" || r.JSONBody || r.XMLBody || r.GraphQLQuery != "" || len(r.Form) > 0 {
+			return nil, errors.New("--data-file cannot be combined with --data, --data-json, --data-xml, --graphql-query or --form")
+		}
+
+		buf := r.bodyFileData
+		if buf == nil {
+			var err error
+			buf, err = ioutil.ReadFile(r.BodyFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// strings in Go are byte sequences, so binary data is not modified
+		// apart from the inserted values
+		body = []byte(insertValue(string(buf)))
+
+		if method == "" {
+			method = http.MethodPost
+		}
+	}
+
+	var contentType string
+	if r.GraphQLQuery != "" {
+		switch {
+		case r.Body != "":
+			return nil, errors.New("a GraphQL query cannot be combined with a body")
+		case len(r.Form) > 0:
+			return nil, errors.New("a GraphQL query cannot be combined with form fields")
+		}
+
+		if graphQL == nil {
+			query, err := loadGraphQLQuery(r.GraphQLQuery)
+			if err != nil {
+				return nil, err
+			}
+
+			graphQL, err = graphQLBody(query, r.GraphQLVariables, insertValue)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		body = graphQL
+		contentType = "application/json"
+
+		if method == "" {
+			method = http.MethodPost
+		}
+	}
+
+	if r.JSONBody {
+		if len(r.Form) > 0 {
+			return nil, errors.New("form fields cannot be combined with a JSON body")
+		}
+
+		if r.Body == "" {
+			return nil, errors.New("JSON body is empty")
+		}
+
+		s, err := insertJSON(r.Body, insertValue)
+		if err != nil {
+			return nil, err
+		}
+
+		body = []byte(s)
+		contentType = "application/json"
+	}
+
+	var soapAction string
+	if r.XMLBody {
+		switch {
+		case r.JSONBody:
+			return nil, errors.New("an XML body cannot be combined with a JSON body")
+		case len(r.Form) > 0:
+			return nil, errors.New("form fields cannot be combined with an XML body")
+		case r.Body == "":
+			return nil, errors.New("XML body is empty")
+		}
+
+		if !r.XMLRaw {
+			escaped := make([]string, 0, len(values))
+			for _, v := range values {
+				escaped = append(escaped, escapeXML(v))
+			}
+			body = []byte(newReplacer(names, escaped).Replace(r.Body))
+		}
+
+		// the template is checked so that the Content-Type does not depend on
+		// the inserted values
+		var err error
+		contentType, soapAction, err = xmlHeaders(r.Body, insertValue(r.SOAPAction))
+		if err != nil {
+			return nil, err
+		}
+
+		if method == "" {
+			method = http.MethodPost
+		}
+	} else if r.SOAPAction != "" {
+		return nil, errors.New("a SOAP action requires an XML body")
+	}
+
+	if len(r.Form) > 0 {
+		if r.Body != "" {
+			return nil, errors.New("form fields cannot be combined with a body")
+		}
+
+		var err error
+		body, contentType, err = buildMultipart(r.Form, insertValue)
+		if err != nil {
+			return nil, err
+		}
+
+		if method == "" {
+			method = http.MethodPost
+		}
+	}
 
 	var req *http.Request
 
@@ -272,8 +687,8 @@ func (r *Request) Apply(value string) (*http.Request, error) {
 			return nil, err
 		}
 
-		req, err = readRequestFromFile(r.TemplateFile, target, func(buf []byte) []byte {
-			return bytes.Replace(buf, []byte(r.Replace), []byte(value), -1)
+		req, err = readRequestFromFile(r.TemplateFile, target, r.RawPath, func(buf []byte) []byte {
+			return []byte(replacer.Replace(string(buf)))
 		})
 		if err != nil {
 			return nil, err
@@ -285,21 +700,58 @@ func (r *Request) Apply(value string) (*http.Request, error) {
 			req.ContentLength = int64(len(body))
 		}
 
-		if r.Method != "" {
-			req.Method = insertValue(r.Method)
+		if method != "" {
+			req.Method = method
 		}
 
 	} else {
 		var err error
 
 		// create new request from scratch
-		req, err = http.NewRequest(insertValue(r.Method), targetURL, bytes.NewReader(body))
+		if r.RawPath {
+			base, target, err := splitRawURL(targetURL)
+			if err != nil {
+				return nil, err
+			}
+
+			req, err = http.NewRequest(method, base, bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			setRawTarget(req, target)
+		} else {
+			req, err = http.NewRequest(method, targetURL, bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the body is compressed after the values have been inserted
+	if r.Compress != "" {
+		var data []byte
+		var err error
+		if req.Body != nil {
+			data, err = ioutil.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		data, err = compressBody(data, r.Compress)
 		if err != nil {
 			return nil, err
 		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+		req.ContentLength = int64(len(data))
+		req.Header.Set("Content-Encoding", r.Compress)
 	}
 
-	if r.ForceChunkedEncoding {
+	if r.ForceChunkedEncoding || r.ChunkSize > 0 || r.ChunkExtension != "" {
 		req.ContentLength = -1
 	}
 
@@ -312,7 +764,7 @@ func (r *Request) Apply(value string) (*http.Request, error) {
 
 	// but if an explicit user and pass is specified, override them again
 	if r.UserPass != "" {
-		data := strings.SplitN(strings.Replace(r.UserPass, r.Replace, value, -1), ":", 2)
+		data := strings.SplitN(insertValue(r.UserPass), ":", 2)
 		u := data[0]
 		p := ""
 		if len(data) > 1 {
@@ -327,8 +779,24 @@ func (r *Request) Apply(value string) (*http.Request, error) {
 		req.URL.Path = "/"
 	}
 
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if soapAction != "" {
+		req.Header.Set("SOAPAction", soapAction)
+	}
+
 	// apply template headers
-	r.Header.Apply(req.Header, insertValue)
+	hdr := r.Header
+	if r.Minimal {
+		hdr = r.Header.withoutDefaults()
+	}
+	hdr.Apply(req.Header, insertValue)
+
+	if r.HTTP10 {
+		req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/1.0", 1, 0
+	}
 
 	// special handling for the Host header, which needs to be set on the
 	// request field Host
@@ -352,8 +820,9 @@ func (r *Request) Apply(value string) (*http.Request, error) {
 		// user-agent header, it's currently not possible to send a request with
 		// multiple user-agent headers.
 
-		// special handling if the Host header is to be removed
-		if name == "Host" {
+		// special handling if the Host header is to be removed, only
+		// possible with our own request writer
+		if name == "Host" && !r.CustomWriter() {
 			return nil, errors.New("request without Host header is not supported")
 		}
 	}
@@ -361,15 +830,48 @@ func (r *Request) Apply(value string) (*http.Request, error) {
 	return req, nil
 }
 
+// TargetURL returns the target URL passed in args. When a template file is
+// used and args is empty, the target is built from the Host header in the
+// file, using HTTPS.
+func (r *Request) TargetURL(args []string) (string, error) {
+	if len(args) > 1 {
+		return "", errors.New("more than one target URL specified")
+	}
+
+	if len(args) == 1 {
+		return args[0], nil
+	}
+
+	if r.TemplateFile == "" {
+		return "", errors.New("last argument needs to be the URL")
+	}
+
+	buf, err := ioutil.ReadFile(r.TemplateFile)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf)))
+	if err != nil {
+		return "", fmt.Errorf("error reading HTTP request from %v: %v", r.TemplateFile, err)
+	}
+
+	if req.Host == "" {
+		return "", fmt.Errorf("no Host header found in %v, the URL must be specified", r.TemplateFile)
+	}
+
+	return "https://" + req.Host, nil
+}
+
 // Target returns the host and port for the request.
 func Target(req *http.Request) (host, port string, err error) {
 	port = req.URL.Port()
 	if port == "" {
 		// fill in default ports
 		switch req.URL.Scheme {
-		case "http":
+		case "http", "ws":
 			port = "80"
-		case "https":
+		case "https", "wss":
 			port = "443"
 		default:
 			return "", "", fmt.Errorf("unknown URL scheme %q", req.URL.Scheme)
@@ -1,6 +1,7 @@
 package request
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -61,13 +62,23 @@ func TestHeaderSet(t *testing.T) {
 			},
 		},
 		{
-			// make sure that replacing FUZZ in header names still works
+			// make sure that replacing FUZZ in header names still works, the
+			// name is not canonicalized
 			start:  http.Header{"User-Agent": []string{"monsoon"}},
 			values: []string{"x-FUZZ: foobar"},
 			item:   "testing",
 			want: http.Header{
 				"User-Agent": []string{"monsoon"},
-				"X-Testing":  []string{"foobar"},
+				"x-testing":  []string{"foobar"},
+			},
+		},
+		{
+			start:  http.Header{"User-Agent": []string{"monsoon"}},
+			values: []string{"FUZZ: 127.0.0.1"},
+			item:   "x-forwarded-FOR",
+			want: http.Header{
+				"User-Agent":      []string{"monsoon"},
+				"x-forwarded-FOR": []string{"127.0.0.1"},
 			},
 		},
 		{
@@ -97,12 +108,11 @@ func TestHeaderSet(t *testing.T) {
 				_ = hdr.Set(v)
 			}
 
-			insertValue := func(s string) string {
-				return replaceTemplate(s, "FUZZ", test.item)
-			}
+			// values are inserted as in apply
+			replacer := newReplacer([]string{"FUZZ"}, []string{test.item})
 
 			res := make(http.Header)
-			hdr.Apply(res, insertValue)
+			hdr.Apply(res, replacer.Replace)
 
 			if !cmp.Equal(test.want, res) {
 				t.Errorf("want:\n  %v\ngot:\n  %v", test.want, res)
@@ -795,7 +805,7 @@ Host: server:1234
 				}
 			}
 
-			genReq, err := req.Apply(test.Value)
+			genReq, err := req.Apply([]string{req.Replace}, []string{test.Value})
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -843,3 +853,277 @@ Host: server:1234
 		})
 	}
 }
+
+func TestHeaderNameInsertion(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-request-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	filename := filepath.Join(tempdir, "request")
+	err = ioutil.WriteFile(filename, []byte("GET / HTTP/1.1\r\nHost: www.example.com\r\nFUZZ: /admin\r\nx-other: 1\r\n\r\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, useFile := range []bool{false, true} {
+		req := New("")
+		req.URL = "http://www.example.com"
+		if useFile {
+			req.TemplateFile = filename
+		} else {
+			err = req.Header.Set("FUZZ: /admin")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		genReq, err := req.Apply([]string{"FUZZ"}, []string{"x-Original-URL"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		err = genReq.Write(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(buf.String(), "\r\nx-Original-URL: /admin\r\n") {
+			t.Errorf("header name was modified, request:\n%s", buf.String())
+		}
+
+		// other names are still canonicalized
+		if useFile && !strings.Contains(buf.String(), "\r\nX-Other: 1\r\n") {
+			t.Errorf("header name was not canonicalized, request:\n%s", buf.String())
+		}
+	}
+}
+
+func TestRawPath(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-request-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	filename := filepath.Join(tempdir, "request")
+	err = ioutil.WriteFile(filename, []byte("GET /static/FUZZ?x=%u0041 HTTP/1.1\r\nHost: www.example.com\r\n\r\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		url, file string
+		value     string
+		line      string
+		url2      string
+	}{
+		{
+			url:   "http://www.example.com/static/FUZZ?x=%u0041",
+			value: `..%2f..\%zz/./admin`,
+			line:  `GET /static/..%2f..\%zz/./admin?x=%u0041 HTTP/1.1`,
+			url2:  `http://www.example.com/static/..%2f..\%zz/./admin?x=%u0041`,
+		},
+		{
+			url:   "https://www.example.com:8443FUZZ",
+			value: "?a=b c",
+			line:  "GET /?a=b c HTTP/1.1",
+			url2:  "https://www.example.com:8443/?a=b c",
+		},
+		{
+			url:   "http://www.example.comFUZZ",
+			value: "//etc/passwd",
+			line:  "GET http://www.example.com//etc/passwd HTTP/1.1",
+			url2:  "http://www.example.com//etc/passwd",
+		},
+		{
+			url:   "https://www.example.com",
+			file:  filename,
+			value: `..;/%zz`,
+			line:  "GET /static/..;/%zz?x=%u0041 HTTP/1.1",
+			url2:  "https://www.example.com/static/..;/%zz?x=%u0041",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			req := New("")
+			req.URL = test.url
+			req.TemplateFile = test.file
+			req.RawPath = true
+
+			genReq, err := req.Apply([]string{"FUZZ"}, []string{test.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			err = genReq.Write(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			line := strings.SplitN(buf.String(), "\r\n", 2)[0]
+			if line != test.line {
+				t.Errorf("wrong request line, want %q, got %q", test.line, line)
+			}
+
+			if !strings.Contains(buf.String(), "\r\nHost: www.example.com") {
+				t.Errorf("wrong Host header in request:\n%s", buf.String())
+			}
+
+			if u := URLString(genReq); u != test.url2 {
+				t.Errorf("wrong URL, want %q, got %q", test.url2, u)
+			}
+		})
+	}
+}
+
+func TestRequestApplyMultiple(t *testing.T) {
+	req := New("")
+	req.URL = "http://www.example.com/FUZZ/FUZZ1"
+	req.Body = "user=FUZZ1&pass=FUZZ2"
+
+	genReq, err := req.Apply([]string{"FUZZ", "FUZZ1", "FUZZ2"}, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if genReq.URL.Path != "/a/b" {
+		t.Errorf("wrong path, want %q, got %q", "/a/b", genReq.URL.Path)
+	}
+
+	checkBody("user=b&pass=c")(t, genReq)
+
+	_, err = req.Apply([]string{"FUZZ", "FUZZ1"}, []string{"a"})
+	if err == nil {
+		t.Errorf("expected error for mismatched number of values not returned")
+	}
+}
+
+func TestTargetURL(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-request-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	filename := filepath.Join(tempdir, "request.txt")
+	err = ioutil.WriteFile(filename, []byte("POST /api/FUZZ HTTP/1.1\r\nHost: www.example.com:8443\r\nContent-Length: 4\r\n\r\nx=23"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		TemplateFile string
+		Args         []string
+		Want         string
+		Error        bool
+	}{
+		{Args: []string{"https://example.com/FUZZ"}, Want: "https://example.com/FUZZ"},
+		{TemplateFile: filename, Args: []string{"http://other.example.com"}, Want: "http://other.example.com"},
+		{TemplateFile: filename, Want: "https://www.example.com:8443"},
+		{Error: true},
+		{Args: []string{"https://example.com", "https://example.org"}, Error: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			req := New("")
+			req.TemplateFile = test.TemplateFile
+
+			u, err := req.TargetURL(test.Args)
+			if test.Error {
+				if err == nil {
+					t.Fatal("expected error not found")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if u != test.Want {
+				t.Errorf("wrong URL, want %q, got %q", test.Want, u)
+			}
+		})
+	}
+}
+
+func TestBodyFile(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-request-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// binary data which is not valid UTF-8, with the placeholder in between
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe, 'F', 'U', 'Z', 'Z', 0x00, 0x80, 0xc3}
+	filename := filepath.Join(tempdir, "payload.bin")
+	err = ioutil.WriteFile(filename, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := New("")
+	req.URL = "http://www.example.com/upload"
+	req.BodyFile = filename
+
+	err = req.LoadBodyFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the file is not read again for each request
+	err = os.Remove(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genReq, err := req.Apply([]string{"FUZZ"}, []string{"\x00\xffvalue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe, 0x00, 0xff, 'v', 'a', 'l', 'u', 'e', 0x00, 0x80, 0xc3}
+	body, err := ioutil.ReadAll(genReq.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(body, want) {
+		t.Errorf("wrong body, want %q, got %q", want, body)
+	}
+
+	if genReq.ContentLength != int64(len(want)) {
+		t.Errorf("wrong content length, want %d, got %d", len(want), genReq.ContentLength)
+	}
+
+	if genReq.Method != http.MethodPost {
+		t.Errorf("wrong method, want %v, got %v", http.MethodPost, genReq.Method)
+	}
+
+	req.Body = "foo"
+	_, err = req.Apply([]string{"FUZZ"}, []string{"x"})
+	if err == nil {
+		t.Error("expected error for --data-file with --data not found")
+	}
+}
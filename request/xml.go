@@ -0,0 +1,75 @@
+package request
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XML namespaces of the SOAP envelope.
+const (
+	soap11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// escapeXML returns s with all characters escaped which are special in XML
+// text and attribute values.
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	// writing to a bytes.Buffer does not return an error
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// rootElement checks that doc is well-formed XML and returns the name of the
+// root element.
+func rootElement(doc string) (xml.Name, error) {
+	dec := xml.NewDecoder(strings.NewReader(doc))
+
+	var root xml.Name
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("body is not valid XML: %v", err)
+		}
+
+		if start, ok := tok.(xml.StartElement); ok && root.Local == "" {
+			root = start.Name
+		}
+	}
+
+	if root.Local == "" {
+		return xml.Name{}, errors.New("XML body has no root element")
+	}
+
+	return root, nil
+}
+
+// xmlHeaders returns the Content-Type for the XML document doc and the value
+// for the SOAPAction header. SOAP 1.2 messages pass the action as a parameter
+// of the Content-Type instead.
+func xmlHeaders(doc, action string) (contentType, soapAction string, err error) {
+	root, err := rootElement(doc)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case root.Local == "Envelope" && root.Space == soap12Namespace:
+		contentType = "application/soap+xml; charset=utf-8"
+		if action != "" {
+			contentType += fmt.Sprintf("; action=%q", action)
+		}
+		return contentType, "", nil
+	case root.Local == "Envelope" && root.Space == soap11Namespace, action != "":
+		return "text/xml; charset=utf-8", fmt.Sprintf("%q", action), nil
+	default:
+		return "application/xml", "", nil
+	}
+}
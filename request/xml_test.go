@@ -0,0 +1,111 @@
+package request
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+const soap11Envelope = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+<soap:Body><Login user="FUZZ"><Name>FUZZ</Name></Login></soap:Body>
+</soap:Envelope>`
+
+const soap12Envelope = `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><Name>FUZZ</Name></env:Body></env:Envelope>`
+
+func TestXMLBody(t *testing.T) {
+	var tests = []struct {
+		body   string
+		raw    bool
+		action string
+		value  string
+
+		want        string
+		contentType string
+		soapAction  string
+	}{
+		{
+			body:        `<user name="FUZZ">FUZZ</user>`,
+			value:       `<a href="x">&`,
+			want:        `<user name="&lt;a href=&#34;x&#34;&gt;&amp;">&lt;a href=&#34;x&#34;&gt;&amp;</user>`,
+			contentType: "application/xml",
+		},
+		{
+			body:        `<user>FUZZ</user>`,
+			raw:         true,
+			value:       `<!ENTITY x "y">`,
+			want:        `<user><!ENTITY x "y"></user>`,
+			contentType: "application/xml",
+		},
+		{
+			body:   soap11Envelope,
+			action: "urn:Login",
+			value:  "admin",
+			want: `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+<soap:Body><Login user="admin"><Name>admin</Name></Login></soap:Body>
+</soap:Envelope>`,
+			contentType: "text/xml; charset=utf-8",
+			soapAction:  `"urn:Login"`,
+		},
+		{
+			body:        soap12Envelope,
+			action:      "urn:FUZZ",
+			value:       "Login",
+			want:        `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><Name>Login</Name></env:Body></env:Envelope>`,
+			contentType: `application/soap+xml; charset=utf-8; action="urn:Login"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			req := New("")
+			req.URL = "https://example.com"
+			req.Body = test.body
+			req.XMLBody = true
+			req.XMLRaw = test.raw
+			req.SOAPAction = test.action
+
+			httpReq, err := req.Apply([]string{"FUZZ"}, []string{test.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := ioutil.ReadAll(httpReq.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf) != test.want {
+				t.Errorf("wrong body, want:\n  %s\ngot:\n  %s", test.want, buf)
+			}
+
+			if httpReq.Method != "POST" {
+				t.Errorf("wrong method %v", httpReq.Method)
+			}
+
+			if ct := httpReq.Header.Get("Content-Type"); ct != test.contentType {
+				t.Errorf("wrong content type, want %q, got %q", test.contentType, ct)
+			}
+
+			if action := httpReq.Header.Get("SOAPAction"); action != test.soapAction {
+				t.Errorf("wrong SOAPAction, want %q, got %q", test.soapAction, action)
+			}
+		})
+	}
+}
+
+func TestXMLBodyInvalid(t *testing.T) {
+	for _, body := range []string{``, `<a>FUZZ</b>`, `FUZZ`} {
+		t.Run("", func(t *testing.T) {
+			req := New("")
+			req.URL = "https://example.com"
+			req.Body = body
+			req.XMLBody = true
+
+			_, err := req.Apply([]string{"FUZZ"}, []string{"x"})
+			if err == nil {
+				t.Fatalf("expected error for body %q not found", body)
+			}
+		})
+	}
+}
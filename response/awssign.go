@@ -0,0 +1,251 @@
+package response
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSCredentials are used to sign requests with AWS Signature Version 4.
+type AWSCredentials struct {
+	AccessKeyID, SecretAccessKey, SessionToken string
+}
+
+// ParseAWSCredentials parses key:secret[:session-token]. If s is empty, the
+// credentials are taken from the environment variables AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+func ParseAWSCredentials(s string) (AWSCredentials, error) {
+	if s == "" {
+		c := AWSCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+			return AWSCredentials{}, errors.New("AWS credentials not found, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+		}
+		return c, nil
+	}
+
+	data := strings.SplitN(s, ":", 3)
+	if len(data) < 2 || data[0] == "" || data[1] == "" {
+		return AWSCredentials{}, fmt.Errorf("invalid AWS credentials %q, expected key:secret[:session-token]", s)
+	}
+
+	c := AWSCredentials{AccessKeyID: data[0], SecretAccessKey: data[1]}
+	if len(data) == 3 {
+		c.SessionToken = data[2]
+	}
+
+	return c, nil
+}
+
+// AWSTransport signs requests with AWS Signature Version 4 before they are
+// sent. The signature covers the host, the Content-Type and the X-Amz-*
+// header fields.
+type AWSTransport struct {
+	Transport   http.RoundTripper
+	Credentials AWSCredentials
+	Region      string
+	Service     string
+
+	now func() time.Time // used for testing
+}
+
+// NewAWSTransport returns a transport which signs requests, scope is
+// region/service (e.g. us-east-1/execute-api).
+func NewAWSTransport(tr http.RoundTripper, cred AWSCredentials, scope string) (*AWSTransport, error) {
+	data := strings.Split(scope, "/")
+	if len(data) != 2 || data[0] == "" || data[1] == "" {
+		return nil, fmt.Errorf("invalid AWS scope %q, expected region/service", scope)
+	}
+
+	return &AWSTransport{
+		Transport:   tr,
+		Credentials: cred,
+		Region:      data[0],
+		Service:     data[1],
+	}, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// awsEscape percent-encodes all bytes except the unreserved characters (RFC
+// 3986), as required for the canonical request.
+func awsEscape(s string, keepSlash bool) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && keepSlash:
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+// canonicalURI returns the path for the canonical request. The path is
+// encoded again, except for S3.
+func (t *AWSTransport) canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+
+	if t.Service == "s3" {
+		return path
+	}
+
+	return awsEscape(path, true)
+}
+
+// canonicalQuery returns the sorted query string for the canonical request.
+func canonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	// values which are not properly encoded (e.g. inserted by the fuzzer)
+	// are used as they are
+	unescape := func(s string) string {
+		v, err := url.QueryUnescape(s)
+		if err != nil {
+			return s
+		}
+		return v
+	}
+
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+
+		data := strings.SplitN(param, "=", 2)
+		value := ""
+		if len(data) == 2 {
+			value = data[1]
+		}
+
+		params = append(params, awsEscape(unescape(data[0]), false)+"="+awsEscape(unescape(value), false))
+	}
+
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// sign adds the signature to req, body is the request body.
+func (t *AWSTransport) sign(req *http.Request, body []byte) {
+	now := time.Now
+	if t.now != nil {
+		now = t.now
+	}
+	timestamp := now().UTC()
+	amzDate := timestamp.Format("20060102T150405Z")
+	date := timestamp.Format("20060102")
+
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if t.Credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", t.Credentials.SessionToken)
+	}
+	if t.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name != "content-type" && !strings.HasPrefix(name, "x-amz-") {
+			continue
+		}
+
+		var vs []string
+		for _, v := range values {
+			vs = append(vs, strings.Join(strings.Fields(v), " "))
+		}
+		headers[name] = strings.Join(vs, ",")
+	}
+
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		t.canonicalURI(req.URL),
+		canonicalQuery(req.URL.RawQuery),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + t.Region + "/" + t.Service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+t.Credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, t.Region)
+	key = hmacSHA256(key, t.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.Credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// RoundTrip signs and sends the request.
+func (t *AWSTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+
+	r := req.Clone(req.Context())
+	if req.Body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	t.sign(r, body)
+	return t.Transport.RoundTrip(r)
+}
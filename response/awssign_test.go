@@ -0,0 +1,141 @@
+package response
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test cases from the AWS Signature Version 4 test suite
+func TestAWSSign(t *testing.T) {
+	var tests = []struct {
+		method string
+		url    string
+		body   string
+		header map[string]string
+		want   string
+	}{
+		{
+			method: "GET",
+			url:    "https://example.amazonaws.com/",
+			want:   "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			method: "GET",
+			url:    "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			want:   "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			method: "POST",
+			url:    "https://example.amazonaws.com/",
+			body:   "Param1=value1",
+			header: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			want:   "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	}
+
+	cred := AWSCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+
+	tr, err := NewAWSTransport(http.DefaultTransport, cred, "us-east-1/service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.now = func() time.Time {
+		return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			req, err := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range test.header {
+				req.Header.Set(k, v)
+			}
+
+			tr.sign(req, []byte(test.body))
+
+			if got := req.Header.Get("Authorization"); got != test.want {
+				t.Errorf("wrong signature:\n  want %v\n   got %v", test.want, got)
+			}
+
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("wrong date %v", got)
+			}
+		})
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	var tests = []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"b=2&a=1&a=0", "a=0&a=1&b=2"},
+		{"x=a+b&y=%7e&z", "x=a%20b&y=~&z="},
+		{"v=%zz", "v=%25zz"},
+	}
+
+	for _, test := range tests {
+		if got := canonicalQuery(test.query); got != test.want {
+			t.Errorf("canonical query for %q: want %q, got %q", test.query, test.want, got)
+		}
+	}
+}
+
+func TestParseAWSCredentials(t *testing.T) {
+	c, err := ParseAWSCredentials("key:secret:token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := AWSCredentials{AccessKeyID: "key", SecretAccessKey: "secret", SessionToken: "token"}
+	if c != want {
+		t.Errorf("wrong credentials, want %+v, got %+v", want, c)
+	}
+
+	defer setenv(t, "AWS_ACCESS_KEY_ID", "envkey")()
+	defer setenv(t, "AWS_SECRET_ACCESS_KEY", "envsecret")()
+	defer setenv(t, "AWS_SESSION_TOKEN", "")()
+
+	c, err = ParseAWSCredentials("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = AWSCredentials{AccessKeyID: "envkey", SecretAccessKey: "envsecret"}
+	if c != want {
+		t.Errorf("wrong credentials, want %+v, got %+v", want, c)
+	}
+
+	_, err = ParseAWSCredentials("key")
+	if err == nil {
+		t.Error("expected error not found")
+	}
+}
+
+// setenv sets the environment variable key to value and returns a function
+// which restores the previous value.
+func setenv(t testing.TB, key, value string) func() {
+	prev, ok := os.LookupEnv(key)
+
+	err := os.Setenv(key, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		if ok {
+			_ = os.Setenv(key, prev)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package response
+
+import "errors"
+
+// Baseline describes the responses to requests with random values, e.g. the
+// "not found" page of a server. Fields are set to -1 if the responses differ.
+type Baseline struct {
+	StatusCode int
+	Bytes      int // size of the body
+	Words      int // number of words in the body
+	Lines      int // number of lines in the body
+}
+
+// NewBaseline returns the baseline for the responses, which must not contain
+// errors.
+func NewBaseline(responses []Response) (Baseline, error) {
+	if len(responses) == 0 {
+		return Baseline{}, errors.New("no responses")
+	}
+
+	for _, res := range responses {
+		if res.Error != nil {
+			return Baseline{}, res.Error
+		}
+		if res.HTTPResponse == nil {
+			return Baseline{}, errors.New("no HTTP response received")
+		}
+	}
+
+	first := responses[0]
+	b := Baseline{
+		StatusCode: first.HTTPResponse.StatusCode,
+		Bytes:      first.Body.Bytes,
+		Words:      first.Body.Words,
+		Lines:      first.Body.Lines,
+	}
+
+	for _, res := range responses[1:] {
+		if res.HTTPResponse.StatusCode != b.StatusCode {
+			b.StatusCode = -1
+		}
+		if res.Body.Bytes != b.Bytes {
+			b.Bytes = -1
+		}
+		if res.Body.Words != b.Words {
+			b.Words = -1
+		}
+		if res.Body.Lines != b.Lines {
+			b.Lines = -1
+		}
+	}
+
+	return b, nil
+}
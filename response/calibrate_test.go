@@ -0,0 +1,58 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestNewBaseline(t *testing.T) {
+	res := func(status, bytes, words, lines int) Response {
+		return Response{
+			HTTPResponse: &http.Response{StatusCode: status},
+			Body:         TextStats{Bytes: bytes, Words: words, Lines: lines},
+		}
+	}
+
+	var tests = []struct {
+		responses []Response
+		want      Baseline
+	}{
+		{
+			[]Response{res(404, 100, 10, 2), res(404, 100, 10, 2)},
+			Baseline{StatusCode: 404, Bytes: 100, Words: 10, Lines: 2},
+		},
+		{
+			// the value is reflected in the body
+			[]Response{res(200, 100, 10, 2), res(200, 108, 10, 2), res(200, 116, 10, 2)},
+			Baseline{StatusCode: 200, Bytes: -1, Words: 10, Lines: 2},
+		},
+		{
+			[]Response{res(404, 100, 10, 2), res(403, 100, 11, 3)},
+			Baseline{StatusCode: -1, Bytes: 100, Words: -1, Lines: -1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			b, err := NewBaseline(test.responses)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b != test.want {
+				t.Fatalf("wrong baseline, want %+v, got %+v", test.want, b)
+			}
+		})
+	}
+
+	_, err := NewBaseline([]Response{res(404, 1, 1, 1), {Error: errors.New("connection refused")}})
+	if err == nil {
+		t.Error("expected error not found")
+	}
+
+	_, err = NewBaseline(nil)
+	if err == nil {
+		t.Error("expected error not found")
+	}
+}
@@ -0,0 +1,125 @@
+package response
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// LoadClientCertificate loads a TLS client certificate and key. The files are
+// either PEM encoded, with the key in certFile or in keyFile, or certFile is
+// a PKCS#12 file (usually .p12 or .pfx) protected with password. If certFile
+// is empty, nil is returned.
+func LoadClientCertificate(certFile, keyFile, password string) (*tls.Certificate, error) {
+	if certFile == "" {
+		if keyFile != "" {
+			return nil, errors.New("TLS client key specified without certificate")
+		}
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("ReadFile: %v", err)
+	}
+
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		if keyFile != "" {
+			return nil, fmt.Errorf("%v is not PEM encoded, a separate key file can only be used with PEM", certFile)
+		}
+
+		crt, err := loadPKCS12(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("error loading TLS cert and key from %v: %v", certFile, err)
+		}
+		return crt, nil
+	}
+
+	if keyFile != "" {
+		key, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("ReadFile: %v", err)
+		}
+		data = append(append(data, '\n'), key...)
+	}
+
+	certs, key, err := splitPEMCertKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("error loading TLS cert and key from %v: %v", certFile, err)
+	}
+
+	crt, err := tls.X509KeyPair(certs, key)
+	if err != nil {
+		return nil, fmt.Errorf("parse TLS client cert or key: %v", err)
+	}
+
+	return &crt, nil
+}
+
+// splitPEMCertKey returns the PEM encoded certificate and key blocks in data.
+func splitPEMCertKey(data []byte) (certs []byte, key []byte, err error) {
+	var block *pem.Block
+	for {
+		if len(data) == 0 {
+			break
+		}
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch {
+		case strings.HasSuffix(block.Type, "CERTIFICATE"):
+			certs = append(certs, pem.EncodeToMemory(block)...)
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			if key != nil {
+				return nil, nil, errors.New("more than one private key found")
+			}
+			key = pem.EncodeToMemory(block)
+		default:
+			return nil, nil, fmt.Errorf("unknown block type %v found", block.Type)
+		}
+	}
+
+	return certs, key, nil
+}
+
+// loadPKCS12 returns the certificate in the PKCS#12 file. The certificate
+// matching the private key is sent first, followed by the others (e.g.
+// intermediate CAs).
+func loadPKCS12(data []byte, password string) (*tls.Certificate, error) {
+	key, certs, err := decodePKCS12(data, password)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+
+	type equaler interface {
+		Equal(crypto.PublicKey) bool
+	}
+
+	crt := &tls.Certificate{PrivateKey: key}
+	for _, cert := range certs {
+		if pub, ok := signer.Public().(equaler); ok && pub.Equal(cert.PublicKey) && crt.Leaf == nil {
+			crt.Leaf = cert
+			crt.Certificate = append([][]byte{cert.Raw}, crt.Certificate...)
+			continue
+		}
+		crt.Certificate = append(crt.Certificate, cert.Raw)
+	}
+
+	if crt.Leaf == nil {
+		return nil, errors.New("no certificate matches the private key")
+	}
+
+	return crt, nil
+}
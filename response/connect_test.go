@@ -0,0 +1,199 @@
+package response
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestConnectTo(t *testing.T) {
+	var mu sync.Mutex
+	var serverNames []string
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Proto, r.Host)
+	}))
+	srv.EnableHTTP2 = true
+	srv.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			mu.Lock()
+			serverNames = append(serverNames, hello.ServerName)
+			mu.Unlock()
+			return nil, nil
+		},
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		template   request.Request
+		url        string
+		want       string
+		serverName string
+	}{
+		{
+			template:   request.Request{Insecure: true, ConnectTo: "127.0.0.1"},
+			url:        "https://vhost.example.com:" + port,
+			want:       "HTTP/2.0 vhost.example.com:" + port,
+			serverName: "vhost.example.com",
+		},
+		{
+			template:   request.Request{Insecure: true, ConnectTo: "127.0.0.1:" + port, SNI: "sni.example.com"},
+			url:        "https://vhost.example.com",
+			want:       "HTTP/2.0 vhost.example.com",
+			serverName: "sni.example.com",
+		},
+		{
+			template:   request.Request{Insecure: true, ForceHTTP2: true, ConnectTo: "[127.0.0.1]", SNI: "sni.example.com"},
+			url:        "https://vhost.example.com:" + port,
+			want:       "HTTP/2.0 vhost.example.com:" + port,
+			serverName: "sni.example.com",
+		},
+		{
+			template:   request.Request{Insecure: true, DisableHTTP2: true, ConnectTo: "127.0.0.1"},
+			url:        "https://vhost.example.com:" + port,
+			want:       "HTTP/1.1 vhost.example.com:" + port,
+			serverName: "vhost.example.com",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			mu.Lock()
+			serverNames = nil
+			mu.Unlock()
+
+			tr, err := NewTransport(&test.template, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tr.CloseIdleConnections()
+
+			res, err := (&http.Client{Transport: tr}).Get(test.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = res.Body.Close()
+
+			if string(buf) != test.want {
+				t.Errorf("wrong response, want %q, got %q", test.want, buf)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(serverNames) != 1 || serverNames[0] != test.serverName {
+				t.Errorf("wrong server name, want %q, got %q", test.serverName, serverNames)
+			}
+		})
+	}
+}
+
+func TestSNIPerValue(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.TLS.ServerName, r.Host)
+	}))
+	srv.StartTLS()
+	defer srv.Close()
+
+	tmpl := request.New("")
+	tmpl.URL = srv.URL
+	tmpl.Insecure = true
+	tmpl.SNI = "FUZZ.example.com"
+	tmpl.Header.Header["Host"] = []string{"vhost.example.com"}
+
+	tr, err := NewTransport(tmpl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make(chan producer.Item, 2)
+	in <- producer.Item{Values: []string{"a"}}
+	in <- producer.Item{Values: []string{"b"}}
+	close(in)
+	out := make(chan Response, 2)
+
+	runner := NewRunner(tr, tmpl, in, out)
+	runner.SNIPerValue = true
+	runner.Run(context.Background())
+
+	for _, want := range []string{"a.example.com vhost.example.com", "b.example.com vhost.example.com"} {
+		res := <-out
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+
+		if string(res.RawBody) != want {
+			t.Errorf("wrong body, want %q, got %q", want, res.RawBody)
+		}
+	}
+}
+
+func TestSNIPerValueWrapTransport(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.TLS.ServerName, r.Header.Get("Authorization"))
+	}))
+	srv.StartTLS()
+	defer srv.Close()
+
+	tmpl := request.New("")
+	tmpl.URL = srv.URL
+	tmpl.Insecure = true
+	tmpl.SNI = "FUZZ.example.com"
+
+	tr, err := NewTransport(tmpl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make(chan producer.Item, 1)
+	in <- producer.Item{Values: []string{"a"}}
+	close(in)
+	out := make(chan Response, 1)
+
+	runner := NewRunner(tr, tmpl, in, out)
+	runner.SNIPerValue = true
+
+	// the wrapper must be applied to the transport built for the value
+	runner.WrapTransport = func(tr *http.Transport) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("Authorization", "secret")
+			return tr.RoundTrip(req)
+		})
+	}
+	runner.Run(context.Background())
+
+	res := <-out
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	want := "a.example.com secret"
+	if string(res.RawBody) != want {
+		t.Errorf("wrong body, want %q, got %q", want, res.RawBody)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
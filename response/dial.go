@@ -0,0 +1,286 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"golang.org/x/net/proxy"
+)
+
+// dialContextFunc opens a connection to address.
+type dialContextFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// dialFunc returns the function used to open connections for the template:
+// it connects to UnixSocket or ConnectTo if set, uses the addresses pinned
+// with Resolve, and resolves host names via DNSServer if set. With SOCKS5, all
+// connections are made through the SOCKS5 proxy.
+func dialFunc(dialer *net.Dialer, template *request.Request) (dialContextFunc, error) {
+	if template.UnixSocket != "" {
+		if template.ConnectTo != "" || len(template.Resolve) > 0 || template.DNSServer != "" || template.SOCKS5 != "" || dialer.LocalAddr != nil {
+			return nil, errors.New("a Unix socket cannot be combined with --connect-to, --resolve, --dns-server, --socks5, --source-ip or --interface")
+		}
+
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", template.UnixSocket)
+		}, nil
+	}
+
+	if template.DNSServer != "" {
+		server := template.DNSServer
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+		}
+
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				d := &net.Dialer{Timeout: 5 * time.Second}
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	dial := dialContextFunc(dialer.DialContext)
+	if template.SOCKS5 != "" {
+		if template.DNSServer != "" {
+			return nil, errors.New("--dns-server cannot be combined with --socks5, host names are resolved by the proxy")
+		}
+
+		var err error
+		dial, err = socks5Dial(dial, template.SOCKS5)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if template.ConnectTo != "" {
+		return connectTo(dial, template.ConnectTo), nil
+	}
+
+	if len(template.Resolve) == 0 {
+		return dial, nil
+	}
+
+	pinned, err := parseResolve(template.Resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		host = strings.ToLower(host)
+		for _, key := range []string{host + ":" + port, host + ":*"} {
+			if ip, ok := pinned[key]; ok {
+				return dial(ctx, network, net.JoinHostPort(ip, port))
+			}
+		}
+
+		return dial(ctx, network, address)
+	}, nil
+}
+
+// parseResolve parses the entries in the form host:port:address, the port
+// may be "*" to match all ports. The result maps host:port to the address.
+func parseResolve(entries []string) (map[string]string, error) {
+	pinned := make(map[string]string)
+	for _, entry := range entries {
+		data := strings.SplitN(entry, ":", 3)
+		if len(data) != 3 || data[0] == "" || data[1] == "" || data[2] == "" {
+			return nil, fmt.Errorf("invalid --resolve entry %q, expected host:port:address", entry)
+		}
+
+		ip := net.ParseIP(strings.Trim(data[2], "[]"))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid address %q in --resolve entry %q", data[2], entry)
+		}
+
+		pinned[strings.ToLower(data[0])+":"+data[1]] = ip.String()
+	}
+
+	return pinned, nil
+}
+
+// connectTo returns a dial function which connects to addr instead of the
+// address passed to it. If addr does not contain a port, the port of the
+// original address is used.
+func connectTo(dial dialContextFunc, addr string) dialContextFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		target := addr
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			_, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			target = net.JoinHostPort(strings.Trim(addr, "[]"), port)
+		}
+
+		return dial(ctx, network, target)
+	}
+}
+
+// socks5Dial returns a dial function which connects through the SOCKS5 proxy
+// at addr ([user:password@]host:port), dial is used to connect to the proxy.
+// The host names are sent to the proxy unresolved.
+func socks5Dial(dial dialContextFunc, addr string) (dialContextFunc, error) {
+	u, err := url.Parse("socks5://" + strings.TrimPrefix(addr, "socks5://"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOCKS5 proxy %q: %v", addr, err)
+	}
+
+	if _, _, err := net.SplitHostPort(u.Host); err != nil {
+		return nil, fmt.Errorf("invalid SOCKS5 proxy %q, expected [user:password@]host:port", addr)
+	}
+
+	var auth *proxy.Auth
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth = &proxy.Auth{User: u.User.Username(), Password: password}
+	}
+
+	d, err := proxy.SOCKS5("tcp", u.Host, auth, contextDialer(dial))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.(proxy.ContextDialer).DialContext, nil
+}
+
+// contextDialer implements proxy.ContextDialer with a dial function.
+type contextDialer dialContextFunc
+
+func (d contextDialer) Dial(network, address string) (net.Conn, error) {
+	return d(context.Background(), network, address)
+}
+
+func (d contextDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d(ctx, network, address)
+}
+
+// localAddr returns the local address connections are made from: SourceIP,
+// or an address of Interface (IPv4 if it has one), or nil.
+func localAddr(template *request.Request) (net.Addr, error) {
+	if template.SourceIP != "" && template.Interface != "" {
+		return nil, errors.New("--source-ip and --interface cannot be used at the same time")
+	}
+
+	if template.SourceIP != "" {
+		ip := net.ParseIP(strings.Trim(template.SourceIP, "[]"))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source IP address %q", template.SourceIP)
+		}
+
+		return &net.TCPAddr{IP: ip}, nil
+	}
+
+	if template.Interface == "" {
+		return nil, nil
+	}
+
+	iface, err := net.InterfaceByName(template.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("interface %v: %v", template.Interface, err)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("interface %v: %v", template.Interface, err)
+	}
+
+	var v6 net.IP
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+
+		if ipnet.IP.To4() != nil {
+			return &net.TCPAddr{IP: ipnet.IP}, nil
+		}
+		if v6 == nil {
+			v6 = ipnet.IP
+		}
+	}
+
+	if v6 == nil {
+		return nil, fmt.Errorf("interface %v has no usable IP address", template.Interface)
+	}
+
+	return &net.TCPAddr{IP: v6}, nil
+}
+
+// ipFamily returns a dial function which only connects via the IP version
+// selected in the template, or tries all addresses of the preferred version
+// before the other one (instead of trying both in parallel).
+func ipFamily(dial dialContextFunc, template *request.Request) (dialContextFunc, error) {
+	var n int
+	for _, set := range []bool{template.IPv4Only, template.IPv6Only, template.PreferIPv4, template.PreferIPv6} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return nil, errors.New("--ipv4, --ipv6, --prefer-ipv4 and --prefer-ipv6 cannot be combined")
+	}
+
+	var networks []string
+	switch {
+	case template.IPv4Only:
+		networks = []string{"tcp4"}
+	case template.IPv6Only:
+		networks = []string{"tcp6"}
+	case template.PreferIPv4:
+		networks = []string{"tcp4", "tcp6"}
+	case template.PreferIPv6:
+		networks = []string{"tcp6", "tcp4"}
+	default:
+		return dial, nil
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if network != "tcp" {
+			return dial(ctx, network, address)
+		}
+
+		var firstErr error
+		for _, network := range networks {
+			conn, err := dial(ctx, network, address)
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		return nil, firstErr
+	}, nil
+}
+
+// removeZone removes the zone from an IPv6 address in host (e.g.
+// [fe80::1%eth0]:80 or fe80::1%eth0), it is not sent to the server.
+func removeZone(host string) string {
+	i := strings.Index(host, "%")
+	if i < 0 || !strings.Contains(host, ":") {
+		return host
+	}
+
+	end := strings.Index(host[i:], "]")
+	if end < 0 {
+		return host[:i]
+	}
+
+	return host[:i] + host[i+end:]
+}
@@ -0,0 +1,463 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func get(t *testing.T, tr *http.Transport, url string) string {
+	res, err := (&http.Client{Transport: tr}).Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	return string(buf)
+}
+
+func TestResolve(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(&request.Request{Resolve: []string{
+		"pinned.invalid:" + port + ":127.0.0.1",
+		"Wildcard.invalid:*:[127.0.0.1]",
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, host := range []string{"pinned.invalid:" + port, "wildcard.invalid:" + port} {
+		if got := get(t, tr, "http://"+host); got != host {
+			t.Errorf("wrong host, want %q, got %q", host, got)
+		}
+	}
+
+	// other ports are not pinned
+	_, err = (&http.Client{Transport: tr}).Get("http://pinned.invalid:1")
+	if err == nil {
+		t.Error("expected error not found")
+	}
+
+	for _, entry := range []string{"host:80", "host:80:", "host:80:name"} {
+		_, err = NewTransport(&request.Request{Resolve: []string{entry}}, nil)
+		if err == nil {
+			t.Errorf("expected error for %q not found", entry)
+		}
+	}
+}
+
+// dnsServer answers all queries for A records with 127.0.0.1.
+func dnsServer(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var msg dnsmessage.Message
+			err = msg.Unpack(buf[:n])
+			if err != nil || len(msg.Questions) != 1 {
+				continue
+			}
+
+			q := msg.Questions[0]
+			msg.Header.Response = true
+			if q.Type == dnsmessage.TypeA {
+				msg.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+					Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+				}}
+			}
+
+			res, err := msg.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(res, addr)
+		}
+	}()
+
+	return conn
+}
+
+func TestDNSServer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dns := dnsServer(t)
+	defer dns.Close()
+
+	tr, err := NewTransport(&request.Request{DNSServer: dns.LocalAddr().String()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := "fuzz.monsoon.invalid:" + port
+	if got := get(t, tr, "http://"+host); got != host {
+		t.Errorf("wrong host, want %q, got %q", host, got)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets are not supported")
+	}
+
+	tempdir, err := ioutil.TempDir("", "monsoon-test-dial-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	l, err := net.Listen("unix", filepath.Join(tempdir, "test.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Host, r.URL)
+	}))
+	srv.Listener = l
+	srv.Start()
+	defer srv.Close()
+
+	tr, err := NewTransport(&request.Request{UnixSocket: l.Addr().String()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "localhost /containers/json?all=1"
+	if got := get(t, tr, "http://localhost/containers/json?all=1"); got != want {
+		t.Errorf("wrong response, want %q, got %q", want, got)
+	}
+
+	_, err = NewTransport(&request.Request{UnixSocket: "/tmp/sock", ConnectTo: "127.0.0.1"}, nil)
+	if err == nil {
+		t.Error("expected error not found")
+	}
+}
+
+// socks5Server accepts SOCKS5 connections authenticated with user and
+// password, and connects all of them to 127.0.0.1 with the requested port.
+// The requested host names are sent to hosts.
+func socks5Server(t *testing.T, user, password string) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	hosts := make(chan string, 10)
+	handle := func(conn net.Conn) error {
+		defer conn.Close()
+
+		// greeting, only username/password authentication is accepted
+		buf := make([]byte, 512)
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+			return err
+		}
+		if _, err := conn.Write([]byte{5, 2}); err != nil {
+			return err
+		}
+
+		// version, user, password
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return err
+		}
+		u := make([]byte, buf[1])
+		if _, err := io.ReadFull(conn, u); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return err
+		}
+		p := make([]byte, buf[0])
+		if _, err := io.ReadFull(conn, p); err != nil {
+			return err
+		}
+		if string(u) != user || string(p) != password {
+			_, err := conn.Write([]byte{1, 1})
+			return err
+		}
+		if _, err := conn.Write([]byte{1, 0}); err != nil {
+			return err
+		}
+
+		// connect request with a domain name
+		if _, err := io.ReadFull(conn, buf[:5]); err != nil {
+			return err
+		}
+		if buf[3] != 3 {
+			return fmt.Errorf("unexpected address type %d", buf[3])
+		}
+		host := make([]byte, buf[4])
+		if _, err := io.ReadFull(conn, host); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return err
+		}
+		port := int(buf[0])<<8 | int(buf[1])
+		hosts <- string(host)
+
+		target, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			return err
+		}
+		defer target.Close()
+
+		if _, err := conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0}); err != nil {
+			return err
+		}
+
+		go func() { _, _ = io.Copy(target, conn) }()
+		_, err = io.Copy(conn, target)
+		return err
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() { _ = handle(conn) }()
+		}
+	}()
+
+	return ln.Addr().String(), hosts
+}
+
+func TestSOCKS5(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, hosts := socks5Server(t, "user", "secret")
+
+	tr, err := NewTransport(&request.Request{SOCKS5: "user:secret@" + addr, DisableHTTP2: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := "target.invalid:" + port
+	if got := get(t, tr, "http://"+host); got != host {
+		t.Errorf("wrong host, want %q, got %q", host, got)
+	}
+
+	// the host name is resolved by the proxy
+	if got := <-hosts; got != "target.invalid" {
+		t.Errorf("wrong host requested from the proxy, want %q, got %q", "target.invalid", got)
+	}
+
+	tr, err = NewTransport(&request.Request{SOCKS5: "user:wrong@" + addr, DisableHTTP2: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = (&http.Client{Transport: tr}).Get("http://" + host)
+	if err == nil {
+		t.Error("expected error for wrong password not found")
+	}
+
+	for _, proxy := range []string{"host", "user:pw@"} {
+		_, err = NewTransport(&request.Request{SOCKS5: proxy}, nil)
+		if err == nil {
+			t.Errorf("expected error for %q not found", proxy)
+		}
+	}
+}
+
+func TestSourceIP(t *testing.T) {
+	// all addresses in 127.0.0.0/8 can be used without configuration on Linux
+	if runtime.GOOS != "linux" {
+		t.Skip("additional loopback addresses are only available on Linux")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			t.Error(err)
+		}
+		fmt.Fprint(w, host)
+	}))
+	defer srv.Close()
+
+	tr, err := NewTransport(&request.Request{SourceIP: "127.0.0.2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := get(t, tr, srv.URL); got != "127.0.0.2" {
+		t.Errorf("wrong source address, want %q, got %q", "127.0.0.2", got)
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback == 0 {
+			continue
+		}
+
+		tr, err = NewTransport(&request.Request{Interface: iface.Name}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := get(t, tr, srv.URL); got != "127.0.0.1" {
+			t.Errorf("wrong source address, want %q, got %q", "127.0.0.1", got)
+		}
+	}
+
+	for _, req := range []*request.Request{
+		{SourceIP: "invalid"},
+		{Interface: "invalid-interface"},
+		{SourceIP: "127.0.0.1", Interface: "lo"},
+		{SourceIP: "127.0.0.1", UnixSocket: "/tmp/socket"},
+	} {
+		_, err = NewTransport(req, nil)
+		if err == nil {
+			t.Errorf("expected error for %+v not found", req)
+		}
+	}
+}
+
+func TestIPFamily(t *testing.T) {
+	var tests = []struct {
+		req  request.Request
+		want []string
+	}{
+		{request.Request{}, []string{"tcp"}},
+		{request.Request{IPv4Only: true}, []string{"tcp4"}},
+		{request.Request{IPv6Only: true}, []string{"tcp6"}},
+		{request.Request{PreferIPv4: true}, []string{"tcp4", "tcp6"}},
+		{request.Request{PreferIPv6: true}, []string{"tcp6", "tcp4"}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v", test.want), func(t *testing.T) {
+			// all connections fail, so all networks are tried
+			var networks []string
+			dial, err := ipFamily(func(_ context.Context, network, _ string) (net.Conn, error) {
+				networks = append(networks, network)
+				return nil, errors.New("failed")
+			}, &test.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = dial(context.Background(), "tcp", "example.com:80")
+			if err == nil {
+				t.Fatal("expected error not found")
+			}
+
+			if !reflect.DeepEqual(networks, test.want) {
+				t.Errorf("wrong networks, want %v, got %v", test.want, networks)
+			}
+		})
+	}
+
+	_, err := NewTransport(&request.Request{IPv4Only: true, PreferIPv6: true}, nil)
+	if err == nil {
+		t.Error("expected error for --ipv4 with --prefer-ipv6 not found")
+	}
+
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	tr, err := NewTransport(&request.Request{IPv6Only: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := get(t, tr, srv.URL); got != ln.Addr().String() {
+		t.Errorf("wrong host, want %q, got %q", ln.Addr().String(), got)
+	}
+
+	tr, err = NewTransport(&request.Request{IPv4Only: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = (&http.Client{Transport: tr}).Get(srv.URL)
+	if err == nil {
+		t.Error("expected error for IPv6 address with --ipv4 not found")
+	}
+}
+
+func TestRemoveZone(t *testing.T) {
+	var tests = []struct {
+		host, want string
+	}{
+		{"example.com:80", "example.com:80"},
+		{"[fe80::1%eth0]:8080", "[fe80::1]:8080"},
+		{"[fe80::1%eth0]", "[fe80::1]"},
+		{"fe80::1%eth0", "fe80::1"},
+		{"[::1]:80", "[::1]:80"},
+	}
+
+	for _, test := range tests {
+		if got := removeZone(test.host); got != test.want {
+			t.Errorf("removeZone(%q): want %q, got %q", test.host, test.want, got)
+		}
+	}
+}
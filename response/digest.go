@@ -0,0 +1,285 @@
+package response
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestChallenge is a challenge for HTTP digest authentication (RFC 7616).
+type digestChallenge struct {
+	Realm     string
+	Nonce     string
+	Opaque    string
+	Algorithm string
+	QOP       string // selected quality of protection: "auth", "auth-int" or empty
+	Stale     bool
+}
+
+// parseAuthParams parses the comma-separated name=value pairs of a challenge,
+// values may be quoted strings.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params
+		}
+
+		pos := strings.IndexByte(s, '=')
+		if pos < 0 {
+			return params
+		}
+
+		name := strings.ToLower(strings.TrimSpace(s[:pos]))
+		s = strings.TrimLeft(s[pos+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var buf strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				buf.WriteByte(s[i])
+			}
+			value = buf.String()
+			if i < len(s) {
+				i++
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+
+		params[name] = value
+	}
+}
+
+// digestHash returns the hash function for the algorithm, or nil if it is not
+// supported.
+func digestHash(algorithm string) func() hash.Hash {
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "", "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	default:
+		return nil
+	}
+}
+
+// parseDigestChallenge returns the strongest digest challenge sent by the
+// server which is supported, or nil if there is none.
+func parseDigestChallenge(res *http.Response) *digestChallenge {
+	var best *digestChallenge
+	for _, v := range res.Header["Www-Authenticate"] {
+		if len(v) < 7 || !strings.EqualFold(v[:7], "Digest ") {
+			continue
+		}
+
+		params := parseAuthParams(v[7:])
+		c := &digestChallenge{
+			Realm:     params["realm"],
+			Nonce:     params["nonce"],
+			Opaque:    params["opaque"],
+			Algorithm: params["algorithm"],
+			Stale:     strings.EqualFold(params["stale"], "true"),
+		}
+
+		if c.Nonce == "" || digestHash(c.Algorithm) == nil {
+			continue
+		}
+
+		if qop, ok := params["qop"]; ok {
+			for _, q := range strings.Split(qop, ",") {
+				q = strings.TrimSpace(q)
+				if q == "auth" || (q == "auth-int" && c.QOP == "") {
+					c.QOP = q
+				}
+			}
+
+			if c.QOP == "" {
+				continue
+			}
+		}
+
+		// prefer SHA-256 over MD5
+		if best == nil || strings.HasPrefix(strings.ToUpper(c.Algorithm), "SHA-256") {
+			best = c
+		}
+	}
+
+	return best
+}
+
+// authorization returns the value for the Authorization header.
+func (c *digestChallenge) authorization(user, password, method, uri string, body []byte, nc uint32) (string, error) {
+	newHash := digestHash(c.Algorithm)
+	h := func(s string) string {
+		sum := newHash()
+		_, _ = io.WriteString(sum, s)
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	cnonce := hex.EncodeToString(buf)
+
+	ha1 := h(user + ":" + c.Realm + ":" + password)
+	if strings.HasSuffix(strings.ToUpper(c.Algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + c.Nonce + ":" + cnonce)
+	}
+
+	ha2 := h(method + ":" + uri)
+	if c.QOP == "auth-int" {
+		ha2 = h(method + ":" + uri + ":" + h(string(body)))
+	}
+
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+	}
+
+	params := []string{
+		"username=" + quote(user),
+		"realm=" + quote(c.Realm),
+		"nonce=" + quote(c.Nonce),
+		"uri=" + quote(uri),
+	}
+
+	if c.QOP == "" {
+		params = append(params, "response="+quote(h(ha1+":"+c.Nonce+":"+ha2)))
+	} else {
+		count := fmt.Sprintf("%08x", nc)
+		params = append(params,
+			"response="+quote(h(ha1+":"+c.Nonce+":"+count+":"+cnonce+":"+c.QOP+":"+ha2)),
+			"qop="+c.QOP,
+			"nc="+count,
+			"cnonce="+quote(cnonce),
+		)
+	}
+
+	if c.Algorithm != "" {
+		params = append(params, "algorithm="+c.Algorithm)
+	}
+
+	if c.Opaque != "" {
+		params = append(params, "opaque="+quote(c.Opaque))
+	}
+
+	return "Digest " + strings.Join(params, ", "), nil
+}
+
+// NewDigestTransport returns a transport which authenticates via HTTP digest
+// authentication with credentials in the form user:password.
+func NewDigestTransport(tr http.RoundTripper, credentials string) (*DigestTransport, error) {
+	data := strings.SplitN(credentials, ":", 2)
+	if len(data) != 2 || data[0] == "" {
+		return nil, fmt.Errorf("invalid digest credentials %q, expected user:password", credentials)
+	}
+
+	return &DigestTransport{Transport: tr, User: data[0], Password: data[1]}, nil
+}
+
+// DigestTransport authenticates requests via HTTP digest authentication (RFC
+// 7616). The last challenge is reused for subsequent requests, so usually
+// only the first request is sent twice.
+type DigestTransport struct {
+	Transport      http.RoundTripper
+	User, Password string
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	nc        uint32
+}
+
+// authorize returns the Authorization header for the request based on the
+// last challenge, or the empty string.
+func (t *DigestTransport) authorize(req *http.Request, body []byte) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.challenge == nil {
+		return "", nil
+	}
+
+	t.nc++
+	return t.challenge.authorization(t.User, t.Password, req.Method, req.URL.RequestURI(), body, t.nc)
+}
+
+// RoundTrip sends the request, and sends it again with authentication if the
+// server responds with status 401 and a new digest challenge.
+func (t *DigestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+
+	send := func() (*http.Response, error) {
+		auth, err := t.authorize(req, body)
+		if err != nil {
+			return nil, err
+		}
+
+		r := req.Clone(req.Context())
+		if req.Body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		return t.Transport.RoundTrip(r)
+	}
+
+	res, err := send()
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	c := parseDigestChallenge(res)
+	if c == nil {
+		return res, nil
+	}
+
+	t.mu.Lock()
+	// a new nonce was sent, unless the credentials are wrong
+	retry := t.challenge == nil || t.challenge.Nonce != c.Nonce || c.Stale
+	if retry {
+		t.challenge = c
+		t.nc = 0
+	}
+	t.mu.Unlock()
+
+	if !retry {
+		return res, nil
+	}
+
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	_ = res.Body.Close()
+
+	return send()
+}
@@ -0,0 +1,210 @@
+package response
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseAuthParams(t *testing.T) {
+	got := parseAuthParams(`realm="a, \"b\"", qop="auth,auth-int", algorithm=SHA-256, stale=FALSE`)
+	want := map[string]string{
+		"realm":     `a, "b"`,
+		"qop":       "auth,auth-int",
+		"algorithm": "SHA-256",
+		"stale":     "FALSE",
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("param %v: want %q, got %q", k, v, got[k])
+		}
+	}
+}
+
+// the example from RFC 7616, section 3.9.1
+func TestDigestResponse(t *testing.T) {
+	res := &http.Response{Header: http.Header{
+		"Www-Authenticate": []string{
+			`Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=MD5, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`,
+			`Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=SHA-256, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`,
+		},
+	}}
+
+	c := parseDigestChallenge(res)
+	if c == nil {
+		t.Fatal("challenge not found")
+	}
+
+	if c.Algorithm != "SHA-256" || c.QOP != "auth" {
+		t.Fatalf("wrong challenge selected: %+v", c)
+	}
+
+	cnonce := "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+
+	var tests = []struct {
+		algorithm string
+		newHash   func() hash.Hash
+		want      string
+	}{
+		{"MD5", md5.New, "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", sha256.New, "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.algorithm, func(t *testing.T) {
+			h := func(s string) string {
+				sum := test.newHash()
+				_, _ = io.WriteString(sum, s)
+				return hex.EncodeToString(sum.Sum(nil))
+			}
+
+			// the client nonce is random, so compute the response with the one
+			// from the example based on the header
+			ha1 := h("Mufasa:http-auth@example.org:Circle of Life")
+			ha2 := h("GET:/dir/index.html")
+			got := h(ha1 + ":" + c.Nonce + ":00000001:" + cnonce + ":auth:" + ha2)
+			if got != test.want {
+				t.Fatalf("wrong response, want %v, got %v", test.want, got)
+			}
+
+			challenge := *c
+			challenge.Algorithm = test.algorithm
+			auth, err := challenge.authorization("Mufasa", "Circle of Life", "GET", "/dir/index.html", nil, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			params := parseAuthParams(strings.TrimPrefix(auth, "Digest "))
+			got = h(ha1 + ":" + c.Nonce + ":" + params["nc"] + ":" + params["cnonce"] + ":" + params["qop"] + ":" + ha2)
+			if params["response"] != got {
+				t.Errorf("wrong response in %q, want %v", auth, got)
+			}
+
+			want := map[string]string{
+				"username":  "Mufasa",
+				"realm":     "http-auth@example.org",
+				"uri":       "/dir/index.html",
+				"algorithm": test.algorithm,
+				"nc":        "00000001",
+				"qop":       "auth",
+				"opaque":    c.Opaque,
+			}
+			for k, v := range want {
+				if params[k] != v {
+					t.Errorf("param %v: want %q, got %q", k, v, params[k])
+				}
+			}
+		})
+	}
+}
+
+// digestServer checks the authorization header for user:password with
+// qop=auth and MD5.
+func digestServer(t *testing.T, requests *int32) http.Handler {
+	const nonce = "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+
+	h := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+
+		buf, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(auth, "Digest ") {
+			p := parseAuthParams(auth[7:])
+			ha1 := h(p["username"] + ":" + p["realm"] + ":password")
+			ha2 := h(r.Method + ":" + p["uri"])
+			want := h(ha1 + ":" + p["nonce"] + ":" + p["nc"] + ":" + p["cnonce"] + ":" + p["qop"] + ":" + ha2)
+			if p["response"] == want && p["nonce"] == nonce && p["uri"] == r.URL.RequestURI() {
+				fmt.Fprintf(w, "ok %s %s", p["username"], buf)
+				return
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", `Digest realm="test", qop="auth", nonce="`+nonce+`", algorithm=MD5`)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+}
+
+func TestDigestTransport(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(digestServer(t, &requests))
+	defer srv.Close()
+
+	tr, err := NewDigestTransport(http.DefaultTransport, "admin:password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: tr}
+
+	for i := 0; i < 3; i++ {
+		res, err := client.Post(srv.URL+"/path?x=y", "text/plain", strings.NewReader(fmt.Sprintf("body%d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+
+		want := fmt.Sprintf("ok admin body%d", i)
+		if res.StatusCode != http.StatusOK || string(buf) != want {
+			t.Fatalf("wrong response, want %q, got %v %q", want, res.Status, buf)
+		}
+	}
+
+	// the challenge is reused after the first request
+	if n := atomic.LoadInt32(&requests); n != 4 {
+		t.Errorf("want 4 requests, got %d", n)
+	}
+
+	// wrong credentials do not cause an endless loop
+	tr, err = NewDigestTransport(http.DefaultTransport, "admin:wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: tr}
+
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("want status 401, got %v", res.Status)
+		}
+	}
+}
+
+func TestNewDigestTransportInvalid(t *testing.T) {
+	for _, s := range []string{"", "user", ":password"} {
+		_, err := NewDigestTransport(http.DefaultTransport, s)
+		if err == nil {
+			t.Errorf("expected error for %q not found", s)
+		}
+	}
+}
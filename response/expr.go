@@ -0,0 +1,420 @@
+package response
+
+import (
+	"fmt"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// exprToken is a token of a filter expression.
+type exprToken struct {
+	kind string // ident, number, string, op, ( or ), or eof
+	text string
+	pos  int
+}
+
+// exprOperators are the comparison and logical operators, longer ones first.
+var exprOperators = []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||", "<", ">", "!"}
+
+// lexExpr splits a filter expression into tokens.
+func lexExpr(s string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, exprToken{kind: string(c), text: string(c), pos: i})
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(s) && s[end] != c {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+
+			str := s[i+1 : end]
+			if c == '"' {
+				var err error
+				str, err = strconv.Unquote(s[i : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid string at position %d: %v", i, err)
+				}
+			}
+			tokens = append(tokens, exprToken{kind: "string", text: str, pos: i})
+			i = end + 1
+		case c >= '0' && c <= '9':
+			// numbers may have a unit, e.g. 100ms
+			end := i
+			for end < len(s) && (s[end] == '.' || unicode.IsLetter(rune(s[end])) || unicode.IsDigit(rune(s[end]))) {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: "number", text: s[i:end], pos: i})
+			i = end
+		case c == '_' || unicode.IsLetter(rune(c)):
+			end := i
+			for end < len(s) && (s[end] == '_' || unicode.IsLetter(rune(s[end])) || unicode.IsDigit(rune(s[end]))) {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: "ident", text: strings.ToLower(s[i:end]), pos: i})
+			i = end
+		default:
+			found := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(s[i:], op) {
+					tokens = append(tokens, exprToken{kind: "op", text: op, pos: i})
+					i += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+		}
+	}
+
+	return append(tokens, exprToken{kind: "eof", pos: len(s)}), nil
+}
+
+// exprNode is a node of a parsed filter expression.
+type exprNode interface {
+	eval(Response) bool
+}
+
+type exprAnd struct{ left, right exprNode }
+type exprOr struct{ left, right exprNode }
+type exprNot struct{ node exprNode }
+
+func (e exprAnd) eval(r Response) bool { return e.left.eval(r) && e.right.eval(r) }
+func (e exprOr) eval(r Response) bool  { return e.left.eval(r) || e.right.eval(r) }
+func (e exprNot) eval(r Response) bool { return !e.node.eval(r) }
+
+// exprNumber compares a number of the response.
+type exprNumber struct {
+	get   func(Response) int64
+	op    string
+	value int64
+}
+
+func (e exprNumber) eval(r Response) bool {
+	v := e.get(r)
+	switch e.op {
+	case "==":
+		return v == e.value
+	case "!=":
+		return v != e.value
+	case "<":
+		return v < e.value
+	case "<=":
+		return v <= e.value
+	case ">":
+		return v > e.value
+	default:
+		return v >= e.value
+	}
+}
+
+// exprString compares or matches a string of the response.
+type exprString struct {
+	get     func(Response) string
+	op      string
+	value   string
+	pattern *regexp.Regexp
+}
+
+func (e exprString) eval(r Response) bool {
+	v := e.get(r)
+	switch e.op {
+	case "==":
+		return v == e.value
+	case "!=":
+		return v != e.value
+	case "contains":
+		return strings.Contains(v, e.value)
+	case "=~":
+		return e.pattern.MatchString(v)
+	default:
+		return !e.pattern.MatchString(v)
+	}
+}
+
+func statusCode(r Response) int64 {
+	if r.HTTPResponse == nil {
+		return 0
+	}
+	return int64(r.HTTPResponse.StatusCode)
+}
+
+// exprNumberFields are the numeric fields of a response.
+var exprNumberFields = map[string]func(Response) int64{
+	"status":      statusCode,
+	"size":        func(r Response) int64 { return int64(r.Body.Bytes) },
+	"header_size": func(r Response) int64 { return int64(r.Header.Bytes) },
+	"words":       func(r Response) int64 { return int64(r.Body.Words) },
+	"lines":       func(r Response) int64 { return int64(r.Body.Lines) },
+}
+
+// exprStringFields are the string fields of a response.
+var exprStringFields = map[string]func(Response) string{
+	"body":   func(r Response) string { return string(r.RawBody) },
+	"header": func(r Response) string { return string(r.RawHeader) },
+	"value":  func(r Response) string { return r.Item },
+	"method": func(r Response) string { return r.Method },
+	"url":    func(r Response) string { return r.URL },
+}
+
+// exprParser is a recursive descent parser for filter expressions.
+type exprParser struct {
+	src    string
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != "eof" {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) errorf(t exprToken, msg string, args ...interface{}) error {
+	return fmt.Errorf("invalid filter expression %q at position %d: %v", p.src, t.pos, fmt.Sprintf(msg, args...))
+}
+
+// is returns true if t is of kind and has one of the texts.
+func (t exprToken) is(kind string, texts ...string) bool {
+	if t.kind != kind {
+		return false
+	}
+	for _, s := range texts {
+		if t.text == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().is("op", "||") || p.peek().is("ident", "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = exprOr{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().is("op", "&&") || p.peek().is("ident", "and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = exprAnd{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.peek().is("op", "!") || p.peek().is("ident", "not") {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return exprNot{node}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.next()
+	if t.kind == "(" {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if end := p.next(); end.kind != ")" {
+			return nil, p.errorf(end, "expected )")
+		}
+		return node, nil
+	}
+
+	if t.kind != "ident" {
+		return nil, p.errorf(t, "expected a field name")
+	}
+
+	field := t.text
+	var getString func(Response) string
+
+	switch {
+	case field == "time":
+		return p.parseTime()
+	case field == "header" && p.peek().kind == "(":
+		// value of a header field, e.g. header("Location")
+		p.next()
+		name := p.next()
+		if name.kind != "string" {
+			return nil, p.errorf(name, "expected the name of a header field")
+		}
+		if end := p.next(); end.kind != ")" {
+			return nil, p.errorf(end, "expected )")
+		}
+		key := textproto.CanonicalMIMEHeaderKey(name.text)
+		getString = func(r Response) string {
+			if r.HTTPResponse == nil {
+				return ""
+			}
+			return strings.Join(r.HTTPResponse.Header[key], ", ")
+		}
+	case exprStringFields[field] != nil:
+		getString = exprStringFields[field]
+	case exprNumberFields[field] != nil:
+		return p.parseNumber(exprNumberFields[field])
+	default:
+		return nil, p.errorf(t, "unknown field %q", field)
+	}
+
+	op := p.next()
+	if !op.is("op", "==", "!=", "=~", "!~") && !op.is("ident", "contains") {
+		return nil, p.errorf(op, "expected ==, !=, =~, !~ or contains")
+	}
+
+	value := p.next()
+	if value.kind != "string" {
+		return nil, p.errorf(value, "expected a string")
+	}
+
+	node := exprString{get: getString, op: op.text, value: value.text}
+	if op.text == "=~" || op.text == "!~" {
+		var err error
+		node.pattern, err = regexp.Compile(value.text)
+		if err != nil {
+			return nil, p.errorf(value, "regexp %q failed to compile: %v", value.text, err)
+		}
+	}
+
+	return node, nil
+}
+
+// comparison parses a numeric comparison operator.
+func (p *exprParser) comparison() (string, error) {
+	op := p.next()
+	if !op.is("op", "==", "!=", "<", "<=", ">", ">=") {
+		return "", p.errorf(op, "expected ==, !=, <, <=, > or >=")
+	}
+	return op.text, nil
+}
+
+func (p *exprParser) parseNumber(get func(Response) int64) (exprNode, error) {
+	op, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.next()
+	v, err := strconv.ParseInt(t.text, 10, 64)
+	if t.kind != "number" || err != nil {
+		return nil, p.errorf(t, "expected a number")
+	}
+
+	return exprNumber{get: get, op: op, value: v}, nil
+}
+
+func (p *exprParser) parseTime() (exprNode, error) {
+	op, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.next()
+	d, err := time.ParseDuration(t.text)
+	if t.kind != "number" || err != nil {
+		return nil, p.errorf(t, "expected a duration (e.g. 500ms or 2s)")
+	}
+
+	get := func(r Response) int64 { return int64(r.Duration) }
+	return exprNumber{get: get, op: op, value: int64(d)}, nil
+}
+
+// FilterExpr shows only responses for which the expression is true. See
+// ParseFilterExpr for the syntax.
+type FilterExpr struct {
+	expr string
+	node exprNode
+}
+
+// ParseFilterExpr parses a filter expression. It consists of comparisons of
+// the fields of a response combined with and, or, not (or &&, ||, !) and
+// parentheses. The numeric fields status, size, header_size, words and lines
+// and the duration time (e.g. 500ms) are compared with ==, !=, <, <=, > and
+// >=. The string fields body, header (the raw header), header("Name") (the
+// values of a header field), value, method and url are compared with == and
+// !=, matched against a regular expression with =~ and !~, or tested with
+// contains. Strings are quoted with " or ', e.g.
+//
+//	status == 200 and (size > 1000 or body =~ "(?i)error")
+func ParseFilterExpr(s string) (*FilterExpr, error) {
+	tokens, err := lexExpr(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter expression %q: %v", s, err)
+	}
+
+	p := &exprParser{src: s, tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.next(); t.kind != "eof" {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+
+	return &FilterExpr{expr: s, node: node}, nil
+}
+
+func (f *FilterExpr) String() string {
+	return f.expr
+}
+
+// Match returns true if the expression is true for r.
+func (f *FilterExpr) Match(r Response) bool {
+	return f.node.eval(r)
+}
+
+// Reject decides if r is to be printed.
+func (f *FilterExpr) Reject(r Response) bool {
+	return !f.Match(r)
+}
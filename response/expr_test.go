@@ -0,0 +1,94 @@
+package response
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFilterExpr(t *testing.T) {
+	res := Response{
+		Item:   "admin",
+		Method: "GET",
+		URL:    "https://example.com/admin",
+		HTTPResponse: &http.Response{
+			StatusCode: 302,
+			Header:     http.Header{"Location": {"/login?next=/admin"}},
+		},
+		RawHeader: []byte("HTTP/1.1 302 Found\r\nLocation: /login?next=/admin\r\n\r\n"),
+		RawBody:   []byte("Redirecting to the login page\n"),
+		Header:    TextStats{Bytes: 52, Words: 5, Lines: 3},
+		Body:      TextStats{Bytes: 30, Words: 5, Lines: 1},
+		Duration:  1500 * time.Millisecond,
+	}
+
+	var tests = []struct {
+		expr  string
+		match bool
+	}{
+		{"status == 302", true},
+		{"status != 302", false},
+		{"status >= 300 and status < 400", true},
+		{"status >= 300 && status < 302", false},
+		{"status == 200 or status == 302", true},
+		{"status == 200 || size == 30", true},
+		{"not status == 302", false},
+		{"!(status == 200)", true},
+		{"size > 29 and words == 5 and lines <= 1 and header_size == 52", true},
+		{"time > 1s", true},
+		{"time > 1s and time < 1500ms", false},
+		{`header("location") =~ "^/login"`, true},
+		{`header('Location') contains 'next=/admin'`, true},
+		{`header("X-Missing") == ""`, true},
+		{`header =~ "302 Found"`, true},
+		{`body contains "login"`, true},
+		{`body !~ '(?i)error'`, true},
+		{`value == "admin" and method == "GET"`, true},
+		{`url =~ '\.com/admin$'`, true},
+		{`(status == 301 or status == 302) and not body contains "login"`, false},
+		{"STATUS == 302 AND Size == 30", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			f, err := ParseFilterExpr(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if f.Match(res) != test.match {
+				t.Fatalf("wrong result, want %v", test.match)
+			}
+
+			if f.Reject(res) == test.match {
+				t.Fatalf("wrong result for Reject, want %v", !test.match)
+			}
+		})
+	}
+
+	invalid := []string{
+		"",
+		"status",
+		"status == ",
+		"status == 2xx",
+		"status =~ '200'",
+		"size > 10 and",
+		"(status == 200",
+		"status == 200)",
+		"time > 2",
+		"body > 10",
+		"body == 10",
+		`body =~ "("`,
+		`header(Location) == "x"`,
+		"foo == 1",
+		`body == "unterminated`,
+		"status == 200 $",
+	}
+
+	for _, expr := range invalid {
+		_, err := ParseFilterExpr(expr)
+		if err == nil {
+			t.Errorf("expected error for %q not found", expr)
+		}
+	}
+}
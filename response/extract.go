@@ -1,10 +1,20 @@
 package response
 
-import "regexp"
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
 
 // Extracter collects data from interesting (non-hidden) responses.
 type Extracter struct {
 	Pattern  []*regexp.Regexp
+	JSONPath []*JSONPath
+	HTML     []*HTMLQuery
+	Headers  []HeaderExtract
+	Cookies  []string
 	Commands [][]string
 	Error    func(error)
 }
@@ -31,6 +41,10 @@ func (e *Extracter) Run(in <-chan Response) <-chan Response {
 			}
 
 			res.ExtractBody(e.Pattern)
+			res.ExtractJSON(e.JSONPath)
+			res.ExtractHTML(e.HTML)
+			res.ExtractHeaderFields(e.Headers)
+			res.ExtractCookies(e.Cookies)
 
 			// forward response to next in chain
 			ch <- res
@@ -39,3 +53,87 @@ func (e *Extracter) Run(in <-chan Response) <-chan Response {
 
 	return ch
 }
+
+// FieldNames returns the names of the named subexpressions in the patterns
+// for the body and the header fields, in order and without duplicates.
+func (e *Extracter) FieldNames() []string {
+	targets := append([]*regexp.Regexp{}, e.Pattern...)
+	for _, h := range e.Headers {
+		if h.Pattern != nil {
+			targets = append(targets, h.Pattern)
+		}
+	}
+	return FieldNames(targets)
+}
+
+// HeaderExtract selects the values of a header field, or the matches of
+// Pattern in the values if it is set.
+type HeaderExtract struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// ParseHeaderExtract parses a header field name, optionally followed by =regex.
+func ParseHeaderExtract(spec string) (HeaderExtract, error) {
+	name, pattern := spec, ""
+	if pos := strings.IndexByte(spec, '='); pos >= 0 {
+		name, pattern = spec[:pos], spec[pos+1:]
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return HeaderExtract{}, fmt.Errorf("invalid header extract %q, use name or name=regex", spec)
+	}
+
+	h := HeaderExtract{Name: textproto.CanonicalMIMEHeaderKey(name)}
+	if pattern != "" {
+		var err error
+		h.Pattern, err = regexp.Compile(pattern)
+		if err != nil {
+			return HeaderExtract{}, fmt.Errorf("regexp %q failed to compile: %v", pattern, err)
+		}
+	}
+
+	return h, nil
+}
+
+// ExtractHeaderFields extracts the values of the header fields of the
+// response. Patterns are applied as for ExtractBody, so named subexpressions
+// are added to Fields.
+func (r *Response) ExtractHeaderFields(list []HeaderExtract) {
+	if r.HTTPResponse == nil {
+		return
+	}
+
+	for _, h := range list {
+		for _, v := range r.HTTPResponse.Header[h.Name] {
+			if h.Pattern == nil {
+				r.Extract = append(r.Extract, v)
+				continue
+			}
+			r.extractRegexp([]byte(v), []*regexp.Regexp{h.Pattern})
+		}
+	}
+}
+
+// ExtractCookies extracts the values of the cookies with the names which are
+// set by the response, including the responses of followed redirects.
+func (r *Response) ExtractCookies(names []string) {
+	if len(names) == 0 || r.HTTPResponse == nil {
+		return
+	}
+
+	var cookies []*http.Cookie
+	for _, redirect := range r.Redirects {
+		cookies = append(cookies, redirect.HTTPResponse.Cookies()...)
+	}
+	cookies = append(cookies, r.HTTPResponse.Cookies()...)
+
+	for _, name := range names {
+		for _, c := range cookies {
+			if c.Name == name {
+				r.Extract = append(r.Extract, c.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package response
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestExtractHeaderFields(t *testing.T) {
+	var tests = []struct {
+		specs  []string
+		data   []string
+		fields map[string][]string
+	}{
+		{
+			specs: []string{"x-ratelimit-remaining"},
+			data:  []string{"42"},
+		},
+		{
+			specs: []string{"Set-Cookie"},
+			data:  []string{"session=abc123; Path=/; HttpOnly", "theme=dark"},
+		},
+		{
+			specs: []string{"Set-Cookie=^session=([^;]+)"},
+			data:  []string{"abc123"},
+		},
+		{
+			specs:  []string{"Server=(?P<server>\\S+)/(?P<version>\\S+)"},
+			fields: map[string][]string{"server": {"nginx"}, "version": {"1.18.0"}},
+		},
+		{
+			specs: []string{"X-Missing"},
+		},
+	}
+
+	header := http.Header{
+		"X-Ratelimit-Remaining": {"42"},
+		"Set-Cookie":            {"session=abc123; Path=/; HttpOnly", "theme=dark"},
+		"Server":                {"nginx/1.18.0"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var list []HeaderExtract
+			for _, spec := range test.specs {
+				h, err := ParseHeaderExtract(spec)
+				if err != nil {
+					t.Fatal(err)
+				}
+				list = append(list, h)
+			}
+
+			r := Response{HTTPResponse: &http.Response{Header: header}}
+			r.ExtractHeaderFields(list)
+
+			if !reflect.DeepEqual(test.data, r.Extract) {
+				t.Errorf("wrong data, want %q, got %q", test.data, r.Extract)
+			}
+
+			if !reflect.DeepEqual(test.fields, r.Fields) {
+				t.Errorf("wrong fields, want %q, got %q", test.fields, r.Fields)
+			}
+		})
+	}
+
+	for _, spec := range []string{"", "=foo", "Server=("} {
+		_, err := ParseHeaderExtract(spec)
+		if err == nil {
+			t.Errorf("expected error for %q not found", spec)
+		}
+	}
+}
+
+func TestExtractCookies(t *testing.T) {
+	r := Response{
+		HTTPResponse: &http.Response{Header: http.Header{
+			"Set-Cookie": {"session=final; Path=/", "theme=dark"},
+		}},
+		Redirects: []Redirect{
+			{HTTPResponse: &http.Response{Header: http.Header{
+				"Set-Cookie": {"session=first; Path=/; Secure"},
+			}}},
+		},
+	}
+
+	r.ExtractCookies([]string{"session", "missing"})
+
+	want := []string{"first", "final"}
+	if !reflect.DeepEqual(want, r.Extract) {
+		t.Errorf("wrong data, want %q, got %q", want, r.Extract)
+	}
+}
@@ -1,9 +1,14 @@
 package response
 
 import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/textproto"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Filter decides whether to reject a Response.
@@ -65,9 +70,9 @@ func (f FilterStatusCode) Reject(r Response) bool {
 // parseRangeFilterSpec returns a function that returns true if the size matches with the spec.
 //
 // possible matches:
-//  * exact: 1234
-//  * range: 100-200
-//  * open range: -200, 200-
+//   - exact: 1234
+//   - range: 100-200
+//   - open range: -200, 200-
 func parseRangeFilterSpec(spec string) (func(int) bool, error) {
 	if strings.HasPrefix(spec, "-") {
 		v, err := strconv.Atoi(spec[1:])
@@ -172,6 +177,55 @@ func (f FilterSize) Reject(r Response) bool {
 	return false
 }
 
+// FilterCount hides responses based on the number of words and lines in the
+// body.
+type FilterCount struct {
+	words []func(int) bool
+	lines []func(int) bool
+}
+
+// NewFilterCount returns an initialized FilterCount.
+func NewFilterCount(words, lines []string) (FilterCount, error) {
+	fc := FilterCount{}
+
+	for _, spec := range words {
+		f, err := parseRangeFilterSpec(spec)
+		if err != nil {
+			return FilterCount{}, err
+		}
+
+		fc.words = append(fc.words, f)
+	}
+
+	for _, spec := range lines {
+		f, err := parseRangeFilterSpec(spec)
+		if err != nil {
+			return FilterCount{}, err
+		}
+
+		fc.lines = append(fc.lines, f)
+	}
+
+	return fc, nil
+}
+
+// Reject decides if r is to be printed.
+func (f FilterCount) Reject(r Response) bool {
+	for _, f := range f.words {
+		if f(r.Body.Words) {
+			return true
+		}
+	}
+
+	for _, f := range f.lines {
+		if f(r.Body.Lines) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // FilterRejectPattern filters responses based on patterns (header and body are matched).
 type FilterRejectPattern struct {
 	Pattern []*regexp.Regexp
@@ -223,3 +277,430 @@ func (f FilterAcceptPattern) Reject(res Response) bool {
 
 	return true
 }
+
+// FilterRejectBodyPattern filters responses based on patterns (only the body is matched).
+type FilterRejectBodyPattern struct {
+	Pattern []*regexp.Regexp
+}
+
+// Reject decides if r is to be printed.
+func (f FilterRejectBodyPattern) Reject(res Response) bool {
+	if res.RawBody == nil {
+		return false
+	}
+
+	for _, r := range f.Pattern {
+		if r.Match(res.RawBody) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterAcceptBodyPattern filters responses based on patterns (only the body is matched).
+type FilterAcceptBodyPattern struct {
+	Pattern []*regexp.Regexp
+}
+
+// Reject decides if r is to be printed.
+func (f FilterAcceptBodyPattern) Reject(res Response) bool {
+	if res.RawBody == nil {
+		return true
+	}
+
+	for _, r := range f.Pattern {
+		if r.Match(res.RawBody) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FilterTime hides responses based on the time it took to receive them.
+type FilterTime struct {
+	rejects []func(time.Duration) bool
+	accepts []func(time.Duration) bool
+}
+
+// NewFilterTime returns a filter based on the duration of the request, see
+// parseTimeFilterSpec.
+func NewFilterTime(rejects, accepts []string) (FilterTime, error) {
+	filter := FilterTime{}
+	for _, s := range rejects {
+		f, err := parseTimeFilterSpec(s)
+		if err != nil {
+			return FilterTime{}, err
+		}
+
+		filter.rejects = append(filter.rejects, f)
+	}
+
+	for _, s := range accepts {
+		f, err := parseTimeFilterSpec(s)
+		if err != nil {
+			return FilterTime{}, err
+		}
+
+		filter.accepts = append(filter.accepts, f)
+	}
+
+	return filter, nil
+}
+
+// Reject decides if r is to be printed. Responses for requests which have not
+// been sent are not filtered.
+func (f FilterTime) Reject(r Response) bool {
+	if r.Duration == 0 {
+		return false
+	}
+
+	for _, f := range f.rejects {
+		if f(r.Duration) {
+			return true
+		}
+	}
+
+	for _, f := range f.accepts {
+		if !f(r.Duration) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTimeFilterSpec returns a function that returns true if the duration
+// matches the spec.
+//
+// possible matches:
+//   - longer: >2s, >=2s
+//   - shorter: <100ms, <=100ms
+//   - range: 1s-2s
+func parseTimeFilterSpec(spec string) (func(time.Duration) bool, error) {
+	parse := func(s string) (time.Duration, error) {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return 0, fmt.Errorf("invalid time filter %q: %v", spec, err)
+		}
+		return d, nil
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(spec, op) {
+			continue
+		}
+
+		v, err := parse(spec[len(op):])
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case ">=":
+			return func(d time.Duration) bool { return d >= v }, nil
+		case "<=":
+			return func(d time.Duration) bool { return d <= v }, nil
+		case ">":
+			return func(d time.Duration) bool { return d > v }, nil
+		default:
+			return func(d time.Duration) bool { return d < v }, nil
+		}
+	}
+
+	pos := strings.IndexByte(spec, '-')
+	if pos < 0 {
+		return nil, fmt.Errorf("invalid time filter %q, use >d, <d or d-d", spec)
+	}
+
+	v1, err := parse(spec[:pos])
+	if err != nil {
+		return nil, err
+	}
+
+	v2, err := parse(spec[pos+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return func(d time.Duration) bool { return d >= v1 && d <= v2 }, nil
+}
+
+// headerPattern matches the values of a header field.
+type headerPattern struct {
+	name    string // canonical name of the field
+	pattern *regexp.Regexp
+}
+
+// match returns true if one of the values of the field in r matches.
+func (p headerPattern) match(r Response) bool {
+	for _, v := range r.HTTPResponse.Header[p.name] {
+		if p.pattern.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHeaderFilterSpec parses a filter in the form name=regex.
+func parseHeaderFilterSpec(spec string) (headerPattern, error) {
+	pos := strings.IndexByte(spec, '=')
+	if pos <= 0 {
+		return headerPattern{}, fmt.Errorf("invalid header filter %q, use name=regex", spec)
+	}
+
+	pat, err := regexp.Compile(spec[pos+1:])
+	if err != nil {
+		return headerPattern{}, fmt.Errorf("regexp %q failed to compile: %v", spec[pos+1:], err)
+	}
+
+	return headerPattern{
+		name:    textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(spec[:pos])),
+		pattern: pat,
+	}, nil
+}
+
+// FilterHeader filters responses based on the values of header fields.
+// Responses with a field which matches one of the rejects are hidden, if
+// accepts is not empty only responses with a field matching one of them are
+// shown.
+type FilterHeader struct {
+	rejects []headerPattern
+	accepts []headerPattern
+}
+
+// NewFilterHeader returns a filter for the specs in the form name=regex.
+func NewFilterHeader(rejects, accepts []string) (FilterHeader, error) {
+	filter := FilterHeader{}
+	for _, s := range rejects {
+		p, err := parseHeaderFilterSpec(s)
+		if err != nil {
+			return FilterHeader{}, err
+		}
+
+		filter.rejects = append(filter.rejects, p)
+	}
+
+	for _, s := range accepts {
+		p, err := parseHeaderFilterSpec(s)
+		if err != nil {
+			return FilterHeader{}, err
+		}
+
+		filter.accepts = append(filter.accepts, p)
+	}
+
+	return filter, nil
+}
+
+// Reject decides if r is to be printed.
+func (f FilterHeader) Reject(r Response) bool {
+	if r.HTTPResponse == nil {
+		return len(f.accepts) > 0
+	}
+
+	for _, p := range f.rejects {
+		if p.match(r) {
+			return true
+		}
+	}
+
+	if len(f.accepts) == 0 {
+		return false
+	}
+
+	for _, p := range f.accepts {
+		if p.match(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FilterLocation filters responses based on the target of a redirect. The
+// patterns are matched against the value of the Location header and the
+// absolute URL it refers to. Responses with a Location which matches one of
+// the rejects are hidden, if accepts is not empty only responses with a
+// Location matching one of them are shown.
+type FilterLocation struct {
+	Rejects []*regexp.Regexp
+	Accepts []*regexp.Regexp
+}
+
+// locations returns the value of the Location header and the absolute URL.
+func locations(res *http.Response) []string {
+	loc := res.Header.Get("Location")
+	if loc == "" {
+		return nil
+	}
+
+	list := []string{loc}
+	if u, err := res.Location(); err == nil && u.String() != loc {
+		list = append(list, u.String())
+	}
+	return list
+}
+
+// Reject decides if r is to be printed.
+func (f FilterLocation) Reject(r Response) bool {
+	if r.HTTPResponse == nil {
+		return len(f.Accepts) > 0
+	}
+
+	locs := locations(r.HTTPResponse)
+	match := func(patterns []*regexp.Regexp) bool {
+		for _, p := range patterns {
+			for _, loc := range locs {
+				if p.MatchString(loc) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if match(f.Rejects) {
+		return true
+	}
+
+	return len(f.Accepts) > 0 && !match(f.Accepts)
+}
+
+func isJSONContentType(t string) bool {
+	return t == "application/json" || t == "text/json" || strings.HasSuffix(t, "+json")
+}
+
+func isXMLContentType(t string) bool {
+	return t == "application/xml" || t == "text/xml" || (strings.HasSuffix(t, "+xml") && t != "application/xhtml+xml")
+}
+
+func isJavaScriptContentType(t string) bool {
+	return t == "application/javascript" || t == "text/javascript" || t == "application/x-javascript"
+}
+
+// isBinaryContentType returns true for media types which are not text.
+func isBinaryContentType(t string) bool {
+	if strings.HasPrefix(t, "text/") || isJSONContentType(t) || isXMLContentType(t) || isJavaScriptContentType(t) {
+		return false
+	}
+
+	switch t {
+	case "application/xhtml+xml", "application/x-www-form-urlencoded":
+		return false
+	}
+
+	return true
+}
+
+// contentTypeClasses are the shorthands for the classes of content types.
+var contentTypeClasses = map[string]func(string) bool{
+	"html": func(t string) bool {
+		return t == "text/html" || t == "application/xhtml+xml"
+	},
+	"json":       isJSONContentType,
+	"xml":        isXMLContentType,
+	"javascript": isJavaScriptContentType,
+	"css": func(t string) bool {
+		return t == "text/css"
+	},
+	"text": func(t string) bool {
+		return strings.HasPrefix(t, "text/")
+	},
+	"image": func(t string) bool {
+		return strings.HasPrefix(t, "image/")
+	},
+	"binary": isBinaryContentType,
+}
+
+// responseContentType returns the media type of r in lower case, which is
+// detected from the body if the Content-Type header is missing.
+func responseContentType(r Response) string {
+	ct := r.HTTPResponse.Header.Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(r.RawBody)
+	}
+
+	t, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		// use the part before the parameters of invalid values
+		t = strings.TrimSpace(strings.Split(ct, ";")[0])
+	}
+
+	return strings.ToLower(t)
+}
+
+// parseContentTypeFilterSpec returns a function which returns true if a
+// media type is in the class, or is the media type in spec.
+func parseContentTypeFilterSpec(spec string) (func(string) bool, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if strings.Contains(spec, "/") {
+		return func(t string) bool { return t == spec }, nil
+	}
+
+	f, ok := contentTypeClasses[spec]
+	if !ok {
+		return nil, fmt.Errorf("unknown content type class %q, use html, json, xml, javascript, css, text, image, binary or a media type", spec)
+	}
+
+	return f, nil
+}
+
+// FilterContentType hides responses based on the class of the content type,
+// e.g. html or image.
+type FilterContentType struct {
+	rejects []func(string) bool
+	accepts []func(string) bool
+}
+
+// NewFilterContentType returns a filter for the classes or media types.
+func NewFilterContentType(rejects, accepts []string) (FilterContentType, error) {
+	filter := FilterContentType{}
+	for _, s := range rejects {
+		f, err := parseContentTypeFilterSpec(s)
+		if err != nil {
+			return FilterContentType{}, err
+		}
+
+		filter.rejects = append(filter.rejects, f)
+	}
+
+	for _, s := range accepts {
+		f, err := parseContentTypeFilterSpec(s)
+		if err != nil {
+			return FilterContentType{}, err
+		}
+
+		filter.accepts = append(filter.accepts, f)
+	}
+
+	return filter, nil
+}
+
+// Reject decides if r is to be printed.
+func (f FilterContentType) Reject(r Response) bool {
+	if r.HTTPResponse == nil {
+		return false
+	}
+
+	t := responseContentType(r)
+
+	for _, f := range f.rejects {
+		if f(t) {
+			return true
+		}
+	}
+
+	if len(f.accepts) == 0 {
+		return false
+	}
+
+	for _, f := range f.accepts {
+		if f(t) {
+			return false
+		}
+	}
+
+	return true
+}
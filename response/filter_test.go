@@ -1,6 +1,12 @@
 package response
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
 
 func TestFilterSize(t *testing.T) {
 	var tests = []struct {
@@ -41,3 +47,258 @@ func TestFilterSize(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterCount(t *testing.T) {
+	f, err := NewFilterCount([]string{"12", "100-"}, []string{"-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		words, lines int
+		reject       bool
+	}{
+		{12, 5, true},
+		{13, 5, false},
+		{150, 5, true},
+		{13, 1, true},
+		{13, 0, true},
+		{99, 2, false},
+	}
+
+	for _, test := range tests {
+		res := Response{Body: TextStats{Words: test.words, Lines: test.lines}}
+		if f.Reject(res) != test.reject {
+			t.Errorf("wrong result for %v words and %v lines, want %v", test.words, test.lines, test.reject)
+		}
+	}
+
+	_, err = NewFilterCount([]string{"x"}, nil)
+	if err == nil {
+		t.Error("expected error for invalid spec not found")
+	}
+}
+
+func TestFilterBodyPattern(t *testing.T) {
+	var tests = []struct {
+		header, body string
+		hide, show   string
+		reject       bool
+	}{
+		{"HTTP/1.1 200 OK\r\n", "login failed", "login failed", "", true},
+		{"HTTP/1.1 200 OK\r\n", "welcome", "login failed", "", false},
+		{"HTTP/1.1 200 OK\r\nX-Status: login failed\r\n", "welcome", "login failed", "", false},
+		{"HTTP/1.1 500 Error\r\n", "error in SQL syntax", "", "SQL syntax", false},
+		{"HTTP/1.1 500 Error\r\n", "internal error", "", "SQL syntax", true},
+		{"HTTP/1.1 500 Error\r\nX-Error: SQL syntax\r\n", "internal error", "", "SQL syntax", true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			res := Response{
+				RawHeader: []byte(test.header),
+				RawBody:   []byte(test.body),
+			}
+
+			var f Filter
+			if test.hide != "" {
+				f = FilterRejectBodyPattern{Pattern: []*regexp.Regexp{regexp.MustCompile(test.hide)}}
+			} else {
+				f = FilterAcceptBodyPattern{Pattern: []*regexp.Regexp{regexp.MustCompile(test.show)}}
+			}
+
+			if f.Reject(res) != test.reject {
+				t.Fatalf("wrong result for header %q and body %q, want %v", test.header, test.body, test.reject)
+			}
+		})
+	}
+}
+
+func TestFilterTime(t *testing.T) {
+	var tests = []struct {
+		spec   string
+		d      time.Duration
+		result bool
+	}{
+		{">2s", 3 * time.Second, true},
+		{">2s", 2 * time.Second, false},
+		{">=2s", 2 * time.Second, true},
+		{"<100ms", 50 * time.Millisecond, true},
+		{"<100ms", 100 * time.Millisecond, false},
+		{"<=100ms", 100 * time.Millisecond, true},
+		{"1s-2s", 999 * time.Millisecond, false},
+		{"1s-2s", time.Second, true},
+		{"1s-2s", 1500 * time.Millisecond, true},
+		{"1s-2s", 2001 * time.Millisecond, false},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			f, err := parseTimeFilterSpec(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := f(test.d)
+			if result != test.result {
+				t.Fatalf("wrong result for %q testing %v: want %v, got %v",
+					test.spec, test.d, test.result, result)
+			}
+		})
+	}
+
+	for _, spec := range []string{"2s", ">2", "<x", "1s-", "-2s"} {
+		_, err := parseTimeFilterSpec(spec)
+		if err == nil {
+			t.Errorf("expected error for %q not found", spec)
+		}
+	}
+
+	f, err := NewFilterTime([]string{"<100ms"}, []string{">50ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		d      time.Duration
+		reject bool
+	}{
+		{0, false},
+		{10 * time.Millisecond, true},
+		{80 * time.Millisecond, true},
+		{3 * time.Second, false},
+	} {
+		if f.Reject(Response{Duration: test.d}) != test.reject {
+			t.Errorf("wrong result for %v, want %v", test.d, test.reject)
+		}
+	}
+}
+
+func TestFilterHeader(t *testing.T) {
+	var tests = []struct {
+		hide, show []string
+		header     http.Header
+		reject     bool
+	}{
+		{nil, []string{"Location=^/admin"}, http.Header{"Location": {"/admin/"}}, false},
+		{nil, []string{"location=^/admin"}, http.Header{"Location": {"/login"}}, true},
+		{nil, []string{"Location=^/admin"}, http.Header{}, true},
+		{nil, []string{"Location=^/admin", "Location=^/internal"}, http.Header{"Location": {"/internal"}}, false},
+		{[]string{"Server=(?i)cloudflare"}, nil, http.Header{"Server": {"CloudFlare"}}, true},
+		{[]string{"Server=(?i)cloudflare"}, nil, http.Header{"Server": {"nginx"}}, false},
+		{[]string{"Set-Cookie=^x="}, nil, http.Header{"Set-Cookie": {"a=b", "x=y"}}, true},
+		{[]string{"Server=nginx"}, []string{"Location=."}, http.Header{"Server": {"nginx"}, "Location": {"/"}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			f, err := NewFilterHeader(test.hide, test.show)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := Response{HTTPResponse: &http.Response{Header: test.header}}
+			if f.Reject(res) != test.reject {
+				t.Fatalf("wrong result for %v, want %v", test.header, test.reject)
+			}
+		})
+	}
+
+	for _, spec := range []string{"Location", "=foo", "Location=("} {
+		_, err := NewFilterHeader([]string{spec}, nil)
+		if err == nil {
+			t.Errorf("expected error for %q not found", spec)
+		}
+	}
+}
+
+func TestFilterLocation(t *testing.T) {
+	var tests = []struct {
+		hide, show []string
+		location   string
+		reject     bool
+	}{
+		{[]string{"/login"}, nil, "/login?next=/admin", true},
+		{[]string{"^https://example\\.com/login"}, nil, "/login", true},
+		{[]string{"^/login"}, nil, "https://example.com/login", false},
+		{[]string{"/login"}, nil, "/admin/", false},
+		{[]string{"/login"}, nil, "", false},
+		{nil, []string{"^/admin"}, "/admin/", false},
+		{nil, []string{"^/admin"}, "/login", true},
+		{nil, []string{"^/admin"}, "", true},
+		{[]string{"/login"}, []string{"^/admin"}, "/admin/login", true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var f FilterLocation
+			for _, s := range test.hide {
+				f.Rejects = append(f.Rejects, regexp.MustCompile(s))
+			}
+			for _, s := range test.show {
+				f.Accepts = append(f.Accepts, regexp.MustCompile(s))
+			}
+
+			req := httptest.NewRequest("GET", "https://example.com/foo", nil)
+			res := Response{HTTPResponse: &http.Response{Header: http.Header{}, Request: req}}
+			if test.location != "" {
+				res.HTTPResponse.StatusCode = http.StatusFound
+				res.HTTPResponse.Header.Set("Location", test.location)
+			}
+
+			if f.Reject(res) != test.reject {
+				t.Fatalf("wrong result for %q, want %v", test.location, test.reject)
+			}
+		})
+	}
+}
+
+func TestFilterContentType(t *testing.T) {
+	var tests = []struct {
+		hide, show  []string
+		contentType string
+		body        string
+		reject      bool
+	}{
+		{[]string{"html"}, nil, "text/html; charset=utf-8", "", true},
+		{[]string{"html"}, nil, "application/json", "", false},
+		{[]string{"html"}, nil, "", "<!DOCTYPE html><html></html>", true},
+		{[]string{"image"}, nil, "image/png", "", true},
+		{[]string{"image", "binary"}, nil, "application/octet-stream", "", true},
+		{[]string{"binary"}, nil, "application/problem+json", "", false},
+		{[]string{"binary"}, nil, "application/pdf", "", true},
+		{[]string{"binary"}, nil, "text/plain", "", false},
+		{[]string{"application/pdf"}, nil, "Application/PDF", "", true},
+		{nil, []string{"json"}, "application/vnd.api+json", "", false},
+		{nil, []string{"json"}, "text/html", "", true},
+		{nil, []string{"json", "xml"}, "text/xml", "", false},
+		{nil, []string{"xml"}, "application/xhtml+xml", "", true},
+		{[]string{"text"}, []string{"html"}, "text/html", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			f, err := NewFilterContentType(test.hide, test.show)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := Response{
+				HTTPResponse: &http.Response{Header: http.Header{}},
+				RawBody:      []byte(test.body),
+			}
+			if test.contentType != "" {
+				res.HTTPResponse.Header.Set("Content-Type", test.contentType)
+			}
+
+			if f.Reject(res) != test.reject {
+				t.Fatalf("wrong result for %q, want %v", test.contentType, test.reject)
+			}
+		})
+	}
+
+	_, err := NewFilterContentType([]string{"video"}, nil)
+	if err == nil {
+		t.Error("expected error for unknown class not found")
+	}
+}
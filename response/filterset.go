@@ -0,0 +1,106 @@
+package response
+
+import (
+	"fmt"
+	"sync"
+)
+
+// FilterSet is a list of filters which can be changed during a run. The
+// filters are described by specs, e.g. "hide-status 404", which are turned
+// into filters by Parse. It is safe for concurrent use.
+type FilterSet struct {
+	Parse func(spec string) (Filter, error)
+
+	mu      sync.RWMutex
+	specs   []string
+	filters []Filter
+}
+
+// index returns the position of spec in the list, or -1.
+func (s *FilterSet) index(spec string) int {
+	for i, existing := range s.specs {
+		if existing == spec {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add adds the filters for specs. Nothing is added if one of the specs is
+// invalid or already exists.
+func (s *FilterSet) Add(specs ...string) error {
+	filters := make([]Filter, 0, len(specs))
+	for i, spec := range specs {
+		for _, prev := range specs[:i] {
+			if prev == spec {
+				return fmt.Errorf("filter %q specified twice", spec)
+			}
+		}
+
+		f, err := s.Parse(spec)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, f)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, spec := range specs {
+		if s.index(spec) >= 0 {
+			return fmt.Errorf("filter %q already exists", spec)
+		}
+	}
+
+	s.specs = append(s.specs, specs...)
+	s.filters = append(s.filters, filters...)
+	return nil
+}
+
+// Remove removes the filters for specs. Nothing is removed if one of the
+// specs is not found.
+func (s *FilterSet) Remove(specs ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, spec := range specs {
+		if s.index(spec) < 0 {
+			return fmt.Errorf("filter %q not found", spec)
+		}
+	}
+
+	for _, spec := range specs {
+		i := s.index(spec)
+		if i < 0 {
+			// spec was listed twice and is already removed
+			continue
+		}
+		s.specs = append(s.specs[:i:i], s.specs[i+1:]...)
+		s.filters = append(s.filters[:i:i], s.filters[i+1:]...)
+	}
+
+	return nil
+}
+
+// Specs returns the specs of the filters.
+func (s *FilterSet) Specs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]string{}, s.specs...)
+}
+
+// Reject decides if r is to be printed.
+func (s *FilterSet) Reject(r Response) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, f := range s.filters {
+		if f.Reject(r) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package response
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilterSet(t *testing.T) {
+	set := &FilterSet{
+		Parse: func(spec string) (Filter, error) {
+			return ParseFilterExpr(spec)
+		},
+	}
+
+	res := Response{HTTPResponse: &http.Response{StatusCode: 404}}
+	if set.Reject(res) {
+		t.Fatal("response rejected by empty set")
+	}
+
+	// the expressions describe the responses which are shown
+	for _, spec := range []string{"status == 200", "size > 10"} {
+		err := set.Add(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !set.Reject(res) {
+		t.Fatal("response not rejected")
+	}
+
+	err := set.Add("status == 200")
+	if err == nil {
+		t.Fatal("expected error for duplicate filter not found")
+	}
+
+	err = set.Add("status ==")
+	if err == nil {
+		t.Fatal("expected error for invalid filter not found")
+	}
+
+	// nothing is added if one of the specs is invalid
+	err = set.Add("status == 500", "status ==")
+	if err == nil {
+		t.Fatal("expected error for invalid filter not found")
+	}
+
+	// nothing is removed if one of the specs is missing
+	err = set.Remove("status == 200", "status == 500")
+	if err == nil {
+		t.Fatal("expected error for removing a missing filter not found")
+	}
+
+	want := []string{"status == 200", "size > 10"}
+	if !cmp.Equal(want, set.Specs()) {
+		t.Fatal(cmp.Diff(want, set.Specs()))
+	}
+
+	err = set.Remove("status == 200")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = []string{"size > 10"}
+	if !cmp.Equal(want, set.Specs()) {
+		t.Fatal(cmp.Diff(want, set.Specs()))
+	}
+
+	res.Body.Bytes = 20
+	if set.Reject(res) {
+		t.Fatal("response rejected by removed filter")
+	}
+
+	err = set.Remove("status == 200")
+	if err == nil {
+		t.Fatal("expected error for removing a missing filter not found")
+	}
+}
@@ -0,0 +1,277 @@
+package response
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// HeaderOrderTransport sends requests via HTTP/1.1 (or HTTP/1.0 if set in the
+// request) with its own request writer, so the order and spelling of the header fields is preserved. The
+// fields named in Order are sent first, in this order and with this spelling.
+// No header fields are added automatically except for Host and
+// Content-Length (or Transfer-Encoding), which are omitted if they are
+// contained in Omit. A new connection is used for each request.
+//
+// Chunked bodies are sent in chunks of ChunkSize bytes (if set), and
+// ChunkExtension is appended to the size of each chunk.
+type HeaderOrderTransport struct {
+	Transport *http.Transport // used for the dial function and TLS configuration
+	Order     []string
+	Omit      []string
+
+	ChunkSize      int
+	ChunkExtension string
+}
+
+// NewHeaderOrderTransport returns a transport which sends the header fields
+// in the order configured in the template, see HeaderOrderTransport. Fields
+// removed in the template (e.g. Host) are omitted.
+func NewHeaderOrderTransport(tr *http.Transport, template *request.Request) *HeaderOrderTransport {
+	t := &HeaderOrderTransport{
+		Transport: tr,
+		Order:     template.HeaderOrder,
+
+		ChunkSize:      template.ChunkSize,
+		ChunkExtension: template.ChunkExtension,
+	}
+
+	for name := range template.Header.Remove {
+		t.Omit = append(t.Omit, name)
+	}
+
+	return t
+}
+
+// headerField is a header field in the order it is sent.
+type headerField struct {
+	name   string
+	values []string
+}
+
+// orderHeader returns the header fields of req in the order they are sent.
+func (t *HeaderOrderTransport) orderHeader(req *http.Request) []headerField {
+	omit := make(map[string]bool)
+	for _, name := range t.Omit {
+		omit[textproto.CanonicalMIMEHeaderKey(name)] = true
+	}
+
+	// the fields net/http would add for framing the request
+	auto := make(http.Header)
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	auto.Set("Host", removeZone(host))
+
+	switch {
+	case req.Body != nil && req.ContentLength < 0:
+		auto.Set("Transfer-Encoding", "chunked")
+	case req.Body != nil && req.ContentLength > 0:
+		auto.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10))
+	case req.Method == http.MethodPost || req.Method == http.MethodPut || req.Method == http.MethodPatch:
+		auto.Set("Content-Length", "0")
+	}
+
+	// removed fields are not sent, e.g. User-Agent, which is set to the empty
+	// string for net/http
+	hdr := req.Header.Clone()
+	for name := range omit {
+		hdr.Del(name)
+	}
+
+	// header fields set in the request replace automatic ones
+	for name := range auto {
+		if _, ok := hdr[name]; ok || omit[name] {
+			auto.Del(name)
+		}
+	}
+
+	var fields []headerField
+	used := make(map[string]bool)
+	take := func(name string, h http.Header, key string) bool {
+		vs, ok := h[key]
+		if !ok || used[key] {
+			return false
+		}
+		used[key] = true
+		fields = append(fields, headerField{name: name, values: vs})
+		return true
+	}
+
+	for _, name := range t.Order {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		_ = take(name, hdr, name) || take(name, hdr, key) || take(name, auto, key)
+	}
+
+	if !used["Host"] {
+		take("Host", auto, "Host")
+	}
+
+	var names []string
+	for name := range hdr {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		take(name, hdr, name)
+	}
+
+	take("Content-Length", auto, "Content-Length")
+	take("Transfer-Encoding", auto, "Transfer-Encoding")
+
+	return fields
+}
+
+// WriteRequest writes req to wr as it is sent by t.
+func (t *HeaderOrderTransport) WriteRequest(wr io.Writer, req *http.Request) error {
+	bw := bufio.NewWriter(wr)
+
+	proto := "HTTP/1.1"
+	if req.ProtoMajor == 1 && req.ProtoMinor == 0 {
+		proto = "HTTP/1.0"
+	}
+
+	_, err := fmt.Fprintf(bw, "%s %s %s\r\n", req.Method, req.URL.RequestURI(), proto)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range t.orderHeader(req) {
+		for _, v := range field.values {
+			_, err = fmt.Fprintf(bw, "%s: %s\r\n", field.name, v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = bw.WriteString("\r\n")
+	if err != nil {
+		return err
+	}
+
+	if req.Body != nil {
+		if req.ContentLength < 0 {
+			err = writeChunked(bw, req.Body, t.ChunkSize, t.ChunkExtension)
+		} else {
+			_, err = io.Copy(bw, req.Body)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// writeChunked writes body with chunked encoding in chunks of size bytes (or
+// less for the last one), ext is appended to the size of each chunk.
+func writeChunked(wr io.Writer, body io.Reader, size int, ext string) error {
+	if size <= 0 {
+		size = 32 * 1024
+	}
+
+	buf := make([]byte, size)
+	for {
+		n, err := io.ReadFull(body, buf)
+		if n > 0 {
+			_, werr := fmt.Fprintf(wr, "%x%s\r\n%s\r\n", n, ext, buf[:n])
+			if werr != nil {
+				return werr
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(wr, "0%s\r\n\r\n", ext)
+	return err
+}
+
+// connBody closes the connection when the response body is closed.
+type connBody struct {
+	io.ReadCloser
+	conn net.Conn
+	once sync.Once
+	done chan struct{}
+}
+
+func (b *connBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		close(b.done)
+		_ = b.conn.Close()
+	})
+	return err
+}
+
+// RoundTrip sends the request on a new connection.
+func (t *HeaderOrderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+
+	conn, err := dialConn(req.Context(), req, t.Transport)
+	if err != nil {
+		return nil, err
+	}
+
+	// close the connection when the context is cancelled
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-req.Context().Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	fail := func(err error) (*http.Response, error) {
+		close(done)
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if t.Transport.ResponseHeaderTimeout > 0 {
+		err = conn.SetDeadline(time.Now().Add(t.Transport.ResponseHeaderTimeout))
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	err = t.WriteRequest(conn, req)
+	if err != nil {
+		return fail(err)
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return fail(err)
+	}
+
+	err = conn.SetDeadline(time.Time{})
+	if err != nil {
+		_ = res.Body.Close()
+		return fail(err)
+	}
+
+	res.Body = &connBody{ReadCloser: res.Body, conn: conn, done: done}
+	return res, nil
+}
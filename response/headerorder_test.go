@@ -0,0 +1,184 @@
+package response
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// rawServer accepts a single connection, returns the raw request header and
+// body on the channel and answers with status 200.
+func rawServer(t *testing.T) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan string, 1)
+	go func() {
+		defer ln.Close()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		rd := textproto.NewReader(bufio.NewReader(conn))
+		var lines []string
+		for {
+			line, err := rd.ReadLine()
+			if err != nil {
+				return
+			}
+			if line == "" {
+				break
+			}
+			lines = append(lines, line)
+		}
+
+		for _, line := range lines {
+			if strings.HasPrefix(line, "Content-Length: ") {
+				n, _ := strconv.Atoi(line[len("Content-Length: "):])
+				buf := make([]byte, n)
+				_, _ = io.ReadFull(rd.R, buf)
+				lines = append(lines, "", string(buf))
+			}
+		}
+
+		ch <- strings.Join(lines, "\n")
+		_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+	}()
+
+	return ln.Addr().String(), ch
+}
+
+func TestHeaderOrder(t *testing.T) {
+	var tests = []struct {
+		method  string
+		order   []string
+		header  []string
+		body    string
+		http10  bool
+		minimal bool
+		want    string
+	}{
+		{
+			order:  []string{"user-agent", "X-Custom", "HOST", "accept"},
+			header: []string{"X-Custom: foo", "X-Other: bar"},
+			want: "GET /path?x=1 HTTP/1.1\n" +
+				"user-agent: monsoon\n" +
+				"X-Custom: foo\n" +
+				"HOST: ADDR\n" +
+				"accept: */*\n" +
+				"X-Other: bar",
+		},
+		{
+			method: "POST",
+			order:  []string{"Content-Length", "content-type"},
+			header: []string{"Host", "Accept", "User-Agent", "Content-Type: text/plain"},
+			body:   "FUZZ",
+			want: "POST /path?x=1 HTTP/1.1\n" +
+				"Content-Length: 4\n" +
+				"content-type: text/plain\n" +
+				"\n" +
+				"test",
+		},
+		{
+			header:  []string{"X-Custom: foo"},
+			http10:  true,
+			minimal: true,
+			want: "GET /path?x=1 HTTP/1.0\n" +
+				"Host: ADDR\n" +
+				"X-Custom: foo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			addr, ch := rawServer(t)
+
+			tmpl := request.New("")
+			tmpl.URL = "http://" + addr + "/path?x=1"
+			tmpl.HeaderOrder = test.order
+			tmpl.Method = test.method
+			tmpl.Body = test.body
+			tmpl.HTTP10 = test.http10
+			tmpl.Minimal = test.minimal
+			for _, h := range test.header {
+				err := tmpl.Header.Set(h)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tr, err := NewTransport(tmpl, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := tmpl.Apply([]string{"FUZZ"}, []string{"test"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := NewRunner(tr, tmpl, nil, nil).Client.Do(req.WithContext(context.Background()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = res.Body.Close()
+
+			if res.StatusCode != http.StatusOK || string(body) != "ok" {
+				t.Errorf("unexpected response %v %q", res.Status, body)
+			}
+
+			want := strings.Replace(test.want, "ADDR", addr, 1)
+			if got := <-ch; got != want {
+				t.Errorf("wrong request, want:\n%s\ngot:\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestWriteChunked(t *testing.T) {
+	var tests = []struct {
+		body string
+		size int
+		ext  string
+		want string
+	}{
+		{"", 0, "", "0\r\n\r\n"},
+		{"foobar", 0, "", "6\r\nfoobar\r\n0\r\n\r\n"},
+		{"foobar", 4, "", "4\r\nfoob\r\n2\r\nar\r\n0\r\n\r\n"},
+		{"foo", 1, ";x=y", "1;x=y\r\nf\r\n1;x=y\r\no\r\n1;x=y\r\no\r\n0;x=y\r\n\r\n"},
+		{strings.Repeat("a", 20), 16, " ", "10 \r\n" + strings.Repeat("a", 16) + "\r\n4 \r\naaaa\r\n0 \r\n\r\n"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var buf strings.Builder
+			err := writeChunked(&buf, strings.NewReader(test.body), test.size, test.ext)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != test.want {
+				t.Errorf("wrong chunked body, want %q, got %q", test.want, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package response
+
+import (
+	"context"
+	"sync"
+)
+
+// HostLimiter limits the number of concurrent requests per host, it is shared
+// by all runners.
+type HostLimiter struct {
+	max int
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// NewHostLimiter returns a limiter which allows max concurrent requests per
+// host.
+func NewHostLimiter(max int) *HostLimiter {
+	return &HostLimiter{
+		max:   max,
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+// Acquire waits until a request to host can be sent. The returned function
+// must be called when the request is done.
+func (l *HostLimiter) Acquire(ctx context.Context, host string) (release func(), err error) {
+	l.mu.Lock()
+	slots, ok := l.slots[host]
+	if !ok {
+		slots = make(chan struct{}, l.max)
+		l.slots[host] = slots
+	}
+	l.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,68 @@
+package response
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	l := NewHostLimiter(2)
+
+	var mu sync.Mutex
+	current := make(map[string]int)
+	max := make(map[string]int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		host := []string{"a:80", "b:80"}[i%2]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			release, err := l.Acquire(context.Background(), host)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mu.Lock()
+			current[host]++
+			if current[host] > max[host] {
+				max[host] = current[host]
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			current[host]--
+			mu.Unlock()
+
+			release()
+		}()
+	}
+	wg.Wait()
+
+	for host, n := range max {
+		if n > 2 {
+			t.Errorf("host %v: %d concurrent requests", host, n)
+		}
+	}
+
+	// the context is checked while waiting
+	release1, _ := l.Acquire(context.Background(), "c:80")
+	release2, _ := l.Acquire(context.Background(), "c:80")
+	defer release1()
+	defer release2()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := l.Acquire(ctx, "c:80")
+	if err != context.DeadlineExceeded {
+		t.Errorf("want error %v, got %v", context.DeadlineExceeded, err)
+	}
+}
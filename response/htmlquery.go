@@ -0,0 +1,415 @@
+package response
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlAttrCondition tests an attribute of an element.
+type htmlAttrCondition struct {
+	name     string
+	value    string
+	hasValue bool // otherwise the attribute must only be present
+}
+
+// htmlStep selects elements relative to a context node.
+type htmlStep struct {
+	descendant bool   // select descendants, otherwise children
+	tag        string // empty or * for all elements
+	id         string
+	classes    []string
+	attrs      []htmlAttrCondition
+	index      int // position among the matching children, starting at 1
+}
+
+// HTMLQuery selects values from HTML documents: the text of elements or the
+// value of an attribute. It is parsed from a CSS selector or an XPath
+// expression, see ParseCSSSelector and ParseXPath.
+type HTMLQuery struct {
+	expr  string
+	steps []htmlStep
+	attr  string // attribute returned, the text is returned if empty
+}
+
+func (q *HTMLQuery) String() string {
+	return q.expr
+}
+
+var (
+	cssCompoundRegexp = regexp.MustCompile(`^([a-zA-Z0-9*-]*)((?:[#.][a-zA-Z0-9_-]+|\[[^\]]+\])*)$`)
+	cssPartRegexp     = regexp.MustCompile(`[#.][a-zA-Z0-9_-]+|\[[^\]]+\]`)
+)
+
+// parseAttrCondition parses name or name=value, the value may be quoted.
+func parseAttrCondition(s string) (htmlAttrCondition, error) {
+	s = strings.TrimSpace(s)
+	pos := strings.IndexByte(s, '=')
+	if pos < 0 {
+		if s == "" {
+			return htmlAttrCondition{}, fmt.Errorf("empty attribute name")
+		}
+		return htmlAttrCondition{name: s}, nil
+	}
+
+	cond := htmlAttrCondition{
+		name:     strings.TrimSpace(s[:pos]),
+		value:    strings.TrimSpace(s[pos+1:]),
+		hasValue: true,
+	}
+	if len(cond.value) >= 2 && (cond.value[0] == '\'' || cond.value[0] == '"') && cond.value[len(cond.value)-1] == cond.value[0] {
+		cond.value = cond.value[1 : len(cond.value)-1]
+	}
+	if cond.name == "" {
+		return htmlAttrCondition{}, fmt.Errorf("empty attribute name")
+	}
+
+	return cond, nil
+}
+
+// ParseCSSSelector parses a CSS selector consisting of elements with tag
+// names, #id, .class and attribute conditions ([name] or [name=value]),
+// combined with descendant (space) and child (>) combinators, e.g.
+// "table#users td.name" or "form > input[name=token]". The value of the
+// elements is returned (see Values), or the value of the attribute appended
+// with @, e.g. "a.download@href".
+func ParseCSSSelector(s string) (*HTMLQuery, error) {
+	q := &HTMLQuery{expr: s}
+
+	invalid := func(msg string) (*HTMLQuery, error) {
+		return nil, fmt.Errorf("invalid selector %q: %v", s, msg)
+	}
+
+	sel := s
+	if pos := strings.LastIndexByte(sel, '@'); pos >= 0 && !strings.ContainsAny(sel[pos:], "]") {
+		q.attr = strings.TrimSpace(sel[pos+1:])
+		sel = sel[:pos]
+		if q.attr == "" {
+			return invalid("empty attribute name")
+		}
+	}
+
+	// separate the child combinator from the compound selectors
+	fields := strings.Fields(strings.Replace(sel, ">", " > ", -1))
+	if len(fields) == 0 {
+		return invalid("empty selector")
+	}
+
+	child := false
+	for i, field := range fields {
+		if field == ">" {
+			if child || i == 0 || i == len(fields)-1 {
+				return invalid("misplaced >")
+			}
+			child = true
+			continue
+		}
+
+		m := cssCompoundRegexp.FindStringSubmatch(field)
+		if m == nil || (m[1] == "" && m[2] == "") {
+			return invalid(fmt.Sprintf("unsupported selector %q", field))
+		}
+
+		step := htmlStep{
+			descendant: !child,
+			tag:        strings.ToLower(m[1]),
+		}
+		child = false
+
+		for _, part := range cssPartRegexp.FindAllString(m[2], -1) {
+			switch part[0] {
+			case '#':
+				step.id = part[1:]
+			case '.':
+				step.classes = append(step.classes, part[1:])
+			default:
+				cond, err := parseAttrCondition(part[1 : len(part)-1])
+				if err != nil {
+					return invalid(err.Error())
+				}
+				step.attrs = append(step.attrs, cond)
+			}
+		}
+
+		q.steps = append(q.steps, step)
+	}
+
+	return q, nil
+}
+
+// splitXPath splits an XPath expression at slashes which are not contained
+// in predicates or quoted strings.
+func splitXPath(s string) (parts []string) {
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0 && s[i] == quote:
+			quote = 0
+		case quote != 0:
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case s[i] == '[':
+			depth++
+		case s[i] == ']':
+			depth--
+		case s[i] == '/' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+var xpathStepRegexp = regexp.MustCompile(`^([a-zA-Z0-9*-]+)((?:\[[^\]]+\])*)$`)
+
+// ParseXPath parses an absolute XPath expression consisting of steps with
+// tag names (or *) separated by / (children) or // (descendants). Each step
+// may have predicates: a position ([2]) or an attribute condition ([@name] or
+// [@name='value']). The value of the selected elements is returned (see
+// Values), or the value of an attribute if the last step is @name, e.g.
+// "//table/tr[2]/td" or "//a[@class='download']/@href". A last step text()
+// is ignored.
+func ParseXPath(s string) (*HTMLQuery, error) {
+	q := &HTMLQuery{expr: s}
+
+	invalid := func(msg string) (*HTMLQuery, error) {
+		return nil, fmt.Errorf("invalid XPath %q: %v", s, msg)
+	}
+
+	if !strings.HasPrefix(s, "/") {
+		return invalid("expression must start with /")
+	}
+
+	parts := splitXPath(s)[1:]
+	descendant := false
+	for i, part := range parts {
+		if part == "" {
+			if descendant || i == len(parts)-1 {
+				return invalid("empty step")
+			}
+			descendant = true
+			continue
+		}
+
+		if i == len(parts)-1 {
+			if part == "text()" {
+				break
+			}
+			if strings.HasPrefix(part, "@") {
+				q.attr = part[1:]
+				if q.attr == "" {
+					return invalid("empty attribute name")
+				}
+				break
+			}
+		}
+
+		m := xpathStepRegexp.FindStringSubmatch(part)
+		if m == nil {
+			return invalid(fmt.Sprintf("unsupported step %q", part))
+		}
+
+		step := htmlStep{
+			descendant: descendant,
+			tag:        strings.ToLower(m[1]),
+		}
+		descendant = false
+
+		for _, pred := range cssPartRegexp.FindAllString(m[2], -1) {
+			pred = strings.TrimSpace(pred[1 : len(pred)-1])
+			if strings.HasPrefix(pred, "@") {
+				cond, err := parseAttrCondition(pred[1:])
+				if err != nil {
+					return invalid(err.Error())
+				}
+				step.attrs = append(step.attrs, cond)
+				continue
+			}
+
+			n, err := strconv.Atoi(pred)
+			if err != nil || n < 1 {
+				return invalid(fmt.Sprintf("unsupported predicate %q", pred))
+			}
+			step.index = n
+		}
+
+		q.steps = append(q.steps, step)
+	}
+
+	if len(q.steps) == 0 {
+		return invalid("no elements selected")
+	}
+
+	return q, nil
+}
+
+// attribute returns the value of the attribute key of n.
+func attribute(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// match returns true if the element n matches the step (except for the
+// position).
+func (step htmlStep) match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+
+	if step.tag != "" && step.tag != "*" && n.Data != step.tag {
+		return false
+	}
+
+	if step.id != "" {
+		if id, _ := attribute(n, "id"); id != step.id {
+			return false
+		}
+	}
+
+	if len(step.classes) > 0 {
+		class, _ := attribute(n, "class")
+		classes := strings.Fields(class)
+		for _, want := range step.classes {
+			found := false
+			for _, c := range classes {
+				if c == want {
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+
+	for _, cond := range step.attrs {
+		v, ok := attribute(n, cond.name)
+		if !ok || (cond.hasValue && v != cond.value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// children returns the children of n which match the step.
+func (step htmlStep) children(n *html.Node) (res []*html.Node) {
+	pos := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if !step.match(c) {
+			continue
+		}
+
+		pos++
+		if step.index == 0 || step.index == pos {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// walk calls fn for n and all its descendants in document order.
+func walk(n *html.Node, fn func(*html.Node)) {
+	fn(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+// find returns the elements in doc selected by q.
+func (q *HTMLQuery) find(doc *html.Node) []*html.Node {
+	cur := []*html.Node{doc}
+	for _, step := range q.steps {
+		var next []*html.Node
+		seen := make(map[*html.Node]bool)
+		add := func(nodes []*html.Node) {
+			for _, n := range nodes {
+				if !seen[n] {
+					seen[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+
+		for _, n := range cur {
+			if !step.descendant {
+				add(step.children(n))
+				continue
+			}
+
+			walk(n, func(d *html.Node) {
+				add(step.children(d))
+			})
+		}
+		cur = next
+	}
+
+	return cur
+}
+
+// text returns the text contained in n with whitespace collapsed.
+func text(n *html.Node) string {
+	var buf bytes.Buffer
+	walk(n, func(d *html.Node) {
+		if d.Type == html.TextNode {
+			buf.WriteString(d.Data)
+			buf.WriteByte(' ')
+		}
+	})
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// defaultAttributes are the attributes returned for elements which hold their
+// value in an attribute instead of the text, unless another attribute is
+// selected.
+var defaultAttributes = map[string]string{
+	"input": "value",
+	"meta":  "content",
+}
+
+// Values returns the attribute selected by q or the value of the elements
+// selected in doc: the value attribute for input elements, the content for
+// meta elements, and the text for all others.
+func (q *HTMLQuery) Values(doc *html.Node) (values []string) {
+	for _, n := range q.find(doc) {
+		attr := q.attr
+		if attr == "" {
+			attr = defaultAttributes[n.Data]
+		}
+
+		if attr == "" {
+			values = append(values, text(n))
+			continue
+		}
+
+		if v, ok := attribute(n, attr); ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// ExtractHTML extracts the values selected by the queries from the HTML body.
+func (r *Response) ExtractHTML(queries []*HTMLQuery) {
+	if len(queries) == 0 {
+		return
+	}
+
+	doc, err := html.Parse(bytes.NewReader(r.RawBody))
+	if err != nil {
+		return
+	}
+
+	for _, q := range queries {
+		r.Extract = append(r.Extract, q.Values(doc)...)
+	}
+}
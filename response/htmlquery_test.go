@@ -0,0 +1,114 @@
+package response
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+const testHTMLDocument = `<!DOCTYPE html>
+<html><head><title>  User
+  list </title><meta name="csrf-token" content="abc123"></head>
+<body>
+<form action="/save"><input type="hidden" name="token" value="t0k3n"><input name="q"></form>
+<table id="users">
+<tr><th>Name</th><th>Role</th></tr>
+<tr class="row odd"><td class="name">alice</td><td>admin</td></tr>
+<tr class="row"><td class="name">bob</td><td>user</td></tr>
+</table>
+<a class="download" href="/file/1">one</a> <a href="/file/2">two</a>
+<div><p>first <b>bold</b></p><span><p>nested</p></span></div>
+</body></html>`
+
+func TestHTMLQuery(t *testing.T) {
+	var tests = []struct {
+		css, xpath string
+		want       []string
+	}{
+		{"title", "/html/head/title", []string{"User list"}},
+		{"meta[name=csrf-token]@content", "//meta[@name='csrf-token']/@content", []string{"abc123"}},
+		{"form > input[type=hidden]@value", "//form/input[@type=\"hidden\"]/@value", []string{"t0k3n"}},
+		{"input@name", "//input/@name", []string{"token", "q"}},
+		{"meta[name=csrf-token]", "//meta[@name='csrf-token']", []string{"abc123"}},
+		{"form > input", "//form/input", []string{"t0k3n"}},
+		{"table#users td.name", "//table[@id='users']//td[@class='name']/text()", []string{"alice", "bob"}},
+		{"tr.row.odd td", "//tr[@class='row odd']/td", []string{"alice", "admin"}},
+		{"a.download@href", "//a[@class='download']/@href", []string{"/file/1"}},
+		{"a@href", "//a/@href", []string{"/file/1", "/file/2"}},
+		{"div > p", "/html/body/div/p", []string{"first bold"}},
+		{"div p", "//div//p", []string{"first bold", "nested"}},
+		{"", "//tr[3]/td[1]", []string{"bob"}},
+		{"", "//td[2]", []string{"admin", "user"}},
+		{"table th@class", "//th/@class", nil},
+		{"video", "//video", nil},
+	}
+
+	doc := parseHTML(t, testHTMLDocument)
+
+	for _, test := range tests {
+		if test.css != "" {
+			t.Run(test.css, func(t *testing.T) {
+				q, err := ParseCSSSelector(test.css)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				res := q.Values(doc)
+				if !cmp.Equal(test.want, res) {
+					t.Fatal(cmp.Diff(test.want, res))
+				}
+			})
+		}
+
+		t.Run(test.xpath, func(t *testing.T) {
+			q, err := ParseXPath(test.xpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := q.Values(doc)
+			if !cmp.Equal(test.want, res) {
+				t.Fatal(cmp.Diff(test.want, res))
+			}
+		})
+	}
+
+	for _, s := range []string{"", "@value", "> a", "a >", "a@", "a:hover", "p[=x]"} {
+		_, err := ParseCSSSelector(s)
+		if err == nil {
+			t.Errorf("expected error for selector %q not found", s)
+		}
+	}
+
+	for _, s := range []string{"a", "/", "//", "//a/@", "//a[0]", "//a[contains(@href, 'x')]", "//a/b()"} {
+		_, err := ParseXPath(s)
+		if err == nil {
+			t.Errorf("expected error for XPath %q not found", s)
+		}
+	}
+}
+
+func TestExtractHTML(t *testing.T) {
+	q, err := ParseCSSSelector("title")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := Response{RawBody: []byte(testHTMLDocument)}
+	res.ExtractHTML([]*HTMLQuery{q})
+
+	want := []string{"User list"}
+	if !cmp.Equal(want, res.Extract) {
+		t.Fatal(cmp.Diff(want, res.Extract))
+	}
+}
+
+func parseHTML(t testing.TB, s string) *html.Node {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
@@ -0,0 +1,165 @@
+package response
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// connectionHeaders are not allowed in HTTP/2 requests (RFC 7540, section
+// 8.1.2.2), they are removed before a request is sent.
+var connectionHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// http2RoundTripper sends requests via HTTP/2 and removes connection-specific
+// header fields, e.g. from requests loaded from a template file.
+type http2RoundTripper struct {
+	*http2.Transport
+}
+
+// RoundTrip sends the request.
+func (rt http2RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var remove []string
+	for _, name := range connectionHeaders {
+		if _, ok := req.Header[name]; ok {
+			remove = append(remove, name)
+		}
+	}
+
+	// TE is only allowed with the value "trailers"
+	if te := req.Header.Get("Te"); te != "" && te != "trailers" {
+		remove = append(remove, "Te")
+	}
+
+	if len(remove) > 0 {
+		// the request passed in must not be modified
+		req = req.Clone(req.Context())
+		for _, name := range remove {
+			req.Header.Del(name)
+		}
+	}
+
+	return rt.Transport.RoundTrip(req)
+}
+
+// tlsHandshake runs the TLS client handshake on conn. The handshake is aborted
+// when ctx is cancelled or its deadline expires. On error, conn is closed.
+func tlsHandshake(ctx context.Context, conn net.Conn, cfg *tls.Config) (*tls.Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		err := conn.SetDeadline(deadline)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	// close the connection when ctx is cancelled during the handshake
+	done := make(chan struct{})
+	aborted := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+			aborted <- true
+		case <-done:
+			aborted <- false
+		}
+	}()
+
+	tlsConn := tls.Client(conn, cfg)
+	err := tlsConn.Handshake()
+	close(done)
+
+	if <-aborted {
+		return nil, ctx.Err()
+	}
+
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	// reset the deadline for the requests sent on the connection
+	err = conn.SetDeadline(time.Time{})
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// configureHTTP2Only configures tr to send all requests via HTTP/2: with TLS (h2) for
+// https URLs, and in cleartext with prior knowledge (h2c) for http URLs. The
+// pseudo-header fields are filled in from the request, the Host header is
+// sent as :authority. Requests sent by several goroutines are multiplexed as
+// concurrent streams on one connection, up to the limit announced by the
+// server. Proxies are not supported. The returned function closes the idle
+// connections of the HTTP/2 transports.
+//
+// The HTTP/2 transport does not pass the context of the request to the dial
+// function, so connecting is limited by connectTimeout and the TLS handshake
+// by the TLSHandshakeTimeout of tr instead.
+func configureHTTP2Only(tr *http.Transport, connectTimeout time.Duration) (closeIdle func()) {
+	cfg := tr.TLSClientConfig.Clone()
+	cfg.NextProtos = []string{http2.NextProtoTLS}
+
+	dial := func(network, addr string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+		defer cancel()
+
+		return tr.DialContext(ctx, network, addr)
+	}
+
+	// connections are opened with the dial function of tr, which may connect
+	// to a different address (see connectTo)
+	h2 := &http2.Transport{
+		TLSClientConfig: cfg,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			conn, err := dial(network, addr)
+			if err != nil {
+				return nil, err
+			}
+
+			ctx := context.Background()
+			if tr.TLSHandshakeTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tr.TLSHandshakeTimeout)
+				defer cancel()
+			}
+
+			tlsConn, err := tlsHandshake(ctx, conn, cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			return tlsConn, nil
+		},
+	}
+
+	h2c := &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dial(network, addr)
+		},
+	}
+
+	tr.RegisterProtocol("https", http2RoundTripper{h2})
+	tr.RegisterProtocol("http", http2RoundTripper{h2c})
+
+	// tr.CloseIdleConnections does not close the connections of registered
+	// protocols
+	return func() {
+		h2.CloseIdleConnections()
+		h2c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,188 @@
+package response
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/request"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestForceHTTP2(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Proto, r.Host)
+	})
+
+	h2cServer := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer h2cServer.Close()
+
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	tr, err := NewTransport(&request.Request{Insecure: true, ForceHTTP2: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: tr}
+
+	for _, url := range []string{h2cServer.URL, tlsServer.URL} {
+		t.Run("", func(t *testing.T) {
+			req, err := http.NewRequest("GET", url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// connection-specific headers are removed
+			req.Header.Set("Connection", "close")
+			req.Header.Set("Keep-Alive", "300")
+			req.Host = "vhost.example.com"
+
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = res.Body.Close()
+
+			want := "HTTP/2.0 vhost.example.com"
+			if string(buf) != want {
+				t.Errorf("wrong response, want %q, got %q", want, buf)
+			}
+
+			if req.Header.Get("Connection") != "close" {
+				t.Errorf("request header has been modified")
+			}
+		})
+	}
+}
+
+func TestForceHTTP2PerValue(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Proto, r.TLS.ServerName)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	tmpl := request.New("")
+	tmpl.URL = srv.URL + "/"
+	tmpl.Insecure = true
+	tmpl.ForceHTTP2 = true
+	tmpl.SNI = "FUZZ.example.com"
+
+	tr, err := NewTransport(tmpl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make(chan producer.Item, 1)
+	in <- producer.Item{Values: []string{"foo"}}
+	close(in)
+	out := make(chan Response, 1)
+
+	runner := NewRunner(tr, tmpl, in, out)
+	runner.SNIPerValue = true
+	runner.Run(context.Background())
+
+	res := <-out
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	want := "HTTP/2.0 foo.example.com"
+	if string(res.RawBody) != want {
+		t.Errorf("wrong response, want %q, got %q", want, res.RawBody)
+	}
+}
+
+func TestForceHTTP2Disabled(t *testing.T) {
+	_, err := NewTransport(&request.Request{DisableHTTP2: true, ForceHTTP2: true}, nil)
+	if err == nil {
+		t.Fatal("expected error not found")
+	}
+}
+
+func TestTLSHandshakeCancel(t *testing.T) {
+	// the server accepts the connection but never answers the handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(ioutil.Discard, conn)
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = tlsHandshake(ctx, conn, &tls.Config{InsecureSkipVerify: true})
+	if err == nil {
+		t.Fatal("expected error not found")
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("handshake was not aborted in time")
+	}
+}
+
+func TestForceHTTP2HandshakeTimeout(t *testing.T) {
+	// the server accepts the connection but never answers the handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(ioutil.Discard, conn)
+	}()
+
+	tr, err := NewTransport(&request.Request{ForceHTTP2: true, Insecure: true, TLSTimeout: 100 * time.Millisecond}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = (&http.Client{Transport: tr}).Get("https://" + ln.Addr().String() + "/")
+	if err == nil {
+		t.Fatal("expected error not found")
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("handshake was not aborted after the TLS timeout")
+	}
+}
@@ -0,0 +1,389 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is one step of a JSONPath expression.
+type jsonPathStep struct {
+	name      string // key of an object
+	index     int    // index into an array (negative counts from the end)
+	isIndex   bool
+	wildcard  bool // all members of an object or elements of an array
+	recursive bool // apply the step to all descendants as well
+}
+
+// JSONPath is a parsed JSONPath expression. The supported syntax is a subset
+// of JSONPath: the root $, members (.name or ['name']), array indexes ([0],
+// [-1]), wildcards (.* or [*]) and recursive descent (..name), e.g.
+// $.items[*].id or $..error.
+type JSONPath struct {
+	expr  string
+	steps []jsonPathStep
+}
+
+func (p *JSONPath) String() string {
+	return p.expr
+}
+
+// ParseJSONPath parses a JSONPath expression, the leading $ is optional.
+func ParseJSONPath(expr string) (*JSONPath, error) {
+	p := &JSONPath{expr: expr}
+
+	s := strings.TrimSpace(expr)
+	switch {
+	case strings.HasPrefix(s, "$"):
+		s = s[1:]
+	case s != "" && s[0] != '.' && s[0] != '[':
+		s = "." + s
+	}
+
+	invalid := func(msg string) (*JSONPath, error) {
+		return nil, fmt.Errorf("invalid JSONPath %q: %v", expr, msg)
+	}
+
+	for s != "" {
+		var step jsonPathStep
+
+		switch {
+		case strings.HasPrefix(s, ".."):
+			step.recursive = true
+			s = s[2:]
+			if strings.HasPrefix(s, "[") {
+				break
+			}
+			fallthrough
+		case s[0] == '.':
+			if !step.recursive {
+				s = s[1:]
+			}
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			name := s[:end]
+			s = s[end:]
+			if name == "" {
+				return invalid("empty member name")
+			}
+			if name == "*" {
+				step.wildcard = true
+			} else {
+				step.name = name
+			}
+			p.steps = append(p.steps, step)
+			continue
+		case s[0] != '[':
+			return invalid(fmt.Sprintf("unexpected %q", s))
+		}
+
+		// bracket notation
+		end := closingBracket(s)
+		if end < 0 {
+			return invalid("missing ]")
+		}
+		inner := strings.TrimSpace(s[1:end])
+		s = s[end+1:]
+
+		switch {
+		case inner == "*":
+			step.wildcard = true
+		case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+			step.name = inner[1 : len(inner)-1]
+		default:
+			i, err := strconv.Atoi(inner)
+			if err != nil {
+				return invalid(fmt.Sprintf("invalid index %q", inner))
+			}
+			step.index = i
+			step.isIndex = true
+		}
+		p.steps = append(p.steps, step)
+	}
+
+	return p, nil
+}
+
+// closingBracket returns the position of the ] which closes the bracket at
+// the start of s, brackets in quoted strings are ignored.
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote != 0 && s[i] == quote:
+			quote = 0
+		case quote != 0:
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case s[i] == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// apply returns the values selected by step from v.
+func (step jsonPathStep) apply(v interface{}) (res []interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if step.wildcard {
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				res = append(res, v[k])
+			}
+		} else if !step.isIndex {
+			if item, ok := v[step.name]; ok {
+				res = append(res, item)
+			}
+		}
+	case []interface{}:
+		if step.wildcard {
+			res = append(res, v...)
+		} else if step.isIndex {
+			i := step.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				res = append(res, v[i])
+			}
+		}
+	}
+
+	return res
+}
+
+// descendants returns v and all values contained in it.
+func descendants(v interface{}) []interface{} {
+	res := []interface{}{v}
+	for _, child := range (jsonPathStep{wildcard: true}).apply(v) {
+		res = append(res, descendants(child)...)
+	}
+	return res
+}
+
+// Eval returns the values selected by p in the JSON document doc, as
+// returned by json.Unmarshal.
+func (p *JSONPath) Eval(doc interface{}) []interface{} {
+	cur := []interface{}{doc}
+	for _, step := range p.steps {
+		var next []interface{}
+		for _, v := range cur {
+			if step.recursive {
+				for _, d := range descendants(v) {
+					next = append(next, step.apply(d)...)
+				}
+				continue
+			}
+			next = append(next, step.apply(v)...)
+		}
+		cur = next
+	}
+
+	return cur
+}
+
+// jsonString returns strings as they are and other values as JSON.
+func jsonString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(buf)
+}
+
+// jsonOperators are the operators of a JSONCondition, longer ones first.
+var jsonOperators = []string{"==", "!=", "=~", "<=", ">=", "<", ">"}
+
+// JSONCondition tests the values selected by a JSONPath, e.g.
+// '$.error == false' or '$.user.role =~ admin'. Without an operator, the
+// condition is true if the path selects at least one value.
+type JSONCondition struct {
+	Path  *JSONPath
+	Op    string
+	Value interface{} // JSON value, or the string if it is not valid JSON
+
+	pattern *regexp.Regexp
+}
+
+// ParseJSONCondition parses a condition in the form "path [op value]", the
+// operators ==, !=, <, <=, > and >= compare the values, =~ matches a regular
+// expression.
+func ParseJSONCondition(s string) (*JSONCondition, error) {
+	pos, op := -1, ""
+	var quote byte
+	depth := 0
+loop:
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0 && s[i] == quote:
+			quote = 0
+		case quote != 0:
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case s[i] == '[':
+			depth++
+		case s[i] == ']':
+			depth--
+		case depth == 0:
+			for _, o := range jsonOperators {
+				if strings.HasPrefix(s[i:], o) {
+					pos, op = i, o
+					break loop
+				}
+			}
+		}
+	}
+
+	if pos < 0 {
+		path, err := ParseJSONPath(s)
+		if err != nil {
+			return nil, err
+		}
+		return &JSONCondition{Path: path}, nil
+	}
+
+	path, err := ParseJSONPath(strings.TrimSpace(s[:pos]))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &JSONCondition{Path: path, Op: op}
+	raw := strings.TrimSpace(s[pos+len(op):])
+
+	if op == "=~" {
+		c.pattern, err = regexp.Compile(raw)
+		if err != nil {
+			return nil, fmt.Errorf("regexp %q failed to compile: %v", raw, err)
+		}
+		c.Value = raw
+		return c, nil
+	}
+
+	err = json.Unmarshal([]byte(raw), &c.Value)
+	if err != nil {
+		c.Value = raw
+	}
+
+	if op != "==" && op != "!=" {
+		if _, ok := c.Value.(float64); !ok {
+			return nil, fmt.Errorf("invalid condition %q: %v requires a number", s, op)
+		}
+	}
+
+	return c, nil
+}
+
+// test returns true if v satisfies the condition.
+func (c *JSONCondition) test(v interface{}) bool {
+	switch c.Op {
+	case "==":
+		return reflect.DeepEqual(v, c.Value)
+	case "!=":
+		return !reflect.DeepEqual(v, c.Value)
+	case "=~":
+		return c.pattern.MatchString(jsonString(v))
+	}
+
+	n, ok := v.(float64)
+	if !ok {
+		return false
+	}
+	want := c.Value.(float64)
+
+	switch c.Op {
+	case "<":
+		return n < want
+	case "<=":
+		return n <= want
+	case ">":
+		return n > want
+	default:
+		return n >= want
+	}
+}
+
+// Match returns true if one of the values selected in the JSON document doc
+// satisfies the condition.
+func (c *JSONCondition) Match(doc interface{}) bool {
+	values := c.Path.Eval(doc)
+	if c.Op == "" {
+		return len(values) > 0
+	}
+
+	for _, v := range values {
+		if c.test(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterJSON filters responses based on conditions on the JSON body.
+// Responses for which one of the rejects matches are hidden, if accepts is
+// not empty only responses for which one of them matches are shown. Bodies
+// which are not valid JSON match no condition.
+type FilterJSON struct {
+	Rejects []*JSONCondition
+	Accepts []*JSONCondition
+}
+
+// Reject decides if r is to be printed.
+func (f FilterJSON) Reject(r Response) bool {
+	var doc interface{}
+	if json.Unmarshal(r.RawBody, &doc) != nil {
+		return len(f.Accepts) > 0
+	}
+
+	for _, c := range f.Rejects {
+		if c.Match(doc) {
+			return true
+		}
+	}
+
+	if len(f.Accepts) == 0 {
+		return false
+	}
+
+	for _, c := range f.Accepts {
+		if c.Match(doc) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ExtractJSON extracts the values selected by the paths from the JSON body.
+// Strings are extracted as they are, other values as JSON.
+func (r *Response) ExtractJSON(paths []*JSONPath) {
+	if len(paths) == 0 {
+		return
+	}
+
+	var doc interface{}
+	if json.Unmarshal(r.RawBody, &doc) != nil {
+		return
+	}
+
+	for _, p := range paths {
+		for _, v := range p.Eval(doc) {
+			r.Extract = append(r.Extract, jsonString(v))
+		}
+	}
+}
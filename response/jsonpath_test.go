@@ -0,0 +1,154 @@
+package response
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testJSONDocument = `{
+	"error": false,
+	"total": 3,
+	"items": [
+		{"id": 1, "name": "foo", "tags": ["a", "b"]},
+		{"id": 2, "name": "bar", "owner": {"id": 23}},
+		{"id": 3, "name": "baz", "data.x": "y"}
+	]
+}`
+
+func TestJSONPath(t *testing.T) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(testJSONDocument), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		path string
+		want []string
+	}{
+		{"$.error", []string{"false"}},
+		{"error", []string{"false"}},
+		{"$.items[*].id", []string{"1", "2", "3"}},
+		{"$.items.*.name", []string{"foo", "bar", "baz"}},
+		{"$.items[0].tags", []string{`["a","b"]`}},
+		{"$.items[-1].name", []string{"baz"}},
+		{"$['items'][1]['owner'].id", []string{"23"}},
+		{`$.items[2]["data.x"]`, []string{"y"}},
+		{"$..id", []string{"1", "2", "23", "3"}},
+		{"$..tags[1]", []string{"b"}},
+		{"$.items[5]", nil},
+		{"$.missing.id", nil},
+		{"$", []string{strings.Join(strings.Fields(testJSONDocument), "")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			p, err := ParseJSONPath(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var res []string
+			for _, v := range p.Eval(doc) {
+				res = append(res, jsonString(v))
+			}
+
+			if test.path == "$" {
+				// the keys are sorted when the document is marshaled again
+				if len(res) != 1 || !strings.HasPrefix(res[0], `{"error":false,"items":[`) {
+					t.Fatalf("wrong result: %v", res)
+				}
+				return
+			}
+
+			if !cmp.Equal(test.want, res) {
+				t.Fatal(cmp.Diff(test.want, res))
+			}
+		})
+	}
+
+	for _, path := range []string{"$.", "$.items[", "$.items[x]", "$foo"} {
+		_, err := ParseJSONPath(path)
+		if err == nil {
+			t.Errorf("expected error for %q not found", path)
+		}
+	}
+}
+
+func TestFilterJSON(t *testing.T) {
+	var tests = []struct {
+		hide, show []string
+		body       string
+		reject     bool
+	}{
+		{nil, []string{"$.error == false"}, testJSONDocument, false},
+		{nil, []string{"$.error == true"}, testJSONDocument, true},
+		{nil, []string{"$.error == false"}, "<html>", true},
+		{nil, []string{"$.total > 2"}, testJSONDocument, false},
+		{nil, []string{"$.total >= 4"}, testJSONDocument, true},
+		{nil, []string{"$.items[*].id<2"}, testJSONDocument, false},
+		{nil, []string{"$.items[*].name == bar"}, testJSONDocument, false},
+		{nil, []string{`$.items[*].name == "qux"`}, testJSONDocument, true},
+		{nil, []string{"$.items[*].name =~ ^b"}, testJSONDocument, false},
+		{nil, []string{"$..owner"}, testJSONDocument, false},
+		{nil, []string{"$..admin"}, testJSONDocument, true},
+		{[]string{"$.error == false"}, nil, testJSONDocument, true},
+		{[]string{"$.error != false"}, nil, testJSONDocument, false},
+		{[]string{"$.error == false"}, nil, "not json", false},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var f FilterJSON
+			for _, s := range test.hide {
+				c, err := ParseJSONCondition(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.Rejects = append(f.Rejects, c)
+			}
+			for _, s := range test.show {
+				c, err := ParseJSONCondition(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.Accepts = append(f.Accepts, c)
+			}
+
+			if f.Reject(Response{RawBody: []byte(test.body)}) != test.reject {
+				t.Fatalf("wrong result for %v %v, want %v", test.hide, test.show, test.reject)
+			}
+		})
+	}
+
+	for _, s := range []string{"$.total > x", "$.name =~ (", "$.items["} {
+		_, err := ParseJSONCondition(s)
+		if err == nil {
+			t.Errorf("expected error for %q not found", s)
+		}
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	p, err := ParseJSONPath("$.items[*].id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := Response{RawBody: []byte(testJSONDocument), Extract: []string{"x"}}
+	res.ExtractJSON([]*JSONPath{p})
+
+	want := []string{"x", "1", "2", "3"}
+	if !cmp.Equal(want, res.Extract) {
+		t.Fatal(cmp.Diff(want, res.Extract))
+	}
+
+	res = Response{RawBody: []byte("<html>")}
+	res.ExtractJSON([]*JSONPath{p})
+	if len(res.Extract) != 0 {
+		t.Fatalf("data extracted from invalid JSON: %v", res.Extract)
+	}
+}
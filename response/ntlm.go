@@ -0,0 +1,330 @@
+package response
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// NTLM negotiate flags (MS-NLMP, section 2.2.2.5).
+const (
+	ntlmNegotiateUnicode                 = 0x00000001
+	ntlmRequestTarget                    = 0x00000004
+	ntlmNegotiateNTLM                    = 0x00000200
+	ntlmNegotiateAlwaysSign              = 0x00008000
+	ntlmNegotiateExtendedSessionSecurity = 0x00080000
+	ntlmNegotiateTargetInfo              = 0x00800000
+	ntlmNegotiate128                     = 0x20000000
+	ntlmNegotiate56                      = 0x80000000
+
+	ntlmFlags = ntlmNegotiateUnicode | ntlmRequestTarget | ntlmNegotiateNTLM |
+		ntlmNegotiateAlwaysSign | ntlmNegotiateExtendedSessionSecurity |
+		ntlmNegotiateTargetInfo | ntlmNegotiate128 | ntlmNegotiate56
+)
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// msvAvTimestamp is the AV_PAIR ID of the server time in the target info.
+const msvAvTimestamp = 7
+
+// NTLMCredentials are used to authenticate via NTLM.
+type NTLMCredentials struct {
+	User, Password, Domain string
+}
+
+// ParseNTLMCredentials parses user:password[:domain], the domain can also be
+// passed as DOMAIN\user.
+func ParseNTLMCredentials(s string) (NTLMCredentials, error) {
+	data := strings.SplitN(s, ":", 3)
+	if len(data) < 2 || data[0] == "" {
+		return NTLMCredentials{}, fmt.Errorf("invalid NTLM credentials %q, expected user:password[:domain]", s)
+	}
+
+	c := NTLMCredentials{User: data[0], Password: data[1]}
+	if len(data) == 3 {
+		c.Domain = data[2]
+	}
+
+	if pos := strings.IndexByte(c.User, '\\'); pos >= 0 && c.Domain == "" {
+		c.Domain, c.User = c.User[:pos], c.User[pos+1:]
+	}
+
+	return c, nil
+}
+
+// utf16le encodes s as UTF-16 little endian.
+func utf16le(s string) []byte {
+	var buf []byte
+	for _, r := range utf16.Encode([]rune(s)) {
+		buf = append(buf, byte(r), byte(r>>8))
+	}
+	return buf
+}
+
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(md5.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// ntowfv2 returns the NTLMv2 hash of the credentials (MS-NLMP, section 3.3.2).
+func ntowfv2(c NTLMCredentials) []byte {
+	hash := md4(utf16le(c.Password))
+	return hmacMD5(hash[:], utf16le(strings.ToUpper(c.User)+c.Domain))
+}
+
+// ntlmNegotiateMessage returns the first message sent by the client.
+func ntlmNegotiateMessage() []byte {
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmFlags)
+	return msg
+}
+
+// ntlmChallenge contains the fields of the challenge message sent by the server.
+type ntlmChallenge struct {
+	Flags      uint32
+	Challenge  []byte
+	TargetInfo []byte
+}
+
+// parseNTLMChallenge parses the second message, sent by the server.
+func parseNTLMChallenge(msg []byte) (ntlmChallenge, error) {
+	if len(msg) < 48 || !bytes.Equal(msg[:8], ntlmSignature) || binary.LittleEndian.Uint32(msg[8:]) != 2 {
+		return ntlmChallenge{}, errors.New("invalid NTLM challenge message")
+	}
+
+	c := ntlmChallenge{
+		Flags:     binary.LittleEndian.Uint32(msg[20:]),
+		Challenge: msg[24:32],
+	}
+
+	// check the bounds before converting to int, the offset may not fit on
+	// 32 bit platforms
+	length := uint64(binary.LittleEndian.Uint16(msg[40:]))
+	offset := uint64(binary.LittleEndian.Uint32(msg[44:]))
+	if offset+length > uint64(len(msg)) {
+		return ntlmChallenge{}, errors.New("invalid target info in NTLM challenge message")
+	}
+	c.TargetInfo = msg[int(offset):int(offset+length)]
+
+	return c, nil
+}
+
+// timestamp returns the server time from the target info, if present.
+func (c ntlmChallenge) timestamp() []byte {
+	info := c.TargetInfo
+	for len(info) >= 4 {
+		id := binary.LittleEndian.Uint16(info)
+		length := int(binary.LittleEndian.Uint16(info[2:]))
+		if len(info) < 4+length {
+			return nil
+		}
+
+		if id == msvAvTimestamp && length == 8 {
+			return info[4:12]
+		}
+
+		info = info[4+length:]
+	}
+
+	return nil
+}
+
+// ntlmv2Response computes the NTLMv2 and LMv2 responses for the challenge
+// (MS-NLMP, section 3.3.2).
+func ntlmv2Response(cred NTLMCredentials, c ntlmChallenge, clientChallenge, timestamp []byte) (nt, lm []byte) {
+	key := ntowfv2(cred)
+
+	temp := []byte{1, 1, 0, 0, 0, 0, 0, 0}
+	temp = append(temp, timestamp...)
+	temp = append(temp, clientChallenge...)
+	temp = append(temp, 0, 0, 0, 0)
+	temp = append(temp, c.TargetInfo...)
+	temp = append(temp, 0, 0, 0, 0)
+
+	proof := hmacMD5(key, c.Challenge, temp)
+	nt = append(proof, temp...)
+
+	// the LMv2 response must be empty if the server sent a timestamp
+	if c.timestamp() != nil {
+		lm = make([]byte, 24)
+	} else {
+		lm = append(hmacMD5(key, c.Challenge, clientChallenge), clientChallenge...)
+	}
+
+	return nt, lm
+}
+
+// ntlmAuthenticateMessage returns the third message, sent by the client.
+func ntlmAuthenticateMessage(cred NTLMCredentials, c ntlmChallenge) ([]byte, error) {
+	clientChallenge := make([]byte, 8)
+	_, err := rand.Read(clientChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := c.timestamp()
+	if timestamp == nil {
+		// Windows time: 100ns intervals since January 1, 1601
+		timestamp = make([]byte, 8)
+		binary.LittleEndian.PutUint64(timestamp, uint64(time.Now().UnixNano()/100+116444736000000000))
+	}
+
+	nt, lm := ntlmv2Response(cred, c, clientChallenge, timestamp)
+
+	fields := [][]byte{lm, nt, utf16le(cred.Domain), utf16le(cred.User), nil, nil}
+
+	msg := make([]byte, 64)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+
+	offset := len(msg)
+	for i, field := range fields {
+		pos := 12 + 8*i
+		binary.LittleEndian.PutUint16(msg[pos:], uint16(len(field)))
+		binary.LittleEndian.PutUint16(msg[pos+2:], uint16(len(field)))
+		binary.LittleEndian.PutUint32(msg[pos+4:], uint32(offset))
+		offset += len(field)
+	}
+	binary.LittleEndian.PutUint32(msg[60:], ntlmFlags&c.Flags|ntlmNegotiateUnicode)
+
+	for _, field := range fields {
+		msg = append(msg, field...)
+	}
+
+	return msg, nil
+}
+
+// NTLMTransport authenticates requests via NTLM when the server asks for it.
+// The authentication is bound to the connection, so the transport must keep
+// connections alive, must not use HTTP/2, and must not be used for several
+// requests at the same time.
+type NTLMTransport struct {
+	Transport   http.RoundTripper
+	Credentials NTLMCredentials
+}
+
+// NewNTLMTransport returns a transport which authenticates via NTLM, based on
+// a clone of tr with HTTP/2 disabled. Each runner needs its own transport.
+func NewNTLMTransport(tr *http.Transport, cred NTLMCredentials) *NTLMTransport {
+	clone := tr.Clone()
+	clone.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	clone.TLSClientConfig.NextProtos = nil
+	clone.MaxConnsPerHost = 1
+
+	return &NTLMTransport{Transport: clone, Credentials: cred}
+}
+
+// ntlmScheme returns the authentication scheme offered by the server which
+// accepts NTLM messages, NTLM is preferred over Negotiate.
+func ntlmScheme(res *http.Response) string {
+	var scheme string
+	for _, v := range res.Header["Www-Authenticate"] {
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "ntlm":
+			return "NTLM"
+		case "negotiate":
+			scheme = "Negotiate"
+		}
+	}
+	return scheme
+}
+
+// ntlmServerMessage returns the decoded message sent by the server for scheme.
+func ntlmServerMessage(res *http.Response, scheme string) ([]byte, error) {
+	for _, v := range res.Header["Www-Authenticate"] {
+		if !strings.HasPrefix(strings.ToLower(v), strings.ToLower(scheme)+" ") {
+			continue
+		}
+
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(v[len(scheme)+1:]))
+	}
+
+	return nil, errors.New("no NTLM challenge found in response")
+}
+
+// RoundTrip sends the request and runs the NTLM handshake if the server
+// responds with status 401 and offers NTLM authentication.
+func (t *NTLMTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the body is needed for up to three requests
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+
+	send := func(auth string) (*http.Response, error) {
+		r := req.Clone(req.Context())
+		if req.Body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		return t.Transport.RoundTrip(r)
+	}
+
+	// discard drains the body so the connection can be used again
+	discard := func(res *http.Response) {
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		_ = res.Body.Close()
+	}
+
+	res, err := send("")
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := ntlmScheme(res)
+	if res.StatusCode != http.StatusUnauthorized || scheme == "" {
+		return res, nil
+	}
+	discard(res)
+
+	res, err = send(scheme + " " + base64.StdEncoding.EncodeToString(ntlmNegotiateMessage()))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusUnauthorized {
+		return res, nil
+	}
+
+	msg, err := ntlmServerMessage(res, scheme)
+	discard(res)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := parseNTLMChallenge(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err = ntlmAuthenticateMessage(t.Credentials, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return send(scheme + " " + base64.StdEncoding.EncodeToString(msg))
+}
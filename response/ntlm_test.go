@@ -0,0 +1,193 @@
+package response
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestMD4(t *testing.T) {
+	// test vectors from RFC 1320
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"", "31d6cfe0d16ae931b73c59d7e0c089c0"},
+		{"abc", "a448017aaf21d8525fc10ae87aa6729d"},
+		{"message digest", "d9130a8164549fe818874806e1c7014b"},
+		{"12345678901234567890123456789012345678901234567890123456789012345678901234567890", "e33b4ddc9c38f2199c3e7b164fcc0536"},
+	}
+
+	for _, test := range tests {
+		sum := md4([]byte(test.input))
+		if hex.EncodeToString(sum[:]) != test.want {
+			t.Errorf("wrong MD4 for %q, want %v, got %x", test.input, test.want, sum)
+		}
+	}
+}
+
+func unhex(t testing.TB, s string) []byte {
+	buf, err := hex.DecodeString(strings.Replace(s, " ", "", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+// test values from MS-NLMP, section 4.2.4
+var (
+	testNTLMCredentials = NTLMCredentials{User: "User", Password: "Password", Domain: "Domain"}
+	testNTLMTargetInfo  = "02 00 0c 00 44 00 6f 00 6d 00 61 00 69 00 6e 00 01 00 0c 00 53 00 65 00 72 00 76 00 65 00 72 00 00 00 00 00"
+)
+
+func TestNTLMv2Response(t *testing.T) {
+	if key := ntowfv2(testNTLMCredentials); !bytes.Equal(key, unhex(t, "0c868a403bfd7a93a3001ef22ef02e3f")) {
+		t.Errorf("wrong NTOWFv2 %x", key)
+	}
+
+	c := ntlmChallenge{
+		Challenge:  unhex(t, "0123456789abcdef"),
+		TargetInfo: unhex(t, testNTLMTargetInfo),
+	}
+
+	nt, lm := ntlmv2Response(testNTLMCredentials, c, unhex(t, "aaaaaaaaaaaaaaaa"), make([]byte, 8))
+
+	if !bytes.Equal(nt[:16], unhex(t, "68cd0ab851e51c96aabc927bebef6a1c")) {
+		t.Errorf("wrong NTProofStr %x", nt[:16])
+	}
+
+	if !bytes.Equal(lm, unhex(t, "86c35097ac9cec102554764a57cccc19aaaaaaaaaaaaaaaa")) {
+		t.Errorf("wrong LMv2 response %x", lm)
+	}
+}
+
+func TestParseNTLMCredentials(t *testing.T) {
+	var tests = []struct {
+		s    string
+		want NTLMCredentials
+	}{
+		{"user:pass", NTLMCredentials{User: "user", Password: "pass"}},
+		{"user:pass:CORP", NTLMCredentials{User: "user", Password: "pass", Domain: "CORP"}},
+		{`CORP\user:pass`, NTLMCredentials{User: "user", Password: "pass", Domain: "CORP"}},
+	}
+
+	for _, test := range tests {
+		c, err := ParseNTLMCredentials(test.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c != test.want {
+			t.Errorf("wrong credentials for %q, want %+v, got %+v", test.s, test.want, c)
+		}
+	}
+
+	for _, s := range []string{"", "user", ":pass"} {
+		_, err := ParseNTLMCredentials(s)
+		if err == nil {
+			t.Errorf("expected error for %q not found", s)
+		}
+	}
+}
+
+func TestParseNTLMChallengeInvalidTargetInfo(t *testing.T) {
+	msg := make([]byte, 48)
+	copy(msg, ntlmSignature)
+	msg[8] = 2
+
+	// length 1 at offset 0xffffffff
+	msg[40] = 1
+	copy(msg[44:], []byte{0xff, 0xff, 0xff, 0xff})
+
+	_, err := parseNTLMChallenge(msg)
+	if err == nil {
+		t.Fatal("expected error not found")
+	}
+}
+
+// ntlmTestHandler requires an NTLM handshake on each connection.
+func ntlmTestHandler(t testing.TB) http.HandlerFunc {
+	challenge := unhex(t, "0123456789abcdef")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "NTLM ") {
+			w.Header().Set("WWW-Authenticate", "NTLM")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		msg, err := base64.StdEncoding.DecodeString(auth[5:])
+		if err != nil || len(msg) < 12 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch msg[8] {
+		case 1:
+			info := unhex(t, testNTLMTargetInfo)
+			res := make([]byte, 48)
+			copy(res, ntlmSignature)
+			res[8] = 2
+			copy(res[20:], []byte{0x05, 0x82, 0x89, 0xa2})
+			copy(res[24:], challenge)
+			res[40], res[42], res[44] = byte(len(info)), byte(len(info)), 48
+			res = append(res, info...)
+
+			w.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(res))
+			w.WriteHeader(http.StatusUnauthorized)
+
+		case 3:
+			// recompute the NTProofStr from the rest of the NT response
+			ntLen, ntOffset := int(msg[20])|int(msg[21])<<8, int(msg[24])|int(msg[25])<<8
+			nt := msg[ntOffset : ntOffset+ntLen]
+			proof := hmacMD5(ntowfv2(testNTLMCredentials), challenge, nt[16:])
+			if !bytes.Equal(proof, nt[:16]) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			body := new(bytes.Buffer)
+			_, _ = body.ReadFrom(r.Body)
+			fmt.Fprintf(w, "authenticated %s", body)
+		}
+	}
+}
+
+func TestNTLMTransport(t *testing.T) {
+	srv := httptest.NewServer(ntlmTestHandler(t))
+	defer srv.Close()
+
+	tr, err := NewTransport(&request.Request{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cred := range []NTLMCredentials{testNTLMCredentials, {User: "User", Password: "wrong", Domain: "Domain"}} {
+		client := &http.Client{Transport: NewNTLMTransport(tr, cred)}
+
+		res, err := client.Post(srv.URL, "text/plain", strings.NewReader("body"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body := new(bytes.Buffer)
+		_, _ = body.ReadFrom(res.Body)
+		_ = res.Body.Close()
+
+		if cred == testNTLMCredentials {
+			if res.StatusCode != http.StatusOK || body.String() != "authenticated body" {
+				t.Errorf("authentication failed: %v %q", res.Status, body)
+			}
+		} else if res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("wrong status for invalid credentials: %v", res.Status)
+		}
+	}
+}
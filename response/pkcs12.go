@@ -0,0 +1,455 @@
+package response
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"unicode/utf16"
+)
+
+// Object identifiers used in PKCS#12 files (RFC 7292).
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509Certificate     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd40BitRC2CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+	oidPBES2                         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2                        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+type pfx struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// bmpString returns the password as a null-terminated UTF-16 big endian
+// string, as used by the PKCS#12 key derivation.
+func bmpString(password string) []byte {
+	var buf []byte
+	for _, r := range utf16.Encode([]rune(password)) {
+		buf = append(buf, byte(r>>8), byte(r))
+	}
+	return append(buf, 0, 0)
+}
+
+// pkcs12KDF derives n bytes from the password and salt (RFC 7292, appendix
+// B.2). The id selects the purpose: 1 for keys, 2 for IVs and 3 for MAC keys.
+func pkcs12KDF(newHash func() hash.Hash, id byte, password, salt []byte, iterations, n int) []byte {
+	h := newHash()
+	u, v := h.Size(), h.BlockSize()
+
+	repeat := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		res := make([]byte, v*((len(b)+v-1)/v))
+		for i := range res {
+			res[i] = b[i%len(b)]
+		}
+		return res
+	}
+
+	d := bytes.Repeat([]byte{id}, v)
+	i := append(repeat(salt), repeat(password)...)
+
+	var res []byte
+	for len(res) < n {
+		h.Reset()
+		h.Write(d)
+		h.Write(i)
+		a := h.Sum(nil)
+		for j := 1; j < iterations; j++ {
+			h.Reset()
+			h.Write(a)
+			a = h.Sum(a[:0])
+		}
+		res = append(res, a...)
+
+		// I_j = (I_j + B + 1) mod 2^(v*8) for each block of I
+		b := make([]byte, v)
+		for j := range b {
+			b[j] = a[j%u]
+		}
+		for j := 0; j < len(i); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(i[j+k]) + int(b[k]) + carry
+				i[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+
+	return res[:n]
+}
+
+// pbkdf2 derives a key from the password (RFC 8018, section 5.2).
+func pbkdf2(newHash func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(newHash, password)
+
+	var res []byte
+	for block := uint32(1); len(res) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		u := prf.Sum(nil)
+
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+
+		res = append(res, t...)
+	}
+
+	return res[:keyLen]
+}
+
+// hashForOID returns the hash function for the digest or HMAC algorithm.
+func hashForOID(oid asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	switch {
+	case oid.Equal(oidSHA1), oid.Equal(oidHMACWithSHA1):
+		return sha1.New, nil
+	case oid.Equal(oidSHA256), oid.Equal(oidHMACWithSHA256):
+		return sha256.New, nil
+	case oid.Equal(oidSHA512), oid.Equal(oidHMACWithSHA512):
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %v", oid)
+	}
+}
+
+// pkcs12Decrypt decrypts data encrypted with the algorithm and password.
+func pkcs12Decrypt(algorithm pkix.AlgorithmIdentifier, data []byte, password string) ([]byte, error) {
+	var block cipher.Block
+	var iv []byte
+
+	switch {
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC):
+		var params pbeParams
+		_, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &params)
+		if err != nil {
+			return nil, err
+		}
+
+		key := pkcs12KDF(sha1.New, 1, bmpString(password), params.Salt, params.Iterations, 24)
+		iv = pkcs12KDF(sha1.New, 2, bmpString(password), params.Salt, params.Iterations, 8)
+
+		block, err = des.NewTripleDESCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+	case algorithm.Algorithm.Equal(oidPBES2):
+		var params pbes2Params
+		_, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &params)
+		if err != nil {
+			return nil, err
+		}
+
+		if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+			return nil, fmt.Errorf("unsupported key derivation function %v", params.KeyDerivationFunc.Algorithm)
+		}
+
+		var kdf pbkdf2Params
+		_, err = asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf)
+		if err != nil {
+			return nil, err
+		}
+
+		prf := sha1.New
+		if len(kdf.PRF.Algorithm) > 0 {
+			prf, err = hashForOID(kdf.PRF.Algorithm)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var keyLen int
+		var newCipher func([]byte) (cipher.Block, error)
+		switch scheme := params.EncryptionScheme.Algorithm; {
+		case scheme.Equal(oidAES128CBC):
+			keyLen, newCipher = 16, aes.NewCipher
+		case scheme.Equal(oidAES192CBC):
+			keyLen, newCipher = 24, aes.NewCipher
+		case scheme.Equal(oidAES256CBC):
+			keyLen, newCipher = 32, aes.NewCipher
+		case scheme.Equal(oidDESEDE3CBC):
+			keyLen, newCipher = 24, des.NewTripleDESCipher
+		default:
+			return nil, fmt.Errorf("unsupported encryption scheme %v", scheme)
+		}
+
+		_, err = asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv)
+		if err != nil {
+			return nil, err
+		}
+
+		block, err = newCipher(pbkdf2(prf, []byte(password), kdf.Salt, kdf.Iterations, keyLen))
+		if err != nil {
+			return nil, err
+		}
+
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd40BitRC2CBC):
+		return nil, errors.New("RC2 encryption is not supported, convert the file to PEM or export it with AES (e.g. openssl pkcs12 -export -certpbe AES-256-CBC)")
+
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm %v", algorithm.Algorithm)
+	}
+
+	if len(iv) != block.BlockSize() || len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, errors.New("invalid encrypted data")
+	}
+
+	res := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(res, data)
+
+	// remove the padding
+	pad := int(res[len(res)-1])
+	if pad == 0 || pad > block.BlockSize() {
+		return nil, errors.New("decryption failed, wrong password?")
+	}
+	for _, b := range res[len(res)-pad:] {
+		if int(b) != pad {
+			return nil, errors.New("decryption failed, wrong password?")
+		}
+	}
+
+	return res[:len(res)-pad], nil
+}
+
+// octetString returns the content of the DER encoded OCTET STRING.
+func octetString(der []byte) ([]byte, error) {
+	var data []byte
+	_, err := asn1.Unmarshal(der, &data)
+	return data, err
+}
+
+// decodePKCS12 returns the private key and certificates in the PKCS#12 file.
+// Password-based encryption with 3DES, AES and the HMAC based integrity check
+// are supported.
+func decodePKCS12(der []byte, password string) (crypto.PrivateKey, []*x509.Certificate, error) {
+	var p pfx
+	rest, err := asn1.Unmarshal(der, &p)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid PKCS#12 data: %v", err)
+	}
+	if len(rest) > 0 {
+		return nil, nil, errors.New("invalid PKCS#12 data: trailing data")
+	}
+
+	if !p.AuthSafe.ContentType.Equal(oidData) {
+		return nil, nil, errors.New("PKCS#12 files protected with public keys are not supported")
+	}
+
+	authSafe, err := octetString(p.AuthSafe.Content.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(p.MacData.Mac.Algorithm.Algorithm) > 0 {
+		newHash, err := hashForOID(p.MacData.Mac.Algorithm.Algorithm)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		key := pkcs12KDF(newHash, 3, bmpString(password), p.MacData.MacSalt, p.MacData.Iterations, newHash().Size())
+		mac := hmac.New(newHash, key)
+		mac.Write(authSafe)
+		if !hmac.Equal(mac.Sum(nil), p.MacData.Mac.Digest) {
+			return nil, nil, errors.New("PKCS#12 integrity check failed, wrong password?")
+		}
+	}
+
+	var infos []contentInfo
+	_, err = asn1.Unmarshal(authSafe, &infos)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var key crypto.PrivateKey
+	var certs []*x509.Certificate
+
+	for _, info := range infos {
+		var data []byte
+		switch {
+		case info.ContentType.Equal(oidData):
+			data, err = octetString(info.Content.Bytes)
+		case info.ContentType.Equal(oidEncryptedData):
+			var ed encryptedData
+			_, err = asn1.Unmarshal(info.Content.Bytes, &ed)
+			if err == nil {
+				data, err = pkcs12Decrypt(ed.EncryptedContentInfo.ContentEncryptionAlgorithm, ed.EncryptedContentInfo.EncryptedContent, password)
+			}
+		default:
+			err = fmt.Errorf("unsupported content type %v", info.ContentType)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var bags []safeBag
+		_, err = asn1.Unmarshal(data, &bags)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, bag := range bags {
+			switch {
+			case bag.ID.Equal(oidCertBag):
+				var cb certBag
+				_, err = asn1.Unmarshal(bag.Value.Bytes, &cb)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				if !cb.ID.Equal(oidX509Certificate) {
+					continue
+				}
+
+				cert, err := x509.ParseCertificate(cb.Data)
+				if err != nil {
+					return nil, nil, err
+				}
+				certs = append(certs, cert)
+
+			case bag.ID.Equal(oidKeyBag), bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+				if key != nil {
+					return nil, nil, errors.New("more than one private key found")
+				}
+
+				pkcs8 := bag.Value.Bytes
+				if bag.ID.Equal(oidPKCS8ShroudedKeyBag) {
+					var info encryptedPrivateKeyInfo
+					_, err = asn1.Unmarshal(bag.Value.Bytes, &info)
+					if err != nil {
+						return nil, nil, err
+					}
+
+					pkcs8, err = pkcs12Decrypt(info.Algorithm, info.EncryptedData, password)
+					if err != nil {
+						return nil, nil, err
+					}
+				}
+
+				key, err = x509.ParsePKCS8PrivateKey(pkcs8)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+
+	if key == nil {
+		return nil, nil, errors.New("no private key found in PKCS#12 file")
+	}
+
+	if len(certs) == 0 {
+		return nil, nil, errors.New("no certificate found in PKCS#12 file")
+	}
+
+	return key, certs, nil
+}
@@ -0,0 +1,153 @@
+package response
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// parseProxy parses the URL of a proxy, the scheme (http, https or socks5)
+// defaults to http. Credentials are passed as user:password in the URL.
+func parseProxy(s string) (*url.URL, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %v", s, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q, host is missing", s)
+	}
+
+	return u, nil
+}
+
+// proxyFor returns the proxy tr uses for req sent with ctx, or nil. WebSocket
+// URLs use the proxy for the corresponding HTTP URL.
+func proxyFor(ctx context.Context, tr *http.Transport, req *http.Request) (*url.URL, error) {
+	if tr == nil || tr.Proxy == nil {
+		return nil, nil
+	}
+
+	r := req.WithContext(ctx)
+	r.URL = cookieURL(req.URL)
+	return tr.Proxy(r)
+}
+
+// proxyAuthorization returns the value of the Proxy-Authorization header
+// for the credentials in the proxy URL, or the empty string.
+func proxyAuthorization(proxyURL *url.URL) string {
+	if proxyURL.User == nil {
+		return ""
+	}
+
+	password, _ := proxyURL.User.Password()
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username()+":"+password))
+}
+
+// dialProxy opens a connection to address through the proxy, which is
+// contacted with dial. HTTP and HTTPS proxies are asked to open a tunnel with
+// CONNECT, TLS for HTTPS proxies is verified unless tr is configured to skip
+// the verification.
+func dialProxy(ctx context.Context, dial dialContextFunc, tr *http.Transport, proxyURL *url.URL, address string) (net.Conn, error) {
+	if proxyURL.Scheme == "socks5" {
+		addr := proxyURL.Host
+		if proxyURL.User != nil {
+			addr = proxyURL.User.String() + "@" + addr
+		}
+
+		socksDial, err := socks5Dial(dial, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return socksDial(ctx, "tcp", address)
+	}
+
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	conn, err := dial(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURL.Scheme == "https" {
+		cfg := &tls.Config{ServerName: proxyURL.Hostname()}
+		if tr.TLSClientConfig != nil {
+			cfg.InsecureSkipVerify = tr.TLSClientConfig.InsecureSkipVerify
+		}
+
+		tlsConn := tls.Client(conn, cfg)
+		err = tlsConn.Handshake()
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("proxy: %v", err)
+		}
+		conn = tlsConn
+	}
+
+	err = conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if auth := proxyAuthorization(proxyURL); auth != "" {
+		req.Header.Set("Proxy-Authorization", auth)
+	}
+
+	err = req.Write(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy: %v", err)
+	}
+
+	// the body of a successful response is the tunnel
+	if res.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy refused to connect to %v: %v", address, res.Status)
+	}
+
+	err = conn.SetDeadline(time.Time{})
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,118 @@
+package response
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// connectProxy is an HTTPS proxy which requires the Proxy-Authorization
+// auth. Tunnels are opened for CONNECT requests, other requests are answered
+// by the proxy itself with the requested URL.
+func connectProxy(t *testing.T, auth string) *httptest.Server {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != auth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		if r.Method != http.MethodConnect {
+			fmt.Fprintf(w, "proxied %v", r.URL)
+			return
+		}
+
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer target.Close()
+
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		_, err = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		go func() { _, _ = io.Copy(target, buf) }()
+		_, _ = io.Copy(conn, target)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestProxy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "target %v", r.URL)
+	}))
+	defer target.Close()
+
+	// Basic dXNlcjpzZWNyZXQ= is user:secret
+	proxy := connectProxy(t, "Basic dXNlcjpzZWNyZXQ=")
+	proxyURL := strings.Replace(proxy.URL, "https://", "https://user:secret@", 1)
+
+	tr, err := NewTransport(&request.Request{Proxy: proxyURL, Insecure: true, DisableHTTP2: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// requests for http URLs are sent to the proxy
+	want := "proxied http://target.invalid/foo"
+	if got := get(t, tr, "http://target.invalid/foo"); got != want {
+		t.Errorf("wrong response, want %q, got %q", want, got)
+	}
+
+	// the custom request writer uses a tunnel
+	client := &http.Client{Transport: &HeaderOrderTransport{Transport: tr}}
+	res, err := client.Get(target.URL + "/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	if string(buf) != "target /bar" {
+		t.Errorf("wrong response, want %q, got %q", "target /bar", buf)
+	}
+
+	// wrong credentials
+	tr, err = NewTransport(&request.Request{Proxy: strings.Replace(proxyURL, "secret", "wrong", 1), Insecure: true, DisableHTTP2: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = (&http.Client{Transport: &HeaderOrderTransport{Transport: tr}}).Get(target.URL)
+	if err == nil || !strings.Contains(err.Error(), "407") {
+		t.Errorf("expected error for wrong credentials not found, got %v", err)
+	}
+
+	for _, proxy := range []string{"ftp://proxy", "http://", "https://proxy with space"} {
+		_, err = NewTransport(&request.Request{Proxy: proxy}, nil)
+		if err == nil {
+			t.Errorf("expected error for %q not found", proxy)
+		}
+	}
+
+	_, err = NewTransport(&request.Request{Proxy: "proxy:3128", ConnectTo: "127.0.0.1"}, nil)
+	if err == nil {
+		t.Error("expected error for --proxy with --connect-to not found")
+	}
+}
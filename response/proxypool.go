@@ -0,0 +1,147 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Defaults for disabling proxies in a ProxyPool.
+const (
+	DefaultProxyMaxFailures = 3
+	DefaultProxyDisable     = time.Minute
+)
+
+// pooledProxy is a proxy in the pool and its error state.
+type pooledProxy struct {
+	url           *url.URL
+	failures      int // consecutive failures
+	disabledUntil time.Time
+}
+
+// ProxyPool selects a proxy for each request from a list, either in order
+// (starting over at the end) or at random. After MaxFailures consecutive
+// failed requests a proxy is disabled for the duration Disable. It is safe
+// for concurrent use by several runners.
+type ProxyPool struct {
+	MaxFailures int
+	Disable     time.Duration
+
+	proxies []*pooledProxy
+	random  bool
+
+	mu   sync.Mutex
+	next int
+	rnd  *rand.Rand
+}
+
+// NewProxyPool returns a pool for the proxy URLs in list, see parseProxy.
+func NewProxyPool(list []string, random bool) (*ProxyPool, error) {
+	if len(list) == 0 {
+		return nil, errors.New("no proxies passed")
+	}
+
+	p := &ProxyPool{
+		MaxFailures: DefaultProxyMaxFailures,
+		Disable:     DefaultProxyDisable,
+		random:      random,
+		rnd:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	for _, s := range list {
+		u, err := parseProxy(s)
+		if err != nil {
+			return nil, err
+		}
+		p.proxies = append(p.proxies, &pooledProxy{url: u})
+	}
+
+	return p, nil
+}
+
+// Next returns the proxy for the next request. If all proxies are disabled,
+// the one which is enabled again first is returned.
+func (p *ProxyPool) Next() *url.URL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var available []int
+	for i, proxy := range p.proxies {
+		if !now.Before(proxy.disabledUntil) {
+			available = append(available, i)
+		}
+	}
+
+	if len(available) == 0 {
+		first := p.proxies[0]
+		for _, proxy := range p.proxies[1:] {
+			if proxy.disabledUntil.Before(first.disabledUntil) {
+				first = proxy
+			}
+		}
+		return first.url
+	}
+
+	if p.random {
+		return p.proxies[available[p.rnd.Intn(len(available))]].url
+	}
+
+	for i := 0; i < len(p.proxies); i++ {
+		idx := (p.next + i) % len(p.proxies)
+		if !now.Before(p.proxies[idx].disabledUntil) {
+			p.next = (idx + 1) % len(p.proxies)
+			return p.proxies[idx].url
+		}
+	}
+
+	// not reached, at least one proxy is available
+	return p.proxies[available[0]].url
+}
+
+// Report records the outcome of a request sent via proxy, which must have
+// been returned by Next.
+func (p *ProxyPool) Report(proxy *url.URL, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, pr := range p.proxies {
+		if pr.url != proxy {
+			continue
+		}
+
+		if err == nil {
+			pr.failures = 0
+			return
+		}
+
+		pr.failures++
+		if pr.failures >= p.MaxFailures {
+			pr.failures = 0
+			pr.disabledUntil = time.Now().Add(p.Disable)
+		}
+		return
+	}
+}
+
+type proxyKey struct{}
+
+// withProxy returns a context which selects proxy for requests sent via a
+// transport using the pool.
+func withProxy(ctx context.Context, proxy *url.URL) context.Context {
+	return context.WithValue(ctx, proxyKey{}, proxy)
+}
+
+// Proxy returns the proxy selected for req (see withProxy), or the next one.
+// It is used as the Proxy function of an http.Transport.
+func (p *ProxyPool) Proxy(req *http.Request) (*url.URL, error) {
+	if proxy, ok := req.Context().Value(proxyKey{}).(*url.URL); ok {
+		return proxy, nil
+	}
+
+	return p.Next(), nil
+}
@@ -0,0 +1,124 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestProxyPool(t *testing.T) {
+	p, err := NewProxyPool([]string{"proxy1:3128", "https://proxy2", "socks5://proxy3:1080"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"http://proxy1:3128", "https://proxy2", "socks5://proxy3:1080"}
+	for i := 0; i < 7; i++ {
+		if proxy := p.Next(); proxy.String() != want[i%3] {
+			t.Errorf("request %d: want proxy %q, got %q", i, want[i%3], proxy)
+		}
+	}
+
+	// the second proxy is disabled after three failures in a row
+	proxy2 := p.proxies[1].url
+	p.Report(proxy2, errors.New("failure"))
+	p.Report(proxy2, nil)
+	p.Report(proxy2, errors.New("failure"))
+	p.Report(proxy2, errors.New("failure"))
+	if proxy := p.Next(); proxy != p.proxies[1].url {
+		t.Errorf("proxy disabled too early, got %v", proxy)
+	}
+	p.Report(proxy2, errors.New("failure"))
+
+	for i := 0; i < 4; i++ {
+		if proxy := p.Next(); proxy == proxy2 {
+			t.Errorf("request %d: disabled proxy %v returned", i, proxy)
+		}
+	}
+
+	seen := make(map[string]bool)
+	p.random = true
+	for i := 0; i < 100; i++ {
+		seen[p.Next().String()] = true
+	}
+
+	if len(seen) != 2 || seen["https://proxy2"] {
+		t.Errorf("wrong proxies were selected: %v", seen)
+	}
+
+	// the proxy which is available again first is used when all are disabled
+	p.proxies[0].disabledUntil = time.Now().Add(time.Hour)
+	p.proxies[2].disabledUntil = time.Now().Add(time.Hour)
+	if proxy := p.Next(); proxy != proxy2 {
+		t.Errorf("wrong proxy returned, want %v, got %v", proxy2, proxy)
+	}
+
+	// the proxy selected in the context is used by the transport
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxy, err := p.Proxy(req.WithContext(withProxy(context.Background(), p.proxies[2].url)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proxy != p.proxies[2].url {
+		t.Errorf("wrong proxy for the request, want %v, got %v", p.proxies[2].url, proxy)
+	}
+
+	_, err = NewProxyPool([]string{"ftp://proxy"}, false)
+	if err == nil {
+		t.Error("expected error for invalid proxy not found")
+	}
+}
+
+func TestRunnerReportsProxy(t *testing.T) {
+	// nothing listens on the proxy port, so all requests fail
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	pool, err := NewProxyPool([]string{"http://" + addr}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.MaxFailures = 1
+
+	tmpl := request.New("")
+	tmpl.URL = "http://example.com/FUZZ"
+	tmpl.Timeout = 5 * time.Second
+
+	tr, err := NewTransport(tmpl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Proxy = pool.Proxy
+
+	in := make(chan producer.Item, 1)
+	in <- producer.Item{Values: []string{"foo"}}
+	close(in)
+	out := make(chan Response, 1)
+
+	runner := NewRunner(tr, tmpl, in, out)
+	runner.ProxyPool = pool
+	runner.Run(context.Background())
+
+	res := <-out
+	if res.Error == nil {
+		t.Fatal("expected error not found")
+	}
+
+	if !pool.proxies[0].disabledUntil.After(time.Now()) {
+		t.Errorf("failed request was not reported for the proxy")
+	}
+}
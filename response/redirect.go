@@ -0,0 +1,113 @@
+package response
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// Redirect is a response with a redirect which has been followed.
+type Redirect struct {
+	URL          string // URL of the request
+	HTTPResponse *http.Response
+	RawHeader    []byte
+	RawBody      []byte
+	Header, Body TextStats
+}
+
+// isRedirect returns true if the status code is one the client follows.
+func isRedirect(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// redirectTransport records the responses with a redirect status. The body is
+// read so that it is still available after the redirect has been followed.
+type redirectTransport struct {
+	http.RoundTripper
+	maxBodySize int
+
+	chain []Redirect
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.RoundTripper.RoundTrip(req)
+	if err != nil || !isRedirect(res.StatusCode) {
+		return res, err
+	}
+
+	var hop Response
+	err = hop.ReadBody(res.Body, t.maxBodySize)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(hop.RawBody))
+
+	err = hop.ExtractHeader(res, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	t.chain = append(t.chain, Redirect{
+		URL:          request.URLString(req),
+		HTTPResponse: res,
+		RawHeader:    hop.RawHeader,
+		RawBody:      hop.RawBody,
+		Header:       hop.Header,
+		Body:         hop.Body,
+	})
+
+	return res, nil
+}
+
+// followed returns the redirects which led to the final response res.
+func (t *redirectTransport) followed(res *http.Response) []Redirect {
+	chain := t.chain
+	if len(chain) > 0 && chain[len(chain)-1].HTTPResponse == res {
+		chain = chain[:len(chain)-1]
+	}
+	return chain
+}
+
+// FinalURL returns the URL of the last request sent for r, after redirects
+// have been followed.
+func (r Response) FinalURL() string {
+	if len(r.Redirects) == 0 || r.HTTPResponse == nil || r.HTTPResponse.Request == nil {
+		return r.URL
+	}
+	return request.URLString(r.HTTPResponse.Request)
+}
+
+// First returns the first response of a redirect chain with all other fields
+// of r, or r itself if no redirects have been followed.
+func (r Response) First() Response {
+	if len(r.Redirects) == 0 {
+		return r
+	}
+
+	first := r.Redirects[0]
+	r.HTTPResponse = first.HTTPResponse
+	r.RawHeader, r.RawBody = first.RawHeader, first.RawBody
+	r.Header, r.Body = first.Header, first.Body
+	r.Redirects = nil
+
+	return r
+}
+
+// FilterFirst applies Filter to the first response of a redirect chain
+// instead of the final one.
+type FilterFirst struct {
+	Filter Filter
+}
+
+// Reject decides if r is to be printed.
+func (f FilterFirst) Reject(r Response) bool {
+	return f.Filter.Reject(r.First())
+}
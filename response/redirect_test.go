@@ -0,0 +1,132 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func redirectServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("final"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestRunnerFollowRedirects(t *testing.T) {
+	srv := redirectServer()
+	defer srv.Close()
+
+	var tests = []struct {
+		follow        int
+		wantStatus    int
+		wantRedirects []int
+		wantFinalURL  string
+	}{
+		{0, http.StatusFound, nil, srv.URL + "/a"},
+		{1, http.StatusMovedPermanently, []int{http.StatusFound}, srv.URL + "/b"},
+		{2, http.StatusOK, []int{http.StatusFound, http.StatusMovedPermanently}, srv.URL + "/c"},
+		{5, http.StatusOK, []int{http.StatusFound, http.StatusMovedPermanently}, srv.URL + "/c"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			tmpl := request.New("")
+			tmpl.URL = srv.URL + "/FUZZ"
+
+			tr, err := NewTransport(tmpl, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			in := make(chan producer.Item, 1)
+			in <- producer.Item{Values: []string{"a"}}
+			close(in)
+			out := make(chan Response, 1)
+
+			runner := NewRunner(tr, tmpl, in, out)
+			runner.FollowRedirects = test.follow
+			runner.Run(context.Background())
+
+			res := <-out
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if res.HTTPResponse.StatusCode != test.wantStatus {
+				t.Errorf("wrong status, want %v, got %v", test.wantStatus, res.HTTPResponse.StatusCode)
+			}
+
+			var redirects []int
+			for _, r := range res.Redirects {
+				redirects = append(redirects, r.HTTPResponse.StatusCode)
+				if !strings.Contains(string(r.RawBody), "<a href=") {
+					t.Errorf("body of redirect not recorded: %q", r.RawBody)
+				}
+			}
+
+			if len(redirects) != len(test.wantRedirects) {
+				t.Fatalf("wrong redirects, want %v, got %v", test.wantRedirects, redirects)
+			}
+			for i := range redirects {
+				if redirects[i] != test.wantRedirects[i] {
+					t.Errorf("wrong redirects, want %v, got %v", test.wantRedirects, redirects)
+				}
+			}
+
+			if len(res.Redirects) > 0 && res.Redirects[0].URL != srv.URL+"/a" {
+				t.Errorf("wrong URL of the first response %q", res.Redirects[0].URL)
+			}
+
+			if res.FinalURL() != test.wantFinalURL {
+				t.Errorf("wrong final URL, want %q, got %q", test.wantFinalURL, res.FinalURL())
+			}
+		})
+	}
+}
+
+func TestFilterFirst(t *testing.T) {
+	hide302, err := NewFilterStatusCode([]string{"302"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := Response{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		RawBody:      []byte("final"),
+		Redirects: []Redirect{
+			{HTTPResponse: &http.Response{StatusCode: http.StatusFound}, RawBody: []byte("redirect")},
+		},
+	}
+
+	if hide302.Reject(res) {
+		t.Errorf("final response was rejected")
+	}
+
+	if !(FilterFirst{Filter: hide302}).Reject(res) {
+		t.Errorf("first response was not rejected")
+	}
+
+	first := res.First()
+	if string(first.RawBody) != "redirect" || len(first.Redirects) != 0 {
+		t.Errorf("wrong first response %+v", first)
+	}
+
+	// without redirects, the response itself is the first one
+	res.Redirects = nil
+	if (FilterFirst{Filter: hide302}).Reject(res) {
+		t.Errorf("response without redirects was rejected")
+	}
+}
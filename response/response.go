@@ -20,18 +20,37 @@ import (
 
 // Response is an HTTP response.
 type Response struct {
-	Item     string
+	Item     string   // all values joined for display
+	Index    int      // position of the values in the sequence of items
+	Values   []string // values inserted into the request
+	Method   string   // HTTP method of the request
+	Host     string   // Host header of the request
+	Depth    int      // recursion depth of the values
+	Feedback bool     // values have been extracted from another response
+	ID       string   // correlation ID sent with the request, if any
 	URL      string
 	Error    error
 	Duration time.Duration
+	Retries  int // number of times the request has been sent again
+
+	// Throttled is the time by which this response extended the pause of
+	// all requests requested with a Retry-After header.
+	Throttled time.Duration
 
 	Header, Body TextStats
 	Extract      []string
+	Fields       map[string][]string // values of named subexpressions of the extract patterns
 
 	HTTPResponse *http.Response
 	RawBody      []byte
 	RawHeader    []byte
 
+	// Redirects are the responses which have been followed before
+	// HTTPResponse was received, in order (see Runner.FollowRedirects).
+	Redirects []Redirect
+
+	BodyFile string // file the body has been saved to, if any
+
 	Hide bool // can be set by a filter, response should not be displayed
 }
 
@@ -66,13 +85,34 @@ func (r Response) String() string {
 			status += ", Location: " + loc[0]
 		}
 	}
+	switch len(r.Redirects) {
+	case 0:
+	case 1:
+		status += ", 1 redirect to " + r.FinalURL()
+	default:
+		status += fmt.Sprintf(", %d redirects to %v", len(r.Redirects), r.FinalURL())
+	}
+	// show how range and conditional requests were handled
+	switch res.StatusCode {
+	case http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable:
+		if rng := res.Header.Get("Content-Range"); rng != "" {
+			status += ", Content-Range: " + rng
+		}
+	case http.StatusNotModified:
+		if etag := res.Header.Get("ETag"); etag != "" {
+			status += ", ETag: " + etag
+		}
+	}
 	if len(r.Extract) > 0 {
 		status += " data: " + strings.Join(quote(r.Extract), ", ")
 	}
 	return status
 }
 
-func extractRegexp(buf []byte, targets []*regexp.Regexp) (data []string) {
+// extractRegexp adds the matches of the targets in buf to r. The values of
+// named subexpressions are added to Fields, the other subexpressions (or the
+// whole match if there are none) to Extract.
+func (r *Response) extractRegexp(buf []byte, targets []*regexp.Regexp) {
 	for _, reg := range targets {
 		if !reg.Match(buf) {
 			continue
@@ -80,19 +120,42 @@ func extractRegexp(buf []byte, targets []*regexp.Regexp) (data []string) {
 
 		if reg.NumSubexp() == 0 {
 			for _, m := range reg.FindAll(buf, -1) {
-				data = append(data, string(m))
+				r.Extract = append(r.Extract, string(m))
 			}
-		} else {
-			matches := reg.FindAll(buf, -1)
-			for _, match := range matches {
-				for _, m := range reg.FindSubmatch(match)[1:] {
-					data = append(data, string(m))
+			continue
+		}
+
+		names := reg.SubexpNames()
+		for _, match := range reg.FindAllSubmatch(buf, -1) {
+			for i, m := range match[1:] {
+				name := names[i+1]
+				if name == "" {
+					r.Extract = append(r.Extract, string(m))
+					continue
+				}
+
+				if r.Fields == nil {
+					r.Fields = make(map[string][]string)
 				}
+				r.Fields[name] = append(r.Fields[name], string(m))
 			}
 		}
 	}
+}
 
-	return data
+// FieldNames returns the names of the subexpressions of the targets, in order
+// and without duplicates.
+func FieldNames(targets []*regexp.Regexp) (names []string) {
+	seen := make(map[string]bool)
+	for _, reg := range targets {
+		for _, name := range reg.SubexpNames() {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
 }
 
 func extractCommand(buf []byte, cmds [][]string) (data []string, err error) {
@@ -138,7 +201,7 @@ func (r *Response) ReadBody(body io.Reader, maxBodySize int) error {
 
 // ExtractBody extracts data from the HTTP response body.
 func (r *Response) ExtractBody(targets []*regexp.Regexp) {
-	r.Extract = append(r.Extract, extractRegexp(r.RawBody, targets)...)
+	r.extractRegexp(r.RawBody, targets)
 }
 
 // ExtractBodyCommand extracts data from the HTTP response body by running an external command.
@@ -160,7 +223,7 @@ func (r *Response) ExtractHeader(res *http.Response, targets []*regexp.Regexp) e
 
 	r.RawHeader = buf
 	r.Header, err = Count(bytes.NewReader(buf))
-	r.Extract = append(r.Extract, extractRegexp(buf, targets)...)
+	r.extractRegexp(buf, targets)
 
 	return err
 }
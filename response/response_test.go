@@ -1,6 +1,7 @@
 package response
 
 import (
+	"net/http"
 	"reflect"
 	"regexp"
 	"strings"
@@ -57,6 +58,7 @@ func TestExtractBody(t *testing.T) {
 		body    string
 		targets []*regexp.Regexp
 		data    []string
+		fields  map[string][]string
 	}{
 		{
 			body: "foo bar baz",
@@ -105,6 +107,29 @@ func TestExtractBody(t *testing.T) {
 				"foo", "bar", "baz",
 			},
 		},
+		{
+			body: `{"name": "alice", "role": "admin"} {"name": "bob"}`,
+			targets: []*regexp.Regexp{
+				regexp.MustCompile(`"name": "(?P<user>[^"]*)"`),
+				regexp.MustCompile(`"role": "(?P<role>[^"]*)"`),
+			},
+			fields: map[string][]string{
+				"user": {"alice", "bob"},
+				"role": {"admin"},
+			},
+		},
+		{
+			body: "id=23 token=abc",
+			targets: []*regexp.Regexp{
+				regexp.MustCompile(`id=(?P<id>\d+) token=(\w+)`),
+			},
+			data: []string{
+				"abc",
+			},
+			fields: map[string][]string{
+				"id": {"23"},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -120,6 +145,47 @@ func TestExtractBody(t *testing.T) {
 			if !reflect.DeepEqual(test.data, r.Extract) {
 				t.Fatalf("wrong data, want %q, got %q", test.data, r.Extract)
 			}
+
+			if !reflect.DeepEqual(test.fields, r.Fields) {
+				t.Fatalf("wrong fields, want %q, got %q", test.fields, r.Fields)
+			}
+		})
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	targets := []*regexp.Regexp{
+		regexp.MustCompile(`(?P<user>\w+)@(?P<domain>\S+)`),
+		regexp.MustCompile(`(\d+)`),
+		regexp.MustCompile(`id=(?P<id>\d+) user=(?P<user>\w+)`),
+	}
+
+	want := []string{"user", "domain", "id"}
+	names := FieldNames(targets)
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("wrong names, want %q, got %q", want, names)
+	}
+}
+
+func TestResponseStringConditional(t *testing.T) {
+	var tests = []struct {
+		status int
+		header http.Header
+		want   string
+	}{
+		{http.StatusPartialContent, http.Header{"Content-Range": []string{"bytes 0-0/100"}}, ", Content-Range: bytes 0-0/100"},
+		{http.StatusRequestedRangeNotSatisfiable, http.Header{"Content-Range": []string{"bytes */100"}}, ", Content-Range: bytes */100"},
+		{http.StatusNotModified, http.Header{"Etag": []string{`"abc"`}}, `, ETag: "abc"`},
+		{http.StatusOK, http.Header{"Etag": []string{`"abc"`}}, "response"},
+	}
+
+	for _, test := range tests {
+		t.Run(http.StatusText(test.status), func(t *testing.T) {
+			res := &http.Response{StatusCode: test.status, Header: test.header, ProtoMajor: 1}
+			s := Response{HTTPResponse: res, Item: "response"}.String()
+			if !strings.HasSuffix(s, test.want) {
+				t.Errorf("wrong suffix, want %q, got %q", test.want, s)
+			}
 		})
 	}
 }
@@ -0,0 +1,116 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultRetryOn lists the conditions for which requests are sent again by
+// default.
+var DefaultRetryOn = []string{"429", "502", "503", "504", "timeout"}
+
+// Retry sends requests again which failed with a transient error, e.g. a
+// timeout or a status code like 503. Between the attempts it waits for an
+// exponential backoff starting at MinBackoff and limited to MaxBackoff.
+type Retry struct {
+	Attempts   int // maximum number of additional requests
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	status  []func(int) bool
+	timeout bool // retry requests which timed out
+	errors  bool // retry requests which failed with any error
+}
+
+// NewRetry returns a Retry which sends requests again at most attempts times.
+// The conditions in on are status codes with the same syntax as for filters
+// (e.g. 429 or 500-599), "timeout" or "error" for all failed requests. The
+// backoff is either a fixed duration (e.g. 2s) or a range (e.g. 1s..30s).
+func NewRetry(attempts int, on []string, backoff string) (*Retry, error) {
+	if attempts < 0 {
+		return nil, fmt.Errorf("invalid number of retries %d", attempts)
+	}
+
+	r := &Retry{Attempts: attempts}
+	for _, spec := range on {
+		switch strings.TrimSpace(spec) {
+		case "timeout":
+			r.timeout = true
+		case "error":
+			r.errors = true
+		default:
+			f, err := parseRangeFilterSpec(spec)
+			if err != nil {
+				return nil, err
+			}
+			r.status = append(r.status, f)
+		}
+	}
+
+	var err error
+	r.MinBackoff, r.MaxBackoff, err = parseBackoff(backoff)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// parseBackoff parses a fixed duration or a range min..max.
+func parseBackoff(s string) (min, max time.Duration, err error) {
+	parts := strings.SplitN(s, "..", 2)
+	min, err = time.ParseDuration(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid backoff %q: %v", s, err)
+	}
+
+	max = min
+	if len(parts) == 2 {
+		max, err = time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid backoff %q: %v", s, err)
+		}
+	}
+
+	if min < 0 || max < min {
+		return 0, 0, fmt.Errorf("invalid backoff %q, use min..max", s)
+	}
+
+	return min, max, nil
+}
+
+// Backoff returns the time to wait before the retry n, starting at zero.
+func (r *Retry) Backoff(n int) time.Duration {
+	d := r.MinBackoff
+	for i := 0; i < n && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+
+	if d > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+	return d
+}
+
+// match returns true if the request for res should be sent again.
+func (r *Retry) match(res Response) bool {
+	if res.Error != nil {
+		if r.errors {
+			return true
+		}
+
+		e, ok := res.Error.(net.Error)
+		return r.timeout && (res.Error == context.DeadlineExceeded || (ok && e.Timeout()))
+	}
+
+	for _, f := range r.status {
+		if f(res.HTTPResponse.StatusCode) {
+			return true
+		}
+	}
+
+	return false
+}
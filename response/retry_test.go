@@ -0,0 +1,163 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	var tests = []struct {
+		backoff string
+		want    []time.Duration
+	}{
+		{"1s..30s", []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}},
+		{"100ms..250ms", []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond}},
+		{"2s", []time.Duration{2 * time.Second, 2 * time.Second}},
+		{"0s", []time.Duration{0, 0}},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r, err := NewRetry(3, nil, test.backoff)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for n, want := range test.want {
+				if got := r.Backoff(n); got != want {
+					t.Errorf("backoff %d: want %v, got %v", n, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRetryInvalid(t *testing.T) {
+	var tests = []struct {
+		attempts int
+		on       []string
+		backoff  string
+	}{
+		{-1, nil, "1s"},
+		{3, []string{"foo"}, "1s"},
+		{3, nil, "1x"},
+		{3, nil, "5s..1s"},
+		{3, nil, "1s..x"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			_, err := NewRetry(test.attempts, test.on, test.backoff)
+			if err == nil {
+				t.Fatal("expected error not found")
+			}
+		})
+	}
+}
+
+func TestRunnerRetry(t *testing.T) {
+	var tests = []struct {
+		failures    int32
+		attempts    int
+		on          []string
+		wantStatus  int
+		wantRetries int
+	}{
+		{2, 3, DefaultRetryOn, http.StatusOK, 2},
+		{5, 3, DefaultRetryOn, http.StatusServiceUnavailable, 3},
+		{2, 3, []string{"429", "timeout"}, http.StatusServiceUnavailable, 0},
+		{2, 0, DefaultRetryOn, http.StatusServiceUnavailable, 0},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&requests, 1) <= test.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer srv.Close()
+
+			tmpl := request.New("")
+			tmpl.URL = srv.URL + "/FUZZ"
+
+			tr, err := NewTransport(tmpl, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			in := make(chan producer.Item, 1)
+			in <- producer.Item{Values: []string{"foo"}}
+			close(in)
+			out := make(chan Response, 1)
+
+			runner := NewRunner(tr, tmpl, in, out)
+			runner.Retry, err = NewRetry(test.attempts, test.on, "1ms..5ms")
+			if err != nil {
+				t.Fatal(err)
+			}
+			runner.Run(context.Background())
+
+			res := <-out
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if res.HTTPResponse.StatusCode != test.wantStatus {
+				t.Errorf("wrong status, want %v, got %v", test.wantStatus, res.HTTPResponse.StatusCode)
+			}
+
+			if res.Retries != test.wantRetries {
+				t.Errorf("wrong number of retries, want %v, got %v", test.wantRetries, res.Retries)
+			}
+		})
+	}
+}
+
+func TestRunnerRetryTimeout(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	tmpl := request.New("")
+	tmpl.URL = srv.URL + "/FUZZ"
+	tmpl.Timeout = 50 * time.Millisecond
+
+	tr, err := NewTransport(tmpl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make(chan producer.Item, 1)
+	in <- producer.Item{Values: []string{"foo"}}
+	close(in)
+	out := make(chan Response, 1)
+
+	runner := NewRunner(tr, tmpl, in, out)
+	runner.Retry, err = NewRetry(2, []string{"timeout"}, "1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.Run(context.Background())
+
+	res := <-out
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	if res.Retries != 1 {
+		t.Errorf("wrong number of retries, want 1, got %v", res.Retries)
+	}
+}
@@ -3,117 +3,207 @@ package response
 import (
 	"context"
 	"crypto/tls"
-	"encoding/pem"
-	"fmt"
-	"io/ioutil"
+	"errors"
 	"net"
 	"net/http"
+	"net/http/cookiejar"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/RedTeamPentesting/monsoon/producer"
 	"github.com/RedTeamPentesting/monsoon/request"
 	"golang.org/x/net/http2"
+	"golang.org/x/net/publicsuffix"
 )
 
 // Runner executes HTTP requests.
 type Runner struct {
 	Template *request.Request
+	Names    []string // placeholders which are replaced with the values
+
+	// FeedbackTemplate is used instead of Template for items with values
+	// extracted from responses, if set.
+	FeedbackTemplate *request.Request
 
 	BodyBufferSize int
 	Extract        []*regexp.Regexp
 
+	// FollowRedirects is the maximal number of redirects which are followed,
+	// the responses are recorded in Redirects.
+	FollowRedirects int
+
+	// ClientCertPerValue loads the TLS client certificate for each request,
+	// with the values inserted into the file names of the template.
+	ClientCertPerValue bool
+
+	// SNIPerValue sets the TLS server name for each request, with the values
+	// inserted into the SNI of the template.
+	SNIPerValue bool
+
+	// WrapTransport returns the round tripper used for the transport built
+	// for each request when ClientCertPerValue or SNIPerValue is set, e.g. to
+	// add authentication. It must add the header order of the template (see
+	// NewHeaderOrderTransport). If it is nil, only the header order is added.
+	WrapTransport func(*http.Transport) http.RoundTripper
+
+	// HideItemValues lists placeholders whose values are left out of the
+	// Item of the responses, e.g. because they are shown in a separate
+	// column. The values are still recorded in Values.
+	HideItemValues []string
+
+	// Retry sends requests again which failed with a transient error, if set.
+	Retry *Retry
+
+	// Throttle pauses all requests when the target asks for it with a
+	// Retry-After header, if set.
+	Throttle *Throttle
+
+	// HostLimiter limits the number of concurrent requests per host, if set.
+	HostLimiter *HostLimiter
+
+	// UserAgents selects the User-Agent for each request, if set.
+	UserAgents *UserAgents
+
+	// CorrelationHeader is the name of a header field which is set to the
+	// ID of each request (see VariableID), the ID is recorded in the
+	// response. No field is sent if it is empty.
+	CorrelationHeader string
+
+	// ProxyPool selects the proxy for each request, if set. The Proxy
+	// function of the transport must be the one of the pool.
+	ProxyPool *ProxyPool
+
+	// Sessions provide tokens inserted into all requests.
+	Sessions []*Session
+
+	// WebSocketWait is the time to wait for each message received via a
+	// WebSocket, at most WebSocketMessages messages are read, see webSocket.
+	WebSocketWait     time.Duration
+	WebSocketMessages int
+
 	Client    *http.Client
 	Transport *http.Transport
 
-	input  <-chan string
+	input  <-chan producer.Item
 	output chan<- Response
 }
 
 // DefaultBodyBufferSize is the default size for peeking at the body to extract strings via regexp.
 const DefaultBodyBufferSize = 5 * 1024 * 1024
 
-// NewTransport creates a new shared transport for clients to use.
-func NewTransport(insecure bool, TLSClientCertKeyFilename string, disableHTTP2 bool) (*http.Transport, error) {
+// NewTransport creates a new shared transport for clients to use, configured
+// with the TLS and connection options of the template. The TLS client
+// certificate is sent if it is not nil, see LoadClientCertificate. With
+// ForceHTTP2, all requests are sent via HTTP/2, see configureHTTP2Only.
+func NewTransport(template *request.Request, clientCert *tls.Certificate) (*http.Transport, error) {
+	if template.DisableHTTP2 && template.ForceHTTP2 {
+		return nil, errors.New("HTTP/2 cannot be disabled and enforced at the same time")
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   orDefault(template.ConnectTimeout, request.DefaultConnectTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+
 	// for timeouts, see
 	// https://blog.cloudflare.com/the-complete-guide-to-golang-net-http-timeouts/
 	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		Dial: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).Dial,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ResponseHeaderTimeout: 10 * time.Second,
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   orDefault(template.TLSTimeout, request.DefaultTLSTimeout),
+		ResponseHeaderTimeout: orDefault(template.HeaderTimeout, request.DefaultHeaderTimeout),
 		ExpectContinueTimeout: 1 * time.Second,
 		IdleConnTimeout:       15 * time.Second,
 		TLSClientConfig:       &tls.Config{},
 	}
 
-	if insecure {
-		tr.TLSClientConfig.InsecureSkipVerify = true
+	if template.ConnectTo != "" || template.UnixSocket != "" || template.SOCKS5 != "" {
+		// the connection target is fixed or the SOCKS5 proxy is used, so an
+		// HTTP proxy cannot be used
+		tr.Proxy = nil
 	}
 
-	if !disableHTTP2 {
-		// enable http2
-		err := http2.ConfigureTransport(tr)
-		if err != nil {
-			return nil, err
+	if template.Proxy != "" {
+		if tr.Proxy == nil {
+			return nil, errors.New("--proxy cannot be combined with --connect-to, --unix-socket or --socks5")
 		}
-	}
 
-	if TLSClientCertKeyFilename != "" {
-		certs, key, err := readPEMCertKey(TLSClientCertKeyFilename)
+		proxyURL, err := parseProxy(template.Proxy)
 		if err != nil {
 			return nil, err
 		}
 
-		crt, err := tls.X509KeyPair(certs, key)
-		if err != nil {
-			return nil, fmt.Errorf("parse TLS client cert or key: %v", err)
+		// the server name would be sent to the proxy as well
+		if proxyURL.Scheme == "https" && template.SNI != "" {
+			return nil, errors.New("--sni cannot be used with an HTTPS proxy")
 		}
-		tr.TLSClientConfig.Certificates = []tls.Certificate{crt}
+
+		tr.Proxy = http.ProxyURL(proxyURL)
 	}
 
-	return tr, nil
-}
+	var err error
+	dialer.LocalAddr, err = localAddr(template)
+	if err != nil {
+		return nil, err
+	}
 
-// readPEMCertKey reads a file and returns the PEM encoded certificate and key
-// blocks.
-func readPEMCertKey(filename string) (certs []byte, key []byte, err error) {
-	data, err := ioutil.ReadFile(filename)
+	tr.DialContext, err = dialFunc(dialer, template)
 	if err != nil {
-		return nil, nil, fmt.Errorf("ReadFile: %v", err)
+		return nil, err
 	}
 
-	var block *pem.Block
-	for {
-		if len(data) == 0 {
-			break
-		}
-		block, data = pem.Decode(data)
-		if block == nil {
-			break
-		}
+	tr.DialContext, err = ipFamily(tr.DialContext, template)
+	if err != nil {
+		return nil, err
+	}
 
-		switch {
-		case strings.HasSuffix(block.Type, "CERTIFICATE"):
-			certs = append(certs, pem.EncodeToMemory(block)...)
-		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
-			if key != nil {
-				return nil, nil, fmt.Errorf("error loading TLS cert and key from %v: more than one private key found", filename)
-			}
-			key = pem.EncodeToMemory(block)
-		default:
-			return nil, nil, fmt.Errorf("error loading TLS cert and key from %v: unknown block type %v found", filename, block.Type)
+	if template.Insecure {
+		tr.TLSClientConfig.InsecureSkipVerify = true
+	}
+
+	tr.TLSClientConfig.ServerName = template.SNI
+
+	err = configureTLS(tr.TLSClientConfig, template)
+	if err != nil {
+		return nil, err
+	}
+
+	if !template.DisableHTTP2 && !template.ForceHTTP2 {
+		// enable http2
+		err = http2.ConfigureTransport(tr)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return certs, key, nil
+	if clientCert != nil {
+		tr.TLSClientConfig.Certificates = []tls.Certificate{*clientCert}
+	}
+
+	if template.ForceHTTP2 {
+		_ = configureHTTP2Only(tr, orDefault(template.ConnectTimeout, request.DefaultConnectTimeout))
+	}
+
+	return tr, nil
+}
+
+// orDefault returns d, or def if d is zero.
+func orDefault(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
+
+// NewCookieJar returns a cookie jar which stores the cookies set in responses
+// and sends them with subsequent requests.
+func NewCookieJar() (http.CookieJar, error) {
+	return cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
 }
 
 // NewRunner returns a new runner to execute HTTP requests.
-func NewRunner(tr *http.Transport, template *request.Request, input <-chan string, output chan<- Response) *Runner {
+func NewRunner(tr *http.Transport, template *request.Request, input <-chan producer.Item, output chan<- Response) *Runner {
 	c := &http.Client{
 		Transport: tr,
 		CheckRedirect: func(*http.Request, []*http.Request) error {
@@ -121,36 +211,298 @@ func NewRunner(tr *http.Transport, template *request.Request, input <-chan strin
 		},
 	}
 
+	if template.CustomWriter() {
+		c.Transport = NewHeaderOrderTransport(tr, template)
+	}
+
 	return &Runner{
 		Template:       template,
+		Names:          []string{template.Replace},
 		Client:         c,
 		Transport:      tr,
 		input:          input,
 		output:         output,
 		BodyBufferSize: DefaultBodyBufferSize,
+
+		WebSocketWait:     DefaultWebSocketWait,
+		WebSocketMessages: DefaultWebSocketMessages,
+	}
+}
+
+func (r *Runner) request(ctx context.Context, item producer.Item) Response {
+	if len(r.Sessions) == 0 {
+		return r.send(ctx, item, r.Names, item.Values, item.Batch, nil)
+	}
+
+	tokens := make([]string, len(r.Sessions))
+	generations := make([]int, len(r.Sessions))
+	for i, s := range r.Sessions {
+		err := s.Refresh(ctx)
+		if err != nil {
+			response := r.newResponse(item)
+			response.Error = err
+			return response
+		}
+
+		tokens[i], generations[i] = s.Token()
+	}
+
+	response := r.sendWithTokens(ctx, item, tokens)
+	if response.Error != nil {
+		return response
+	}
+
+	// renew expired sessions and retry the request once
+	var renewed bool
+	for i, s := range r.Sessions {
+		if !s.expired(response) {
+			continue
+		}
+
+		err := s.Renew(ctx, generations[i])
+		if err != nil {
+			response.Error = err
+			return response
+		}
+
+		tokens[i], _ = s.Token()
+		renewed = true
+	}
+
+	if renewed {
+		response = r.sendWithTokens(ctx, item, tokens)
+	}
+
+	return response
+}
+
+// requestWithRetries sends the request for item again as long as the
+// response matches the retry policy, waiting for the backoff in between.
+func (r *Runner) requestWithRetries(ctx context.Context, item producer.Item) Response {
+	res := r.request(ctx, item)
+	if r.Retry == nil {
+		return res
 	}
+
+	for n := 0; n < r.Retry.Attempts && r.Retry.match(res); n++ {
+		t := time.NewTimer(r.Retry.Backoff(n))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return res
+		case <-t.C:
+		}
+
+		res = r.request(ctx, item)
+		res.Retries = n + 1
+	}
+
+	return res
 }
 
-func (r *Runner) request(ctx context.Context, item string) (response Response) {
-	req, err := r.Template.Apply(item)
+// sendWithTokens sends the request for item with the tokens of the sessions
+// inserted or set in the header.
+func (r *Runner) sendWithTokens(ctx context.Context, item producer.Item, tokens []string) Response {
+	names := append([]string{}, r.Names...)
+	var inserted []string
+	header := make(http.Header)
+	for i, s := range r.Sessions {
+		if s.Header != "" {
+			header.Set(s.Header, s.HeaderPrefix+tokens[i])
+			continue
+		}
+		names = append(names, s.Placeholder)
+		inserted = append(inserted, tokens[i])
+	}
+
+	values := append(append([]string{}, item.Values...), inserted...)
+
+	var batch [][]string
+	for _, v := range item.Batch {
+		batch = append(batch, append(append([]string{}, v...), inserted...))
+	}
+
+	return r.send(ctx, item, names, values, batch, header)
+}
+
+// hideItemValue returns true if the value for the placeholder name is left
+// out of the Item of responses.
+func (r *Runner) hideItemValue(name string) bool {
+	for _, n := range r.HideItemValues {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// newResponse returns a response for item. The values for the placeholders in
+// HideItemValues are left out of the Item.
+func (r *Runner) newResponse(item producer.Item) Response {
+	shown := item.Values
+	if len(r.HideItemValues) > 0 {
+		shown = nil
+		for i, v := range item.Values {
+			if i < len(r.Names) && r.hideItemValue(r.Names[i]) {
+				continue
+			}
+			shown = append(shown, v)
+		}
+	}
+
+	return Response{
+		Item:   strings.Join(shown, ", "),
+		Index:  item.Index,
+		Values: item.Values,
+		Depth:  item.Depth,
+
+		Feedback: item.Feedback,
+	}
+}
+
+// send executes the HTTP request for item with the values inserted for the
+// placeholders in names and the fields in header set. The built-in variables
+// are inserted as well, see Variables.
+func (r *Runner) send(ctx context.Context, item producer.Item, names, values []string, batch [][]string, header http.Header) (response Response) {
+	response = r.newResponse(item)
+
+	varNames, varValues, err := Variables(item.Index)
+	if err != nil {
+		response.Error = err
+		return
+	}
+
+	names = append(append([]string{}, names...), varNames...)
+	values = append(append([]string{}, values...), varValues...)
+
+	var batchValues [][]string
+	for _, v := range batch {
+		batchValues = append(batchValues, append(append([]string{}, v...), varValues...))
+	}
+	batch = batchValues
+
+	tmpl := r.Template
+	if item.Feedback && r.FeedbackTemplate != nil {
+		tmpl = r.FeedbackTemplate
+	}
+
+	var req *http.Request
+	if len(batch) > 0 {
+		req, err = tmpl.ApplyBatch(names, batch)
+	} else {
+		req, err = tmpl.Apply(names, values)
+	}
 	if err != nil {
 		response.Error = err
 		return
 	}
 
-	response = Response{
-		URL:  req.URL.String(),
-		Item: item,
+	for name, v := range header {
+		req.Header[name] = v
+	}
+
+	if r.UserAgents != nil {
+		req.Header.Set("User-Agent", r.UserAgents.Next())
+	}
+
+	if r.CorrelationHeader != "" {
+		for i, name := range varNames {
+			if name == VariableID {
+				response.ID = varValues[i]
+			}
+		}
+		req.Header.Set(r.CorrelationHeader, response.ID)
+	}
+
+	response.URL = request.URLString(req)
+	response.Method = req.Method
+	response.Host = req.Host
+	if response.Host == "" {
+		response.Host = req.URL.Host
+	}
+
+	client, tr := r.Client, r.Transport
+	if r.ClientCertPerValue || r.SNIPerValue {
+		var closeIdle func()
+		client, tr, closeIdle, err = r.clientPerValue(tmpl, names, values)
+		if err != nil {
+			response.Error = err
+			return
+		}
+		defer closeIdle()
+	}
+
+	var redirects *redirectTransport
+	if r.FollowRedirects > 0 {
+		redirects = &redirectTransport{RoundTripper: client.Transport, maxBodySize: r.BodyBufferSize}
+		c := *client
+		c.Transport = redirects
+		c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) <= r.FollowRedirects {
+				return nil
+			}
+			return http.ErrUseLastResponse
+		}
+		client = &c
+	}
+
+	if r.Throttle != nil {
+		err = r.Throttle.Wait(ctx)
+		if err != nil {
+			response.Error = err
+			return
+		}
+	}
+
+	if r.HostLimiter != nil {
+		release, err := r.HostLimiter.Acquire(ctx, req.URL.Host)
+		if err != nil {
+			response.Error = err
+			return
+		}
+		defer release()
+	}
+
+	if r.ProxyPool != nil {
+		proxy := r.ProxyPool.Next()
+		ctx = withProxy(ctx, proxy)
+		// the request timeout below replaces ctx and is cancelled first
+		proxyCtx := ctx
+		defer func() {
+			// requests are not counted as failed when the run is interrupted
+			if proxyCtx.Err() == nil {
+				r.ProxyPool.Report(proxy, response.Error)
+			}
+		}()
+	}
+
+	if tmpl.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tmpl.Timeout)
+		defer cancel()
 	}
 
 	start := time.Now()
-	res, err := r.Client.Do(req.WithContext(ctx))
+	var res *http.Response
+	if IsWebSocket(req) {
+		res, err = r.webSocket(ctx, req, tr)
+	} else {
+		res, err = client.Do(req.WithContext(ctx))
+	}
 	response.Duration = time.Since(start)
 	if err != nil {
 		response.Error = err
 		return
 	}
 
+	if r.Throttle != nil {
+		response.Throttled = r.Throttle.Observe(res)
+	}
+
+	if redirects != nil {
+		response.Redirects = redirects.followed(res)
+	}
+
 	err = response.ReadBody(res.Body, r.BodyBufferSize)
 	if err != nil {
 		response.Error = err
@@ -177,10 +529,62 @@ func (r *Runner) request(ctx context.Context, item string) (response Response) {
 	return
 }
 
+// clientPerValue returns a client which uses a new transport with the TLS
+// client certificate and the server name for the values. HTTP/2 is only used
+// if it is enforced (see configureHTTP2Only). The returned function closes the
+// idle connections of the transport.
+func (r *Runner) clientPerValue(tmpl *request.Request, names, values []string) (*http.Client, *http.Transport, func(), error) {
+	tr := r.Transport.Clone()
+	tr.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	tr.TLSClientConfig.NextProtos = nil
+
+	if r.ClientCertPerValue {
+		certFile := request.Insert(tmpl.TLSClientKeyCertFile, names, values)
+		keyFile := request.Insert(tmpl.TLSClientKeyFile, names, values)
+
+		cert, err := LoadClientCertificate(certFile, keyFile, tmpl.TLSClientCertPassword)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		tr.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+	}
+
+	if r.SNIPerValue {
+		tr.TLSClientConfig.ServerName = request.Insert(tmpl.SNI, names, values)
+	}
+
+	closeIdle := tr.CloseIdleConnections
+
+	// Clone does not copy the protocols registered for HTTP/2, the TLS
+	// config must be complete before they are registered
+	if tmpl.ForceHTTP2 {
+		closeH2 := configureHTTP2Only(tr, orDefault(tmpl.ConnectTimeout, request.DefaultConnectTimeout))
+		closeIdle = func() {
+			tr.CloseIdleConnections()
+			closeH2()
+		}
+	}
+
+	client := &http.Client{
+		Transport:     tr,
+		CheckRedirect: r.Client.CheckRedirect,
+		Jar:           r.Client.Jar,
+	}
+
+	switch {
+	case r.WrapTransport != nil:
+		client.Transport = r.WrapTransport(tr)
+	case tmpl.CustomWriter():
+		client.Transport = NewHeaderOrderTransport(tr, tmpl)
+	}
+
+	return client, tr, closeIdle, nil
+}
+
 // Run processes items read from ch and executes HTTP requests.
 func (r *Runner) Run(ctx context.Context) {
 	for item := range r.input {
-		res := r.request(ctx, item)
+		res := r.requestWithRetries(ctx, item)
 
 		select {
 		case <-ctx.Done():
@@ -0,0 +1,169 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestTimeouts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-header" {
+			time.Sleep(300 * time.Millisecond)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		if r.URL.Path == "/slow-body" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		fmt.Fprint(w, "body")
+	}))
+	defer srv.Close()
+
+	var tests = []struct {
+		path          string
+		headerTimeout time.Duration
+		timeout       time.Duration
+		fail          bool
+	}{
+		{"/slow-header", 0, 0, false},
+		{"/slow-header", 100 * time.Millisecond, 0, true},
+		{"/slow-body", 100 * time.Millisecond, 0, false},
+		{"/slow-body", 0, 100 * time.Millisecond, true},
+		{"/slow-body", 0, 2 * time.Second, false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v-%v-%v", test.path[1:], test.headerTimeout, test.timeout), func(t *testing.T) {
+			tmpl := request.New("")
+			tmpl.URL = srv.URL + test.path
+			tmpl.HeaderTimeout = test.headerTimeout
+			tmpl.Timeout = test.timeout
+
+			tr, err := NewTransport(tmpl, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			in := make(chan producer.Item, 1)
+			in <- producer.Item{Values: []string{"a"}}
+			close(in)
+			out := make(chan Response, 1)
+
+			NewRunner(tr, tmpl, in, out).Run(context.Background())
+
+			res := <-out
+			if test.fail && res.Error == nil {
+				t.Fatal("expected error not found")
+			}
+			if !test.fail && res.Error != nil {
+				t.Fatal(res.Error)
+			}
+		})
+	}
+}
+
+func TestCorrelationHeader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Header.Get("X-Request-Id"), r.URL.Query().Get("id"))
+	}))
+	defer srv.Close()
+
+	tmpl := request.New("")
+	tmpl.URL = srv.URL + "/?id=" + VariableID
+
+	tr, err := NewTransport(tmpl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make(chan producer.Item, 2)
+	in <- producer.Item{Values: []string{"a"}}
+	in <- producer.Item{Values: []string{"b"}}
+	close(in)
+	out := make(chan Response, 2)
+
+	runner := NewRunner(tr, tmpl, in, out)
+	runner.CorrelationHeader = "X-Request-ID"
+	runner.Run(context.Background())
+	close(out)
+
+	seen := make(map[string]bool)
+	for res := range out {
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+
+		if len(res.ID) != 16 {
+			t.Errorf("invalid ID %q", res.ID)
+		}
+		if seen[res.ID] {
+			t.Errorf("ID %q was used twice", res.ID)
+		}
+		seen[res.ID] = true
+
+		want := res.ID + " " + res.ID
+		if string(res.RawBody) != want {
+			t.Errorf("wrong body, want %q, got %q", want, res.RawBody)
+		}
+	}
+
+	if len(seen) != 2 {
+		t.Errorf("wrong number of responses: %v", len(seen))
+	}
+}
+
+func TestHideItemValues(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	tmpl := request.New("")
+	tmpl.URL = srv.URL + "/FUZZ"
+	tmpl.Method = "FUZZ_METHOD"
+
+	tr, err := NewTransport(tmpl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make(chan producer.Item, 1)
+	in <- producer.Item{Values: []string{"foo", "POST"}}
+	close(in)
+	out := make(chan Response, 1)
+
+	runner := NewRunner(tr, tmpl, in, out)
+	runner.Names = []string{"FUZZ", "FUZZ_METHOD"}
+	runner.HideItemValues = []string{"FUZZ_METHOD"}
+	runner.Run(context.Background())
+
+	res := <-out
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	if string(res.RawBody) != "POST /foo" {
+		t.Errorf("wrong body %q", res.RawBody)
+	}
+
+	if res.Method != "POST" {
+		t.Errorf("wrong method, want %q, got %q", "POST", res.Method)
+	}
+
+	if res.Item != "foo" {
+		t.Errorf("wrong item, want %q, got %q", "foo", res.Item)
+	}
+
+	if len(res.Values) != 2 {
+		t.Errorf("wrong values %q", res.Values)
+	}
+}
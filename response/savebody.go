@@ -0,0 +1,92 @@
+package response
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxBodyFileName is the maximal length of the part of a file name which is
+// derived from the values.
+const maxBodyFileName = 100
+
+// BodySaver writes the bodies of interesting (non-hidden) responses to files
+// in Dir, named after the values and the status code.
+type BodySaver struct {
+	Dir   string
+	Error func(error)
+}
+
+// bodyFileName returns a file name for the item and the status code which
+// only contains safe characters.
+func bodyFileName(item string, status int) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, item)
+
+	name = strings.TrimLeft(name, ".")
+	if len(name) > maxBodyFileName {
+		name = name[:maxBodyFileName]
+	}
+	if name == "" {
+		name = "_"
+	}
+
+	return fmt.Sprintf("%s_%d", name, status)
+}
+
+// save writes the body of res to a new file and returns the file name.
+// Existing files are not overwritten, a number is appended to the name
+// instead.
+func (s *BodySaver) save(res Response) (string, error) {
+	base := filepath.Join(s.Dir, bodyFileName(res.Item, res.HTTPResponse.StatusCode))
+	filename := base
+	for i := 2; ; i++ {
+		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			filename = fmt.Sprintf("%s-%d", base, i)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		_, err = f.Write(res.RawBody)
+		if err != nil {
+			_ = f.Close()
+			return "", err
+		}
+
+		return filename, f.Close()
+	}
+}
+
+// Run saves the bodies of all responses which are not hidden and records the
+// file name in BodyFile. Saving is done in a separate goroutine, which
+// terminates when the input channel is closed.
+func (s *BodySaver) Run(in <-chan Response) <-chan Response {
+	ch := make(chan Response)
+
+	go func() {
+		defer close(ch)
+		for res := range in {
+			if !res.Hide && res.Error == nil {
+				filename, err := s.save(res)
+				if err != nil && s.Error != nil {
+					s.Error(err)
+				}
+				res.BodyFile = filename
+			}
+
+			// forward response to next in chain
+			ch <- res
+		}
+	}()
+
+	return ch
+}
@@ -0,0 +1,89 @@
+package response
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBodyFileName(t *testing.T) {
+	var tests = []struct {
+		item   string
+		status int
+		want   string
+	}{
+		{"admin", 200, "admin_200"},
+		{"backup.tar.gz", 403, "backup.tar.gz_403"},
+		{"../../etc/passwd", 200, "_.._etc_passwd_200"},
+		{".htaccess", 403, "htaccess_403"},
+		{"foo bar, 23", 500, "foo_bar__23_500"},
+		{"", 404, "__404"},
+		{strings.Repeat("x", 300), 200, strings.Repeat("x", maxBodyFileName) + "_200"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			name := bodyFileName(test.item, test.status)
+			if name != test.want {
+				t.Errorf("wrong name for %q, want %q, got %q", test.item, test.want, name)
+			}
+		})
+	}
+}
+
+func TestBodySaver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monsoon-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := make(chan Response, 4)
+	in <- Response{Item: "admin", RawBody: []byte("first"), HTTPResponse: &http.Response{StatusCode: 200}}
+	in <- Response{Item: "admin", RawBody: []byte("second"), HTTPResponse: &http.Response{StatusCode: 200}}
+	in <- Response{Item: "hidden", RawBody: []byte("hidden"), HTTPResponse: &http.Response{StatusCode: 404}, Hide: true}
+	in <- Response{Item: "failed", Error: errors.New("connection refused")}
+	close(in)
+
+	saver := &BodySaver{
+		Dir: dir,
+		Error: func(err error) {
+			t.Error(err)
+		},
+	}
+
+	var files []string
+	for res := range saver.Run(in) {
+		files = append(files, res.BodyFile)
+	}
+
+	want := []string{filepath.Join(dir, "admin_200"), filepath.Join(dir, "admin_200-2"), "", ""}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("wrong file for response %d, want %q, got %q", i, want[i], files[i])
+		}
+	}
+
+	for i, body := range []string{"first", "second"} {
+		buf, err := ioutil.ReadFile(want[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(buf) != body {
+			t.Errorf("wrong body in %v, want %q, got %q", want[i], body, buf)
+		}
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("wrong number of files, want 2, got %d", len(entries))
+	}
+}
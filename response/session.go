@@ -0,0 +1,219 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Session sends a login request and extracts a token from the response. The
+// token is inserted into all requests sent by a Runner in place of
+// Placeholder, or sent in the header field Header prefixed with HeaderPrefix.
+// When a response indicates that the session has expired, the login request
+// is sent again.
+type Session struct {
+	Placeholder  string
+	Header       string
+	HeaderPrefix string
+	Request      *http.Request // login request, the body is taken from Body
+	Body         []byte
+
+	// the token is extracted with the first subexpression of Pattern (or the
+	// whole match) from the header and body of the response, or from the
+	// body with JSONPath or Selector (the first value, see HTMLQuery.Values)
+	Pattern  *regexp.Regexp
+	JSONPath *JSONPath
+	Selector *HTMLQuery
+
+	// responses rejected by one of the Expired filters indicate that the
+	// session has expired, the request is sent again after logging in
+	Expired []Filter
+
+	// LifetimeJSONPath is the path of the token lifetime in seconds in the
+	// JSON body of the login response, the login request is sent again
+	// shortly before the token expires
+	LifetimeJSONPath *JSONPath
+
+	Client *http.Client
+
+	mu         sync.Mutex
+	token      string
+	generation int
+	expires    time.Time
+}
+
+// renewBefore is the time before a token expires at which it is renewed.
+const renewBefore = 10 * time.Second
+
+// expired returns true if res indicates that the session has expired.
+func (s *Session) expired(res Response) bool {
+	for _, f := range s.Expired {
+		if f.Reject(res) {
+			return true
+		}
+	}
+	return false
+}
+
+// Token returns the current token and the number of logins.
+func (s *Session) Token() (token string, generation int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token, s.generation
+}
+
+// Login sends the login request and stores the token.
+func (s *Session) Login(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.login(ctx)
+}
+
+// Refresh logs in again if the token expires soon.
+func (s *Session) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expires.IsZero() || time.Until(s.expires) > renewBefore {
+		return nil
+	}
+
+	return s.login(ctx)
+}
+
+// Renew logs in again if no other login happened since the token for
+// generation was returned by Token, e.g. in another runner.
+func (s *Session) Renew(ctx context.Context, generation int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.generation != generation {
+		return nil
+	}
+
+	return s.login(ctx)
+}
+
+func (s *Session) login(ctx context.Context) error {
+	req := s.Request.Clone(ctx)
+	req.Body = ioutil.NopCloser(bytes.NewReader(s.Body))
+
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("login request failed: %v", err)
+	}
+
+	header, err := httputil.DumpResponse(res, false)
+	if err != nil {
+		_ = res.Body.Close()
+		return err
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, DefaultBodyBufferSize))
+	if err != nil {
+		_ = res.Body.Close()
+		return err
+	}
+
+	err = res.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	token, err := s.extract(header, body)
+	if err != nil {
+		return fmt.Errorf("login request returned status %v: %v", res.StatusCode, err)
+	}
+
+	s.token = token
+	s.generation++
+
+	s.expires = time.Time{}
+	if s.LifetimeJSONPath != nil {
+		// the lifetime is optional
+		lifetime, err := extractJSONPath(body, s.LifetimeJSONPath)
+		if err == nil {
+			seconds, err := strconv.ParseFloat(lifetime, 64)
+			if err != nil {
+				return fmt.Errorf("invalid token lifetime %q: %v", lifetime, err)
+			}
+			s.expires = time.Now().Add(time.Duration(seconds * float64(time.Second)))
+		}
+	}
+
+	return nil
+}
+
+// extract returns the token from the response.
+func (s *Session) extract(header, body []byte) (string, error) {
+	if s.JSONPath != nil {
+		return extractJSONPath(body, s.JSONPath)
+	}
+
+	if s.Selector != nil {
+		return extractHTMLQuery(body, s.Selector)
+	}
+
+	if s.Pattern == nil {
+		return "", errors.New("no pattern to extract the token specified")
+	}
+
+	for _, buf := range [][]byte{header, body} {
+		m := s.Pattern.FindSubmatch(buf)
+		if m == nil {
+			continue
+		}
+
+		if len(m) > 1 {
+			return string(m[1]), nil
+		}
+		return string(m[0]), nil
+	}
+
+	return "", fmt.Errorf("token not found with pattern %q", s.Pattern)
+}
+
+// extractJSONPath returns the first value selected by path in the JSON
+// document buf. Values which are not strings are returned as JSON.
+func extractJSONPath(buf []byte, path *JSONPath) (string, error) {
+	var doc interface{}
+	err := json.Unmarshal(buf, &doc)
+	if err != nil {
+		return "", fmt.Errorf("response is not valid JSON: %v", err)
+	}
+
+	values := path.Eval(doc)
+	if len(values) == 0 {
+		return "", fmt.Errorf("JSONPath %v not found", path)
+	}
+
+	return jsonString(values[0]), nil
+}
+
+// extractHTMLQuery returns the first value selected by q in the HTML document
+// buf.
+func extractHTMLQuery(buf []byte, q *HTMLQuery) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(buf))
+	if err != nil {
+		return "", err
+	}
+
+	values := q.Values(doc)
+	if len(values) == 0 {
+		return "", fmt.Errorf("no value found for selector %q", q)
+	}
+
+	return values[0], nil
+}
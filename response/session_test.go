@@ -0,0 +1,266 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestExtractJSONPath(t *testing.T) {
+	doc := []byte(`{"data": {"token": "secret", "list": [{"id": 23}, {"id": "x"}]}}`)
+
+	var tests = []struct {
+		path string
+		want string
+	}{
+		{"data.token", "secret"},
+		{"$.data.token", "secret"},
+		{"$.data.list[0].id", "23"},
+		{"$.data.list[-1].id", "x"},
+		{"$.data.list[1]", `{"id":"x"}`},
+		{"$..id", "23"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			path, err := ParseJSONPath(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := extractJSONPath(doc, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res != test.want {
+				t.Errorf("wrong result for %v, want %q, got %q", test.path, test.want, res)
+			}
+		})
+	}
+
+	for _, expr := range []string{"foo", "$.data.list[2]", "$.data.token.x", "$.data.list.x"} {
+		path, err := ParseJSONPath(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = extractJSONPath(doc, path)
+		if err == nil {
+			t.Errorf("expected error for path %v not found", expr)
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	var mu sync.Mutex
+	var logins int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/login" {
+			logins++
+			fmt.Fprintf(w, `{"token": "token%d"}`, logins)
+			return
+		}
+
+		// only the token from the second login is valid
+		if r.Header.Get("Authorization") != "token2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, "ok %v", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	login, err := http.NewRequest("POST", srv.URL+"/login", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := NewFilterStatusCode([]string{"401"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := &Session{
+		Placeholder: "TOKEN",
+		Request:     login,
+		Pattern:     regexp.MustCompile(`"(token[0-9]+)"`),
+		Expired:     []Filter{expired},
+		Client:      http.DefaultClient,
+	}
+
+	err = session.Login(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := request.New("")
+	tmpl.URL = srv.URL + "/FUZZ"
+	tmpl.Header.Header["Authorization"] = []string{"TOKEN"}
+
+	in := make(chan producer.Item, 2)
+	in <- producer.Item{Values: []string{"a"}}
+	in <- producer.Item{Values: []string{"b"}}
+	close(in)
+	out := make(chan Response, 2)
+
+	runner := NewRunner(http.DefaultTransport.(*http.Transport), tmpl, in, out)
+	runner.Sessions = []*Session{session}
+	runner.Run(context.Background())
+
+	for _, want := range []string{"ok /a", "ok /b"} {
+		res := <-out
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+
+		if string(res.RawBody) != want {
+			t.Errorf("wrong body, want %q, got %q (status %v)", want, res.RawBody, res.HTTPResponse.StatusCode)
+		}
+	}
+
+	if logins != 2 {
+		t.Errorf("wrong number of logins, want 2, got %d", logins)
+	}
+}
+
+func TestSessionHeaderLifetime(t *testing.T) {
+	var mu sync.Mutex
+	var logins int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/token" {
+			logins++
+			// the token expires soon, so it is renewed before each request
+			fmt.Fprintf(w, `{"access_token": "token%d", "token_type": "Bearer", "expires_in": 5}`, logins)
+			return
+		}
+
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token%d", logins) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, "ok %v", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	login, err := http.NewRequest("POST", srv.URL+"/token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenPath, err := ParseJSONPath("$.access_token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lifetimePath, err := ParseJSONPath("$.expires_in")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := &Session{
+		Header:           "Authorization",
+		HeaderPrefix:     "Bearer ",
+		Request:          login,
+		JSONPath:         tokenPath,
+		LifetimeJSONPath: lifetimePath,
+		Client:           http.DefaultClient,
+	}
+
+	err = session.Login(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := request.New("")
+	tmpl.URL = srv.URL + "/FUZZ"
+
+	in := make(chan producer.Item, 2)
+	in <- producer.Item{Values: []string{"a"}}
+	in <- producer.Item{Values: []string{"b"}}
+	close(in)
+	out := make(chan Response, 2)
+
+	runner := NewRunner(http.DefaultTransport.(*http.Transport), tmpl, in, out)
+	runner.Sessions = []*Session{session}
+	runner.Run(context.Background())
+
+	for _, want := range []string{"ok /a", "ok /b"} {
+		res := <-out
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+
+		if string(res.RawBody) != want {
+			t.Errorf("wrong body, want %q, got %q (status %v)", want, res.RawBody, res.HTTPResponse.StatusCode)
+		}
+	}
+
+	if logins != 3 {
+		t.Errorf("wrong number of logins, want 3, got %d", logins)
+	}
+}
+
+func TestExtractHTMLQuery(t *testing.T) {
+	doc := []byte(`<html><head><meta name="csrf-token" content="meta-token"></head>
+<body><form><input type="hidden" name="other" value="x"><input type="hidden" name='csrf' value="input-token"/>
+<div id="app" data-token="div-token"> div  text </div></form></body></html>`)
+
+	var tests = []struct {
+		selector string
+		want     string
+	}{
+		{"meta[name=csrf-token]", "meta-token"},
+		{"input[name=csrf]", "input-token"},
+		{`[name="csrf"]`, "input-token"},
+		{"input", "x"},
+		{"form > div", "div text"},
+		{"div#app@data-token", "div-token"},
+		{"#app@data-token", "div-token"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			q, err := ParseCSSSelector(test.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := extractHTMLQuery(doc, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res != test.want {
+				t.Errorf("wrong result for %v, want %q, got %q", test.selector, test.want, res)
+			}
+		})
+	}
+
+	for _, sel := range []string{"input[name=missing]", "div#app@value"} {
+		q, err := ParseCSSSelector(sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = extractHTMLQuery(doc, q)
+		if err == nil {
+			t.Errorf("expected error for selector %q not found", sel)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// SignCommandTransport runs an external command for each request, which can
+// add header fields, e.g. for a custom signature. The command receives the
+// raw request on stdin (and the method and URL in the environment variables
+// MONSOON_METHOD and MONSOON_URL) and prints header fields ("Name: value", one
+// per line) to stdout, which replace the fields of the request.
+type SignCommandTransport struct {
+	Transport http.RoundTripper
+	Command   []string
+}
+
+// parseHeaderLines parses the header fields printed by the command.
+func parseHeaderLines(buf []byte) (http.Header, error) {
+	header := make(http.Header)
+
+	sc := bufio.NewScanner(bytes.NewReader(buf))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+
+		pos := strings.IndexByte(line, ':')
+		if pos <= 0 {
+			return nil, fmt.Errorf("invalid header line %q, expected \"name: value\"", line)
+		}
+
+		name := strings.TrimSpace(line[:pos])
+		header.Add(textproto.CanonicalMIMEHeaderKey(name), strings.TrimSpace(line[pos+1:]))
+	}
+
+	return header, sc.Err()
+}
+
+// RoundTrip runs the command, adds the header fields and sends the request.
+func (t *SignCommandTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.Body = req.Body
+
+	// the body is restored after it has been read
+	dump, err := httputil.DumpRequestOut(r, true)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(req.Context(), t.Command[0], t.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(dump)
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"MONSOON_METHOD="+req.Method,
+		"MONSOON_URL="+request.URLString(req),
+	)
+
+	buf, err := cmd.Output()
+	if err != nil {
+		if r.Body != nil {
+			_ = r.Body.Close()
+		}
+		return nil, fmt.Errorf("sign command %s failed: %v", t.Command, err)
+	}
+
+	header, err := parseHeaderLines(buf)
+	if err != nil {
+		if r.Body != nil {
+			_ = r.Body.Close()
+		}
+		return nil, fmt.Errorf("sign command %s: %v", t.Command, err)
+	}
+
+	for name, values := range header {
+		if name == "Host" {
+			r.Host = values[0]
+			continue
+		}
+		r.Header[name] = values
+	}
+
+	return t.Transport.RoundTrip(r)
+}
@@ -0,0 +1,81 @@
+package response
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseHeaderLines(t *testing.T) {
+	header, err := parseHeaderLines([]byte("x-signature: abc\n\nX-Multi: 1\nX-Multi:2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header.Get("X-Signature") != "abc" {
+		t.Errorf("wrong header %v", header)
+	}
+
+	if v := header["X-Multi"]; len(v) != 2 || v[0] != "1" || v[1] != "2" {
+		t.Errorf("wrong header %v", header)
+	}
+
+	_, err = parseHeaderLines([]byte("no header"))
+	if err == nil {
+		t.Error("expected error not found")
+	}
+}
+
+func TestSignCommandTransport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test needs sh")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		fmt.Fprintf(w, "%s|%s|%s|%s", r.Header.Get("X-Method"), r.Header.Get("X-Sig"), r.Header.Get("X-Old"), buf)
+	}))
+	defer srv.Close()
+
+	// the signature is the last line of the raw request, which is the body
+	tr := &SignCommandTransport{
+		Transport: http.DefaultTransport,
+		Command:   []string{"sh", "-c", `echo "X-Method: $MONSOON_METHOD"; echo "X-Sig: $(tail -n 1 | tr a-z A-Z)"; echo "X-Old: new"`},
+	}
+	client := &http.Client{Transport: tr}
+
+	req, err := http.NewRequest("POST", srv.URL, strings.NewReader("secret body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Old", "old")
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	want := "POST|SECRET BODY|new|secret body"
+	if string(buf) != want {
+		t.Errorf("wrong response, want %q, got %q", want, buf)
+	}
+
+	tr.Command = []string{"false"}
+	_, err = client.Get(srv.URL)
+	if err == nil {
+		t.Error("expected error for failing command not found")
+	}
+}
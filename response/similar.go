@@ -0,0 +1,112 @@
+package response
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"sync"
+	"unicode"
+)
+
+// DefaultSimilarDistance is the default number of bits in which the hashes of
+// similar bodies may differ.
+const DefaultSimilarDistance = 3
+
+// Simhash returns a 64 bit similarity hash of the words in body: the hashes
+// of similar bodies differ in only a few bits.
+func Simhash(body []byte) (hash uint64, ok bool) {
+	var weights [64]int
+	var words int
+
+	add := func(word []byte) {
+		h := fnv.New64a()
+		_, _ = h.Write(word)
+		sum := h.Sum64()
+		for i := range weights {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+		words++
+	}
+
+	start := -1
+	for i, c := range body {
+		isWord := c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			add(body[start:i])
+			start = -1
+		}
+	}
+	if start >= 0 {
+		add(body[start:])
+	}
+
+	if words == 0 {
+		return 0, false
+	}
+
+	for i, w := range weights {
+		if w > 0 {
+			hash |= 1 << uint(i)
+		}
+	}
+
+	return hash, true
+}
+
+// FilterSimilar hides responses with a body similar to the body of a response
+// with the same status code seen before, e.g. "not found" pages which contain
+// the requested path. The hashes (see Simhash) of similar bodies differ in at
+// most Distance bits. Responses without words in the body are not hidden.
+// Only responses which are not hidden by other filters must be passed to it,
+// so it must be the last one.
+type FilterSimilar struct {
+	Distance int
+
+	mu     sync.Mutex
+	seen   map[int][]uint64 // hashes of the bodies per status code
+	hidden int
+}
+
+// Reject decides if r is to be printed.
+func (f *FilterSimilar) Reject(r Response) bool {
+	if r.HTTPResponse == nil {
+		return false
+	}
+
+	hash, ok := Simhash(r.RawBody)
+	if !ok {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.seen == nil {
+		f.seen = make(map[int][]uint64)
+	}
+
+	status := r.HTTPResponse.StatusCode
+	for _, h := range f.seen[status] {
+		if bits.OnesCount64(h^hash) <= f.Distance {
+			f.hidden++
+			return true
+		}
+	}
+
+	f.seen[status] = append(f.seen[status], hash)
+	return false
+}
+
+// Hidden returns the number of responses hidden so far.
+func (f *FilterSimilar) Hidden() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.hidden
+}
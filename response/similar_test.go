@@ -0,0 +1,70 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+const notFoundPage = `<!DOCTYPE html>
+<html><head><title>Page not found - Example Shop</title></head>
+<body>
+<div class="header"><a href="/">Home</a> <a href="/products">Products</a> <a href="/contact">Contact</a></div>
+<h1>Sorry, the page %v could not be found</h1>
+<p>The page you requested does not exist or has been moved. Please check the
+address or use the search below to find what you are looking for.</p>
+<form action="/search"><input name="q"><button>Search</button></form>
+<div class="footer">Copyright Example Shop, all rights reserved</div>
+</body></html>`
+
+func TestFilterSimilar(t *testing.T) {
+	res := func(status int, body string) Response {
+		return Response{
+			HTTPResponse: &http.Response{StatusCode: status},
+			RawBody:      []byte(body),
+		}
+	}
+
+	f := &FilterSimilar{Distance: DefaultSimilarDistance}
+
+	var tests = []struct {
+		res    Response
+		reject bool
+	}{
+		{res(200, fmt.Sprintf(notFoundPage, "/admin")), false},
+		{res(200, fmt.Sprintf(notFoundPage, "/backup")), true},
+		{res(200, fmt.Sprintf(notFoundPage, "/a/much/longer/path/to/something.php")), true},
+		{res(404, fmt.Sprintf(notFoundPage, "/admin")), false},
+		{res(200, "Welcome to the admin interface, please log in with your credentials."), false},
+		{res(200, ""), false},
+		{res(200, ""), false},
+		{Response{}, false},
+	}
+
+	for i, test := range tests {
+		if f.Reject(test.res) != test.reject {
+			t.Errorf("test %d: wrong result, want %v", i, test.reject)
+		}
+	}
+
+	if f.Hidden() != 2 {
+		t.Errorf("wrong number of hidden responses, want 2, got %d", f.Hidden())
+	}
+}
+
+func TestSimhash(t *testing.T) {
+	h1, ok := Simhash([]byte("foo bar baz"))
+	if !ok {
+		t.Fatal("no hash returned")
+	}
+
+	h2, _ := Simhash([]byte("foo, bar\nbaz!"))
+	if h1 != h2 {
+		t.Errorf("hashes for the same words differ: %x != %x", h1, h2)
+	}
+
+	_, ok = Simhash([]byte(" <>\n"))
+	if ok {
+		t.Error("hash returned for a body without words")
+	}
+}
@@ -0,0 +1,162 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Request smuggling techniques, named after the header used by the front-end
+// and the back-end server. With an obfuscated Transfer-Encoding header, one of
+// the servers may ignore it (TE.TE), which results in one of the two.
+const (
+	SmuggleCLTE = "CL.TE"
+	SmuggleTECL = "TE.CL"
+)
+
+// SmuggleVariant is a (possibly obfuscated) Transfer-Encoding header.
+type SmuggleVariant struct {
+	Name   string
+	Header string // raw header lines, separated by CRLF
+}
+
+// SmuggleVariants are the Transfer-Encoding headers used for the probes.
+var SmuggleVariants = []SmuggleVariant{
+	{"plain", "Transfer-Encoding: chunked"},
+	{"space before colon", "Transfer-Encoding : chunked"},
+	{"tab", "Transfer-Encoding:\tchunked"},
+	{"vertical tab", "Transfer-Encoding:\x0bchunked"},
+	{"mixed case", "Transfer-Encoding: cHuNkEd"},
+	{"xchunked", "Transfer-Encoding: xchunked"},
+	{"duplicate", "Transfer-Encoding: chunked\r\nTransfer-Encoding: x"},
+	{"duplicate reversed", "Transfer-Encoding: x\r\nTransfer-Encoding: chunked"},
+	{"line folding", "Transfer-Encoding:\r\n chunked"},
+	{"bare LF", "X: X\nTransfer-Encoding: chunked"},
+	{"leading space", " Transfer-Encoding: chunked"},
+}
+
+// SmuggleProbe is a request with conflicting Content-Length and
+// Transfer-Encoding headers. The body is incomplete for the back-end if the
+// front-end and back-end server use different headers to determine its
+// length, so the back-end waits for the rest and the request times out.
+type SmuggleProbe struct {
+	Technique string // SmuggleCLTE or SmuggleTECL, empty for the baseline
+	Variant   SmuggleVariant
+}
+
+// header returns the header lines and the body of the probe.
+func (p SmuggleProbe) header() (header, body string) {
+	switch p.Technique {
+	case SmuggleCLTE:
+		// the front-end forwards "1\r\nA" (Content-Length), the back-end
+		// waits for the end of the chunk. A server which uses
+		// Transfer-Encoding rejects the invalid chunk size X right away.
+		return p.Variant.Header + "\r\nContent-Length: 4", "1\r\nA\r\nX\r\n\r\n"
+	case SmuggleTECL:
+		// the front-end forwards "0\r\n\r\n" (the last chunk), the back-end
+		// waits for the sixth byte
+		return p.Variant.Header + "\r\nContent-Length: 6", "0\r\n\r\nX"
+	default:
+		return "Content-Length: 0", ""
+	}
+}
+
+// SmuggleResult is the outcome of a probe.
+type SmuggleResult struct {
+	SmuggleProbe
+	Status   string // status line of the response
+	Duration time.Duration
+	Timeout  bool // no response was received in time
+	Error    error
+}
+
+// rawRequest returns the probe with the request line and header fields of
+// req (except for Content-Length and Transfer-Encoding).
+func (p SmuggleProbe) rawRequest(req *http.Request) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	fmt.Fprintf(&buf, "Host: %s\r\n", removeZone(host))
+
+	var names []string
+	for name := range req.Header {
+		if name == "Content-Length" || name == "Transfer-Encoding" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, v := range req.Header[name] {
+			fmt.Fprintf(&buf, "%s: %s\r\n", name, v)
+		}
+	}
+
+	header, body := p.header()
+	buf.WriteString(header + "\r\n\r\n" + body)
+
+	return buf.Bytes()
+}
+
+// Smuggle sends the probe on a new connection opened with the settings of tr
+// and waits at most timeout for the response.
+func Smuggle(ctx context.Context, req *http.Request, tr *http.Transport, probe SmuggleProbe, timeout time.Duration) SmuggleResult {
+	res := SmuggleResult{SmuggleProbe: probe}
+
+	conn, err := dialConn(ctx, req, tr)
+	if err != nil {
+		res.Error = err
+		return res
+	}
+	defer conn.Close()
+
+	// close the connection when the context is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	start := time.Now()
+	err = conn.SetDeadline(start.Add(timeout))
+	if err != nil {
+		res.Error = err
+		return res
+	}
+
+	_, err = conn.Write(probe.rawRequest(req))
+	if err != nil {
+		res.Error = err
+		return res
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	res.Duration = time.Since(start)
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		res.Timeout = true
+		return res
+	}
+	if err != nil {
+		res.Error = err
+		return res
+	}
+	_ = response.Body.Close()
+
+	res.Status = response.Status
+	return res
+}
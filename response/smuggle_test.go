@@ -0,0 +1,111 @@
+package response
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// desyncServer answers requests on ln. The front-end determines the length of
+// the body via Content-Length and forwards it to the back-end, which uses
+// Transfer-Encoding if it is set to "chunked" (CL.TE). Without a front-end,
+// the server uses Transfer-Encoding.
+func desyncServer(t *testing.T, frontend bool) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil {
+					_, _ = conn.Write([]byte("HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n"))
+					return
+				}
+
+				body := req.Body
+				if frontend {
+					// forward exactly Content-Length bytes, the back-end
+					// parses the chunked body
+					n, _ := strconv.Atoi(req.Header.Get("Content-Length"))
+					pr, pw := io.Pipe()
+					go func() {
+						_, _ = io.CopyN(pw, conn, int64(n))
+					}()
+					body = ioutil.NopCloser(httputil.NewChunkedReader(pr))
+				}
+
+				_, err = ioutil.ReadAll(body)
+				if err != nil {
+					_, _ = conn.Write([]byte("HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n"))
+					return
+				}
+
+				_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestSmuggle(t *testing.T) {
+	var tests = []struct {
+		frontend bool
+		probe    SmuggleProbe
+		timeout  bool
+	}{
+		{false, SmuggleProbe{}, false},
+		{false, SmuggleProbe{Technique: SmuggleCLTE, Variant: SmuggleVariants[0]}, false},
+		{false, SmuggleProbe{Technique: SmuggleTECL, Variant: SmuggleVariants[0]}, false},
+		{true, SmuggleProbe{Technique: SmuggleCLTE, Variant: SmuggleVariants[0]}, true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			addr := desyncServer(t, test.frontend)
+
+			tmpl := request.New("")
+			tmpl.URL = "http://" + addr + "/"
+			tmpl.Method = "POST"
+
+			req, err := tmpl.Apply(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tr, err := NewTransport(tmpl, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := Smuggle(context.Background(), req, tr, test.probe, 300*time.Millisecond)
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if res.Timeout != test.timeout {
+				t.Errorf("wrong timeout result, want %v, got %v (status %q)", test.timeout, res.Timeout, res.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package response
+
+import (
+	"math/bits"
+	"net/http"
+)
+
+// Fingerprint describes the response to a request for a path which does not
+// exist, so that "not found" pages with a status code other than 404 (soft
+// 404s, e.g. the start page of a single page application) can be detected.
+type Fingerprint struct {
+	StatusCode int
+	Bytes      int    // size of the body, compared if it contains no words
+	Hash       uint64 // see Simhash
+	HasHash    bool
+}
+
+// NewFingerprint returns the fingerprint for res with body.
+func NewFingerprint(res *http.Response, body []byte) Fingerprint {
+	fp := Fingerprint{
+		StatusCode: res.StatusCode,
+		Bytes:      len(body),
+	}
+	fp.Hash, fp.HasHash = Simhash(body)
+	return fp
+}
+
+// Match returns true if r has the same status code and a similar body, the
+// hashes of the bodies may differ in at most distance bits.
+func (fp Fingerprint) Match(r Response, distance int) bool {
+	if r.HTTPResponse == nil || r.HTTPResponse.StatusCode != fp.StatusCode {
+		return false
+	}
+
+	hash, ok := Simhash(r.RawBody)
+	if ok != fp.HasHash {
+		return false
+	}
+	if !ok {
+		return len(r.RawBody) == fp.Bytes
+	}
+
+	return bits.OnesCount64(hash^fp.Hash) <= distance
+}
+
+// FilterSoft404 hides responses which match one of the fingerprints.
+type FilterSoft404 struct {
+	Fingerprints []Fingerprint
+	Distance     int
+}
+
+// Reject decides if r is to be printed.
+func (f FilterSoft404) Reject(r Response) bool {
+	for _, fp := range f.Fingerprints {
+		if fp.Match(r, f.Distance) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestFilterSoft404(t *testing.T) {
+	res := func(status int, body string) Response {
+		return Response{
+			HTTPResponse: &http.Response{StatusCode: status},
+			RawBody:      []byte(body),
+		}
+	}
+
+	notFound := fmt.Sprintf(notFoundPage, "/xkqhwzmbpl")
+	f := FilterSoft404{
+		Fingerprints: []Fingerprint{
+			NewFingerprint(&http.Response{StatusCode: 200}, []byte(notFound)),
+			NewFingerprint(&http.Response{StatusCode: 302}, nil),
+		},
+		Distance: DefaultSimilarDistance,
+	}
+
+	var tests = []struct {
+		res    Response
+		reject bool
+	}{
+		{res(200, fmt.Sprintf(notFoundPage, "/admin")), true},
+		{res(404, fmt.Sprintf(notFoundPage, "/admin")), false},
+		{res(200, "Welcome to the admin interface, please log in with your credentials."), false},
+		{res(200, ""), false},
+		{res(302, ""), true},
+		{res(302, "Found"), false},
+		{Response{}, false},
+	}
+
+	for i, test := range tests {
+		if f.Reject(test.res) != test.reject {
+			t.Errorf("test %d: wrong result, want %v", i, test.reject)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package response
+
+import (
+	"fmt"
+)
+
+// Stop ends a run early: Cancel is called when Matches responses have been
+// shown, or for the first shown response with one of the status codes. Only
+// responses which are not hidden by a filter are taken into account.
+type Stop struct {
+	Matches int    // number of shown responses, 0 means no limit
+	Cancel  func() // called once when a condition is met
+
+	status []func(int) bool
+	reason string
+}
+
+// NewStop returns a Stop for the number of matches and the status codes,
+// using the same syntax as for filters, e.g. 200 or 300-399.
+func NewStop(matches int, status []string, cancel func()) (*Stop, error) {
+	if matches < 0 {
+		return nil, fmt.Errorf("invalid number of matches %d", matches)
+	}
+
+	s := &Stop{Matches: matches, Cancel: cancel}
+	for _, spec := range status {
+		f, err := parseRangeFilterSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		s.status = append(s.status, f)
+	}
+
+	return s, nil
+}
+
+// check returns the reason for stopping after res, or the empty string.
+func (s *Stop) check(res Response, shown int) string {
+	if s.Matches > 0 && shown >= s.Matches {
+		return fmt.Sprintf("stopped after %d matching responses", shown)
+	}
+
+	if res.HTTPResponse == nil {
+		return ""
+	}
+
+	for _, f := range s.status {
+		if f(res.HTTPResponse.StatusCode) {
+			return fmt.Sprintf("stopped at status %d for %v", res.HTTPResponse.StatusCode, res.Item)
+		}
+	}
+
+	return ""
+}
+
+// Run forwards all responses from in, and calls Cancel after forwarding the
+// response for which a condition is met. The responses which are still
+// received afterwards are forwarded as well.
+func (s *Stop) Run(in <-chan Response) <-chan Response {
+	out := make(chan Response)
+
+	go func() {
+		defer close(out)
+
+		shown := 0
+		for res := range in {
+			out <- res
+
+			if s.reason != "" || res.Hide || res.Error != nil {
+				continue
+			}
+
+			shown++
+			s.reason = s.check(res, shown)
+			if s.reason != "" {
+				s.Cancel()
+			}
+		}
+	}()
+
+	return out
+}
+
+// Reason describes why the run was stopped, or is empty if no condition was
+// met. It must only be called after the channel returned by Run is closed.
+func (s *Stop) Reason() string {
+	return s.reason
+}
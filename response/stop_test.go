@@ -0,0 +1,83 @@
+package response
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestStop(t *testing.T) {
+	var tests = []struct {
+		matches int
+		status  []string
+		reason  string
+	}{
+		{0, nil, ""},
+		{2, nil, "stopped after 2 matching responses"},
+		{5, nil, ""},
+		{0, []string{"200"}, "stopped at status 200 for foo"},
+		{0, []string{"300-399"}, "stopped at status 302 for bar"},
+		{0, []string{"201"}, ""},
+		{3, []string{"500-"}, "stopped after 3 matching responses"},
+	}
+
+	responses := []Response{
+		{HTTPResponse: &http.Response{StatusCode: 404}},
+		{HTTPResponse: &http.Response{StatusCode: 404}, Hide: true},
+		{HTTPResponse: &http.Response{StatusCode: 200}, Item: "foo"},
+		{HTTPResponse: &http.Response{StatusCode: 302}, Item: "bar"},
+		{HTTPResponse: &http.Response{StatusCode: 200}, Hide: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var cancelled int
+			stop, err := NewStop(test.matches, test.status, func() {
+				cancelled++
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			in := make(chan Response)
+			go func() {
+				for _, res := range responses {
+					in <- res
+				}
+				close(in)
+			}()
+
+			var received int
+			for range stop.Run(in) {
+				received++
+			}
+
+			if received != len(responses) {
+				t.Errorf("wrong number of responses forwarded, want %d, got %d", len(responses), received)
+			}
+
+			if stop.Reason() != test.reason {
+				t.Errorf("wrong reason, want %q, got %q", test.reason, stop.Reason())
+			}
+
+			want := 0
+			if test.reason != "" {
+				want = 1
+			}
+			if cancelled != want {
+				t.Errorf("Cancel called %d times, want %d", cancelled, want)
+			}
+		})
+	}
+}
+
+func TestStopInvalid(t *testing.T) {
+	_, err := NewStop(-1, nil, func() {})
+	if err == nil {
+		t.Error("expected error for negative number of matches not found")
+	}
+
+	_, err = NewStop(0, []string{"foo"}, func() {})
+	if err == nil {
+		t.Error("expected error for invalid status not found")
+	}
+}
@@ -0,0 +1,104 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultThrottleMax is the default for the longest pause requested by a
+// Retry-After header which is honored.
+const DefaultThrottleMax = 5 * time.Minute
+
+// Throttle pauses all runners when the target responds with the status 429
+// (Too Many Requests) or 503 (Service Unavailable) and a Retry-After header,
+// it is shared by all runners.
+type Throttle struct {
+	Max time.Duration // pauses are limited to this duration
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+// NewThrottle returns a throttle which pauses for at most max.
+func NewThrottle(max time.Duration) *Throttle {
+	return &Throttle{Max: max}
+}
+
+// Wait blocks until the current pause is over.
+func (t *Throttle) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	d := time.Until(t.until)
+	t.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Observe starts or extends the pause when res requests it. It returns the
+// duration the pause was extended by, or zero.
+func (t *Throttle) Observe(res *http.Response) time.Duration {
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+
+	d, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+	if !ok || d <= 0 {
+		return 0
+	}
+
+	if d > t.Max {
+		d = t.Max
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if !until.After(t.until) {
+		return 0
+	}
+
+	extended := until.Sub(t.until)
+	if extended > d {
+		extended = d
+	}
+	t.until = until
+
+	return extended
+}
+
+// parseRetryAfter returns the duration requested by the value of a
+// Retry-After header, either in seconds or as an HTTP date.
+func parseRetryAfter(s string, now time.Time) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+
+	secs, err := strconv.ParseUint(s, 10, 32)
+	if err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	date, err := http.ParseTime(s)
+	if err != nil {
+		return 0, false
+	}
+
+	return date.Sub(now), true
+}
@@ -0,0 +1,135 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"Wed, 01 Apr 2020 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 Apr 2020 11:00:00 GMT", -time.Hour, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			d, ok := parseRetryAfter(test.value, now)
+			if ok != test.ok {
+				t.Fatalf("wrong result for %q, want %v, got %v", test.value, test.ok, ok)
+			}
+
+			if d != test.want {
+				t.Errorf("wrong duration for %q, want %v, got %v", test.value, test.want, d)
+			}
+		})
+	}
+}
+
+func TestThrottleObserve(t *testing.T) {
+	var tests = []struct {
+		status     int
+		retryAfter string
+		throttled  bool
+	}{
+		{http.StatusTooManyRequests, "1", true},
+		{http.StatusServiceUnavailable, "1", true},
+		{http.StatusTooManyRequests, "", false},
+		{http.StatusTooManyRequests, "0", false},
+		{http.StatusOK, "1", false},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			th := NewThrottle(100 * time.Millisecond)
+			res := &http.Response{StatusCode: test.status, Header: http.Header{}}
+			if test.retryAfter != "" {
+				res.Header.Set("Retry-After", test.retryAfter)
+			}
+
+			d := th.Observe(res)
+			if test.throttled && d != 100*time.Millisecond {
+				t.Errorf("pause not limited to the maximum, got %v", d)
+			}
+			if !test.throttled && d != 0 {
+				t.Errorf("unexpected pause %v", d)
+			}
+
+			// a second response does not extend the pause
+			if d = th.Observe(res); d > 10*time.Millisecond {
+				t.Errorf("pause extended by %v", d)
+			}
+		})
+	}
+}
+
+func TestRunnerThrottle(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	tmpl := request.New("")
+	tmpl.URL = srv.URL + "/FUZZ"
+
+	tr, err := NewTransport(tmpl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make(chan producer.Item, 2)
+	in <- producer.Item{Values: []string{"foo"}}
+	in <- producer.Item{Values: []string{"bar"}}
+	close(in)
+	out := make(chan Response, 2)
+
+	runner := NewRunner(tr, tmpl, in, out)
+	runner.Throttle = NewThrottle(200 * time.Millisecond)
+
+	start := time.Now()
+	runner.Run(context.Background())
+	close(out)
+
+	var statuses []int
+	var throttled time.Duration
+	for res := range out {
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		statuses = append(statuses, res.HTTPResponse.StatusCode)
+		throttled += res.Throttled
+	}
+
+	if len(statuses) != 2 || statuses[0] != http.StatusTooManyRequests || statuses[1] != http.StatusOK {
+		t.Errorf("wrong status codes %v", statuses)
+	}
+
+	if throttled != 200*time.Millisecond {
+		t.Errorf("wrong pause, want 200ms, got %v", throttled)
+	}
+
+	if time.Since(start) < 200*time.Millisecond {
+		t.Errorf("second request was not paused")
+	}
+}
@@ -0,0 +1,173 @@
+package response
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// tlsVersions maps the names of the TLS versions to the constants.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseTLSVersion returns the TLS version for the name, e.g. 1.2.
+func parseTLSVersion(name string) (uint16, error) {
+	v, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(name), "tls")]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q, use 1.0, 1.1, 1.2 or 1.3", name)
+	}
+
+	return v, nil
+}
+
+// parseCipherSuites returns the IDs of the cipher suites, including insecure
+// ones. TLS 1.3 suites are rejected, they cannot be configured.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	suites := make(map[string]*tls.CipherSuite)
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites[s.Name] = s
+	}
+
+	var ids []uint16
+	for _, name := range names {
+		s, ok := suites[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+
+		if len(s.SupportedVersions) == 1 && s.SupportedVersions[0] == tls.VersionTLS13 {
+			return nil, fmt.Errorf("cipher suite %v is only used for TLS 1.3 and cannot be selected", s.Name)
+		}
+
+		ids = append(ids, s.ID)
+	}
+
+	return ids, nil
+}
+
+// configureTLS sets the TLS versions, cipher suites, CA certificates and pinned
+// hashes of the template in cfg.
+func configureTLS(cfg *tls.Config, template *request.Request) error {
+	if template.TLSMinVersion != "" {
+		v, err := parseTLSVersion(template.TLSMinVersion)
+		if err != nil {
+			return err
+		}
+		cfg.MinVersion = v
+	}
+
+	if template.TLSMaxVersion != "" {
+		v, err := parseTLSVersion(template.TLSMaxVersion)
+		if err != nil {
+			return err
+		}
+		cfg.MaxVersion = v
+	}
+
+	if cfg.MinVersion != 0 && cfg.MaxVersion != 0 && cfg.MinVersion > cfg.MaxVersion {
+		return fmt.Errorf("minimum TLS version %v is higher than the maximum version %v", template.TLSMinVersion, template.TLSMaxVersion)
+	}
+
+	if template.CACert != "" {
+		buf, err := ioutil.ReadFile(template.CACert)
+		if err != nil {
+			return err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return fmt.Errorf("no CA certificates found in %v", template.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(template.PinSHA256) > 0 {
+		pins, err := parsePins(template.PinSHA256)
+		if err != nil {
+			return err
+		}
+		cfg.VerifyPeerCertificate = pins.verify
+	}
+
+	if len(template.TLSCiphers) > 0 {
+		ids, err := parseCipherSuites(template.TLSCiphers)
+		if err != nil {
+			return err
+		}
+		cfg.CipherSuites = ids
+	}
+
+	return nil
+}
+
+// pins are the accepted SHA-256 hashes of public keys (SubjectPublicKeyInfo)
+// and certificates.
+type pins struct {
+	keys  map[[sha256.Size]byte]bool
+	certs map[[sha256.Size]byte]bool
+}
+
+// parsePins parses the hashes, either base64 encoded hashes of a public key
+// (optionally prefixed with sha256//) or hex encoded hashes of a certificate
+// (optionally separated by colons).
+func parsePins(hashes []string) (pins, error) {
+	p := pins{
+		keys:  make(map[[sha256.Size]byte]bool),
+		certs: make(map[[sha256.Size]byte]bool),
+	}
+
+	for _, hash := range hashes {
+		var sum [sha256.Size]byte
+
+		buf, err := hex.DecodeString(strings.Replace(hash, ":", "", -1))
+		if err == nil && len(buf) == sha256.Size {
+			copy(sum[:], buf)
+			p.certs[sum] = true
+			continue
+		}
+
+		buf, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(hash, "sha256//"))
+		if err == nil && len(buf) == sha256.Size {
+			copy(sum[:], buf)
+			p.keys[sum] = true
+			continue
+		}
+
+		return pins{}, fmt.Errorf("invalid SHA-256 hash %q, expected base64 (public key) or hex (certificate)", hash)
+	}
+
+	return p, nil
+}
+
+// verify returns an error unless one of the certificates presented by the
+// server matches a pin.
+func (p pins) verify(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	for _, raw := range rawCerts {
+		if p.certs[sha256.Sum256(raw)] {
+			return nil
+		}
+
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			continue
+		}
+
+		if p.keys[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+			return nil
+		}
+	}
+
+	return errors.New("server certificate does not match any pinned hash")
+}
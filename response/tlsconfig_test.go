@@ -0,0 +1,146 @@
+package response
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// versionName returns the name of the TLS version v, e.g. "TLS 1.2".
+func versionName(v uint16) string {
+	for name, version := range tlsVersions {
+		if version == v {
+			return "TLS " + name
+		}
+	}
+	return fmt.Sprintf("0x%04X", v)
+}
+
+func TestConfigureTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%v %v", versionName(r.TLS.Version), tls.CipherSuiteName(r.TLS.CipherSuite))
+	}))
+	srv.TLS = &tls.Config{MinVersion: tls.VersionTLS10}
+	srv.StartTLS()
+	defer srv.Close()
+
+	var tests = []struct {
+		min, max string
+		ciphers  []string
+		want     string
+	}{
+		{"", "1.2", []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}, "TLS 1.2 TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+		{"1.1", "1.1", []string{"tls_ecdhe_rsa_with_aes_128_cbc_sha"}, "TLS 1.1 TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA"},
+		// the cipher suite for TLS 1.3 depends on the hardware
+		{"1.3", "", nil, "TLS 1.3 "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			tr, err := NewTransport(&request.Request{
+				Insecure:      true,
+				TLSMinVersion: test.min,
+				TLSMaxVersion: test.max,
+				TLSCiphers:    test.ciphers,
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := get(t, tr, srv.URL); !strings.HasPrefix(got, test.want) {
+				t.Errorf("wrong connection, want %q, got %q", test.want, got)
+			}
+		})
+	}
+
+	for _, req := range []*request.Request{
+		{TLSMinVersion: "1.4"},
+		{TLSMinVersion: "1.2", TLSMaxVersion: "1.0"},
+		{TLSCiphers: []string{"TLS_UNKNOWN"}},
+		{TLSCiphers: []string{"TLS_AES_128_GCM_SHA256"}},
+	} {
+		_, err := NewTransport(req, nil)
+		if err == nil {
+			t.Errorf("expected error for %+v not found", req)
+		}
+	}
+}
+
+func TestCACertAndPins(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	tempdir, err := ioutil.TempDir("", "monsoon-test-response-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	cert := srv.Certificate()
+	caFile := filepath.Join(tempdir, "ca.pem")
+	err = ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyHash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	certHash := sha256.Sum256(cert.Raw)
+	keyPin := base64.StdEncoding.EncodeToString(keyHash[:])
+	certPin := strings.ToUpper(hex.EncodeToString(certHash[:]))
+
+	var tests = []struct {
+		req  request.Request
+		fail bool
+	}{
+		{request.Request{}, true},
+		{request.Request{CACert: caFile}, false},
+		{request.Request{CACert: caFile, PinSHA256: []string{keyPin}}, false},
+		{request.Request{Insecure: true, PinSHA256: []string{"sha256//" + keyPin}}, false},
+		{request.Request{Insecure: true, PinSHA256: []string{certPin[:2] + ":" + certPin[2:]}}, false},
+		{request.Request{Insecure: true, PinSHA256: []string{strings.Repeat("00", 32), keyPin}}, false},
+		{request.Request{Insecure: true, PinSHA256: []string{strings.Repeat("00", 32)}}, true},
+		{request.Request{CACert: caFile, PinSHA256: []string{strings.Repeat("A", 43) + "="}}, true},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			tr, err := NewTransport(&test.req, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = (&http.Client{Transport: tr}).Get(srv.URL)
+			if test.fail && err == nil {
+				t.Fatal("expected error not found")
+			}
+			if !test.fail && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	for _, req := range []*request.Request{
+		{CACert: filepath.Join(tempdir, "missing.pem")},
+		{PinSHA256: []string{"invalid"}},
+		{PinSHA256: []string{"abcd"}},
+	} {
+		_, err = NewTransport(req, nil)
+		if err == nil {
+			t.Errorf("expected error for %+v not found", req)
+		}
+	}
+}
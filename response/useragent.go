@@ -0,0 +1,42 @@
+package response
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// UserAgents selects a User-Agent for each request from a list, either in
+// order (starting over at the end) or at random. It is safe for concurrent
+// use by several runners.
+type UserAgents struct {
+	list   []string
+	random bool
+
+	mu   sync.Mutex
+	next int
+	rnd  *rand.Rand
+}
+
+// NewUserAgents returns a selector for the user agents in list.
+func NewUserAgents(list []string, random bool) *UserAgents {
+	return &UserAgents{
+		list:   list,
+		random: random,
+		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Next returns the User-Agent for the next request.
+func (u *UserAgents) Next() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.random {
+		return u.list[u.rnd.Intn(len(u.list))]
+	}
+
+	ua := u.list[u.next]
+	u.next = (u.next + 1) % len(u.list)
+	return ua
+}
@@ -0,0 +1,24 @@
+package response
+
+import "testing"
+
+func TestUserAgents(t *testing.T) {
+	list := []string{"a", "b", "c"}
+
+	u := NewUserAgents(list, false)
+	for i := 0; i < 7; i++ {
+		if ua := u.Next(); ua != list[i%3] {
+			t.Errorf("request %d: want user agent %q, got %q", i, list[i%3], ua)
+		}
+	}
+
+	seen := make(map[string]bool)
+	u = NewUserAgents(list, true)
+	for i := 0; i < 100; i++ {
+		seen[u.Next()] = true
+	}
+
+	if len(seen) != len(list) {
+		t.Errorf("not all user agents were selected: %v", seen)
+	}
+}
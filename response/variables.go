@@ -0,0 +1,48 @@
+package response
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Built-in variables which are replaced in all requests in addition to the
+// placeholders, e.g. for cache busting.
+const (
+	VariableRandom = "%RANDOM%" // random string of eight lower case letters and digits
+	VariableDate   = "%DATE%"   // current time in UTC, e.g. 20200102T150405Z
+	VariableNumber = "%N%"      // position of the values in the sequence, starting at 1
+	VariableID     = "%ID%"     // unique ID of the request, 16 hex digits
+)
+
+const randomChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// Variables returns the names and values of the built-in variables for the
+// request with the index (starting at 0). The index is the position of the
+// values, so it is the same for retries and includes the values skipped with
+// --skip or on resume.
+func Variables(index int) (names, values []string, err error) {
+	buf := make([]byte, 16)
+	_, err = rand.Read(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	id := hex.EncodeToString(buf[8:])
+
+	random := buf[:8]
+	for i := range random {
+		random[i] = randomChars[int(random[i])%len(randomChars)]
+	}
+
+	names = []string{VariableRandom, VariableDate, VariableNumber, VariableID}
+	values = []string{
+		string(random),
+		time.Now().UTC().Format("20060102T150405Z"),
+		strconv.Itoa(index + 1),
+		id,
+	}
+
+	return names, values, nil
+}
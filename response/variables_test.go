@@ -0,0 +1,43 @@
+package response
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestVariables(t *testing.T) {
+	names, values, err := Variables(41)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]*regexp.Regexp{
+		VariableRandom: regexp.MustCompile(`^[a-z0-9]{8}$`),
+		VariableDate:   regexp.MustCompile(`^\d{8}T\d{6}Z$`),
+		VariableNumber: regexp.MustCompile(`^42$`),
+		VariableID:     regexp.MustCompile(`^[0-9a-f]{16}$`),
+	}
+
+	if len(names) != len(want) || len(values) != len(want) {
+		t.Fatalf("wrong number of variables: %v %v", names, values)
+	}
+
+	for i, name := range names {
+		if !want[name].MatchString(values[i]) {
+			t.Errorf("wrong value %q for %v", values[i], name)
+		}
+	}
+
+	_, other, err := Variables(41)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other[0] == values[0] {
+		t.Errorf("random string %q was returned twice", other[0])
+	}
+
+	if other[3] == values[3] {
+		t.Errorf("ID %q was returned twice", other[3])
+	}
+}
@@ -0,0 +1,362 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// WebSocket opcodes (RFC 6455, section 5.2).
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// wsGUID is appended to the key to compute the Sec-WebSocket-Accept header.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Default values for the WebSocket options of the runner.
+const (
+	DefaultWebSocketWait     = 2 * time.Second
+	DefaultWebSocketMessages = 1
+)
+
+// IsWebSocket returns true if req is to be sent via a WebSocket.
+func IsWebSocket(req *http.Request) bool {
+	return req.URL.Scheme == "ws" || req.URL.Scheme == "wss"
+}
+
+// wsAccept returns the value the server must send in the
+// Sec-WebSocket-Accept header for key.
+func wsAccept(key string) string {
+	hash := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// writeFrame sends a single masked frame, as required for clients.
+func writeFrame(wr io.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode, 0}
+
+	switch {
+	case len(payload) < 126:
+		header[1] = byte(len(payload))
+	case len(payload) <= 0xffff:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	default:
+		header[1] = 127
+		header = append(header, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(header[2:], uint64(len(payload)))
+	}
+	header[1] |= 0x80
+
+	mask := make([]byte, 4)
+	_, err := rand.Read(mask)
+	if err != nil {
+		return err
+	}
+	header = append(header, mask...)
+
+	buf := make([]byte, len(payload))
+	for i := range payload {
+		buf[i] = payload[i] ^ mask[i%4]
+	}
+
+	_, err = wr.Write(append(header, buf...))
+	return err
+}
+
+// readFrame reads a single frame. Payloads larger than maxSize are rejected.
+func readFrame(rd io.Reader, maxSize int) (fin bool, opcode byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	_, err = io.ReadFull(rd, header)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+
+	size := uint64(header[1] & 0x7f)
+	switch size {
+	case 126:
+		buf := make([]byte, 2)
+		_, err = io.ReadFull(rd, buf)
+		size = uint64(binary.BigEndian.Uint16(buf))
+	case 127:
+		buf := make([]byte, 8)
+		_, err = io.ReadFull(rd, buf)
+		size = binary.BigEndian.Uint64(buf)
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	if size > uint64(maxSize) {
+		return false, 0, nil, fmt.Errorf("WebSocket frame of %d bytes is too large", size)
+	}
+
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		_, err = io.ReadFull(rd, mask)
+		if err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload = make([]byte, size)
+	_, err = io.ReadFull(rd, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	for i := range mask {
+		for j := i; j < len(payload); j += 4 {
+			payload[j] ^= mask[i]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// cookieURL returns the URL used for the cookie jar, which only handles http
+// and https.
+func cookieURL(u *url.URL) *url.URL {
+	res := *u
+	res.Scheme = "http"
+	if u.Scheme == "wss" {
+		res.Scheme = "https"
+	}
+	return &res
+}
+
+// dialConn opens the connection to the target of req with the dial function
+// and TLS configuration of tr, TLS is used for https and wss URLs. If tr uses
+// a proxy for req, the connection is tunnelled through it.
+func dialConn(ctx context.Context, req *http.Request, tr *http.Transport) (net.Conn, error) {
+	host, port, err := request.Target(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// use the dial function of the transport, which may connect to a
+	// different address (see connectTo)
+	dial := (&net.Dialer{Timeout: 30 * time.Second}).DialContext
+	if tr != nil && tr.DialContext != nil {
+		dial = tr.DialContext
+	}
+
+	proxyURL, err := proxyFor(ctx, tr, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	if proxyURL != nil {
+		conn, err = dialProxy(ctx, dial, tr, proxyURL, net.JoinHostPort(host, port))
+	} else {
+		conn, err = dial(ctx, "tcp", net.JoinHostPort(host, port))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL.Scheme != "https" && req.URL.Scheme != "wss" {
+		return conn, nil
+	}
+
+	cfg := &tls.Config{}
+	if tr != nil && tr.TLSClientConfig != nil {
+		cfg = tr.TLSClientConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = removeZone(host)
+	}
+	cfg.NextProtos = []string{"http/1.1"}
+
+	hctx := ctx
+	if tr != nil && tr.TLSHandshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		hctx, cancel = context.WithTimeout(ctx, tr.TLSHandshakeTimeout)
+		defer cancel()
+	}
+
+	tlsConn, err := tlsHandshake(hctx, conn, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// webSocket upgrades the connection to a WebSocket and sends the body of req
+// as a text message. The messages received afterwards are returned as the
+// body of the response, separated by newlines. Reading stops when
+// WebSocketMessages messages have been received, no further message arrived
+// within WebSocketWait, or the server closed the connection. If the server
+// does not switch protocols, the response is returned as it is. The
+// connection is opened with the settings of tr.
+func (r *Runner) webSocket(ctx context.Context, req *http.Request, tr *http.Transport) (*http.Response, error) {
+	var message []byte
+	if req.Body != nil {
+		var err error
+		message, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := dialConn(ctx, req, tr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// close the connection when the context is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	key := make([]byte, 16)
+	_, err = rand.Read(key)
+	if err != nil {
+		return nil, err
+	}
+
+	hs := &http.Request{
+		Method:     http.MethodGet,
+		URL:        req.URL,
+		Header:     req.Header.Clone(),
+		Host:       req.Host,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}
+	hs.Header.Del("Content-Type")
+	hs.Header.Del("Content-Length")
+	hs.Header.Set("Upgrade", "websocket")
+	hs.Header.Set("Connection", "Upgrade")
+	hs.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+	hs.Header.Set("Sec-WebSocket-Version", "13")
+
+	jar := r.Client.Jar
+	if jar != nil {
+		for _, cookie := range jar.Cookies(cookieURL(req.URL)) {
+			hs.AddCookie(cookie)
+		}
+	}
+
+	err = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	err = hs.Write(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	rd := bufio.NewReader(conn)
+	res, err := http.ReadResponse(rd, hs)
+	if err != nil {
+		return nil, err
+	}
+
+	if jar != nil {
+		if cookies := res.Cookies(); len(cookies) > 0 {
+			jar.SetCookies(cookieURL(req.URL), cookies)
+		}
+	}
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		// read the body now, the connection is closed on return
+		buf, err := ioutil.ReadAll(io.LimitReader(res.Body, int64(r.BodyBufferSize)))
+		if err != nil {
+			return nil, err
+		}
+		res.Body = ioutil.NopCloser(bytes.NewReader(buf))
+		return res, nil
+	}
+
+	if res.Header.Get("Sec-WebSocket-Accept") != wsAccept(hs.Header.Get("Sec-WebSocket-Key")) {
+		return nil, errors.New("WebSocket handshake failed: invalid Sec-WebSocket-Accept header")
+	}
+
+	err = writeFrame(conn, wsText, message)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages [][]byte
+	var current []byte
+	for len(messages) < r.WebSocketMessages {
+		err = conn.SetDeadline(time.Now().Add(r.WebSocketWait))
+		if err != nil {
+			return nil, err
+		}
+
+		fin, opcode, payload, err := readFrame(rd, r.BodyBufferSize)
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			break
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsText, wsBinary, wsContinuation:
+			current = append(current, payload...)
+			if fin {
+				messages = append(messages, current)
+				current = nil
+			}
+		case wsPing:
+			err = writeFrame(conn, wsPong, payload)
+			if err != nil {
+				return nil, err
+			}
+		case wsClose:
+			_ = writeFrame(conn, wsClose, payload)
+			res.Body = ioutil.NopCloser(bytes.NewReader(bytes.Join(messages, []byte("\n"))))
+			return res, nil
+		}
+	}
+
+	// close the connection properly (status code 1000)
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+	_ = writeFrame(conn, wsClose, []byte{0x03, 0xe8})
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(bytes.Join(messages, []byte("\n"))))
+	return res, nil
+}
@@ -0,0 +1,100 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// webSocketHandler answers each text message with two messages, the second
+// one fragmented.
+func webSocketHandler(t testing.TB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/forbidden" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + wsAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		_ = rw.Flush()
+
+		_, opcode, payload, err := readFrame(rw, 1024)
+		if err != nil || opcode != wsText {
+			t.Errorf("reading message failed: %v (opcode %d)", err, opcode)
+			return
+		}
+
+		_, _ = conn.Write(append([]byte{0x81, byte(len(payload) + 6)}, append([]byte("hello "), payload...)...))
+		_, _ = conn.Write([]byte{0x01, 3, 'f', 'o', 'o'})
+		_, _ = conn.Write([]byte{0x80, 3, 'b', 'a', 'r'})
+		_, _ = conn.Write([]byte{0x81, 4, 'm', 'o', 'r', 'e'})
+
+		// wait for the close frame
+		_, _, _, _ = readFrame(rw, 1024)
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	srv := httptest.NewServer(webSocketHandler(t))
+	defer srv.Close()
+
+	var tests = []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/", 101, "hello admin\nfoobar"},
+		{"/forbidden", 403, "forbidden\n"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			tmpl := request.New("")
+			tmpl.URL = strings.Replace(srv.URL, "http://", "ws://", 1) + test.path
+			tmpl.Body = "FUZZ"
+
+			in := make(chan producer.Item, 1)
+			in <- producer.Item{Values: []string{"admin"}}
+			close(in)
+			out := make(chan Response, 1)
+
+			tr, err := NewTransport(&request.Request{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			runner := NewRunner(tr, tmpl, in, out)
+			runner.WebSocketMessages = 2
+			runner.WebSocketWait = time.Second
+			runner.Run(context.Background())
+
+			res := <-out
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if res.HTTPResponse.StatusCode != test.status {
+				t.Errorf("wrong status code, want %d, got %d", test.status, res.HTTPResponse.StatusCode)
+			}
+
+			if string(res.RawBody) != test.body {
+				t.Errorf("wrong body, want %q, got %q", test.body, res.RawBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// Package state saves the progress of a run so that it can be resumed later.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/producer"
+	"github.com/RedTeamPentesting/monsoon/response"
+)
+
+// State describes the progress of a run.
+type State struct {
+	Updated      time.Time `json:"updated"`
+	URL          string    `json:"url"`
+	TemplateHash string    `json:"template_hash"`
+	Sources      []string  `json:"sources"`
+	Skip         int       `json:"skip,omitempty"`
+	Limit        int       `json:"limit,omitempty"`
+	Filters      []string  `json:"filters,omitempty"`
+
+	// Position is the index of the first item which has not been processed
+	// yet, Done is the number of items processed before Position.
+	Position int `json:"position"`
+	Done     int `json:"done"`
+}
+
+// Load reads the state from a file.
+func Load(filename string) (State, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return State{}, err
+	}
+
+	var s State
+	err = json.Unmarshal(buf, &s)
+	if err != nil {
+		return State{}, err
+	}
+
+	return s, nil
+}
+
+// Save writes the state to a file. A temporary file is written first and then
+// renamed, so the previous state is not lost when the process is killed while
+// writing.
+func (s State) Save(filename string) error {
+	s.Updated = time.Now()
+
+	buf, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	buf = append(buf, '\n')
+
+	tempfile := filename + ".tmp"
+	err = ioutil.WriteFile(tempfile, buf, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempfile, filename)
+}
+
+// Tracker keeps track of the items sent to the runners and the responses
+// received, and computes the position from which a run can be resumed.
+type Tracker struct {
+	m         sync.Mutex
+	pending   []int // indexes of dispatched items, in order
+	completed map[int]struct{}
+	position  int
+	done      int
+
+	saved    int           // value of done at the last checkpoint
+	progress chan struct{} // signalled when items have been processed
+}
+
+// NewTracker returns a new tracker, starting at position with done items
+// already processed.
+func NewTracker(position, done int) *Tracker {
+	return &Tracker{
+		completed: make(map[int]struct{}),
+		position:  position,
+		done:      done,
+		saved:     done,
+		progress:  make(chan struct{}, 1),
+	}
+}
+
+// Position returns the index of the first item which has not been processed
+// yet, and the number of items processed before it.
+func (t *Tracker) Position() (position, done int) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if len(t.pending) > 0 {
+		return t.pending[0], t.done
+	}
+
+	return t.position, t.done
+}
+
+func (t *Tracker) dispatch(index int) {
+	t.m.Lock()
+	t.pending = append(t.pending, index)
+	t.m.Unlock()
+}
+
+func (t *Tracker) complete(index int) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.completed[index] = struct{}{}
+
+	done := t.done
+
+	// remove completed items from the front of the list
+	for len(t.pending) > 0 {
+		idx := t.pending[0]
+		if _, ok := t.completed[idx]; !ok {
+			break
+		}
+
+		delete(t.completed, idx)
+		t.pending = t.pending[1:]
+		t.position = idx + 1
+		t.done++
+	}
+
+	if t.done > done {
+		select {
+		case t.progress <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// unsaved returns the number of items processed since the last checkpoint.
+func (t *Tracker) unsaved() int {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return t.done - t.saved
+}
+
+// Dispatch records all items passed from in to the returned channel. A new
+// goroutine is started, which terminates when in is closed or the context is
+// cancelled.
+func (t *Tracker) Dispatch(ctx context.Context, in <-chan producer.Item) <-chan producer.Item {
+	out := make(chan producer.Item)
+
+	go func() {
+		defer close(out)
+		for item := range in {
+			t.dispatch(item.Index)
+
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// cancelled returns true if err means the request has been cancelled.
+func cancelled(err error) bool {
+	if err == context.Canceled {
+		return true
+	}
+
+	if e, ok := err.(*url.Error); ok && e.Err == context.Canceled {
+		return true
+	}
+
+	return false
+}
+
+// Complete records all responses passed from in to the returned channel.
+// Responses for cancelled requests are not recorded as completed. A new
+// goroutine is started, which terminates when in is closed.
+func (t *Tracker) Complete(in <-chan response.Response) <-chan response.Response {
+	out := make(chan response.Response)
+
+	go func() {
+		defer close(out)
+		for res := range in {
+			if !cancelled(res.Error) {
+				t.complete(res.Index)
+			}
+
+			out <- res
+		}
+	}()
+
+	return out
+}
+
+// Checkpoint saves s with the current position of t to filename every
+// interval and every time values more items have been processed, so that the
+// progress is not lost when the process crashes. A zero interval or values
+// disables the respective trigger. Errors are passed to onError. Checkpoint
+// returns when the context is cancelled.
+func (t *Tracker) Checkpoint(ctx context.Context, s State, filename string, interval time.Duration, values int, onError func(error)) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	save := func() {
+		s.Position, s.Done = t.Position()
+
+		t.m.Lock()
+		t.saved = s.Done
+		t.m.Unlock()
+
+		err := s.Save(filename)
+		if err != nil {
+			onError(err)
+		}
+	}
+
+	for {
+		select {
+		case <-tick:
+			save()
+		case <-t.progress:
+			if values > 0 && t.unsaved() >= values {
+				save()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
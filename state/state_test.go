@@ -0,0 +1,149 @@
+package state
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker(0, 0)
+
+	check := func(position, done int) {
+		t.Helper()
+		p, d := tr.Position()
+		if p != position || d != done {
+			t.Fatalf("wrong position, want %d/%d, got %d/%d", position, done, p, d)
+		}
+	}
+
+	check(0, 0)
+
+	// items 0-2 and 5-6 are dispatched, 3 and 4 are skipped
+	for _, idx := range []int{0, 1, 2, 5, 6} {
+		tr.dispatch(idx)
+	}
+	check(0, 0)
+
+	tr.complete(1)
+	check(0, 0)
+
+	tr.complete(0)
+	check(2, 2)
+
+	tr.complete(5)
+	check(2, 2)
+
+	tr.complete(2)
+	check(6, 4)
+
+	tr.complete(6)
+	check(7, 5)
+}
+
+func TestCheckpoint(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	filename := filepath.Join(tempdir, "run.state")
+
+	tr := NewTracker(0, 0)
+	for i := 0; i < 5; i++ {
+		tr.dispatch(i)
+	}
+	tr.complete(0)
+	tr.complete(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tr.Checkpoint(ctx, State{URL: "http://example.com/FUZZ"}, filename, 10*time.Millisecond, 0, func(err error) {
+			t.Error(err)
+		})
+		close(done)
+	}()
+
+	// wait for the first checkpoint
+	var s State
+	for i := 0; i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+		s, err = Load(filename)
+		if err == nil {
+			break
+		}
+	}
+
+	cancel()
+	<-done
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.URL != "http://example.com/FUZZ" || s.Position != 2 || s.Done != 2 {
+		t.Errorf("wrong state saved: %+v", s)
+	}
+}
+
+func TestCheckpointValues(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	filename := filepath.Join(tempdir, "run.state")
+
+	tr := NewTracker(0, 0)
+	for i := 0; i < 5; i++ {
+		tr.dispatch(i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tr.Checkpoint(ctx, State{URL: "http://example.com/FUZZ"}, filename, 0, 3, func(err error) {
+			t.Error(err)
+		})
+		close(done)
+	}()
+
+	// two values are not enough for a checkpoint
+	tr.complete(0)
+	tr.complete(1)
+	time.Sleep(50 * time.Millisecond)
+
+	_, err = Load(filename)
+	if !os.IsNotExist(err) {
+		t.Fatalf("state saved too early, error %v", err)
+	}
+
+	tr.complete(2)
+
+	// wait for the checkpoint
+	var s State
+	for i := 0; i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+		s, err = Load(filename)
+		if err == nil {
+			break
+		}
+	}
+
+	cancel()
+	<-done
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Position != 3 || s.Done != 3 {
+		t.Errorf("wrong state saved: %+v", s)
+	}
+}